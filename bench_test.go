@@ -122,6 +122,140 @@ func BenchmarkDecoderDecodeInterfaceAny(b *testing.B) {
 	}
 }
 
+func BenchmarkDecodeInterface(b *testing.B) {
+	data, err := io.ReadAll(fixture(b, "citm_catalog"))
+	check(b, err)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var v interface{}
+		check(b, NewDecoder(data).Decode(&v))
+	}
+}
+
+func BenchmarkDecodeStringMap(b *testing.B) {
+	data, err := io.ReadAll(fixture(b, "citm_catalog"))
+	check(b, err)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var m map[string]interface{}
+		check(b, NewDecoder(data).Decode(&m))
+	}
+}
+
+func BenchmarkDecodeTypedSlice(b *testing.B) {
+	// decoding into a named type takes the general, reflect based path.
+	type strings []string
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"item` + strconv.Itoa(i) + `"`)
+	}
+	buf.WriteByte(']')
+	data := buf.Bytes()
+
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var s []string
+			check(b, NewDecoder(data).Decode(&s))
+		}
+	})
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var s strings
+			check(b, NewDecoder(data).Decode(&s))
+		}
+	})
+}
+
+func BenchmarkDecodeInternKeys(b *testing.B) {
+	// log-like records sharing the same 15 keys.
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`{"ts": ` + strconv.Itoa(i) + `, "level": "info", "msg": "request", "host": "web1",` +
+			` "pid": 42, "method": "GET", "path": "/", "status": 200, "bytes": 512, "ms": 1.5,` +
+			` "ip": "10.0.0.1", "ua": "curl", "ref": null, "user": "u", "trace": "t"}`)
+	}
+	buf.WriteByte(']')
+	data := buf.Bytes()
+
+	for _, intern := range []bool{false, true} {
+		b.Run("intern="+strconv.FormatBool(intern), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				dec := NewDecoder(data)
+				if intern {
+					dec.InternKeys()
+				}
+				var v []map[string]interface{}
+				check(b, dec.Decode(&v))
+			}
+		})
+	}
+}
+
+func BenchmarkGetDecoder(b *testing.B) {
+	// a small request body decoded per request, as in an HTTP handler.
+	data := []byte(`{"id": 12345, "name": "widget", "tags": ["a", "b", "c"], "price": 9.99, "stock": true}`)
+	type request struct {
+		ID    int
+		Name  string
+		Tags  []string
+		Price float64
+		Stock bool
+	}
+	b.Run("NewDecoder", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				var v request
+				check(b, NewDecoder(data).Decode(&v))
+			}
+		})
+	})
+	b.Run("GetDecoder", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				var v request
+				d := GetDecoder(data)
+				check(b, d.Decode(&v))
+				PutDecoder(d)
+			}
+		})
+	})
+}
+
+func BenchmarkDrain(b *testing.B) {
+	data, err := io.ReadAll(fixture(b, "canada"))
+	check(b, err)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	d := NewDecoder(nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Reset(data)
+		check(b, d.Drain())
+	}
+}
+
 func BenchmarkDecoderDecodeMapInt(b *testing.B) {
 	in := `{"a": 97, "b": 98, "c": 99, "d": 100, "e": 101, "f": 102, "g": 103 }`
 	r := strings.NewReader(in)
@@ -155,6 +289,69 @@ func BenchmarkDecoderDecodeMapInt(b *testing.B) {
 	})
 }
 
+// twitterUser is the 20 most commonly used fields of a user in the twitter
+// fixture.
+type twitterUser struct {
+	ID                   int64  `json:"id"`
+	IDStr                string `json:"id_str"`
+	Name                 string `json:"name"`
+	ScreenName           string `json:"screen_name"`
+	Location             string `json:"location"`
+	Description          string `json:"description"`
+	Protected            bool   `json:"protected"`
+	FollowersCount       int    `json:"followers_count"`
+	FriendsCount         int    `json:"friends_count"`
+	ListedCount          int    `json:"listed_count"`
+	CreatedAt            string `json:"created_at"`
+	FavouritesCount      int    `json:"favourites_count"`
+	GeoEnabled           bool   `json:"geo_enabled"`
+	Verified             bool   `json:"verified"`
+	StatusesCount        int    `json:"statuses_count"`
+	Lang                 string `json:"lang"`
+	ProfileImageURL      string `json:"profile_image_url"`
+	ProfileImageURLHTTPS string `json:"profile_image_url_https"`
+	DefaultProfile       bool   `json:"default_profile"`
+	Following            bool   `json:"following"`
+}
+
+type twitterStatuses struct {
+	Statuses []struct {
+		User twitterUser `json:"user"`
+	} `json:"statuses"`
+}
+
+func BenchmarkDecoderDecodeStruct(b *testing.B) {
+	r := fixture(b, "twitter")
+	data, err := io.ReadAll(r)
+	if err != nil {
+		b.Fatalf("failed to read fixture: %v", err)
+	}
+	r.Seek(0, 0)
+	b.Run("pkgjson", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			dec := NewDecoder(data)
+			var v twitterStatuses
+			err := dec.Decode(&v)
+			check(b, err)
+		}
+	})
+	b.Run("encodingjson", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(r.Size())
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			r.Seek(0, 0)
+			dec := json.NewDecoder(r)
+			var v twitterStatuses
+			err := dec.Decode(&v)
+			check(b, err)
+		}
+	})
+}
+
 func BenchmarkDecoderToken(b *testing.B) {
 	for _, tc := range inputs {
 		r := fixture(b, tc.path)
@@ -278,3 +475,82 @@ func check(tb testing.TB, err error) {
 		tb.Fatal(err)
 	}
 }
+
+func BenchmarkCompact(b *testing.B) {
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(b, tc.path))
+		check(b, err)
+		b.Run("pkgjson/"+tc.path, func(b *testing.B) {
+			var buf bytes.Buffer
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				check(b, Compact(&buf, data))
+			}
+		})
+		b.Run("encodingjson/"+tc.path, func(b *testing.B) {
+			var buf bytes.Buffer
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				check(b, json.Compact(&buf, data))
+			}
+		})
+	}
+}
+
+// getPaths are spread through twitter.json, the last near its end.
+var getPaths = [][]string{
+	{"statuses", "0", "id"},
+	{"statuses", "25", "user", "name"},
+	{"statuses", "50", "text"},
+	{"statuses", "99", "user", "screen_name"},
+	{"search_metadata", "count"},
+}
+
+func BenchmarkGet(b *testing.B) {
+	data, err := io.ReadAll(fixture(b, "twitter"))
+	check(b, err)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range getPaths {
+			_, err := Get(data, path...)
+			check(b, err)
+		}
+	}
+}
+
+func BenchmarkGetMany(b *testing.B) {
+	data, err := io.ReadAll(fixture(b, "twitter"))
+	check(b, err)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := GetMany(data, getPaths...)
+		check(b, err)
+	}
+}
+
+func BenchmarkTranscode(b *testing.B) {
+	for _, tc := range inputs {
+		r := fixture(b, tc.path)
+		data, err := io.ReadAll(r)
+		check(b, err)
+		b.Run(tc.path, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			enc := NewEncoder(io.Discard)
+			d := NewDecoder(nil)
+			for i := 0; i < b.N; i++ {
+				d.Reset(data)
+				check(b, Transcode(enc, d))
+			}
+		})
+	}
+}
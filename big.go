@@ -0,0 +1,99 @@
+package json
+
+import (
+	"math/big"
+	"reflect"
+)
+
+// Values of the arbitrary-precision types of math/big are encoded as JSON
+// numbers, and decoded from them, without going through a float64, so that
+// no digits are lost either way.
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// isBig reports whether t is big.Int, big.Float or big.Rat.
+func isBig(t reflect.Type) bool {
+	return t == bigIntType || t == bigFloatType || t == bigRatType
+}
+
+// appendBig appends v, a big.Int, big.Float or big.Rat or a pointer to
+// one, to b as a JSON number. A nil pointer is null. An infinite big.Float,
+// and a big.Rat with no finite decimal representation, such as 1/3, are
+// reported as an *UnsupportedValueError.
+func appendBig(b []byte, v reflect.Value) ([]byte, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return append(b, "null"...), nil
+		}
+	} else if v.CanAddr() {
+		v = v.Addr()
+	} else {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p
+	}
+	switch x := v.Interface().(type) {
+	case *big.Int:
+		return x.Append(b, 10), nil
+	case *big.Float:
+		if x.IsInf() {
+			return b, &UnsupportedValueError{Value: v, Str: x.String()}
+		}
+		return x.Append(b, 'g', -1), nil
+	default:
+		r := x.(*big.Rat)
+		if r.IsInt() {
+			return r.Num().Append(b, 10), nil
+		}
+		n, ok := decimalPlaces(r.Denom())
+		if !ok {
+			return b, &UnsupportedValueError{Value: v, Str: r.String()}
+		}
+		return append(b, r.FloatString(n)...), nil
+	}
+}
+
+// decimalPlaces returns the number of decimal places needed to write a
+// fraction with the denominator d exactly, which is possible only if 2 and
+// 5 are its only prime factors.
+func decimalPlaces(d *big.Int) (int, bool) {
+	twos := d.TrailingZeroBits()
+	q := new(big.Int).Rsh(d, twos)
+	five, r := big.NewInt(5), new(big.Int)
+	fives := 0
+	for q.Cmp(big.NewInt(1)) != 0 {
+		if q.QuoRem(q, five, r); r.Sign() != 0 {
+			return 0, false
+		}
+		fives++
+	}
+	return max(int(twos), fives), true
+}
+
+// decodeBig decodes the number token tok into v, a big.Int, big.Float or
+// big.Rat, keeping all its digits. A big.Int only accepts integers written
+// without a fraction or exponent. A big.Float whose precision is 0, as for
+// a new one, gets enough precision to hold every digit of tok.
+func (d *Decoder) decodeBig(tok []byte, v reflect.Value) error {
+	s := bytesToString(tok)
+	ok := false
+	switch x := v.Addr().Interface().(type) {
+	case *big.Int:
+		_, ok = x.SetString(s, 10)
+	case *big.Float:
+		if x.Prec() == 0 {
+			x.SetPrec(max(64, 4*uint(len(tok))))
+		}
+		_, ok = x.SetString(s)
+	case *big.Rat:
+		_, ok = x.SetString(s)
+	}
+	if !ok {
+		return d.typeError("number "+s, v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package json
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+type bigAmounts struct {
+	Int   *big.Int   `json:"int"`
+	Float *big.Float `json:"float"`
+	Rat   *big.Rat   `json:"rat"`
+	Value big.Int    `json:"value"`
+}
+
+func TestBigRoundTrip(t *testing.T) {
+	tests := []string{
+		`{"int":1234567890123456789012345678901234567890,"float":123456789012345.678901234567890,"rat":0.125,"value":-9876543210987654321098765432109876543210}`,
+		`{"int":-1,"float":0.000000000000000000000000000001234567890123456789012345678901,"rat":-12345678901234567890.0000000001,"value":0}`,
+		`{"int":0,"float":1e+100,"rat":3,"value":18446744073709551616}`,
+		`{"int":null,"float":null,"rat":null,"value":1}`,
+	}
+	for _, in := range tests {
+		var v bigAmounts
+		if err := Unmarshal([]byte(in), &v); err != nil {
+			t.Errorf("Unmarshal(%s): %v", in, err)
+			continue
+		}
+		b, err := Marshal(v)
+		if err != nil {
+			t.Errorf("Marshal(%s): %v", in, err)
+			continue
+		}
+		if ok, err := Equal(b, []byte(in)); err != nil || !ok {
+			t.Errorf("round trip: got %s, want %s", b, in)
+		}
+	}
+
+	var v bigAmounts
+	check(t, Unmarshal([]byte(tests[0]), &v))
+	if got := v.Int.String(); got != "1234567890123456789012345678901234567890" {
+		t.Errorf("int: got %s", got)
+	}
+	if got := v.Float.Text('f', 15); got != "123456789012345.678901234567890" {
+		t.Errorf("float: got %s, want all 30 significant digits", got)
+	}
+	if got := v.Rat.String(); got != "1/8" {
+		t.Errorf("rat: got %s, want 1/8", got)
+	}
+
+	// A big.Float with a precision set keeps it.
+	f := new(big.Float).SetPrec(24)
+	check(t, Unmarshal([]byte(`0.1`), f))
+	if f.Prec() != 24 {
+		t.Errorf("got precision %d, want 24", f.Prec())
+	}
+}
+
+func TestBigErrors(t *testing.T) {
+	for _, tc := range []struct{ in, err string }{
+		{`{"int": 1.5}`, "json: cannot decode number 1.5 into Go struct field bigAmounts.int of type big.Int"},
+		{`{"int": 1e3}`, "json: cannot decode number 1e3 into Go struct field bigAmounts.int of type big.Int"},
+		{`{"int": "1"}`, "json: cannot decode string into Go struct field bigAmounts.int of type big.Int"},
+		{`{"float": {}}`, "json: cannot decode object into Go struct field bigAmounts.float of type big.Float"},
+		{`{"rat": true}`, "json: cannot decode bool into Go struct field bigAmounts.rat of type big.Rat"},
+	} {
+		err := Unmarshal([]byte(tc.in), new(bigAmounts))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Unmarshal(%s): got %v, want %s", tc.in, err, tc.err)
+		}
+	}
+
+	for _, v := range []interface{}{
+		big.NewRat(1, 3),
+		new(big.Float).SetInf(false),
+	} {
+		if b, err := Marshal(v); err == nil {
+			t.Errorf("Marshal(%v): got %s, want error", v, b)
+		} else if _, ok := err.(*UnsupportedValueError); !ok {
+			t.Errorf("Marshal(%v): got %T, want *UnsupportedValueError", v, err)
+		}
+	}
+	for _, tc := range []struct {
+		v    interface{}
+		want string
+	}{
+		{big.NewRat(7, 40), "0.175"},
+		{big.NewRat(-1, 1024), "-0.0009765625"},
+		{*big.NewInt(42), "42"},
+		{[]*big.Int{nil, big.NewInt(-7)}, "[null,-7]"},
+		{map[string]big.Float{"a": *big.NewFloat(2.5)}, `{"a":2.5}`},
+	} {
+		if b, err := Marshal(tc.v); err != nil || string(b) != tc.want {
+			t.Errorf("Marshal(%v): got %s, %v, want %s", tc.v, b, err, tc.want)
+		}
+	}
+}
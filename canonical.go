@@ -0,0 +1,189 @@
+package json
+
+import (
+	"io"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Canonicalize appends to dst the canonical form of the single JSON value in
+// src, as defined by RFC 8785, the JSON Canonicalization Scheme: without
+// whitespace, with object members sorted by the UTF-16 code units of their
+// keys, numbers written as ECMAScript writes them, and strings with only
+// the escapes JSON requires. src must be valid I-JSON: strings must be
+// valid UTF-8 without unpaired surrogate escapes, numbers must be within the
+// range of a float64, and an object holding the same key twice is an error,
+// as it would make a signature over the output ambiguous. On error the
+// contents of the returned buffer beyond len(dst) are unspecified.
+func Canonicalize(dst, src []byte) ([]byte, error) {
+	d := GetDecoder(src, ValidateUTF8(), (*Decoder).DisallowDuplicateKeys, (*Decoder).DisallowTrailingData)
+	defer PutDecoder(d)
+	c := canonicalizer{d: d}
+	dst, err := c.value(dst)
+	if err == nil {
+		if _, err = d.NextToken(); err == io.EOF {
+			return dst, nil
+		}
+	}
+	return dst, endOfInput(src, err)
+}
+
+// canonicalizer holds the state of a call to Canonicalize.
+type canonicalizer struct {
+	d *Decoder
+	// the members of the objects being written, in input order, used as a
+	// stack by nested objects.
+	members []canonicalMember
+	tmp     []byte // the members of an object being sorted
+}
+
+// canonicalMember is an object member written by the canonicalizer, at
+// [start, end) of the output, before the members are sorted.
+type canonicalMember struct {
+	key        string
+	start, end int
+}
+
+// value appends the canonical form of the next value to b.
+func (c *canonicalizer) value(b []byte) ([]byte, error) {
+	tok, err := c.d.NextToken()
+	if err != nil {
+		return b, err
+	}
+	switch tok[0] {
+	case ObjectStart:
+		return c.object(b)
+	case ArrayStart:
+		b = append(b, ArrayStart)
+		for i := 0; c.d.PeekKind() != KindArrayEnd; i++ {
+			if i > 0 {
+				b = append(b, Comma)
+			}
+			if b, err = c.value(b); err != nil {
+				return b, err
+			}
+		}
+		if _, err := c.d.NextToken(); err != nil {
+			return b, err
+		}
+		return append(b, ArrayEnd), nil
+	case String:
+		s, err := c.d.unquoteBytes(tok)
+		if err != nil {
+			return b, err
+		}
+		return appendCanonicalString(b, s), nil
+	case True, False, Null:
+		return append(b, tok...), nil
+	}
+	f, err := strconv.ParseFloat(bytesToString(tok), 64)
+	if err != nil {
+		return b, c.d.scanner.syntaxError(c.d.scanner.start, "number "+string(tok)+" out of range")
+	}
+	if f == 0 {
+		// including -0.
+		return append(b, '0'), nil
+	}
+	return AppendFloat(b, f, 64)
+}
+
+// object appends the canonical form of the object just opened to b. The
+// members are written in input order, then sorted in place.
+func (c *canonicalizer) object(b []byte) ([]byte, error) {
+	b = append(b, ObjectStart)
+	base, n := len(b), len(c.members)
+	defer func() { c.members = c.members[:n] }()
+	for c.d.PeekKind() != KindObjectEnd {
+		key, err := c.d.ReadStringBytes()
+		if err != nil {
+			return b, err
+		}
+		m := canonicalMember{key: string(key), start: len(b)}
+		b = appendCanonicalString(b, key)
+		b = append(b, Colon)
+		if b, err = c.value(b); err != nil {
+			return b, err
+		}
+		m.end = len(b)
+		c.members = append(c.members, m)
+	}
+	if _, err := c.d.NextToken(); err != nil {
+		return b, err
+	}
+
+	members := c.members[n:]
+	slices.SortFunc(members, func(x, y canonicalMember) int {
+		return compareUTF16(x.key, y.key)
+	})
+	c.tmp = append(c.tmp[:0], b[base:]...)
+	b = b[:base]
+	for i, m := range members {
+		if i > 0 {
+			b = append(b, Comma)
+		}
+		b = append(b, c.tmp[m.start-base:m.end-base]...)
+	}
+	return append(b, ObjectEnd), nil
+}
+
+// compareUTF16 compares a and b, which are valid UTF-8, by the UTF-16 code
+// units of their encodings. This is the order of their code points, except
+// that those above U+FFFF, encoded as surrogates, sort before U+E000 to
+// U+FFFF.
+func compareUTF16(a, b string) int {
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			if ka, kb := utf16Key(ra), utf16Key(rb); ka != kb {
+				return int(ka - kb)
+			}
+			// both above U+FFFF, with the same high surrogate.
+			return int(ra - rb)
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return len(a) - len(b)
+}
+
+// utf16Key returns the first UTF-16 code unit of r.
+func utf16Key(r rune) rune {
+	if r > 0xFFFF {
+		return 0xD800 + (r-0x10000)>>10
+	}
+	return r
+}
+
+// appendCanonicalString appends s, which is valid UTF-8, to b as a quoted
+// string with only the escapes RFC 8785 requires: quotes, backslashes and
+// control characters, with the short forms for those that have one.
+func appendCanonicalString(b, s []byte) []byte {
+	b = append(b, '"')
+	start := 0
+	for i, c := range s {
+		if c >= ' ' && c != '"' && c != '\\' {
+			continue
+		}
+		b = append(b, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		}
+		start = i + 1
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
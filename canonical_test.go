@@ -0,0 +1,119 @@
+package json
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// RFC 8785, section 3.2.2.
+		{`{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		// RFC 8785, section 3.2.3.
+		{`{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\"," +
+			"\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"},
+		{` [ ] `, `[]`},
+		{`{}`, `{}`},
+		{`{"b": {"d": 1, "c": [{"f": 1, "e": 2}]}, "a": null}`, `{"a":null,"b":{"c":[{"e":2,"f":1}],"d":1}}`},
+		{`{"a": 1, "a\u0000": 2, "": 3}`, `{"":3,"a":1,"a\u0000":2}`},
+		{`" <\b\f\t\u001f\u007f"`, "\" <\\b\\f\\t\\u001f\x7f\""},
+		{`-0.0`, `0`},
+		{`1e-400`, `0`},
+		{`100E-2`, `1`},
+		{`"😀" `, `"😀"`},
+		{`{"𐀀": 1, "￿": 2}`, `{"` + "\U00010000" + `":1,"` + "￿" + `":2}`},
+		{`{"😁": 1, "😀": 2}`, `{"😀":2,"😁":1}`},
+	}
+	for _, tc := range tests {
+		got, err := Canonicalize([]byte("prefix"), []byte(tc.in))
+		if err != nil || string(got) != "prefix"+tc.want {
+			t.Errorf("Canonicalize(%s): got %s, %v, want %s", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestCanonicalizeNumbers(t *testing.T) {
+	// RFC 8785, appendix B.
+	tests := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for _, tc := range tests {
+		in := strconv.FormatFloat(math.Float64frombits(tc.bits), 'g', -1, 64)
+		got, err := Canonicalize(nil, []byte(in))
+		if err != nil || string(got) != tc.want {
+			t.Errorf("Canonicalize(%s) for %#016x: got %s, %v, want %s", in, tc.bits, got, err, tc.want)
+		}
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	tests := []struct {
+		in, err string
+	}{
+		{`{"a": 1, "a": 2}`, `duplicate key "a"`},
+		{`{"a": {"b": 1, "b": 2}}`, `duplicate key`},
+		{`{"a": 1, "\u0061": 2}`, `duplicate key "a"`},
+		{`{"\/": 1, "/": 2}`, `duplicate key "/"`},
+		{`{"\u00e9": 1, "\u00E9": 2}`, `duplicate key "é"`},
+		{`1e400`, `number 1e400 out of range`},
+		{`[-1e309]`, `out of range`},
+		{`"\ud800"`, `unpaired surrogate`},
+		{"\"\xff\"", `invalid UTF-8`},
+		{`[1, 2`, `unexpected end of JSON input`},
+		{`[1] [2]`, `after top-level value`},
+		{``, `unexpected end of JSON input`},
+	}
+	for _, tc := range tests {
+		_, err := Canonicalize(nil, []byte(tc.in))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Canonicalize(%s): got %v, want error %q", tc.in, err, tc.err)
+		}
+	}
+	var serr *SyntaxError
+	if _, err := Canonicalize(nil, []byte(`{"a" 1}`)); !errors.As(err, &serr) {
+		t.Errorf("Canonicalize: got %v, want *SyntaxError", err)
+	}
+}
@@ -1,56 +1,520 @@
-// Package json decodes JSON.
+// Package json decodes and encodes JSON.
 package json
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 	"unsafe"
 )
 
+// Unmarshal parses the JSON-encoded data and stores the result in the value
+// pointed to by v. data must hold exactly one JSON value, optionally
+// surrounded by whitespace and preceded by a UTF-8 byte order mark.
+func Unmarshal(data []byte, v interface{}) error {
+	d := NewDecoder(data)
+	d.DisallowTrailingData()
+	err := d.Decode(v)
+	if err == io.EOF {
+		// no value at all.
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Token, Delim and Number are the types of the same names in encoding/json,
+// so that code type switching on the result of Token works with either
+// package, and values can be passed between them.
+type (
+	Token  = json.Token
+	Delim  = json.Delim
+	Number = json.Number
+)
+
+// A TokenDecoder is a token-level JSON decoder: both *Decoder and the
+// Decoder of encoding/json implement it, so code written against the
+// streaming API of encoding/json can be handed either. Its Token method
+// returns Delim values for brackets, as encoding/json's does. The tokens it
+// produces can be written out again with Encoder.WriteToken.
+type TokenDecoder interface {
+	Token() (Token, error)
+	More() bool
+	Decode(v interface{}) error
+	InputOffset() int64
+}
+
+var (
+	_ TokenDecoder = (*Decoder)(nil)
+	_ TokenDecoder = (*json.Decoder)(nil)
+)
+
+var (
+	numberType   = reflect.TypeOf(Number(""))
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	rawType      = reflect.TypeOf(json.RawMessage(nil))
+)
+
 // A Decoder decodes JSON values from an input stream.
+//
+// Strings with escapes are unescaped into a buffer which the Decoder reuses,
+// so a []byte returned by one of its methods, such as NextToken or
+// ReadStringBytes, is only valid until the next call to a method of the
+// Decoder. Strings returned or stored by Decode and Token are copies.
 type Decoder struct {
 	scanner Scanner
 	state   func(*Decoder) ([]byte, error)
 	stack
+	values int // number of top-level values begun
+
+	// initial backing arrays of stack and scanner.brackets, so that
+	// documents nested up to 32 levels deep are decoded without allocating.
+	stackBuf   [32]frame
+	bracketBuf [32]byte
+
+	buf     []byte // input read by ResetReader, retained across resets
+	reading bool   // the input is read by ResetReader, into scanner.data
+	scratch []byte // unescaped string contents, reused across reads and resets
+
+	maxDepth              int   // maximum nesting depth, or 0 for no limit
+	maxStringLen          int   // maximum length of a string token's contents, or 0 for no limit
+	maxValueBytes         int   // maximum size of a value returned by NextAsBytes or Skip, or 0 for no limit
+	maxInputBytes         int64 // maximum size of the input, or 0 for no limit
+	skipOverLimit         bool
+	disallowTrailingData  bool
+	allowTrailingCommas   bool
+	allowLoneSurrogates   bool
+	disallowUnknownFields bool
+	disallowDuplicateKeys bool
+	matchCaseSensitive    bool
+	useNumber             bool
+	durationStrings       bool
+	lenient               bool
+	orderedMaps           bool
+
+	// keys of the open objects, while duplicate keys are disallowed. The
+	// map and the slice are reused from object to object.
+	seenKeys map[objectKey]struct{}
+	keyStack []objectKey
+
+	// copies of the current key tokens of the open objects, by depth, made
+	// for Path before Skip or CopyValue discards the input holding them.
+	savedKeys []savedKey
+
+	// strings of decoded object keys, by contents, while keys are interned.
+	keys map[string]string
+
+	// functions registered with RegisterDecodeFunc, overriding the
+	// package-level ones.
+	decodeFuncs map[reflect.Type]DecodeFunc
 }
 
-// NewDecoder returns a new Decoder for the supplied Reader r.
-func NewDecoder(buf []byte) *Decoder {
-	return &Decoder{
+// DefaultMaxDepth is the maximum nesting depth of arrays and objects a new
+// Decoder accepts.
+const DefaultMaxDepth = 10000
+
+// An Option configures a Decoder when it is created, or the decoder used
+// internally by one of the package-level functions that accept options.
+type Option func(*Decoder)
+
+// AllowComments returns an Option which calls Decoder.AllowComments.
+func AllowComments() Option { return (*Decoder).AllowComments }
+
+// AllowRelaxedStrings returns an Option which calls
+// Decoder.AllowRelaxedStrings.
+func AllowRelaxedStrings() Option { return (*Decoder).AllowRelaxedStrings }
+
+// AllowNaNInf returns an Option which calls Decoder.AllowNaNInf.
+func AllowNaNInf() Option { return (*Decoder).AllowNaNInf }
+
+// AllowTrailingCommas returns an Option which calls
+// Decoder.AllowTrailingCommas.
+func AllowTrailingCommas() Option { return (*Decoder).AllowTrailingCommas }
+
+// AllowLoneSurrogates returns an Option which calls
+// Decoder.AllowLoneSurrogates.
+func AllowLoneSurrogates() Option { return (*Decoder).AllowLoneSurrogates }
+
+// AllowDurationStrings returns an Option which calls
+// Decoder.AllowDurationStrings.
+func AllowDurationStrings() Option { return (*Decoder).AllowDurationStrings }
+
+// UseNumber returns an Option which calls Decoder.UseNumber.
+func UseNumber() Option { return (*Decoder).UseNumber }
+
+// Lenient returns an Option which calls Decoder.Lenient.
+func Lenient() Option { return (*Decoder).Lenient }
+
+// InternKeys returns an Option which calls Decoder.InternKeys.
+func InternKeys() Option { return (*Decoder).InternKeys }
+
+// ValidateUTF8 returns an Option which calls Decoder.ValidateUTF8.
+func ValidateUTF8() Option { return (*Decoder).ValidateUTF8 }
+
+// ReplaceInvalidUTF8 returns an Option which calls
+// Decoder.ReplaceInvalidUTF8.
+func ReplaceInvalidUTF8() Option { return (*Decoder).ReplaceInvalidUTF8 }
+
+// NewDecoder returns a new Decoder reading buf, configured by opts. A UTF-8
+// byte order mark at the start of buf is skipped, but offsets in errors and
+// from InputOffset remain relative to buf.
+func NewDecoder(buf []byte, opts ...Option) *Decoder {
+	n := bomLen(buf)
+	d := &Decoder{
 		scanner: Scanner{
-			data: buf,
+			data:   buf,
+			offset: n,
+			start:  n,
 		},
-		state: (*Decoder).stateValue,
+		state:    (*Decoder).stateValue,
+		maxDepth: DefaultMaxDepth,
 	}
+	d.stack = d.stackBuf[:0]
+	d.scanner.brackets = d.bracketBuf[:0]
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// NewDecoderString is like NewDecoder, but reads s directly rather than a
+// copy of it. The Decoder never writes to its input, but the []byte results
+// of methods such as NextToken, NextAsBytes and ReadStringBytes may refer to
+// s, and must not be modified.
+func NewDecoderString(s string, opts ...Option) *Decoder {
+	return NewDecoder(stringToBytes(s), opts...)
 }
 
-// Reset resets the Decoder to read from a new input stream.
+// Reset resets the Decoder to read from a new input stream. Any error and
+// parse state from the previous input is discarded. As with NewDecoder, a
+// leading UTF-8 byte order mark is skipped.
 func (d *Decoder) Reset(buf []byte) {
-	d.scanner.offset = 0
-	d.scanner.data = buf
+	d.buf, d.reading = d.readBuf(), false
+	d.scanner.reset(buf)
 	d.stack = d.stack[:0]
 	d.state = (*Decoder).stateValue
+	d.values = 0
+	clear(d.seenKeys)
+	d.keyStack = d.keyStack[:0]
+	d.savedKeys = d.savedKeys[:0]
+	d.limitInput()
+}
+
+// DisallowTrailingData causes the Decoder to return an error if anything
+// other than whitespace follows the first top-level value, rather than
+// reading the input as a stream of concatenated values.
+func (d *Decoder) DisallowTrailingData() { d.disallowTrailingData = true }
+
+// AllowComments causes the Decoder to treat // line comments and /* block */
+// comments as whitespace. By default, as in encoding/json, a comment is a
+// syntax error. An unterminated block comment is always an error. The raw
+// values returned by NextAsBytes and written by CopyValue keep any comments
+// inside them.
+func (d *Decoder) AllowComments() { d.scanner.flags |= scanComments }
+
+// AllowTrailingCommas causes the Decoder to accept a comma after the last
+// element of an array or the last member of an object, as in [1, 2,]. A
+// comma is still an error in an empty container or after another comma.
+func (d *Decoder) AllowTrailingCommas() { d.allowTrailingCommas = true }
+
+// AllowRelaxedStrings causes the Decoder to accept strings in single quotes,
+// in which \' is an escaped quote, and object keys written as bare
+// identifiers matching [A-Za-z_$][A-Za-z0-9_$]*, as in {key: 'value'}. They
+// are returned by NextToken as standard double-quoted string tokens, so the
+// rest of the Decoder treats them like any other string. The raw values
+// returned by NextAsBytes and written by CopyValue keep their original form.
+func (d *Decoder) AllowRelaxedStrings() { d.scanner.flags |= scanRelaxed }
+
+// AllowNaNInf causes the Decoder to accept the literals NaN, Infinity and
+// -Infinity, as written by Python's json module, as numbers. Decode stores
+// them in floating-point values, and in an interface{} as float64 values.
+// The Encoder rejects such values regardless.
+func (d *Decoder) AllowNaNInf() { d.scanner.flags |= scanNaNInf }
+
+// AllowLoneSurrogates causes the Decoder to decode a \u escape of a UTF-16
+// surrogate which is not part of a pair as U+FFFD, the Unicode replacement
+// character, as encoding/json does. By default such an escape is reported as
+// a *SyntaxError when the string is unescaped. Validate and Skip accept them
+// regardless, as the JSON grammar does.
+func (d *Decoder) AllowLoneSurrogates() { d.allowLoneSurrogates = true }
+
+// ValidateUTF8 causes the Decoder to report a string containing invalid
+// UTF-8 as a *SyntaxError at the offset of the first invalid byte. By
+// default the bytes of strings are passed through unchecked. Skip, and the
+// raw values returned by NextAsBytes, are not checked.
+func (d *Decoder) ValidateUTF8() { d.scanner.flags |= scanValidUTF8 }
+
+// ReplaceInvalidUTF8 causes the Decoder to replace each byte of a string
+// which is not part of a valid UTF-8 sequence with U+FFFD, the Unicode
+// replacement character, as encoding/json does. It has no effect if
+// ValidateUTF8 is also set. The raw values returned by NextAsBytes and
+// written by CopyValue are left unchanged.
+func (d *Decoder) ReplaceInvalidUTF8() { d.scanner.flags |= scanReplaceUTF8 }
+
+// DisallowUnknownFields causes Decode to return an error when the destination
+// is a struct and the input contains an object key which does not match any
+// non-ignored, exported field in the destination. The error names the key,
+// the struct type and the offset of the key in the input.
+func (d *Decoder) DisallowUnknownFields() { d.disallowUnknownFields = true }
+
+// MatchCaseSensitive causes Decode to match object keys to struct fields
+// exactly. By default, as in encoding/json, a key with no exact match is
+// matched to the first field whose name is equal under case-folding.
+func (d *Decoder) MatchCaseSensitive() { d.matchCaseSensitive = true }
+
+// UseNumber causes Token, and Decode into an interface{}, to return numbers
+// as a Number rather than a float64. The Encoder writes a Number as it is,
+// so numbers decoded this way are encoded again exactly as they were
+// written.
+func (d *Decoder) UseNumber() { d.useNumber = true }
+
+// AllowDurationStrings causes Decode to accept a string such as "1h30m", as
+// parsed by time.ParseDuration, for a time.Duration, as well as the number of
+// nanoseconds which the Encoder writes.
+func (d *Decoder) AllowDurationStrings() { d.durationStrings = true }
+
+// Lenient causes Decode to convert between strings, numbers and booleans
+// where the destination calls for it, as is common in data from weakly typed
+// languages: a string holding a JSON number is accepted for an integer or
+// floating-point value, a number is stored in a string as it is written,
+// and "true", "false", "1", "0", 1 and 0 are accepted for a bool. Values
+// that cannot be converted, such as "abc" for an int, are still an
+// *UnmarshalTypeError, and values decoded into an interface{} are not
+// converted.
+func (d *Decoder) Lenient() { d.lenient = true }
+
+// InternKeys causes Decode to reuse the string it created for an object key
+// when an equal key is decoded into a map with string keys or an interface{},
+// so that decoding many objects with the same keys allocates each key once.
+// Up to MaxInternedKeys distinct keys are kept; once that many are held,
+// further new keys are allocated as usual. Decoding into a struct does not
+// allocate keys either way. Reset keeps the interned keys; ClearInternedKeys
+// discards them.
+func (d *Decoder) InternKeys() {
+	if d.keys == nil {
+		d.keys = make(map[string]string)
+	}
+}
+
+// ClearInternedKeys discards the keys interned by InternKeys, so that they
+// can be garbage collected. Interning continues with an empty set of keys.
+func (d *Decoder) ClearInternedKeys() { clear(d.keys) }
+
+// MaxInternedKeys is the maximum number of distinct object keys a Decoder
+// interns.
+const MaxInternedKeys = 1024
+
+// DisallowDuplicateKeys causes NextToken, and so Decode, to return an error
+// when an object contains the same key twice, naming the key and the offset
+// of its second occurrence. Keys are compared once unescaped, so "a" and
+// "\u0061" are the same key. By default the last value for a key wins, as in
+// encoding/json. Keys inside values consumed by Skip are not checked.
+func (d *Decoder) DisallowDuplicateKeys() { d.disallowDuplicateKeys = true }
+
+// SetMaxDepth sets the maximum nesting depth of arrays and objects. Input
+// nested deeper makes NextToken, Decode and Skip return an error wrapping
+// ErrMaxDepthExceeded. The default is DefaultMaxDepth; n <= 0 removes the
+// limit, which lets untrusted input grow the Decoder's stack without bound.
+func (d *Decoder) SetMaxDepth(n int) { d.maxDepth = n }
+
+// SetMaxStringLen sets the maximum length, in bytes of raw input between the
+// quotes, of a string. A longer string, whether an object key or a value,
+// makes NextToken and Decode return a *LimitError instead of the token, so
+// it is never copied into a Go string. n <= 0 removes the limit, which is
+// the default.
+//
+// Reading from an io.Reader, the Decoder stops at a string as soon as more
+// than n bytes of it have been read, rather than buffer the rest of it: see
+// SetMaxValueBytes.
+func (d *Decoder) SetMaxStringLen(n int) {
+	d.maxStringLen = n
+	d.scanner.maxString = max(n, 0)
+}
+
+// SetMaxValueBytes sets the maximum size, in bytes of raw input, of a value
+// returned by NextAsBytes or consumed by Skip. A larger value makes them
+// return a *LimitError. n <= 0 removes the limit, which is the default.
+//
+// The limits apply alike to input given to NewDecoder or Reset and to input
+// read by ResetReader. Reading from an io.Reader, the Decoder stops at a
+// string or value as soon as it has read more of it than the limit allows,
+// so that an oversized one is never buffered in full. The *LimitError then
+// gives the size read so far rather than the full size, and is returned
+// from then on, as the input after the value is not read.
+func (d *Decoder) SetMaxValueBytes(n int) { d.maxValueBytes = n }
+
+// SetMaxInputBytes sets the maximum size, in bytes, of the input the Decoder
+// reads, so that a hostile client cannot make it parse an arbitrarily large
+// document. Once decoding, or skipping, needs to read past the first n bytes
+// of the input, the Decoder returns an error wrapping ErrInputTooLarge rather
+// than continuing. No more than n+1 bytes are read from the io.Reader given
+// to ResetReader. n <= 0 removes the limit, which is the default.
+//
+// The limit applies to the input the Decoder has when it is set and to that
+// of later calls to Reset and ResetReader, and should be set before
+// decoding begins.
+func (d *Decoder) SetMaxInputBytes(n int64) {
+	d.maxInputBytes = n
+	d.limitInput()
+}
+
+// limitInput truncates the input to the maximum input size, if it is longer,
+// and limits how much more of it is read.
+func (d *Decoder) limitInput() {
+	n := d.maxInputBytes
+	if d.scanner.r != nil {
+		d.scanner.size = int(max(n, 0))
+	}
+	if n > 0 && int64(d.scanner.end()) > n {
+		d.scanner.data = d.scanner.data[:max(int(n)-d.scanner.base, 0)]
+		d.scanner.truncated, d.scanner.r = true, nil
+	}
+}
+
+// SetSkipOverLimit specifies whether Skip may consume a value that exceeds
+// the maximum string length or value size. By default Skip reports such a
+// value as a *LimitError; with SetSkipOverLimit(true) it skips past it, so
+// an oversized member of an object can be ignored. Reading from an
+// io.Reader, Skip then discards the input of the value as it reads it, so
+// that the value is skipped in bounded memory however large it is; the
+// contents of its strings are not checked.
+func (d *Decoder) SetSkipOverLimit(on bool) { d.skipOverLimit = on }
+
+// ResetReader resets the Decoder to read from r, as Reset does for a []byte.
+// Nothing is read until a token is needed, and then r is read incrementally
+// into a buffer owned by the Decoder, which is retained and reused by later
+// calls to ResetReader. The input of top-level values already decoded is
+// discarded to make room, so that a long stream of values is decoded in
+// memory bounded by the size of the largest one. Offsets, in errors and
+// from InputOffset, remain relative to the whole of the input read from r.
+//
+// An error reading r is returned by the method which needed the input, in
+// place of io.ErrUnexpectedEOF or io.EOF; ResetReader itself returns nil.
+func (d *Decoder) ResetReader(r io.Reader) error {
+	d.Reset(nil)
+	d.scanner.data, d.scanner.r, d.reading = d.buf[:0], r, true
+	d.limitInput()
+	return nil
 }
 
-type stack []bool
+// readBuf returns the buffer of the input read by ResetReader, which the
+// scanner may have replaced with a larger one since.
+func (d *Decoder) readBuf() []byte {
+	if d.reading {
+		return d.scanner.data
+	}
+	return d.buf
+}
+
+// A frame is an array or object the Decoder is inside, with the position
+// within it of the most recently returned token.
+type frame struct {
+	obj    bool
+	index  int // index of the current array element, or -1 before the first
+	key    int // offset of the current object key token
+	keyEnd int // offset of the end of the key token, or 0 before the first key
+}
 
-func (s *stack) push(v bool) {
-	*s = append(*s, v)
+type stack []frame
+
+func (s *stack) push(obj bool) {
+	*s = append(*s, frame{obj: obj, index: -1})
 }
 
+// pop removes the innermost frame and reports whether the enclosing one is
+// an object.
 func (s *stack) pop() bool {
 	*s = (*s)[:len(*s)-1]
 	if len(*s) == 0 {
 		return false
 	}
-	return (*s)[len(*s)-1]
+	return (*s)[len(*s)-1].obj
 }
 
+// top returns the innermost frame.
+func (s *stack) top() *frame { return &(*s)[len(*s)-1] }
+
 func (s *stack) len() int { return len(*s) }
 
+// openContainer pushes an array or object onto the stack, unless that
+// would nest deeper than the maximum depth.
+func (d *Decoder) openContainer(obj bool) error {
+	if d.maxDepth > 0 && d.len() >= d.maxDepth {
+		return depthError(d.maxDepth, d.scanner.start)
+	}
+	d.push(obj)
+	return nil
+}
+
+// objectKey is an object key seen at a nesting depth, for detecting
+// duplicate keys. The name is unescaped, and refers to the input rather
+// than a copy of it if the key has no escapes.
+type objectKey struct {
+	depth int
+	name  string
+}
+
+// checkDuplicateKey returns an error if the key token tok, once unescaped,
+// has been seen before in the innermost object, and otherwise records it.
+func (d *Decoder) checkDuplicateKey(tok []byte) error {
+	name, err := d.unquoteBytes(tok)
+	if err != nil {
+		return err
+	}
+	k := objectKey{depth: d.len(), name: bytesToString(name)}
+	if _, dup := d.seenKeys[k]; dup {
+		return fmt.Errorf("json: duplicate key %q at offset %d", k.name, d.scanner.start)
+	}
+	if len(name) != len(tok)-2 || d.scanner.flags&(scanRelaxed|scanReplaceUTF8) != 0 || d.scanner.r != nil {
+		// the key was unescaped into the scratch buffer, or tok may be a
+		// rewritten key, which does not refer to the input, or the input is
+		// still being read, and may be moved to make room.
+		k.name = strings.Clone(k.name)
+	}
+	if d.seenKeys == nil {
+		d.seenKeys = make(map[objectKey]struct{})
+	}
+	d.seenKeys[k] = struct{}{}
+	d.keyStack = append(d.keyStack, k)
+	return nil
+}
+
+// forgetKeys drops the keys recorded for objects deeper than the current
+// nesting depth, which have been closed.
+func (d *Decoder) forgetKeys() {
+	n := len(d.keyStack)
+	for n > 0 && d.keyStack[n-1].depth > d.len() {
+		n--
+		delete(d.seenKeys, d.keyStack[n])
+	}
+	d.keyStack = d.keyStack[:n]
+}
+
+// closeContainer pops the innermost array or object off the stack and moves
+// the Decoder to the state expected after a complete value in the enclosing
+// container, or to the end state if the closed container was the top level.
+func (d *Decoder) closeContainer() {
+	inObj := d.pop()
+	if len(d.keyStack) > 0 {
+		d.forgetKeys()
+	}
+	switch {
+	case d.len() == 0:
+		d.state = (*Decoder).stateEnd
+	case inObj:
+		d.state = (*Decoder).stateObjectComma
+	case !inObj:
+		d.state = (*Decoder).stateArrayComma
+	}
+}
+
 // Token returns the next JSON token in the input stream.
 // At the end of the input stream, Token returns nil, io.EOF.
 //
@@ -59,30 +523,44 @@ func (s *stack) len() int { return len(*s) }
 // delimiter in the input, it will return an error.
 //
 // The input stream consists of basic JSON values—bool, string,
-// number, and null—along with delimiters [ ] { } of type json.Delim
+// number, and null—along with delimiters [ ] { } of type Delim
 // to mark the start and end of arrays and objects.
-// Commas and colons are elided.
+// Commas and colons are elided. Strings are unescaped, and numbers are
+// float64, or Number if UseNumber has been called.
 //
 // Note: this API is provided for compatibility with the encoding/json
 // package and carries a significant allocation cost. See NextToken for
 // a more efficient API.
-func (d *Decoder) Token() (json.Token, error) {
+func (d *Decoder) Token() (Token, error) {
 	tok, err := d.NextToken()
 	if err != nil {
 		return nil, err
 	}
 	switch tok[0] {
 	case '{', '[', ']', '}':
-		return json.Delim(tok[0]), nil
+		return Delim(tok[0]), nil
 	case 't', 'f':
 		return tok[0] == 't', nil
 	case 'n':
 		return nil, nil
 	case '"':
-		return string(tok[1 : len(tok)-1]), nil
+		return d.unquote(tok)
 	default:
-		return strconv.ParseFloat(bytesToString(tok), 64)
+		return d.number(tok)
+	}
+}
+
+// number converts the number token tok to a float64, or a Number if
+// UseNumber has been called.
+func (d *Decoder) number(tok []byte) (interface{}, error) {
+	if d.useNumber {
+		return Number(tok), nil
 	}
+	f, err := strconv.ParseFloat(bytesToString(tok), 64)
+	if err != nil {
+		return nil, d.typeError("number "+string(tok), reflect.TypeOf(f))
+	}
+	return f, nil
 }
 
 // NextToken returns a []byte referencing the next logical token in the stream.
@@ -106,63 +584,123 @@ func (d *Decoder) Token() (json.Token, error) {
 //	-, 0-9 A number
 //
 // Commas and colons are elided.
+//
+// If a maximum string length is set, a longer string token is reported as a
+// *LimitError.
 func (d *Decoder) NextToken() ([]byte, error) {
-	return d.state(d)
+	tok, err := d.state(d)
+	if err == nil && d.maxStringLen > 0 && tok[0] == String {
+		if err := d.checkStringLen(tok); err != nil {
+			return nil, err
+		}
+	}
+	return tok, err
+}
+
+// checkStringLen returns a *LimitError if the contents of the string token
+// tok, most recently returned by the scanner, exceed the maximum length.
+func (d *Decoder) checkStringLen(tok []byte) error {
+	if n := len(tok) - 2; n > d.maxStringLen {
+		return &LimitError{What: "string", Len: n, Limit: d.maxStringLen, Offset: int64(d.scanner.start)}
+	}
+	return nil
+}
+
+// checkValueBytes returns a *LimitError if the value spanning [start, end)
+// of the input exceeds the maximum size.
+func (d *Decoder) checkValueBytes(start, end int) error {
+	if d.maxValueBytes > 0 && end-start > d.maxValueBytes {
+		return &LimitError{What: "value", Len: end - start, Limit: d.maxValueBytes, Offset: int64(start)}
+	}
+	return nil
+}
+
+// More reports whether there is another element in the current array or
+// object being parsed. At the top level More reports whether any value
+// remains before the end of the input. More does not consume any tokens.
+func (d *Decoder) More() bool {
+	c := d.peek()
+	return c != 0 && c != ArrayEnd && c != ObjectEnd
+}
+
+// PeekKind returns the Kind of the next token NextToken would return,
+// without consuming it. At the end of the input PeekKind returns KindInvalid.
+func (d *Decoder) PeekKind() Kind {
+	if d.scanner.flags&(scanRelaxed|scanNaNInf) != 0 {
+		return d.scanner.extendedKind(d.peekOffset())
+	}
+	return kinds[d.peek()]
+}
+
+// peek returns the first byte of the next token NextToken would return.
+// Leading whitespace and comments are consumed, but a comma or colon separator is looked
+// past rather than consumed, so the state machine still validates it.
+func (d *Decoder) peek() byte {
+	c := d.scanner.peek()
+	if c != Comma && c != Colon {
+		return c
+	}
+	return d.scanner.at(d.scanner.skipSpace(d.scanner.offset + 1))
 }
 
 func (d *Decoder) stateObjectString() ([]byte, error) {
 	tok := d.scanner.Next()
 	if len(tok) < 1 {
-		return nil, io.ErrUnexpectedEOF
+		return nil, d.scanner.tokenError()
 	}
 	switch tok[0] {
 	case '}':
-		inObj := d.pop()
-		switch {
-		case d.len() == 0:
-			d.state = (*Decoder).stateEnd
-		case inObj:
-			d.state = (*Decoder).stateObjectComma
-		case !inObj:
-			d.state = (*Decoder).stateArrayComma
-		}
+		d.closeContainer()
 		return tok, nil
 	case '"':
+		if d.disallowDuplicateKeys {
+			if err := d.checkDuplicateKey(tok); err != nil {
+				return nil, err
+			}
+		}
+		f := d.top()
+		f.key, f.keyEnd = d.scanner.start, d.scanner.offset
 		d.state = (*Decoder).stateObjectColon
 		return tok, nil
 	default:
-		return nil, fmt.Errorf("stateObjectString: missing string key")
+		return nil, d.syntaxError(tok, "looking for beginning of object key string")
 	}
 }
 
 func (d *Decoder) stateObjectColon() ([]byte, error) {
 	tok := d.scanner.Next()
 	if len(tok) < 1 {
-		return nil, io.ErrUnexpectedEOF
+		return nil, d.scanner.tokenError()
 	}
 	switch tok[0] {
 	case Colon:
 		d.state = (*Decoder).stateObjectValue
-		return d.NextToken()
+		return d.state(d)
 	default:
-		return tok, fmt.Errorf("stateObjectColon: expecting colon")
+		return nil, d.syntaxError(tok, "after object key")
 	}
 }
 
 func (d *Decoder) stateObjectValue() ([]byte, error) {
 	tok := d.scanner.Next()
 	if len(tok) < 1 {
-		return nil, io.ErrUnexpectedEOF
+		return nil, d.scanner.tokenError()
 	}
 	switch tok[0] {
 	case '{':
+		if err := d.openContainer(true); err != nil {
+			return nil, err
+		}
 		d.state = (*Decoder).stateObjectString
-		d.push(true)
 		return tok, nil
 	case '[':
+		if err := d.openContainer(false); err != nil {
+			return nil, err
+		}
 		d.state = (*Decoder).stateArrayValue
-		d.push(false)
 		return tok, nil
+	case ObjectEnd, ArrayEnd, Colon, Comma:
+		return nil, d.syntaxError(tok, "looking for beginning of value")
 	default:
 		d.state = (*Decoder).stateObjectComma
 		return tok, nil
@@ -172,55 +710,49 @@ func (d *Decoder) stateObjectValue() ([]byte, error) {
 func (d *Decoder) stateObjectComma() ([]byte, error) {
 	tok := d.scanner.Next()
 	if len(tok) < 1 {
-		return nil, io.ErrUnexpectedEOF
+		return nil, d.scanner.tokenError()
 	}
 	switch tok[0] {
 	case '}':
-		inObj := d.pop()
-		switch {
-		case d.len() == 0:
-			d.state = (*Decoder).stateEnd
-		case inObj:
-			d.state = (*Decoder).stateObjectComma
-		case !inObj:
-			d.state = (*Decoder).stateArrayComma
-		}
+		d.closeContainer()
 		return tok, nil
 	case Comma:
+		if c := d.scanner.peek(); c == ObjectEnd && !d.allowTrailingCommas {
+			return nil, d.scanner.syntaxError(d.scanner.offset, "invalid character "+quoteChar(c)+" looking for beginning of object key string")
+		}
 		d.state = (*Decoder).stateObjectString
-		return d.NextToken()
+		return d.state(d)
 	default:
-		return tok, fmt.Errorf("stateObjectComma: expecting comma")
+		return nil, d.syntaxError(tok, "after object key:value pair")
 	}
 }
 
 func (d *Decoder) stateArrayValue() ([]byte, error) {
 	tok := d.scanner.Next()
 	if len(tok) < 1 {
-		return nil, io.ErrUnexpectedEOF
+		return nil, d.scanner.tokenError()
+	}
+	if tok[0] != ArrayEnd {
+		d.top().index++
 	}
 	switch tok[0] {
 	case '{':
+		if err := d.openContainer(true); err != nil {
+			return nil, err
+		}
 		d.state = (*Decoder).stateObjectString
-		d.push(true)
 		return tok, nil
 	case '[':
+		if err := d.openContainer(false); err != nil {
+			return nil, err
+		}
 		d.state = (*Decoder).stateArrayValue
-		d.push(false)
 		return tok, nil
 	case ']':
-		inObj := d.pop()
-		switch {
-		case d.len() == 0:
-			d.state = (*Decoder).stateEnd
-		case inObj:
-			d.state = (*Decoder).stateObjectComma
-		case !inObj:
-			d.state = (*Decoder).stateArrayComma
-		}
+		d.closeContainer()
 		return tok, nil
-	case ',':
-		return nil, fmt.Errorf("stateArrayValue: unexpected comma")
+	case ObjectEnd, Colon, Comma:
+		return nil, d.syntaxError(tok, "looking for beginning of value")
 	default:
 		d.state = (*Decoder).stateArrayComma
 		return tok, nil
@@ -230,55 +762,102 @@ func (d *Decoder) stateArrayValue() ([]byte, error) {
 func (d *Decoder) stateArrayComma() ([]byte, error) {
 	tok := d.scanner.Next()
 	if len(tok) < 1 {
-		return nil, io.ErrUnexpectedEOF
+		return nil, d.scanner.tokenError()
 	}
 	switch tok[0] {
 	case ']':
-		inObj := d.pop()
-		switch {
-		case d.len() == 0:
-			d.state = (*Decoder).stateEnd
-		case inObj:
-			d.state = (*Decoder).stateObjectComma
-		case !inObj:
-			d.state = (*Decoder).stateArrayComma
-		}
+		d.closeContainer()
 		return tok, nil
 	case Comma:
+		if c := d.scanner.peek(); c == ArrayEnd && !d.allowTrailingCommas {
+			return nil, d.scanner.syntaxError(d.scanner.offset, "invalid character "+quoteChar(c)+" looking for beginning of value")
+		}
 		d.state = (*Decoder).stateArrayValue
-		return d.NextToken()
+		return d.state(d)
 	default:
-		return nil, fmt.Errorf("stateArrayComma: expected comma, %v", d.stack)
+		return nil, d.syntaxError(tok, "after array element")
 	}
 }
 
 func (d *Decoder) stateValue() ([]byte, error) {
+	// the input before a top-level value is no longer needed.
+	d.scanner.keep = d.scanner.offset
 	tok := d.scanner.Next()
 	if len(tok) < 1 {
-		return nil, io.ErrUnexpectedEOF
+		if d.scanner.err == io.EOF {
+			// no more top-level values.
+			return nil, io.EOF
+		}
+		return nil, d.scanner.tokenError()
 	}
+	d.values++
 	switch tok[0] {
 	case '{':
+		if err := d.openContainer(true); err != nil {
+			return nil, err
+		}
 		d.state = (*Decoder).stateObjectString
-		d.push(true)
 		return tok, nil
 	case '[':
+		if err := d.openContainer(false); err != nil {
+			return nil, err
+		}
 		d.state = (*Decoder).stateArrayValue
-		d.push(false)
 		return tok, nil
-	case ',':
-		return nil, fmt.Errorf("stateValue: unexpected comma")
+	case ObjectEnd, ArrayEnd, Colon, Comma:
+		return nil, d.syntaxError(tok, "looking for beginning of value")
 	default:
 		d.state = (*Decoder).stateEnd
 		return tok, nil
 	}
 }
 
-func (d *Decoder) stateEnd() ([]byte, error) { return nil, io.EOF }
+// syntaxError returns a SyntaxError for the unexpected token tok, which must
+// be the token most recently returned by the scanner.
+func (d *Decoder) syntaxError(tok []byte, context string) error {
+	offset := d.scanner.offset - len(tok)
+	return d.scanner.syntaxError(offset, "invalid character "+quoteChar(tok[0])+" "+context)
+}
+
+// stateEnd is entered after a complete top-level value. The input may hold
+// further top-level values, optionally separated by whitespace, which are
+// read in turn until the input is exhausted, unless DisallowTrailingData
+// is in effect.
+func (d *Decoder) stateEnd() ([]byte, error) {
+	if d.disallowTrailingData {
+		if d.scanner.peek(); d.scanner.offset < d.scanner.end() {
+			return nil, d.scanner.trailingError()
+		}
+		return nil, d.scanner.endError(io.EOF)
+	}
+	d.state = (*Decoder).stateValue
+	return d.state(d)
+}
 
 // Decode reads the next JSON-encoded value from its input and stores it
-// in the value pointed to by v.
+// in the value pointed to by v. The input may hold a stream of concatenated
+// top-level values; each call to Decode reads the next one, and Decode
+// returns io.EOF once the input is exhausted.
+//
+// As in encoding/json, a value for an interface which holds a non-nil
+// pointer is decoded into the value the pointer points to, so that setting
+// an interface{} to a *T before calling Decode selects the type to decode.
+//
+// A value which cannot be stored in the corresponding Go value is reported
+// as an *UnmarshalTypeError giving its location.
 func (d *Decoder) Decode(v interface{}) error {
+	// only a top-level value is checked for trailing data, not one decoded
+	// from within a document, as by a DecodeFunc or an Array callback.
+	top := d.len() == 0
+	// the fast paths would bypass a DecodeFunc registered for their types.
+	if !d.hasDecodeFuncs() {
+		if ok, err := d.decodeFast(v); ok {
+			if err != nil {
+				return err
+			}
+			return d.checkTrailingData(top)
+		}
+	}
 	rv := reflect.ValueOf(v)
 	switch {
 	case rv.Kind() != reflect.Ptr:
@@ -286,116 +865,534 @@ func (d *Decoder) Decode(v interface{}) error {
 	case rv.IsNil():
 		return fmt.Errorf("nil")
 	default:
-		return d.decodeValue(rv.Elem())
+		if err := d.decodeValue(rv.Elem()); err != nil {
+			return err
+		}
+		return d.checkTrailingData(top)
 	}
 }
 
-func (d *Decoder) decodeValue(v reflect.Value) error {
+// decodeFast decodes the next value into v without reflection if v is a
+// non-nil pointer to one of the common destination types, and reports
+// whether it did. Tokens that do not fit the destination are handed to
+// decodeToken so the errors match the general path.
+func (d *Decoder) decodeFast(v interface{}) (bool, error) {
+	switch v := v.(type) {
+	case *interface{}:
+		if v == nil || *v != nil && reflect.TypeOf(*v).Kind() == reflect.Ptr {
+			return false, nil
+		}
+		x, err := d.decodeValueAny()
+		if err != nil {
+			return true, err
+		}
+		*v = x
+		return true, nil
+	case *string:
+		if v == nil {
+			return false, nil
+		}
+		tok, err := d.NextToken()
+		if err != nil {
+			return true, err
+		}
+		str, ok := d.parseStringToken(tok)
+		if !ok {
+			return true, d.decodeToken(tok, reflect.ValueOf(v).Elem())
+		}
+		*v = str
+		return true, nil
+	case *int:
+		if v == nil {
+			return false, nil
+		}
+		tok, err := d.NextToken()
+		if err != nil {
+			return true, err
+		}
+		i, ok := parseIntToken(tok)
+		if !ok {
+			return true, d.decodeToken(tok, reflect.ValueOf(v).Elem())
+		}
+		*v = i
+		return true, nil
+	case *float64:
+		if v == nil {
+			return false, nil
+		}
+		tok, err := d.NextToken()
+		if err != nil {
+			return true, err
+		}
+		f, ok := parseFloatToken(tok)
+		if !ok {
+			return true, d.decodeToken(tok, reflect.ValueOf(v).Elem())
+		}
+		*v = f
+		return true, nil
+	case *bool:
+		if v == nil {
+			return false, nil
+		}
+		tok, err := d.NextToken()
+		if err != nil {
+			return true, err
+		}
+		if tok[0] != True && tok[0] != False {
+			return true, d.decodeToken(tok, reflect.ValueOf(v).Elem())
+		}
+		*v = tok[0] == True
+		return true, nil
+	case *map[string]interface{}:
+		if v == nil {
+			return false, nil
+		}
+		tok, err := d.NextToken()
+		if err != nil {
+			return true, err
+		}
+		if tok[0] != ObjectStart {
+			return true, d.decodeToken(tok, reflect.ValueOf(v).Elem())
+		}
+		m, err := d.decodeMapAny(*v)
+		if err != nil {
+			return true, err
+		}
+		*v = m
+		return true, nil
+	case *[]interface{}:
+		if v == nil {
+			return false, nil
+		}
+		tok, err := d.NextToken()
+		if err != nil {
+			return true, err
+		}
+		if tok[0] != ArrayStart {
+			return true, d.decodeToken(tok, reflect.ValueOf(v).Elem())
+		}
+		s, err := d.decodeSliceAny((*v)[:0])
+		if err != nil {
+			return true, err
+		}
+		if len(s) == 0 {
+			s = []interface{}{}
+		}
+		*v = s
+		return true, nil
+	case *[]string:
+		if v == nil {
+			return false, nil
+		}
+		return true, decodeSliceFast(d, v, d.parseStringToken)
+	case *[]int:
+		if v == nil {
+			return false, nil
+		}
+		return true, decodeSliceFast(d, v, parseIntToken)
+	case *[]float64:
+		if v == nil {
+			return false, nil
+		}
+		return true, decodeSliceFast(d, v, parseFloatToken)
+	case *map[string]string:
+		if v == nil {
+			return false, nil
+		}
+		return true, d.decodeStringMap(v)
+	}
+	return false, nil
+}
+
+// decodeSliceFast decodes an array of scalars into *v, reusing its backing
+// array, with parse converting each token. Nulls leave reused elements
+// unchanged and otherwise become zero values. Tokens
+// parse rejects are handed to decodeToken, so the error matches the general
+// path, and errors name the index of the offending element.
+func decodeSliceFast[T any](d *Decoder, v *[]T, parse func(tok []byte) (T, bool)) error {
 	tok, err := d.NextToken()
 	if err != nil {
 		return err
 	}
-	switch tok[0] {
-	case '{':
-		switch v.Kind() {
-		case reflect.Interface:
-			if v.NumMethod() > 0 {
-				return fmt.Errorf("cannot decode object into Go value of type %v", v.Type())
-			}
-			m, err := d.decodeMapAny()
-			if err != nil {
-				return err
-			}
-			v.Set(reflect.ValueOf(m))
-		case reflect.Map:
-			return d.decodeMap(v)
-		default:
-			return fmt.Errorf("decodeValue: unhandled type: %v", v.Kind())
+	if tok[0] != ArrayStart {
+		return d.decodeToken(tok, reflect.ValueOf(v).Elem())
+	}
+	s := (*v)[:0]
+	for i := 0; ; i++ {
+		tok, err := d.NextToken()
+		if err != nil {
+			return err
 		}
-		return nil
-	case '[':
-		switch v.Kind() {
-		case reflect.Interface:
-			if v.NumMethod() > 0 {
-				return fmt.Errorf("cannot decode array into Go value of type %v", v.Type())
+		var x T
+		switch tok[0] {
+		case ArrayEnd:
+			if len(s) == 0 {
+				s = []T{}
 			}
-			s, err := d.decodeSliceAny()
-			if err != nil {
-				return err
+			*v = s
+			return nil
+		case Null:
+			// null leaves a reused element unchanged.
+			if len(s) < cap(s) {
+				s = s[:len(s)+1]
+				continue
 			}
-			v.Set(reflect.ValueOf(s))
+		case ObjectStart, ArrayStart:
+			_, err := decodeElement[T](d, tok)
+			return err
 		default:
-			return fmt.Errorf("unhandled type: %v", v.Kind())
+			var ok bool
+			if x, ok = parse(tok); !ok {
+				if x, err = decodeElement[T](d, tok); err != nil {
+					return err
+				}
+			}
 		}
-		return nil
-	case True, False:
-		value := tok[0] == 't'
-		switch v.Kind() {
-		case reflect.Bool:
-			v.SetBool(value)
+		s = append(s, x)
+	}
+}
+
+// decodeElement decodes the token tok, which cannot be parsed as an element
+// of type T, by the general path, which converts it if the Decoder is
+// lenient and otherwise returns the error. It is kept apart from
+// decodeSliceFast so that the reflection it needs does not make every
+// element escape to the heap.
+func decodeElement[T any](d *Decoder, tok []byte) (T, error) {
+	var x T
+	v := reflect.ValueOf(&x).Elem()
+	if tok[0] == ObjectStart || tok[0] == ArrayStart {
+		return x, d.typeError(valueName(tok), v.Type())
+	}
+	err := d.decodeToken(tok, v)
+	return x, err
+}
+
+// decodeStringMap decodes an object of strings into *v, merging into the
+// map if it is not nil. Nulls become empty strings.
+func (d *Decoder) decodeStringMap(v *map[string]string) error {
+	tok, err := d.NextToken()
+	if err != nil {
+		return err
+	}
+	if tok[0] != ObjectStart {
+		return d.decodeToken(tok, reflect.ValueOf(v).Elem())
+	}
+	m := *v
+	if m == nil {
+		m = make(map[string]string)
+		*v = m
+	}
+	for {
+		tok, err := d.NextToken()
+		if err != nil {
+			return err
+		}
+		if tok[0] == ObjectEnd {
+			return nil
+		}
+		key, err := d.unquoteKey(tok)
+		if err != nil {
+			return err
+		}
+		if tok, err = d.NextToken(); err != nil {
+			return err
+		}
+		switch tok[0] {
+		case String:
+			if m[key], err = d.unquote(tok); err != nil {
+				return err
+			}
+		case Null:
+			m[key] = ""
+		default:
+			var s string
+			if err := d.decodeToken(tok, reflect.ValueOf(&s).Elem()); err != nil {
+				return err
+			}
+			m[key] = s
+		}
+	}
+}
+
+// unquote returns the contents of the string token tok, most recently
+// returned by the scanner, with escape sequences decoded.
+func (d *Decoder) unquote(tok []byte) (string, error) {
+	b, err := d.unquoteBytes(tok)
+	return string(b), err
+}
+
+// unquoteKey is like unquote for an object key, interning the result while
+// keys are interned.
+func (d *Decoder) unquoteKey(tok []byte) (string, error) {
+	if d.keys == nil {
+		return d.unquote(tok)
+	}
+	b, err := d.unquoteBytes(tok)
+	if err != nil {
+		return "", err
+	}
+	return d.intern(b), nil
+}
+
+// intern returns the interned string equal to b, interning it if there is
+// room for another key.
+func (d *Decoder) intern(b []byte) string {
+	if s, ok := d.keys[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(d.keys) < MaxInternedKeys {
+		d.keys[s] = s
+	}
+	return s
+}
+
+// unquoteBytes is like unquote, but returns a view of the contents which is
+// only valid until the next string is unquoted.
+func (d *Decoder) unquoteBytes(tok []byte) ([]byte, error) {
+	if bytes.IndexByte(tok, '\\') < 0 {
+		return tok[1 : len(tok)-1], nil
+	}
+	var err error
+	d.scratch, err = d.unescapeToken(d.scratch[:0], tok)
+	return d.scratch, err
+}
+
+// parseStringToken unquotes a string token, failing for any other token or
+// an invalid escape, which decodeToken then reports.
+func (d *Decoder) parseStringToken(tok []byte) (string, bool) {
+	if tok[0] != String {
+		return "", false
+	}
+	str, err := d.unquote(tok)
+	return str, err == nil
+}
+
+func parseIntToken(tok []byte) (int, bool) {
+	i, err := strconv.ParseInt(bytesToString(tok), 10, strconv.IntSize)
+	return int(i), err == nil
+}
+
+func parseFloatToken(tok []byte) (float64, bool) {
+	if tokenKind(tok) != KindNumber {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(bytesToString(tok), 64)
+	return f, err == nil
+}
+
+// checkTrailingData reports an error if the Decoder disallows trailing data
+// and anything follows the value just decoded, if that value was top-level.
+func (d *Decoder) checkTrailingData(top bool) error {
+	if d.disallowTrailingData && top && d.len() == 0 {
+		// the value is complete, check nothing follows it.
+		if _, err := d.NextToken(); err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) decodeValue(v reflect.Value) error {
+	if d.hasDecodeFuncs() {
+		if ok, err := d.callDecodeFunc(v); ok {
+			return err
+		}
+	}
+	tok, err := d.NextToken()
+	if err != nil {
+		return err
+	}
+	return d.decodeToken(tok, v)
+}
+
+// decodeToken decodes the value starting with tok, which has already been
+// consumed, into v.
+func (d *Decoder) decodeToken(tok []byte, v reflect.Value) error {
+	for {
+		// as in encoding/json, a non-nil pointer held by an interface is
+		// decoded into rather than replaced, so that the caller can choose
+		// the type of the value. A null sets the interface to nil, unless
+		// the pointer points to another pointer, which is set to nil.
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			if e := v.Elem(); e.Kind() == reflect.Ptr && !e.IsNil() && (tok[0] != Null || e.Elem().Kind() == reflect.Ptr) {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr || tok[0] == Null && v.CanSet() {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Array && tok[0] != ArrayStart && tok[0] != Null {
+		return d.typeError(valueName(tok), v.Type())
+	}
+	switch tok[0] {
+	case '{':
+		switch v.Kind() {
+		case reflect.Interface:
+			if v.NumMethod() > 0 {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			m, err := d.decodeObjectAny()
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(m))
+		case reflect.Map:
+			return d.decodeMap(v)
+		case reflect.Slice:
+			if v.Type() != orderedMapType {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			m, err := d.decodeOrderedMap(v.Interface().(OrderedMap))
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(m))
+		case reflect.Struct:
+			if v.Type() == timeType || isBig(v.Type()) {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			return d.decodeStruct(v)
+		default:
+			return d.typeError(valueName(tok), v.Type())
+		}
+		return nil
+	case '[':
+		switch v.Kind() {
+		case reflect.Interface:
+			if v.NumMethod() > 0 {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			s, err := d.decodeSliceAny(nil)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(s))
+		case reflect.Slice:
+			if v.Type() == orderedMapType {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			return d.decodeSlice(v)
+		case reflect.Array:
+			return d.decodeArray(v)
+		default:
+			return d.typeError(valueName(tok), v.Type())
+		}
+		return nil
+	case True, False:
+		value := tok[0] == 't'
+		switch v.Kind() {
+		case reflect.Bool:
+			v.SetBool(value)
 		case reflect.Interface:
 			if v.NumMethod() > 0 {
-				return fmt.Errorf("cannot decode bool into Go value of type %v", v.Type())
+				return d.typeError(valueName(tok), v.Type())
 			}
 			v.Set(reflect.ValueOf(value))
 		default:
-			return fmt.Errorf("unhandled type: %v", v.Kind())
+			return d.typeError(valueName(tok), v.Type())
 		}
 		return nil
 	case Null:
+		// As in encoding/json, null sets pointers, interfaces, maps and
+		// slices to nil, and leaves any other value unchanged.
 		switch v.Kind() {
-		case reflect.Ptr, reflect.Map, reflect.Slice:
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
 			v.Set(reflect.Zero(v.Type()))
-			return nil
-		default:
-			return fmt.Errorf("unhandled type: %v", v.Kind())
 		}
+		return nil
 	case '"':
 		switch v.Kind() {
 		case reflect.Interface:
 			if v.NumMethod() > 0 {
-				return fmt.Errorf("cannot decode object into Go value of type %v", v.Type())
+				return d.typeError(valueName(tok), v.Type())
+			}
+			s, err := d.unquote(tok)
+			if err != nil {
+				return err
 			}
-			s := string(tok[1 : len(tok)-1])
 			v.Set(reflect.ValueOf(s))
 		case reflect.String:
-			s := string(tok[1 : len(tok)-1])
+			s, err := d.unquote(tok)
+			if err != nil {
+				return err
+			}
 			v.SetString(s)
+		case reflect.Struct:
+			if v.Type() != timeType {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			return d.decodeTime(tok, v)
+		case reflect.Int64:
+			if v.Type() == durationType && d.durationStrings {
+				return d.decodeDuration(tok, v)
+			}
+			return d.decodeStringAs(tok, v)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64, reflect.Bool:
+			return d.decodeStringAs(tok, v)
+		case reflect.Slice:
+			if v.Type().Elem().Kind() != reflect.Uint8 || v.Type() == rawType {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			return d.decodeBytes(tok, v)
 		default:
-			return fmt.Errorf("unhandled type: %v", v.Kind())
+			return d.typeError(valueName(tok), v.Type())
 		}
 		return nil
-	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'N', 'I':
 		switch v.Kind() {
 		case reflect.Interface:
 			if v.NumMethod() > 0 {
-				return fmt.Errorf("cannot decode number into Go value of type %v", v.Type())
+				return d.typeError(valueName(tok), v.Type())
 			}
-			f, err := strconv.ParseFloat(bytesToString(tok), 64)
+			n, err := d.number(tok)
 			if err != nil {
-				return fmt.Errorf("cannot convert %q to float: %v", tok, err)
+				return err
 			}
-			v.Set(reflect.ValueOf(f))
+			v.Set(reflect.ValueOf(n))
+		case reflect.String:
+			if v.Type() != numberType && !d.lenient {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			v.SetString(string(tok))
+		case reflect.Bool:
+			if !d.lenient || len(tok) != 1 || tok[0] != '0' && tok[0] != '1' {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			v.SetBool(tok[0] == '1')
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			i, err := strconv.ParseInt(bytesToString(tok), 10, 64)
 			if err != nil || v.OverflowInt(i) {
-				return fmt.Errorf("cannot convert %q to int: %v", tok, err)
+				return d.typeError("number "+string(tok), v.Type())
 			}
 			v.SetInt(i)
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 			u, err := strconv.ParseUint(bytesToString(tok), 10, 64)
 			if err != nil || v.OverflowUint(u) {
-				return fmt.Errorf("cannot convert %q to uint: %v", tok, err)
+				return d.typeError("number "+string(tok), v.Type())
 			}
 			v.SetUint(u)
 		case reflect.Float64, reflect.Float32:
 			f, err := strconv.ParseFloat(bytesToString(tok), v.Type().Bits())
 			if err != nil || v.OverflowFloat(f) {
-				return fmt.Errorf("cannot convert %q to float: %v", tok, err)
+				return d.typeError("number "+string(tok), v.Type())
 			}
 			v.SetFloat(f)
+		case reflect.Struct:
+			if !isBig(v.Type()) {
+				return d.typeError(valueName(tok), v.Type())
+			}
+			return d.decodeBig(tok, v)
 		default:
-			return fmt.Errorf("unhandled type: %v", v.Kind())
+			return d.typeError(valueName(tok), v.Type())
 		}
 		return nil
 	default:
@@ -403,6 +1400,97 @@ func (d *Decoder) decodeValue(v reflect.Value) error {
 	}
 }
 
+// decodeStringAs decodes the string token tok into v, a number or bool, if
+// the Decoder is lenient and the string holds a value of that type.
+func (d *Decoder) decodeStringAs(tok []byte, v reflect.Value) error {
+	if !d.lenient {
+		return d.typeError(valueName(tok), v.Type())
+	}
+	s, err := d.unquoteBytes(tok)
+	if err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Bool {
+		switch string(s) {
+		case "true", "1":
+			v.SetBool(true)
+		case "false", "0":
+			v.SetBool(false)
+		default:
+			return d.typeError(valueName(tok), v.Type())
+		}
+		return nil
+	}
+	if !isNumber(bytesToString(s)) {
+		return d.typeError(valueName(tok), v.Type())
+	}
+	return d.decodeToken(s, v)
+}
+
+// decodeTime decodes the string token tok, an RFC 3339 timestamp, into the
+// time.Time v.
+func (d *Decoder) decodeTime(tok []byte, v reflect.Value) error {
+	s, err := d.unquoteBytes(tok)
+	if err != nil {
+		return err
+	}
+	t, err := time.Parse(time.RFC3339Nano, bytesToString(s))
+	if err != nil {
+		return d.parseError(s, v.Type(), err)
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// decodeDuration decodes the string token tok, as parsed by
+// time.ParseDuration, into the time.Duration v.
+func (d *Decoder) decodeDuration(tok []byte, v reflect.Value) error {
+	s, err := d.unquoteBytes(tok)
+	if err != nil {
+		return err
+	}
+	x, err := time.ParseDuration(bytesToString(s))
+	if err != nil {
+		return d.parseError(s, v.Type(), err)
+	}
+	v.SetInt(int64(x))
+	return nil
+}
+
+// decodeBytes decodes the string token tok, holding base64-encoded data,
+// into the byte slice v. The standard encoding is expected, as written by the
+// Encoder, but the URL-safe alphabet and missing padding are accepted too.
+func (d *Decoder) decodeBytes(tok []byte, v reflect.Value) error {
+	s, err := d.unquoteBytes(tok)
+	if err != nil {
+		return err
+	}
+	enc := base64.StdEncoding
+	if bytes.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	if len(s)%4 != 0 && bytes.IndexByte(s, '=') < 0 {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	b := make([]byte, enc.DecodedLen(len(s)))
+	n, err := enc.Decode(b, s)
+	if err != nil {
+		return d.parseError(s, v.Type(), err)
+	}
+	v.SetBytes(b[:n])
+	return nil
+}
+
+// parseError returns the error for the contents s of the most recently
+// read string, which could not be parsed as a value of type t. A long
+// string is cut short.
+func (d *Decoder) parseError(s []byte, t reflect.Type, err error) error {
+	if len(s) > 64 {
+		return fmt.Errorf("json: cannot decode string %q... into Go value of type %v at offset %d, path %s: %w", s[:64], t, d.scanner.start, d.Path(), err)
+	}
+	return fmt.Errorf("json: cannot decode string %q into Go value of type %v at offset %d, path %s: %w", s, t, d.scanner.start, d.Path(), err)
+}
+
 func (d *Decoder) decodeValueAny() (interface{}, error) {
 	tok, err := d.NextToken()
 	if err != nil {
@@ -410,25 +1498,28 @@ func (d *Decoder) decodeValueAny() (interface{}, error) {
 	}
 	switch tok[0] {
 	case '{':
-		return d.decodeMapAny()
+		return d.decodeObjectAny()
 	case '[':
-		return d.decodeSliceAny()
+		return d.decodeSliceAny(nil)
 	case True, False:
 		return tok[0] == 't', nil
 	case '"':
-		return string(tok[1 : len(tok)-1]), nil
+		return d.unquote(tok)
 	case Null:
 		return nil, nil
-	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		s := bytesToString(tok)
-		return strconv.ParseFloat(s, 64)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'N', 'I':
+		return d.number(tok)
 	default:
 		return fmt.Errorf("decodeValueAny: unhandled token: %c", tok[0]), nil
 	}
 }
 
-func (d *Decoder) decodeMapAny() (map[string]interface{}, error) {
-	m := make(map[string]interface{})
+// decodeMapAny decodes the members of an object, whose opening brace has
+// already been consumed, into m. If m is nil a new map is allocated.
+func (d *Decoder) decodeMapAny(m map[string]interface{}) (map[string]interface{}, error) {
+	if m == nil {
+		m = make(map[string]interface{})
+	}
 	for {
 		tok, err := d.NextToken()
 		if err != nil {
@@ -438,7 +1529,10 @@ func (d *Decoder) decodeMapAny() (map[string]interface{}, error) {
 			return m, nil
 		}
 
-		key := string(tok[1 : len(tok)-1])
+		key, err := d.unquoteKey(tok)
+		if err != nil {
+			return nil, err
+		}
 		val, err := d.decodeValueAny()
 		if err != nil {
 			return nil, fmt.Errorf("decodeMapAny: %w", err)
@@ -447,11 +1541,50 @@ func (d *Decoder) decodeMapAny() (map[string]interface{}, error) {
 	}
 }
 
+// decodeArray decodes an array into the Go array v. As in encoding/json,
+// elements beyond the length of v are skipped, and elements of v beyond the
+// end of the input are set to zero.
+func (d *Decoder) decodeArray(v reflect.Value) error {
+	n := 0
+	for d.peek() != ArrayEnd {
+		var err error
+		if n < v.Len() {
+			err = d.decodeValue(v.Index(n))
+		} else {
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+		n++
+	}
+	if _, err := d.NextToken(); err != nil {
+		return err
+	}
+	for ; n < v.Len(); n++ {
+		v.Index(n).SetZero()
+	}
+	return nil
+}
+
+// decodeMap decodes an object into the map v, allocating it if it is nil.
+// Keys in the input are added or overwritten, with each value decoded into
+// a fresh zero value; keys not in the input are kept.
 func (d *Decoder) decodeMap(v reflect.Value) error {
 	t := v.Type()
 	kt := t.Key()
-	if kt.Kind() != reflect.String {
-		return fmt.Errorf("cannot decode object into map with key type %v", kt)
+	textKey := reflect.PointerTo(kt).Implements(textUnmarshalerType)
+	if !textKey {
+		switch kt.Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			return d.typeError("object", t)
+		}
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
 	}
 
 	for {
@@ -462,8 +1595,16 @@ func (d *Decoder) decodeMap(v reflect.Value) error {
 		if tok[0] == '}' {
 			return nil
 		}
-		key := string(tok[1 : len(tok)-1])
-		kv := reflect.ValueOf(key).Convert(kt)
+		key, err := d.unquoteBytes(tok)
+		if err != nil {
+			return err
+		}
+		var kv reflect.Value
+		if d.keys != nil && !textKey && kt.Kind() == reflect.String {
+			kv = reflect.ValueOf(d.intern(key)).Convert(kt)
+		} else if kv, err = parseMapKey(key, kt, textKey); err != nil {
+			return fmt.Errorf("json: cannot decode key %q into %v at offset %d: %w", key, kt, d.scanner.start, err)
+		}
 
 		value := reflect.New(t.Elem()).Elem()
 		if err := d.decodeValue(value); err != nil {
@@ -473,8 +1614,144 @@ func (d *Decoder) decodeMap(v reflect.Value) error {
 	}
 }
 
-func (d *Decoder) decodeSliceAny() ([]interface{}, error) {
-	s := make([]interface{}, 0, 1)
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// parseMapKey converts the object key key to the map key type kt. As in
+// encoding/json, a key type implementing encoding.TextUnmarshaler is
+// unmarshaled from the key, and integer key types are parsed from it.
+func parseMapKey(key []byte, kt reflect.Type, textKey bool) (reflect.Value, error) {
+	switch {
+	case textKey:
+		kv := reflect.New(kt)
+		if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText(key); err != nil {
+			return reflect.Value{}, err
+		}
+		return kv.Elem(), nil
+	case kt.Kind() == reflect.String:
+		return reflect.ValueOf(string(key)).Convert(kt), nil
+	}
+	kv := reflect.New(kt).Elem()
+	switch kt.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(bytesToString(key), 10, kt.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		kv.SetInt(i)
+	default:
+		u, err := strconv.ParseUint(bytesToString(key), 10, kt.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		kv.SetUint(u)
+	}
+	return kv, nil
+}
+
+// decodeSlice decodes an array into the slice v. As in encoding/json, the
+// existing backing array is reused: elements are decoded into whatever it
+// holds, so struct elements are merged into rather than reset, and the
+// slice only grows, with zeroed elements, once its capacity is exhausted.
+func (d *Decoder) decodeSlice(v reflect.Value) error {
+	n := 0
+	for d.peek() != ArrayEnd {
+		if n >= v.Cap() {
+			v.Grow(1)
+		}
+		if n >= v.Len() {
+			v.SetLen(n + 1)
+		}
+		if err := d.decodeValue(v.Index(n)); err != nil {
+			return err
+		}
+		n++
+	}
+	if _, err := d.NextToken(); err != nil {
+		return err
+	}
+	if n == 0 {
+		// as in encoding/json, an empty array gives a new empty slice.
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		return nil
+	}
+	v.SetLen(n)
+	return nil
+}
+
+func (d *Decoder) decodeStruct(v reflect.Value) error {
+	fields := structFields(v.Type())
+	for {
+		tok, err := d.NextToken()
+		if err != nil {
+			return err
+		}
+		if tok[0] == '}' {
+			return nil
+		}
+		key, err := d.unquoteBytes(tok)
+		if err != nil {
+			return err
+		}
+		f := fields.field(key)
+		if f == nil && !d.matchCaseSensitive {
+			f = fields.foldField(key)
+		}
+		if f == nil {
+			if d.disallowUnknownFields {
+				return fmt.Errorf("json: unknown field %q in %v at offset %d", key, v.Type(), d.scanner.start)
+			}
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		fv, err := f.settableValue(v)
+		if err != nil {
+			return err
+		}
+		if err := d.decodeValue(fv); err != nil {
+			return fieldError(err, v.Type(), f.name)
+		}
+	}
+}
+
+// typeError returns an *UnmarshalTypeError for the value described by value,
+// which starts with the token most recently returned and cannot be stored in
+// a Go value of type t.
+func (d *Decoder) typeError(value string, t reflect.Type) error {
+	return &UnmarshalTypeError{Value: value, Type: t, Offset: int64(d.scanner.start), Path: d.Path()}
+}
+
+// valueName describes the value starting with tok for an *UnmarshalTypeError.
+func valueName(tok []byte) string {
+	switch tok[0] {
+	case ObjectStart:
+		return "object"
+	case ArrayStart:
+		return "array"
+	}
+	return tokenKind(tok).String()
+}
+
+// fieldError adds the field name, the key of a field of the struct type t,
+// to err if it is an *UnmarshalTypeError.
+func fieldError(err error, t reflect.Type, name string) error {
+	if terr, ok := err.(*UnmarshalTypeError); ok {
+		if terr.Field == "" {
+			terr.Struct, terr.Field = t.Name(), name
+		} else {
+			terr.Field = name + "." + terr.Field
+		}
+	}
+	return err
+}
+
+// decodeSliceAny appends the elements of an array, whose opening bracket has
+// already been consumed, to s. If s is nil a new slice is allocated.
+func (d *Decoder) decodeSliceAny(s []interface{}) ([]interface{}, error) {
+	if s == nil {
+		s = make([]interface{}, 0, 1)
+	}
 	for {
 		tok, err := d.NextToken()
 		if err != nil {
@@ -484,13 +1761,13 @@ func (d *Decoder) decodeSliceAny() ([]interface{}, error) {
 		case ']':
 			return s, nil
 		case '{':
-			m, err := d.decodeMapAny()
+			m, err := d.decodeObjectAny()
 			if err != nil {
 				return nil, err
 			}
 			s = append(s, m)
 		case '[':
-			sv, err := d.decodeSliceAny()
+			sv, err := d.decodeSliceAny(nil)
 			if err != nil {
 				return nil, err
 			}
@@ -498,67 +1775,306 @@ func (d *Decoder) decodeSliceAny() ([]interface{}, error) {
 		case True, False:
 			s = append(s, tok[0] == 't')
 		case '"':
-			s = append(s, string(tok[1:len(tok)-1]))
+			str, err := d.unquote(tok)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, str)
 		case Null:
 			s = append(s, nil)
-		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			ss := bytesToString(tok)
-			f, err := strconv.ParseFloat(ss, 64)
+		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'N', 'I':
+			n, err := d.number(tok)
 			if err != nil {
-				return nil, fmt.Errorf("cannot convert %q to float: %v", tok, err)
+				return nil, err
 			}
-			s = append(s, f)
+			s = append(s, n)
 		}
 	}
 }
 
-// Skip the next JSON value(string/number/array/object)
-// Implementation is quite naive, it just skips the next value without proper validation(it doesn't relies on the decoder state).
+// Skip consumes exactly one complete JSON value (string/number/literal/array/object)
+// from the current position, whether that is the top level, an array element
+// or an object member value. Containers are skipped without full validation;
+// however they must be properly nested; crossed or unterminated brackets are
+// reported as errors.
+//
+// Skip reports a value exceeding the maximum string length or value size as
+// a *LimitError, unless SetSkipOverLimit(true) has been called.
 func (d *Decoder) Skip() error {
-	tok, err := d.NextToken()
+	if d.skipOverLimit && d.scanner.r != nil && d.atValue() {
+		d.saveKeys()
+		d.scanner.discard, d.scanner.maxString = true, 0
+		err := d.skip()
+		d.scanner.discard, d.scanner.maxString = false, max(d.maxStringLen, 0)
+		return err
+	}
+	return d.skip()
+}
+
+// atValue reports whether the next token is a value rather than an object
+// key or the end of an object.
+func (d *Decoder) atValue() bool {
+	return d.len() == 0 || !d.top().obj || d.scanner.peek() == Colon
+}
+
+// skip is Skip without discarding the input of the value, which the caller
+// may use.
+func (d *Decoder) skip() error {
+	tok, err := d.state(d)
 	if err != nil {
 		return err
 	}
-	d.state = (*Decoder).stateObjectComma
+	start := d.scanner.start
 	switch tok[0] {
-	case ObjectStart:
-		_ = d.pop()
-		d.scanner.skipObject()
-		return nil
-	case ArrayStart:
-		_ = d.pop()
-		d.scanner.skipArray()
+	case ObjectStart, ArrayStart:
+		if !d.skipOverLimit {
+			d.scanner.valueStart, d.scanner.maxValue = start, d.maxValueBytes
+		}
+		err := d.scanner.skipContainer(tok[0], d.remainingDepth())
+		d.scanner.maxValue = 0
+		if err != nil {
+			return fmt.Errorf("Skip: container at offset %d: %w", start, err)
+		}
+		d.closeContainer()
+	case String:
+		if d.maxStringLen > 0 && !d.skipOverLimit {
+			if err := d.checkStringLen(tok); err != nil {
+				return err
+			}
+		}
+	}
+	if d.skipOverLimit {
 		return nil
 	}
-	return nil
+	return d.checkValueBytes(start, d.getOffset())
+}
+
+// Drain consumes the rest of the current top-level value, or the next one if
+// none has been read, checking every token as NextToken does but without
+// decoding any values, and then checks that nothing but whitespace follows.
+// It returns nil if the input closes correctly, and otherwise the first
+// error, which for input ending early is a *SyntaxError at its end. Drain is
+// stricter than Skip, which only matches brackets.
+func (d *Decoder) Drain() error {
+	disallow := d.disallowTrailingData
+	d.disallowTrailingData = true
+	defer func() { d.disallowTrailingData = disallow }()
+	for {
+		if _, err := d.NextToken(); err != nil {
+			switch err {
+			case io.EOF:
+				return nil
+			case io.ErrUnexpectedEOF:
+				return d.scanner.syntaxError(d.scanner.end(), "unexpected end of JSON input")
+			}
+			return err
+		}
+	}
+}
+
+// remainingDepth returns the number of levels of nesting skipContainer may
+// enter, counting the container just opened, or 0 for no limit.
+func (d *Decoder) remainingDepth() int {
+	if d.maxDepth <= 0 {
+		return 0
+	}
+	return d.maxDepth - d.len() + 1
 }
 
-// NextAsBytes returns the next JSON element as a []byte.
+// NextAsBytes returns the next JSON element as a []byte, without any
+// surrounding whitespace. The Decoder is left positioned after the element,
+// as if it had been read with NextToken. A value larger than the maximum
+// value size is reported as a *LimitError.
 func (d *Decoder) NextAsBytes() ([]byte, error) {
-	tok, err := d.NextToken()
+	start, end, err := d.nextSpan("NextAsBytes")
 	if err != nil {
 		return nil, err
 	}
-	offset := d.getOffset() - 1
-	d.state = (*Decoder).stateObjectComma
+	return d.scanner.span(start, end), nil
+}
+
+// NextValueSpan consumes the next JSON element, as NextAsBytes does, and
+// returns its offsets in the input rather than its bytes: the element is
+// input[start:end], exactly the slice NextAsBytes would have returned, where
+// input is the buffer given to NewDecoder or Reset, or the whole of the
+// input read from the io.Reader given to ResetReader. Spans let an index
+// over a document be built in one pass and the values be sliced from it
+// later.
+func (d *Decoder) NextValueSpan() (start, end int, err error) {
+	return d.nextSpan("NextValueSpan")
+}
+
+// nextSpan consumes the next element and returns its offsets, naming method
+// in the error for a malformed container.
+func (d *Decoder) nextSpan(method string) (start, end int, err error) {
+	tok, err := d.NextToken()
+	if err != nil {
+		return 0, 0, err
+	}
+	start = d.scanner.start
 	switch tok[0] {
-	case ObjectStart:
-		_ = d.pop()
-		d.scanner.skipObject()
-	case ArrayStart:
-		_ = d.pop()
-		d.scanner.skipArray()
-	default:
-		offset := d.getOffset()
-		return d.scanner.data[offset-len(tok) : offset], nil
+	case ObjectStart, ArrayStart:
+		d.scanner.valueStart, d.scanner.maxValue = start, d.maxValueBytes
+		err := d.scanner.skipContainer(tok[0], d.remainingDepth())
+		d.scanner.maxValue = 0
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s: container at offset %d: %w", method, start, err)
+		}
+		d.closeContainer()
+	}
+	end = d.getOffset()
+	if err := d.checkValueBytes(start, end); err != nil {
+		return 0, 0, err
 	}
-	return d.scanner.data[offset:d.getOffset()], nil
+	return start, end, nil
+}
+
+// NextAsBytesCompact is like NextAsBytes, but reads the element token by
+// token, so that any syntax error within it is reported, and appends it to
+// dst with all insignificant whitespace removed, returning the extended
+// buffer. It returns an error if there is no element to read, that is if the
+// next token closes an array or object.
+func (d *Decoder) NextAsBytesCompact(dst []byte) ([]byte, error) {
+	tok, err := d.NextToken()
+	if err != nil {
+		return dst, err
+	}
+	start := d.scanner.start
+	switch tok[0] {
+	case ArrayEnd, ObjectEnd:
+		return dst, d.syntaxError(tok, "looking for beginning of value")
+	}
+	dst = append(dst, tok...)
+	depth := d.len()
+	if tok[0] == ObjectStart || tok[0] == ArrayStart {
+		depth--
+	}
+	// whether a comma, or a colon, is due before the next token.
+	comma, colon := false, false
+	for d.len() > depth {
+		tok, err := d.NextToken()
+		if err != nil {
+			return dst, err
+		}
+		switch tok[0] {
+		case ArrayEnd, ObjectEnd:
+			dst = append(dst, tok...)
+			comma = true
+			continue
+		}
+		if comma {
+			dst = append(dst, Comma)
+		}
+		if colon {
+			dst = append(dst, Colon)
+		}
+		dst = append(dst, tok...)
+		switch {
+		case tok[0] == ObjectStart || tok[0] == ArrayStart:
+			comma, colon = false, false
+		case d.top().obj && !colon:
+			// an object key.
+			comma, colon = false, true
+		default:
+			comma, colon = true, false
+		}
+	}
+	if err := d.checkValueBytes(start, d.getOffset()); err != nil {
+		return dst, err
+	}
+	return dst, nil
+}
+
+// CopyValue consumes the next element, reading it token by token so that
+// any syntax error within it is reported, and writes it to w as it appears
+// in the input, without surrounding whitespace. It returns the number of
+// bytes written. As for NextAsBytesCompact, it is an error if there is no
+// element to read.
+//
+// Given its input as a []byte, the Decoder writes the element with a single
+// call to w.Write, and nothing if the element is invalid. Reading from an
+// io.Reader, it writes an array or object in chunks as it reads it, and
+// discards the input written, so that an element larger than the buffer is
+// copied in bounded memory; an invalid element, or one over the maximum
+// value size, may then have been partly written when the error is found.
+func (d *Decoder) CopyValue(w io.Writer) (int64, error) {
+	tok, err := d.NextToken()
+	if err != nil {
+		return 0, err
+	}
+	start := d.scanner.start
+	switch tok[0] {
+	case ArrayEnd, ObjectEnd:
+		return 0, d.syntaxError(tok, "looking for beginning of value")
+	case ObjectStart, ArrayStart:
+		if d.scanner.r != nil {
+			return d.copyContainer(w, start)
+		}
+		for depth := d.len() - 1; d.len() > depth; {
+			if _, err := d.NextToken(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	end := d.getOffset()
+	if err := d.checkValueBytes(start, end); err != nil {
+		return 0, err
+	}
+	n, err := w.Write(d.scanner.span(start, end))
+	return int64(n), err
+}
+
+// copyChunk is the size above which CopyValue writes what it has read of a
+// container from an io.Reader.
+const copyChunk = 4 << 10
+
+// copyContainer completes CopyValue for the array or object starting at
+// offset start, which is read from an io.Reader.
+func (d *Decoder) copyContainer(w io.Writer, start int) (int64, error) {
+	d.scanner.valueStart, d.scanner.maxValue = start, d.maxValueBytes
+	defer func() { d.scanner.maxValue = 0 }()
+	var n int64
+	written := start
+	for depth := d.len() - 1; d.len() > depth; {
+		if _, err := d.NextToken(); err != nil {
+			return n, err
+		}
+		if end := d.scanner.offset; end-written >= copyChunk && d.len() > depth {
+			m, err := w.Write(d.scanner.span(written, end))
+			n += int64(m)
+			if err != nil {
+				return n, err
+			}
+			// the input written is no longer needed, except for the keys
+			// of the open objects.
+			d.saveKeys()
+			written, d.scanner.keep = end, end
+		}
+	}
+	end := d.getOffset()
+	if err := d.checkValueBytes(start, end); err != nil {
+		return n, err
+	}
+	m, err := w.Write(d.scanner.span(written, end))
+	return n + int64(m), err
 }
 
 func bytesToString(b []byte) string {
 	return unsafe.String(unsafe.SliceData(b), len(b))
 }
 
+// stringToBytes returns a view of the bytes of s, which must not be modified.
+func stringToBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// InputOffset returns the input stream byte offset of the current decoder
+// position. The offset gives the location of the end of the most recently
+// returned token and the beginning of the next token.
+func (d *Decoder) InputOffset() int64 {
+	return int64(d.scanner.offset)
+}
+
 func (d *Decoder) getOffset() int {
 	return d.scanner.offset
 }
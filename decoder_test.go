@@ -2,9 +2,22 @@ package json
 
 import (
 	"bytes"
+	"encoding/base64"
+	hexenc "encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
+	"unicode/utf8"
+	"unsafe"
 )
 
 func TestDecoderNextToken(t *testing.T) {
@@ -25,6 +38,8 @@ func TestDecoderNextToken(t *testing.T) {
 		{json: `""`, tokens: []string{`""`}},
 		{json: `[{}]`, tokens: []string{`[`, `{`, `}`, `]`}},
 		{json: `[{"a": [{}]}]`, tokens: []string{`[`, `{`, `"a"`, `[`, `{`, `}`, `]`, `}`, `]`}},
+		{json: `1 "two" [3] {"four": 4}`, tokens: []string{`1`, `"two"`, `[`, `3`, `]`, `{`, `"four"`, `4`, `}`}},
+		{json: "{}\n{}\n", tokens: []string{`{`, `}`, `{`, `}`}},
 		{json: `[{"a": 1,"b": 123.456, "c": null, "d": [1, -2, "three", true, false, ""]}]`,
 			tokens: []string{`[`,
 				`{`,
@@ -61,41 +76,66 @@ func TestDecoderNextToken(t *testing.T) {
 	}
 }
 
-func TestDecoderInvalidJSON(t *testing.T) {
-	tests := []struct {
-		json string
-	}{
-		{json: `[`},
-		{json: `{"":2`},
-		{json: `[[[[]]]`},
-		{json: `{"`},
-		{json: `{"":` + "\n" + `}`},
-		{json: `{{"key": 1}: 2}}`},
-		{json: `{1: 1}`},
-		// {json: `"\6"`},
-		{json: `[[],[], [[]],�[[]]]`},
-		{json: `+`},
-		{json: `,`},
-		// {json: `00`},
-		// {json: `1a`},
-		{json: `1.e1`},
-		{json: `{"a":"b":"c"}`},
-		{json: `{"test"::"input"}`},
-		{json: `e1`},
-		{json: `-.1e-1`},
-		{json: `123.`},
-		{json: `--123`},
-		{json: `.1`},
-		{json: `0.1e`},
-		// fuzz testing
-		// {json: "\"\x00outC: .| >\x185\x014\x80\x00\x01n" +
-		//	"E4255425067\x014\x80\x00\x01.242" +
-		//	"55425.E420679586036\xef" +
-		//	"\xbf9586036�\""},
-	}
+// invalidJSON holds inputs which are not valid JSON documents.
+var invalidJSON = []struct {
+	json   string
+	stream bool // valid as a stream of top-level values
+}{
+	{json: `[`},
+	{json: `{"":2`},
+	{json: `[[[[]]]`},
+	{json: `{"`},
+	{json: `{"":` + "\n" + `}`},
+	{json: `{{"key": 1}: 2}}`},
+	{json: `{1: 1}`},
+	{json: `"\6"`},
+	{json: `"\u00g0"`},
+	{json: "\"\t\""},
+	{json: `[[],[], [[]],�[[]]]`},
+	{json: `+`},
+	{json: `,`},
+	{json: `00`, stream: true},
+	{json: `1a`},
+	{json: `truefalse`, stream: true},
+	{json: `{} []`, stream: true},
+	{json: `1.e1`},
+	{json: `{"a":"b":"c"}`},
+	{json: `{"test"::"input"}`},
+	{json: `e1`},
+	{json: `-.1e-1`},
+	{json: `123.`},
+	{json: `--123`},
+	{json: `.1`},
+	{json: `0.1e`},
+	{json: `[1,]`},
+	{json: `{"a": [0,]}`},
+	{json: `{"a": 1,}`},
+	{json: "\"000\"\x00"},
+	{json: ``, stream: true},
+	{json: ` `, stream: true},
+	// fuzz testing
+	{json: "\"\x00outC: .| >\x185\x014\x80\x00\x01n" +
+		"E4255425067\x014\x80\x00\x01.242" +
+		"55425.E420679586036\xef" +
+		"\xbf9586036�\""},
+}
 
-	for _, tc := range tests {
+func TestDecoderInvalidJSON(t *testing.T) {
+	for _, tc := range invalidJSON {
 		t.Run(tc.json, func(t *testing.T) {
+			var serr *SyntaxError
+			if err := Validate([]byte(tc.json)); !errors.As(err, &serr) {
+				t.Fatalf("Validate: expected *SyntaxError, got: %v", err)
+			}
+			if Valid([]byte(tc.json)) {
+				t.Fatalf("Valid: expected false")
+			}
+			if json.Valid([]byte(tc.json)) {
+				t.Fatalf("encoding/json considers the input valid")
+			}
+			if tc.stream {
+				return
+			}
 			dec := NewDecoder([]byte(tc.json))
 			var err error
 			for {
@@ -111,6 +151,155 @@ func TestDecoderInvalidJSON(t *testing.T) {
 	}
 }
 
+func TestDecoderSyntaxError(t *testing.T) {
+	tests := []struct {
+		json   string
+		offset int64
+		line   int
+		column int
+		msg    string
+	}{
+		{json: `{"a" 1}`, offset: 5, line: 1, column: 6, msg: `invalid character '1' after object key`},
+		{json: `{"a": 1 "b": 2}`, offset: 8, line: 1, column: 9, msg: `invalid character '"' after object key:value pair`},
+		{json: `[1 2]`, offset: 3, line: 1, column: 4, msg: `invalid character '2' after array element`},
+		{json: `[1,,2]`, offset: 3, line: 1, column: 4, msg: `invalid character ',' looking for beginning of value`},
+		{json: `{1: 1}`, offset: 1, line: 1, column: 2, msg: `invalid character '1' looking for beginning of object key string`},
+		{json: "{\n  \"a\": tru3\n}", offset: 12, line: 2, column: 11, msg: `invalid character '3' in literal true (expecting 'e')`},
+		{json: "[\n1,\n  -x]", offset: 8, line: 3, column: 4, msg: `invalid character 'x' in numeric literal`},
+		{json: "[\n1,\n  1.e1]", offset: 9, line: 3, column: 5, msg: `invalid character 'e' in numeric literal`},
+		{json: `+`, offset: 0, line: 1, column: 1, msg: `invalid character '+' looking for beginning of value`},
+		{json: `{"a":"b":"c"}`, offset: 8, line: 1, column: 9, msg: `invalid character ':' after object key:value pair`},
+		{json: `{"a": [1, }, 2]}`, offset: 10, line: 1, column: 11, msg: `invalid character '}' looking for beginning of value`},
+		{json: `[1, 2, ]`, offset: 7, line: 1, column: 8, msg: `invalid character ']' looking for beginning of value`},
+		{json: `{"a": 1,}`, offset: 8, line: 1, column: 9, msg: `invalid character '}' looking for beginning of object key string`},
+		// pretty-printed, with the error on line 5; an escaped \n is not a
+		// line break, and a CRLF is one.
+		{json: "{\n  \"name\": \"a\\nb\",\n  \"tags\": [\n    \"x\",\n    \"y\" \"z\"\n  ]\n}", offset: 49, line: 5, column: 9, msg: `invalid character '"' after array element`},
+		{json: "{\r\n  \"name\": \"a\\nb\",\r\n  \"tags\": [\r\n    \"x\",\r\n    \"y\" \"z\"\r\n  ]\r\n}", offset: 53, line: 5, column: 9, msg: `invalid character '"' after array element`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			check := func(err error) {
+				t.Helper()
+				var serr *SyntaxError
+				if !errors.As(err, &serr) {
+					t.Fatalf("expected *SyntaxError, got: %v", err)
+				}
+				if serr.Offset != tc.offset || serr.Line != tc.line || serr.Column != tc.column {
+					t.Fatalf("expected offset %v, line %v, column %v, got: %v", tc.offset, tc.line, tc.column, serr)
+				}
+				if serr.msg != tc.msg {
+					t.Fatalf("expected: %q, got: %q", tc.msg, serr.msg)
+				}
+			}
+
+			dec := NewDecoder([]byte(tc.json))
+			var err error
+			for err == nil {
+				_, err = dec.NextToken()
+			}
+			check(err)
+
+			var v interface{}
+			check(NewDecoder([]byte(tc.json)).Decode(&v))
+		})
+	}
+}
+
+func TestDecoderInputOffset(t *testing.T) {
+	input := `{  "a" :  [ 1 ,  2 ] }`
+	want := []struct {
+		tok    string
+		offset int64
+	}{
+		{`{`, 1},
+		{`"a"`, 6},
+		{`[`, 11},
+		{`1`, 13},
+		{`2`, 18},
+		{`]`, 20},
+		{`}`, 22},
+	}
+	dec := NewDecoder([]byte(input))
+	for n, w := range want {
+		tok, err := dec.NextToken()
+		if string(tok) != w.tok {
+			t.Fatalf("%v: expected: %q, got: %q, %v", n+1, w.tok, tok, err)
+		}
+		if got := dec.InputOffset(); got != w.offset {
+			t.Fatalf("%v: expected offset %v, got %v", n+1, w.offset, got)
+		}
+		if got := input[dec.scanner.TokenStart():dec.InputOffset()]; got != w.tok {
+			t.Fatalf("%v: expected span %q, got %q", n+1, w.tok, got)
+		}
+	}
+}
+
+func TestDecoderToken(t *testing.T) {
+	input := `{"s": "a\u00e9\n", "n": [1, -2.5e3], "b": true, "z": null}`
+	want := []Token{
+		Delim('{'), "s", "a\u00e9\n", "n", Delim('['), 1.0, -2500.0, Delim(']'),
+		"b", true, "z", nil, Delim('}'),
+	}
+	for _, useNumber := range []bool{false, true} {
+		dec := NewDecoder([]byte(input))
+		if useNumber {
+			dec.UseNumber()
+			want[5], want[6] = Number("1"), Number("-2.5e3")
+		}
+		var got []Token
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			check(t, err)
+			got = append(got, tok)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("UseNumber %v: got %#v, want %#v", useNumber, got, want)
+		}
+	}
+
+	// the tokens are those of encoding/json.
+	ours, err := NewDecoder([]byte(input)).Token()
+	check(t, err)
+	theirs, err := json.NewDecoder(strings.NewReader(input)).Token()
+	check(t, err)
+	if ours != theirs {
+		t.Errorf("got %#v, encoding/json: %#v", ours, theirs)
+	}
+	if _, ok := ours.(json.Delim); !ok {
+		t.Errorf("got %T, want json.Delim", ours)
+	}
+}
+
+func TestDecoderUseNumber(t *testing.T) {
+	input := `{"a": 12345678901234567890, "b": [1.5, {"c": -0}]}`
+	dec := NewDecoder([]byte(input))
+	dec.UseNumber()
+	var got interface{}
+	check(t, dec.Decode(&got))
+	want := map[string]interface{}{
+		"a": Number("12345678901234567890"),
+		"b": []interface{}{Number("1.5"), map[string]interface{}{"c": Number("-0")}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	var s struct {
+		N Number
+		I interface{}
+	}
+	dec = NewDecoder([]byte(`{"N": 1e3, "I": 7}`))
+	check(t, dec.Decode(&s))
+	if s.N != "1e3" || s.I != 7.0 {
+		t.Errorf("got %#v", s)
+	}
+}
+
 func TestDecoderDecode(t *testing.T) {
 
 	assert := func(v interface{}, want interface{}) {
@@ -201,6 +390,21 @@ func TestDecoderDecode(t *testing.T) {
 		},
 	})
 
+	var si []int
+	decode(`[1, 2, 3]`, &si)
+	assert(si, []int{1, 2, 3})
+
+	decode(`[4]`, &si)
+	assert(si, []int{4})
+
+	var ss []string
+	decode(`[]`, &ss)
+	assert(ss, []string{})
+
+	var mn map[string]int
+	decode(`{"a": 1}`, &mn)
+	assert(mn, map[string]int{"a": 1})
+
 	ms := make(map[string]string)
 	decode(`{"hello": "world"}`, &ms)
 	assert(ms, map[string]string{
@@ -220,109 +424,3140 @@ func TestDecoderDecode(t *testing.T) {
 	})
 }
 
-func TestDecoder_NextAsBytes(t *testing.T) {
+func TestDecoderDecodeFast(t *testing.T) {
+	// named types are not handled by the fast paths, so decoding into them
+	// exercises the general path for comparison.
+	type (
+		namedString string
+		namedInt    int
+		namedFloat  float64
+		namedBool   bool
+		namedMap    map[string]interface{}
+		namedSlice  []interface{}
+	)
+	inputs := []string{
+		`"abc"`, `""`, `12`, `-7`, `1.5`, `1e3`, `99999999999999999999`, `true`, `false`, `null`,
+		`{}`, `{"a": [1, "b", {"c": null}], "d": true}`, `[]`, `[1, "b", [true], {}]`, `[1,`,
+	}
+	decode := func(input string, v interface{}) (interface{}, error) {
+		err := NewDecoder([]byte(input)).Decode(v)
+		return reflect.ValueOf(v).Elem().Interface(), err
+	}
+	for _, input := range inputs {
+		pairs := []struct{ fast, general interface{} }{
+			{new(string), new(namedString)},
+			{new(int), new(namedInt)},
+			{new(float64), new(namedFloat)},
+			{new(bool), new(namedBool)},
+			{new(map[string]interface{}), new(namedMap)},
+			{new([]interface{}), new(namedSlice)},
+		}
+		for _, p := range pairs {
+			got, gotErr := decode(input, p.fast)
+			want, wantErr := decode(input, p.general)
+			if (gotErr == nil) != (wantErr == nil) {
+				t.Errorf("decode %q into %T: expected error %v, got %v", input, p.fast, wantErr, gotErr)
+				continue
+			}
+			want = reflect.ValueOf(want).Convert(reflect.TypeOf(got)).Interface()
+			if gotErr == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("decode %q into %T: expected: %#v, got: %#v", input, p.fast, want, got)
+			}
+		}
+	}
+
+	// existing maps are merged into, existing slices are reused.
+	m := map[string]interface{}{"x": 1.0}
+	if err := NewDecoder([]byte(`{"y": 2}`)).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"x": 1.0, "y": 2.0}; !reflect.DeepEqual(m, want) {
+		t.Fatalf("expected: %v, got: %v", want, m)
+	}
+	sl := make([]interface{}, 3, 8)
+	if err := NewDecoder([]byte(`[true]`)).Decode(&sl); err != nil {
+		t.Fatal(err)
+	}
+	if len(sl) != 1 || cap(sl) != 8 || sl[0] != true {
+		t.Fatalf("expected [true] in the original array, got: %v (cap %v)", sl, cap(sl))
+	}
+}
+
+func TestDecoderDecodeTypedFast(t *testing.T) {
+	decode := func(input string, v interface{}) error {
+		t.Helper()
+		return NewDecoder([]byte(input)).Decode(v)
+	}
+
+	var ss []string
+	check(t, decode(`["a", null, "", "b"]`, &ss))
+	if want := []string{"a", "", "", "b"}; !reflect.DeepEqual(ss, want) {
+		t.Fatalf("expected: %q, got: %q", want, ss)
+	}
+	check(t, NewDecoder([]byte(`["\ud800", "\u00e9"]`), AllowLoneSurrogates()).Decode(&ss))
+	if want := []string{"\ufffd", "\u00e9"}; !reflect.DeepEqual(ss, want) {
+		t.Fatalf("expected: %q, got: %q", want, ss)
+	}
+	check(t, decode(`[]`, &ss))
+	if ss == nil || len(ss) != 0 {
+		t.Fatalf("expected empty slice, got: %#v", ss)
+	}
+
+	var is []int
+	check(t, decode(`[1, -2, null, 3]`, &is))
+	if want := []int{1, -2, 0, 3}; !reflect.DeepEqual(is, want) {
+		t.Fatalf("expected: %v, got: %v", want, is)
+	}
+
+	var fs []float64
+	check(t, decode(`[1, -2.5, null, 3e2]`, &fs))
+	if want := []float64{1, -2.5, 0, 300}; !reflect.DeepEqual(fs, want) {
+		t.Fatalf("expected: %v, got: %v", want, fs)
+	}
+	check(t, decode(`null`, &fs))
+	if fs != nil {
+		t.Fatalf("expected nil, got: %v", fs)
+	}
+
+	m := map[string]string{"x": "1"}
+	check(t, decode(`{"a": "b", "c": null}`, &m))
+	if want := map[string]string{"x": "1", "a": "b", "c": ""}; !reflect.DeepEqual(m, want) {
+		t.Fatalf("expected: %v, got: %v", want, m)
+	}
+
 	tests := []struct {
-		json   string
-		tokens []string
-		next   []byte
+		input string
+		v     interface{}
+		err   string
 	}{
-		{json: `{"a":"test"}`, tokens: []string{"{", `"a"`}, next: []byte(`"test"`)},
-		{json: `{"a":  "test"}`, tokens: []string{"{", `"a"`}, next: []byte(`"test"`)},
-		{json: `{"a":  [1, 2, 3]}`, tokens: []string{"{", `"a"`}, next: []byte(`[1, 2, 3]`)},
-		{json: `{"obj": {"some": "key"}}`, tokens: []string{"{", `"obj"`}, next: []byte(`{"some": "key"}`)},
+		{`["a", {}]`, new([]string), "at offset 6, path $[1]"},
+		{`["a", "b", []]`, new([]string), "path $[2]"},
+		{`[1, "a"]`, new([]int), "cannot decode string into Go value of type int at offset 4, path $[1]"},
+		{`[1, 1.5]`, new([]int), "cannot decode number 1.5 into Go value of type int"},
+		{`[1, true]`, new([]float64), "path $[1]"},
+		{`{"a": 1}`, new(map[string]string), `path $.a`},
+		{`{"a": ["b"]}`, new(map[string]string), `cannot decode array into Go value of type string at offset 6, path $.a`},
+		{`"a"`, new([]string), ""},
+		{`["a"`, new([]string), ""},
 	}
 	for _, tc := range tests {
-		t.Run(tc.json, func(t *testing.T) {
-			dec := NewDecoder([]byte(tc.json))
-			for n, want := range tc.tokens {
-				got, err := dec.NextToken()
-				if string(got) != want {
-					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+		err := decode(tc.input, tc.v)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("decode %q into %T: expected error containing %q, got: %v", tc.input, tc.v, tc.err, err)
+		}
+	}
+}
+
+func TestDecoderDecodeFastAllocs(t *testing.T) {
+	data := []byte(`12 1.5 true`)
+	dec := NewDecoder(nil)
+	var i int
+	var f float64
+	var b bool
+	allocs := testing.AllocsPerRun(100, func() {
+		dec.Reset(data)
+		check(t, dec.Decode(&i))
+		check(t, dec.Decode(&f))
+		check(t, dec.Decode(&b))
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestDecoderDecodeStringAllocs(t *testing.T) {
+	// once the scratch buffer has grown, a string with escapes allocates
+	// only the final string.
+	data := []byte(`"a\tb\u00e9\"c" ["x\ny", "zw"]`)
+	dec := NewDecoder(nil)
+	var s string
+	ss := make([]string, 0, 2)
+	allocs := testing.AllocsPerRun(100, func() {
+		dec.Reset(data)
+		check(t, dec.Decode(&s))
+	})
+	if allocs != 1 || s != "a\tb\u00e9\"c" {
+		t.Errorf("Decode into *string: got %q with %v allocs, want 1", s, allocs)
+	}
+	allocs = testing.AllocsPerRun(100, func() {
+		dec.Reset(data)
+		check(t, dec.Skip())
+		check(t, dec.Decode(&ss))
+	})
+	if allocs != 2 || !slices.Equal(ss, []string{"x\ny", "zw"}) {
+		t.Errorf("Decode into *[]string: got %q with %v allocs, want 2", ss, allocs)
+	}
+}
+
+func TestDecoderNextTokenAllocs(t *testing.T) {
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(t, tc.path))
+		check(t, err)
+		t.Run(tc.path, func(t *testing.T) {
+			dec := NewDecoder(data)
+			allocs := testing.AllocsPerRun(5, func() {
+				dec.Reset(data)
+				n := 0
+				for {
+					_, err := dec.NextToken()
+					if err == io.EOF {
+						break
+					}
+					check(t, err)
+					n++
 				}
+				if n != tc.tokens {
+					t.Fatalf("expected %v tokens, got %v", tc.tokens, n)
+				}
+			})
+			if allocs != 0 {
+				t.Fatalf("expected no allocations, got %v", allocs)
 			}
-			got, err := dec.NextAsBytes()
-			if !bytes.Equal(got, tc.next) {
-				t.Fatalf("expected: %q, got: %q, %v", tc.next, got, err)
+		})
+	}
+}
+
+func TestDecoderSkipAllocs(t *testing.T) {
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(t, tc.path))
+		check(t, err)
+		t.Run(tc.path, func(t *testing.T) {
+			dec := NewDecoder(data)
+			allocs := testing.AllocsPerRun(5, func() {
+				// skip each member of the top level container.
+				dec.Reset(data)
+				tok, err := dec.NextToken()
+				check(t, err)
+				obj := tok[0] == ObjectStart
+				for dec.More() {
+					if obj {
+						_, err := dec.NextToken()
+						check(t, err)
+					}
+					check(t, dec.Skip())
+				}
+				_, err = dec.NextToken()
+				check(t, err)
+
+				// and the whole document in one go.
+				dec.Reset(data)
+				check(t, dec.Skip())
+				if _, err := dec.NextToken(); err != io.EOF {
+					t.Fatalf("expected: %v, got: %v", io.EOF, err)
+				}
+			})
+			if allocs != 0 {
+				t.Fatalf("expected no allocations, got %v", allocs)
 			}
 		})
 	}
 }
 
-func TestDecoder_Skip(t *testing.T) {
-	tests := []struct {
-		json      string
-		tokens    []string
-		nextToken string
+func TestDecoderNextTokenDeepAllocs(t *testing.T) {
+	// The Decoder tracks up to 32 levels of nesting without allocating.
+	// Deeper input grows the stack once, which is the only allocation; the
+	// grown stack is kept by Reset.
+	const depth = 40
+	data := []byte(strings.Repeat("[", depth) + strings.Repeat("]", depth))
+	walk := func(dec *Decoder) {
+		for {
+			_, err := dec.NextToken()
+			if err == io.EOF {
+				return
+			}
+			check(t, err)
+		}
+	}
+
+	// one allocation for the Decoder itself, one for growing the stack.
+	allocs := testing.AllocsPerRun(5, func() {
+		walk(NewDecoder(data))
+	})
+	if allocs != 2 {
+		t.Fatalf("expected 2 allocations, got %v", allocs)
+	}
+
+	dec := NewDecoder(data)
+	allocs = testing.AllocsPerRun(5, func() {
+		dec.Reset(data)
+		walk(dec)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations after Reset, got %v", allocs)
+	}
+
+	// likewise for the bracket stack used by Skip.
+	allocs = testing.AllocsPerRun(5, func() {
+		check(t, NewDecoder(data).Skip())
+	})
+	if allocs != 2 {
+		t.Fatalf("expected 2 allocations, got %v", allocs)
+	}
+
+	shallow := data[depth-32 : depth+32]
+	allocs = testing.AllocsPerRun(5, func() {
+		walk(NewDecoder(shallow))
+		check(t, NewDecoder(shallow).Skip())
+	})
+	if allocs != 2 {
+		t.Fatalf("expected only the Decoders to be allocated, got %v", allocs)
+	}
+}
+
+func TestDecoderMaxDepth(t *testing.T) {
+	// nested returns depth levels of alternating arrays and objects around
+	// a number, and the offset of the bracket opening the last level.
+	nested := func(depth int) ([]byte, int) {
+		var open, close strings.Builder
+		last := 0
+		for i := 0; i < depth; i++ {
+			last = open.Len()
+			if i%2 == 0 {
+				open.WriteString("[")
+				close.WriteString("]")
+			} else {
+				open.WriteString(`{"a":`)
+				close.WriteString("}")
+			}
+		}
+		c := []byte(close.String())
+		slices.Reverse(c)
+		return []byte(open.String() + "1" + string(c)), last
+	}
+	walk := func(dec *Decoder) error {
+		for {
+			_, err := dec.NextToken()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	skipInner := func(dec *Decoder) error {
+		// descend a few levels with NextToken before skipping the rest.
+		for i := 0; i < 3; i++ {
+			if _, err := dec.NextToken(); err != nil {
+				return err
+			}
+		}
+		return dec.Skip()
+	}
+	ops := []struct {
+		name string
+		op   func(dec *Decoder) error
 	}{
-		{
-			json:      `{"a": "some long string", "b": true}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `"b"`,
-		},
-		{
-			json:      `{"a": {"key": "value"}, "b": true}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `"b"`,
-		},
-		{
-			json:      `{"a": [1, 2, 3]}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `}`,
-		},
-		{
-			json:      `{"a": [1, "][", 2], "b": true}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `"b"`,
-		},
-		{
-			json:      `{"a": [1, "strings starts here\" and continue after escape sequence][", 2]}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `}`,
-		},
-		{
-			json:      `{"a": [1, "strings starts here\" and continue after escape sequence][", 2]}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `}`,
-		},
-		{
-			json:      `{"a": [1, "strings starts here\" and continue after escape sequence][", 2]}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `}`,
-		},
-		{
-			json:      `{"a": {"b": 1}, "c": 3.1}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `"c"`,
-		},
-		{
-			json:      `{"a": {"b": [ "one", {"g": 1}, [true, false] ]}, "c": 3.1}`,
-			tokens:    []string{`{`, `"a"`},
-			nextToken: `"c"`,
-		},
+		{"NextToken", walk},
+		{"Decode", func(dec *Decoder) error {
+			var v interface{}
+			return dec.Decode(&v)
+		}},
+		{"Skip", func(dec *Decoder) error { return dec.Skip() }},
+		{"SkipInner", skipInner},
+		{"NextAsBytes", func(dec *Decoder) error {
+			_, err := dec.NextAsBytes()
+			return err
+		}},
 	}
 
-	for _, tc := range tests {
-		t.Run(tc.json, func(t *testing.T) {
-			dec := NewDecoder([]byte(tc.json))
-			for n, want := range tc.tokens {
-				got, err := dec.NextToken()
-				if string(got) != want {
-					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+	for _, limit := range []int{1, 2, 5, 64, DefaultMaxDepth} {
+		for _, op := range ops {
+			t.Run(fmt.Sprintf("%s/%d", op.name, limit), func(t *testing.T) {
+				if op.name == "SkipInner" && limit < 3 {
+					t.Skip("input too shallow")
 				}
-				t.Logf("token: %q, stack: %v", got, dec.stack)
+				data, _ := nested(limit)
+				dec := NewDecoder(data)
+				if limit != DefaultMaxDepth {
+					dec.SetMaxDepth(limit)
+				}
+				if err := op.op(dec); err != nil {
+					t.Fatalf("depth %d: unexpected error: %v", limit, err)
+				}
+
+				data, offset := nested(limit + 1)
+				dec.Reset(data)
+				err := op.op(dec)
+				if !errors.Is(err, ErrMaxDepthExceeded) {
+					t.Fatalf("depth %d: expected ErrMaxDepthExceeded, got: %v", limit+1, err)
+				}
+				if !strings.Contains(err.Error(), fmt.Sprintf("at offset %d", offset)) {
+					t.Fatalf("expected offset %d in error, got: %v", offset, err)
+				}
+			})
+		}
+	}
+
+	// no limit.
+	data, _ := nested(3 * DefaultMaxDepth)
+	dec := NewDecoder(data)
+	dec.SetMaxDepth(0)
+	if err := walk(dec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDecoderLimits(t *testing.T) {
+	long := strings.Repeat("x", 10)
+	data := []byte(`{"a": "` + long + `", "` + long + `": 1, "b": [1, 2, 3, 4, 5, 6], "c": true}`)
+
+	expectLimit := func(t *testing.T, err error, what string, offset int64) {
+		t.Helper()
+		var lerr *LimitError
+		if !errors.As(err, &lerr) {
+			t.Fatalf("expected *LimitError, got: %v", err)
+		}
+		if lerr.What != what || lerr.Offset != offset {
+			t.Fatalf("expected %s at offset %d, got: %v", what, offset, lerr)
+		}
+	}
+	readers := []struct {
+		name  string
+		reset func(dec *Decoder)
+	}{
+		{"bytes", func(dec *Decoder) { dec.Reset(data) }},
+		{"reader", func(dec *Decoder) { check(t, dec.ResetReader(bytes.NewReader(data))) }},
+	}
+	for _, r := range readers {
+		t.Run(r.name, func(t *testing.T) {
+			dec := NewDecoder(nil)
+
+			// at the limit.
+			dec.SetMaxStringLen(len(long))
+			r.reset(dec)
+			var v interface{}
+			check(t, dec.Decode(&v))
+
+			// over the limit, as a value and as a key.
+			dec.SetMaxStringLen(len(long) - 1)
+			r.reset(dec)
+			expectLimit(t, dec.Decode(&v), "string", 6)
+			r.reset(dec)
+			for i := 0; i < 2; i++ {
+				_, err := dec.NextToken()
+				check(t, err)
 			}
-			if err := dec.Skip(); err != nil {
-				t.Fatalf("skip: %v", err)
+			_, err := dec.NextToken()
+			expectLimit(t, err, "string", 6)
+
+			r.reset(dec)
+			for i := 0; i < 2; i++ {
+				_, err := dec.NextToken()
+				check(t, err)
 			}
-			got, err := dec.NextToken()
-			if string(got) != tc.nextToken {
-				t.Fatalf("expected: %q, got: %q, %v", tc.nextToken, string(got), err)
+			dec.SetSkipOverLimit(true)
+			check(t, dec.Skip())
+			dec.SetSkipOverLimit(false)
+			_, err = dec.NextToken()
+			expectLimit(t, err, "string", 20)
+			dec.SetMaxStringLen(0)
+
+			// values.
+			dec.SetMaxValueBytes(len(long) + 1)
+			r.reset(dec)
+			for i := 0; i < 2; i++ {
+				_, err := dec.NextToken()
+				check(t, err)
+			}
+			_, err = dec.NextAsBytes()
+			expectLimit(t, err, "value", 6)
+
+			dec.SetMaxValueBytes(len("[1, 2, 3, 4, 5, 6]") - 1)
+			r.reset(dec)
+			var keys []string
+			_, err = dec.NextToken()
+			check(t, err)
+			for dec.More() {
+				key, err := dec.NextToken()
+				check(t, err)
+				keys = append(keys, string(key))
+				if err := dec.Skip(); err != nil {
+					expectLimit(t, err, "value", int64(bytes.IndexByte(data, '[')))
+					break
+				}
+			}
+			if want := []string{`"a"`, `"` + long + `"`, `"b"`}; !reflect.DeepEqual(keys, want) {
+				t.Fatalf("expected keys %v, got: %v", want, keys)
+			}
+
+			// opting in, Skip steps over the oversized value.
+			dec.SetSkipOverLimit(true)
+			r.reset(dec)
+			keys = keys[:0]
+			_, err = dec.NextToken()
+			check(t, err)
+			for dec.More() {
+				key, err := dec.NextToken()
+				check(t, err)
+				keys = append(keys, string(key))
+				check(t, dec.Skip())
+			}
+			if want := []string{`"a"`, `"` + long + `"`, `"b"`, `"c"`}; !reflect.DeepEqual(keys, want) {
+				t.Fatalf("expected keys %v, got: %v", want, keys)
 			}
 		})
 	}
 }
 
-func BenchmarkDecoder_Skip(b *testing.B) {
+func TestDecoderMaxInputBytes(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	tests := []struct {
+		name  string
+		input string
+		limit int64
+	}{
+		{"string", `{"a": "` + long + `"}`, 50},
+		{"number", `[1, 2, 1234567890123]`, 15},
+		{"number at the limit", `[1, 2, 1234567890123]`, 20},
+		{"key", `{"` + long + `": 1}`, 20},
+		{"literal", `[true, false]`, 9},
+	}
+	readers := []struct {
+		name  string
+		reset func(dec *Decoder, data string)
+	}{
+		{"bytes", func(dec *Decoder, data string) { dec.Reset([]byte(data)) }},
+		{"reader", func(dec *Decoder, data string) { check(t, dec.ResetReader(strings.NewReader(data))) }},
+	}
+	for _, r := range readers {
+		for _, tc := range tests {
+			dec := NewDecoder(nil)
+			dec.SetMaxInputBytes(tc.limit)
+			dec.DisallowTrailingData()
+			r.reset(dec, tc.input)
+			var v interface{}
+			if err := dec.Decode(&v); !errors.Is(err, ErrInputTooLarge) {
+				t.Errorf("%s: %s: Decode: got %v, want ErrInputTooLarge", r.name, tc.name, err)
+			}
+			r.reset(dec, tc.input)
+			if err := dec.Skip(); !errors.Is(err, ErrInputTooLarge) {
+				t.Errorf("%s: %s: Skip: got %v, want ErrInputTooLarge", r.name, tc.name, err)
+			}
+			r.reset(dec, tc.input)
+			if _, err := dec.NextAsBytes(); !errors.Is(err, ErrInputTooLarge) {
+				t.Errorf("%s: %s: NextAsBytes: got %v, want ErrInputTooLarge", r.name, tc.name, err)
+			}
+
+			// the whole input fits.
+			dec.SetMaxInputBytes(int64(len(tc.input)))
+			r.reset(dec, tc.input)
+			if err := dec.Decode(&v); err != nil {
+				t.Errorf("%s: %s: at the limit: %v", r.name, tc.name, err)
+			}
+		}
+	}
+
+	dec := NewDecoder([]byte(`"` + long + `"`))
+	dec.SetMaxInputBytes(10)
+	err := dec.Decode(new(string))
+	if want := "json: input exceeds the maximum size: more than 10 bytes"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+
+	// whether anything follows a value cannot be told within the limit.
+	dec = NewDecoder([]byte(`[1, 2, 3] `), (*Decoder).DisallowTrailingData)
+	dec.SetMaxInputBytes(9)
+	if err := dec.Decode(new([]int)); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("trailing data: got %v, want ErrInputTooLarge", err)
+	}
+
+	// a value which ends within the limit is decoded, even if the rest of
+	// the input is longer.
+	dec = NewDecoder([]byte(`{"a": 1} {"b": 2}`))
+	dec.SetMaxInputBytes(10)
+	var m map[string]int
+	check(t, dec.Decode(&m))
+	if err := dec.Decode(&m); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("second value: got %v, want ErrInputTooLarge", err)
+	}
+
+	// no more is read from an io.Reader than needed to tell the input is
+	// too large.
+	r := &countingReader{r: strings.NewReader(strings.Repeat(" ", 1<<20))}
+	dec = NewDecoder(nil)
+	dec.SetMaxInputBytes(1000)
+	check(t, dec.ResetReader(r))
+	if err := dec.Skip(); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("got %v, want ErrInputTooLarge", err)
+	}
+	if r.n != 1001 {
+		t.Errorf("read %d bytes, want 1001", r.n)
+	}
+}
+
+func TestDecoderReaderLimits(t *testing.T) {
+	input := `["` + strings.Repeat("x", 1<<20) + `"]`
+
+	// an oversized string is reported once the limit is passed, without
+	// reading, or buffering, the rest of it.
+	r := &countingReader{r: strings.NewReader(input)}
+	dec := NewDecoder(nil)
+	dec.SetMaxStringLen(100)
+	check(t, dec.ResetReader(r))
+	var lerr *LimitError
+	if err := dec.Decode(new([]string)); !errors.As(err, &lerr) || lerr.What != "string" || lerr.Offset != 1 {
+		t.Errorf("Decode: got %v, want a *LimitError for the string", err)
+	}
+	if r.n > 4096 || cap(dec.readBuf()) > 4096 {
+		t.Errorf("read %d bytes into a buffer of %d, want at most 4096", r.n, cap(dec.readBuf()))
+	}
+	if err := dec.Skip(); !errors.As(err, &lerr) {
+		t.Errorf("Skip after the limit: got %v, want the *LimitError", err)
+	}
+
+	// so is an oversized value, whether returned or skipped.
+	values := `[` + strings.Repeat(`1, `, 1<<20) + `2]`
+	for _, next := range []func(*Decoder) error{
+		func(d *Decoder) error { _, err := d.NextAsBytes(); return err },
+		(*Decoder).Skip,
+	} {
+		r = &countingReader{r: strings.NewReader(values)}
+		dec = NewDecoder(nil)
+		dec.SetMaxValueBytes(100)
+		check(t, dec.ResetReader(r))
+		if err := next(dec); !errors.As(err, &lerr) || lerr.What != "value" || lerr.Offset != 0 {
+			t.Errorf("got %v, want a *LimitError for the value", err)
+		}
+		if r.n > 4096 || cap(dec.readBuf()) > 4096 {
+			t.Errorf("read %d bytes into a buffer of %d, want at most 4096", r.n, cap(dec.readBuf()))
+		}
+	}
+
+	// with SetSkipOverLimit, oversized values are skipped as they are read,
+	// in bounded memory, and the path of the next value is still known.
+	in := `{"outer": {"a": "` + strings.Repeat("x", 1<<20) + `", "b": ` + values + `, "c": "x"}}`
+	r = &countingReader{r: strings.NewReader(in)}
+	dec = NewDecoder(nil)
+	dec.SetMaxStringLen(100)
+	dec.SetMaxValueBytes(100)
+	dec.SetSkipOverLimit(true)
+	check(t, dec.ResetReader(r))
+	var v struct{ Outer struct{ C int } }
+	var typeErr *UnmarshalTypeError
+	if err := dec.Decode(&v); !errors.As(err, &typeErr) || typeErr.Path != "$.outer.c" {
+		t.Errorf("Decode: got %v, want an *UnmarshalTypeError at $.outer.c", err)
+	}
+	if r.n != len(in) || cap(dec.readBuf()) > 4096 {
+		t.Errorf("read %d bytes of %d into a buffer of %d, want all into at most 4096", r.n, len(in), cap(dec.readBuf()))
+	}
+
+	// the maximum input size bounds what is read.
+	r = &countingReader{r: strings.NewReader(input)}
+	dec = NewDecoder(nil)
+	dec.SetMaxInputBytes(1000)
+	check(t, dec.ResetReader(r))
+	if err := dec.Decode(new([]string)); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("Decode: got %v, want ErrInputTooLarge", err)
+	}
+	if r.n != 1001 {
+		t.Errorf("read %d bytes, want 1001", r.n)
+	}
+}
+
+func TestDecoderReaderIncremental(t *testing.T) {
+	// reading one byte at a time, the tokens, values and errors are those of
+	// the same input given as a []byte, wherever the reads split it.
+	for _, tc := range []struct {
+		in   string
+		opts []Option
+	}{
+		{"\xef\xbb\xbf[1, -2.5e+10, \"a\\\"b\\u00e9\", true, null, {}]", nil},
+		{`{"a": [1, "]\\"], "b": {"c": "d\\\\"}} [3] "x" 4`, nil},
+		{`[1, 2`, nil},
+		{`["abc\u12`, nil},
+		{`["abc\q"]`, nil},
+		{`[tru]`, nil},
+		{`[1.]`, nil},
+		{"{a: 'b\\'c', // comment\n /* block */ \"d\": NaN, e: [-Infinity, '}'],}", []Option{AllowRelaxedStrings(), AllowComments(), AllowNaNInf(), AllowTrailingCommas()}},
+		{`[1] /* unterminated`, []Option{AllowComments()}},
+		{"[\"\xff\"]", []Option{ValidateUTF8()}},
+		{"[\"\xff\"]", []Option{ReplaceInvalidUTF8()}},
+	} {
+		for _, next := range []func(*Decoder) ([]byte, error){(*Decoder).NextToken, (*Decoder).NextAsBytes} {
+			want := collect(NewDecoder([]byte(tc.in), tc.opts...), next)
+			dec := NewDecoder(nil, tc.opts...)
+			check(t, dec.ResetReader(iotest.OneByteReader(strings.NewReader(tc.in))))
+			if got := collect(dec, next); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %q, want %q", tc.in, got, want)
+			}
+		}
+	}
+
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(t, tc.path))
+		check(t, err)
+		var want, got interface{}
+		check(t, Unmarshal(data, &want))
+		dec := NewDecoder(nil)
+		check(t, dec.ResetReader(iotest.OneByteReader(bytes.NewReader(data))))
+		check(t, dec.Decode(&got))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Decode from an io.Reader differs", tc.path)
+		}
+		check(t, dec.ResetReader(iotest.HalfReader(bytes.NewReader(data))))
+		if b, err := dec.NextAsBytes(); err != nil || !bytes.Equal(b, bytes.TrimSpace(data)) {
+			t.Errorf("%s: NextAsBytes from an io.Reader: got %d bytes, %v", tc.path, len(b), err)
+		}
+	}
+
+	// a stream of values is read as it is decoded, and the input of those
+	// decoded is discarded, so that the buffer stays small.
+	value := `{"id": 12345, "name": "` + strings.Repeat("x", 100) + `", "tags": ["a", "b"]}` + "\n"
+	const n = 10000
+	r := &countingReader{r: strings.NewReader(strings.Repeat(value, n))}
+	dec := NewDecoder(nil)
+	check(t, dec.ResetReader(r))
+	var v map[string]interface{}
+	check(t, dec.Decode(&v))
+	if r.n > 1024 {
+		t.Errorf("first Decode read %d bytes, want at most 1024", r.n)
+	}
+	for i := 1; i < n; i++ {
+		check(t, dec.Decode(&v))
+	}
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Errorf("Decode: got %v, want io.EOF", err)
+	}
+	if c := cap(dec.readBuf()); c > 4096 {
+		t.Errorf("buffer grew to %d bytes", c)
+	}
+	if dec.InputOffset() != int64(len(value)*n) {
+		t.Errorf("InputOffset: got %d, want %d", dec.InputOffset(), len(value)*n)
+	}
+
+	// the lines and columns of errors count the input discarded.
+	in := strings.Repeat("{\"a\": 1}\n", 1000) + `{"a": x}`
+	var want error
+	for dec.Reset([]byte(in)); want == nil; want = dec.Decode(&v) {
+	}
+	var got error
+	for check(t, dec.ResetReader(strings.NewReader(in))); got == nil; got = dec.Decode(&v) {
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// an error reading the input is returned in place of the end of input.
+	check(t, dec.ResetReader(io.MultiReader(strings.NewReader(`[1, `), iotest.ErrReader(iotest.ErrTimeout))))
+	if err := dec.Decode(new(interface{})); err != iotest.ErrTimeout {
+		t.Errorf("Decode: got %v, want %v", err, iotest.ErrTimeout)
+	}
+}
+
+// collect returns what next returns for each value or token of d, with the
+// offset after it, and the error which ends them.
+func collect(d *Decoder, next func(*Decoder) ([]byte, error)) []string {
+	var out []string
+	for {
+		b, err := next(d)
+		if err != nil {
+			return append(out, err.Error())
+		}
+		out = append(out, fmt.Sprintf("%s@%d", b, d.InputOffset()))
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	type inner struct {
+		X int `json:"x"`
+	}
+	type outer struct {
+		A  int               `json:"a"`
+		In inner             `json:"in"`
+		M  map[string]string `json:"m"`
+		_  int
+	}
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{"a": 1, "in": {"x": 2}, "m": {"any": "key"}}`, ""},
+		{`{"a": 1, "b": 2}`, `unknown field "b" in json.outer at offset 9`},
+		{`{"a": 1, "in": {"x": 2, "y": 3}}`, `unknown field "y" in json.inner at offset 24`},
+		{`{"in": {}, "z": 1}`, `unknown field "z" in json.outer at offset 11`},
+	}
+	for _, tc := range tests {
+		dec := NewDecoder([]byte(tc.input))
+		dec.DisallowUnknownFields()
+		var v outer
+		err := dec.Decode(&v)
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("decode %q: unexpected error: %v", tc.input, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("decode %q: expected error containing %q, got: %v", tc.input, tc.err, err)
+		}
+
+		// without the option unknown keys are skipped.
+		if err := NewDecoder([]byte(tc.input)).Decode(&v); err != nil {
+			t.Errorf("decode %q: unexpected error: %v", tc.input, err)
+		}
+	}
+
+	// map destinations accept any key.
+	dec := NewDecoder([]byte(`{"a": {"b": 1}}`))
+	dec.DisallowUnknownFields()
+	var m map[string]map[string]int
+	if err := dec.Decode(&m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDecoderDisallowDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{"a": 1, "b": {"a": 2, "b": [{"a": 3}, {"a": 4}]}, "c": {"a": 5}}`, ""},
+		{`[{"a": 1}, {"a": 2}]`, ""},
+		{`{"a": 1, "a": 2}`, `duplicate key "a" at offset 9`},
+		{`{"a": {"b": 1, "c": 2, "b": 3}}`, `duplicate key "b" at offset 23`},
+		{`{"a": {"b": 1}, "b": 2, "a": 3}`, `duplicate key "a" at offset 24`},
+		{`[{"a": [], "x": 1, "a": {}}]`, `duplicate key "a" at offset 19`},
+
+		// keys are compared once unescaped.
+		{`{"a": 1, "\u0061": 2}`, `duplicate key "a" at offset 9`},
+		{`{"\u0061": 1, "a": 2}`, `duplicate key "a" at offset 14`},
+		{`{"\/": 1, "/": 2}`, `duplicate key "/" at offset 10`},
+		{`{"a/b": 1, "a\/b": 2}`, `duplicate key "a/b" at offset 11`},
+		{`{"\u00e9": {"\u00e9": 1}, "\u00E9": 2}`, `duplicate key "é" at offset 26`},
+		{`{"\u0061": 1, "\u0062": 2, "\n": 3, "\\n": 4}`, ""},
+	}
+	for _, tc := range tests {
+		for _, mode := range []string{"NextToken", "Decode"} {
+			dec := NewDecoder([]byte(tc.input))
+			dec.DisallowDuplicateKeys()
+			var err error
+			if mode == "Decode" {
+				var v interface{}
+				err = dec.Decode(&v)
+			} else {
+				for err == nil {
+					_, err = dec.NextToken()
+				}
+				if err == io.EOF {
+					err = nil
+				}
+			}
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("%s %q: unexpected error: %v", mode, tc.input, err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("%s %q: expected error containing %q, got: %v", mode, tc.input, tc.err, err)
+			}
+		}
+	}
+
+	// by default the last value wins.
+	var m map[string]int
+	check(t, NewDecoder([]byte(`{"a": 1, "a": 2}`)).Decode(&m))
+	if m["a"] != 2 {
+		t.Fatalf("expected the last value to win, got: %v", m)
+	}
+
+	// Skip does not check the values it consumes.
+	dec := NewDecoder([]byte(`{"a": {"b": 1, "b": 2}}`))
+	dec.DisallowDuplicateKeys()
+	for _, f := range []func() error{
+		func() error { _, err := dec.NextToken(); return err },
+		func() error { _, err := dec.NextToken(); return err },
+		dec.Skip,
+		func() error { _, err := dec.NextToken(); return err },
+	} {
+		check(t, f())
+	}
+
+	// the scratch space is reused from object to object.
+	data := []byte(`[{"a": 1, "b": 2}, {"a": 1, "b": 2}, {"c": {"a": 1}}]`)
+	dec.Reset(data)
+	allocs := testing.AllocsPerRun(10, func() {
+		dec.Reset(data)
+		var err error
+		for err == nil {
+			_, err = dec.NextToken()
+		}
+		if err != io.EOF {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestDecoderCaseInsensitiveFields(t *testing.T) {
+	type record struct {
+		ID    int
+		Id    int
+		Name  string `json:"name"`
+		Skate string
+	}
+	tests := []string{
+		`{"ID": 1, "Id": 2}`,
+		`{"Id": 2, "ID": 1}`,
+		`{"id": 3}`,
+		`{"iD": 4, "ID": 5}`,
+		`{"NAME": "n"}`,
+		`{"ſkate": "unicode fold", "name": "x"}`,
+		`{"nope": 1}`,
+	}
+	for _, input := range tests {
+		var got, want record
+		check(t, NewDecoder([]byte(input)).Decode(&got))
+		check(t, json.Unmarshal([]byte(input), &want))
+		if got != want {
+			t.Errorf("decode %q: expected: %+v, got: %+v", input, want, got)
+		}
+	}
+
+	dec := NewDecoder([]byte(`{"id": 1, "Id": 2, "NAME": "n"}`))
+	dec.MatchCaseSensitive()
+	var r record
+	check(t, dec.Decode(&r))
+	if want := (record{Id: 2}); r != want {
+		t.Fatalf("expected: %+v, got: %+v", want, r)
+	}
+
+	data := []byte(`{"iD": 1, "NaMe": "name"}`)
+	allocs := testing.AllocsPerRun(10, func() {
+		dec.Reset(data)
+		dec.matchCaseSensitive = false
+		check(t, dec.Decode(&r))
+	})
+	if allocs != 1 {
+		t.Fatalf("expected 1 allocation for the name string, got %v", allocs)
+	}
+}
+
+func TestDecoderNullNoop(t *testing.T) {
+	type inner struct {
+		X int `json:"x"`
+	}
+	type record struct {
+		Name  string            `json:"name"`
+		Count int               `json:"count"`
+		Ok    bool              `json:"ok"`
+		Score float64           `json:"score"`
+		In    inner             `json:"in"`
+		Ptr   *int              `json:"ptr"`
+		Tags  []string          `json:"tags"`
+		Attrs map[string]string `json:"attrs"`
+		Any   interface{}       `json:"any"`
+	}
+	one := 1
+	prefilled := func() record {
+		return record{
+			Name: "name", Count: 2, Ok: true, Score: 1.5, In: inner{X: 3}, Ptr: &one,
+			Tags: []string{"a"}, Attrs: map[string]string{"k": "v"}, Any: "x",
+		}
+	}
+
+	got := prefilled()
+	check(t, NewDecoder([]byte(`{"name": null}`)).Decode(&got))
+	if !reflect.DeepEqual(got, prefilled()) {
+		t.Fatalf("expected Name to be untouched, got: %+v", got)
+	}
+
+	input := `{"name": null, "count": null, "ok": null, "score": null, "in": null,
+		"ptr": null, "tags": null, "attrs": null, "any": null}`
+	got, want := prefilled(), prefilled()
+	check(t, NewDecoder([]byte(input)).Decode(&got))
+	check(t, json.Unmarshal([]byte(input), &want))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected: %+v, got: %+v", want, got)
+	}
+	if got.Name != "name" || got.Count != 2 || !got.Ok || got.In.X != 3 || got.Ptr != nil || got.Tags != nil || got.Attrs != nil || got.Any != nil {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+
+	// top level values, and elements of reused slices.
+	n, str, b := 7, "s", true
+	check(t, NewDecoder([]byte(`null`)).Decode(&n))
+	check(t, NewDecoder([]byte(`null`)).Decode(&str))
+	check(t, NewDecoder([]byte(`null`)).Decode(&b))
+	if n != 7 || str != "s" || !b {
+		t.Fatalf("expected values to be untouched, got: %v %q %v", n, str, b)
+	}
+	type ints []int
+	for _, v := range []interface{}{&[]int{1, 2, 3}, &[]string{"a", "b", "c"}, &ints{1, 2, 3}} {
+		// want is a copy of v, with its own backing array.
+		sv := reflect.ValueOf(v).Elem()
+		want := reflect.New(sv.Type())
+		want.Elem().Set(reflect.AppendSlice(reflect.MakeSlice(sv.Type(), 0, sv.Len()), sv))
+		check(t, NewDecoder([]byte(`[null, null]`)).Decode(v))
+		check(t, json.Unmarshal([]byte(`[null, null]`), want.Interface()))
+		if !reflect.DeepEqual(v, want.Interface()) {
+			t.Errorf("expected: %v, got: %v", want.Elem(), reflect.ValueOf(v).Elem())
+		}
+	}
+}
+
+func TestDecoderMergeSemantics(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	type ints []int // takes the reflect based path
+
+	// slices are truncated or grown, reusing the backing array.
+	s := []int{9, 9, 9, 9, 9}
+	orig := &s[0]
+	check(t, NewDecoder([]byte(`[1, 2]`)).Decode(&s))
+	if !reflect.DeepEqual(s, []int{1, 2}) || cap(s) != 5 || &s[0] != orig {
+		t.Fatalf("expected [1 2] in the original array, got: %v (cap %v)", s, cap(s))
+	}
+	is := ints{9, 9, 9, 9, 9}
+	orig = &is[0]
+	check(t, NewDecoder([]byte(`[1, 2]`)).Decode(&is))
+	if !reflect.DeepEqual(is, ints{1, 2}) || cap(is) != 5 || &is[0] != orig {
+		t.Fatalf("expected [1 2] in the original array, got: %v (cap %v)", is, cap(is))
+	}
+	check(t, NewDecoder([]byte(`[1, 2, 3, 4, 5, 6, 7]`)).Decode(&is))
+	if !reflect.DeepEqual(is, ints{1, 2, 3, 4, 5, 6, 7}) {
+		t.Fatalf("expected the slice to grow, got: %v", is)
+	}
+
+	// reused struct elements, within the length and in spare capacity, are
+	// merged into rather than reset; as in encoding/json.
+	inputs := []string{`[{"X": 5}]`, `[{"Y": 6}, {"X": 7}]`, `[{"X": 1}, {"Y": 2}, {"X": 3}, {"Y": 4}]`, `[]`}
+	for _, input := range inputs {
+		backing := func() []point { return []point{{1, 2}, {3, 4}, {5, 6}}[:1] }
+		got, want := backing(), backing()
+		check(t, NewDecoder([]byte(input)).Decode(&got))
+		check(t, json.Unmarshal([]byte(input), &want))
+		if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(got[:cap(got)], want[:cap(want)]) {
+			t.Errorf("decode %s: expected: %v (%v), got: %v (%v)", input, want, want[:cap(want)], got, got[:cap(got)])
+		}
+	}
+
+	// maps are merged into, each value is decoded into a fresh value.
+	m := map[string]point{"a": {1, 2}, "b": {3, 4}}
+	want := map[string]point{"a": {1, 2}, "b": {3, 4}}
+	input := `{"b": {"X": 5}, "c": {"Y": 6}}`
+	check(t, NewDecoder([]byte(input)).Decode(&m))
+	check(t, json.Unmarshal([]byte(input), &want))
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("expected: %v, got: %v", want, m)
+	}
+	for _, v := range []interface{}{
+		&map[string]interface{}{"a": 1.0, "b": 2.0},
+		&map[string]string{"a": "1", "b": "2"},
+		&map[string]int{"a": 1, "b": 2},
+	} {
+		check(t, NewDecoder([]byte(`{"b": null, "c": null}`)).Decode(v))
+		if m := reflect.ValueOf(v).Elem(); m.Len() != 3 || !m.MapIndex(reflect.ValueOf("a")).IsValid() {
+			t.Errorf("expected keys a, b and c, got: %v", m)
+		}
+	}
+}
+
+func TestDecoderInterfacePointer(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	type shape struct {
+		Kind   string
+		Center interface{}
+	}
+
+	// a non-nil pointer held by an interface is decoded into, so the caller
+	// chooses the type of the value.
+	p := &point{X: 1, Y: 2}
+	var v interface{} = p
+	check(t, NewDecoder([]byte(`{"Y": 3}`)).Decode(&v))
+	if v != p || *p != (point{1, 3}) {
+		t.Errorf("got %#v, want &point{1, 3} in the original pointer", v)
+	}
+	var s fmt.Stringer = &time.Location{}
+	if err := NewDecoder([]byte(`"x"`)).Decode(&s); err == nil {
+		t.Errorf("decoding a string into a *time.Location: got nil error")
+	}
+
+	newPoint := func() interface{} { return &point{X: 1} }
+	for _, tc := range []struct {
+		in  string
+		new func() interface{}
+	}{
+		{`{"Y": 2}`, newPoint},
+		{`null`, newPoint},
+		{`"x"`, newPoint},
+		{`{"Y": 2}`, func() interface{} { return point{X: 1} }},
+		{`{"Y": 2}`, func() interface{} { return (*point)(nil) }},
+		{`{"Y": 2}`, func() interface{} { q := &point{X: 1}; return &q }},
+		{`null`, func() interface{} { q := &point{X: 1}; return &q }},
+		{`{"Kind": "circle", "Center": {"Y": 2}}`, func() interface{} { return &shape{Center: &point{X: 1}} }},
+		{`{"Kind": "circle", "Center": null}`, func() interface{} { return &shape{Center: &point{X: 1}} }},
+		{`[1, 2]`, func() interface{} { return &[]int{9, 9, 9} }},
+		{`[{"Y": 2}]`, func() interface{} { return []interface{}{&point{X: 1}} }},
+	} {
+		got, want := tc.new(), tc.new()
+		gotErr := NewDecoder([]byte(tc.in)).Decode(&got)
+		wantErr := json.Unmarshal([]byte(tc.in), &want)
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Errorf("decode %s: got error %v, want %v", tc.in, gotErr, wantErr)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("decode %s: got %#v, want %#v", tc.in, got, want)
+		}
+	}
+}
+
+func TestDecoderArray(t *testing.T) {
+	type vertex struct {
+		P    [3]float64 `json:"p"`
+		Tags [2]string  `json:"tags"`
+	}
+	type mesh struct {
+		Faces [2][3]vertex `json:"faces"`
+		Hash  [16]byte     `json:"hash"`
+	}
+	tests := []struct {
+		input string
+		v     func() interface{}
+	}{
+		{`[1, 2]`, func() interface{} { return &[4]float64{9, 9, 9, 9} }},
+		{`[1, 2, 3, 4]`, func() interface{} { return &[4]float64{9, 9, 9, 9} }},
+		{`[1, 2, 3, 4, 5, {"x": [6]}, [7]]`, func() interface{} { return &[4]float64{9, 9, 9, 9} }},
+		{`[]`, func() interface{} { return &[2]int{9, 9} }},
+		{`null`, func() interface{} { return &[2]int{9, 9} }},
+		{`[1, null]`, func() interface{} { return &[2]int{9, 9} }},
+		{`[[1], [2, 3, 4], [5, 6]]`, func() interface{} { return &[2][2]int{} }},
+		{`[0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 255]`, func() interface{} { return new([16]byte) }},
+		{`{"faces": [[{"p": [1, 2, 3], "tags": ["a"]}, {"p": [4]}], [{}, {}, {}, {"p": [0]}]], "hash": [1, 2]}`,
+			func() interface{} { return new(mesh) }},
+	}
+	for _, tc := range tests {
+		got, want := tc.v(), tc.v()
+		check(t, NewDecoder([]byte(tc.input)).Decode(got))
+		check(t, json.Unmarshal([]byte(tc.input), want))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("decode %s: expected: %v, got: %v", tc.input, want, got)
+		}
+	}
+
+	errs := []struct {
+		input string
+		v     interface{}
+		err   string
+	}{
+		{`{"a": 1}`, new([2]int), "cannot decode object into Go value of type [2]int at offset 0"},
+		{`  "ab"`, new([2]int), "cannot decode string into Go value of type [2]int at offset 2"},
+		{`{"hash": 5}`, new(mesh), "cannot decode number into Go struct field mesh.hash of type [16]uint8 at offset 9"},
+		{`[1, "a"]`, new([2]int), ""},
+	}
+	for _, tc := range errs {
+		err := NewDecoder([]byte(tc.input)).Decode(tc.v)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("decode %s into %T: expected error containing %q, got: %v", tc.input, tc.v, tc.err, err)
+		}
+	}
+}
+
+type Meta struct {
+	ID      int    `json:"id"`
+	Label   string `json:"Kind"` // tagged, so wins over Payload.Kind
+	Version int    // ambiguous with Payload.Version, so dropped
+}
+
+type Payload struct {
+	Kind    string
+	Version int
+	Data    map[string]int
+}
+
+type Deep struct {
+	Who   string `json:"who"` // deeper than Audit.Who, so shadowed
+	Level int    `json:"level"`
+}
+
+type Audit struct {
+	*Deep
+	Who string `json:"who"`
+}
+
+type event struct {
+	Meta
+	*Payload
+	Audit
+	Source string `json:"source"`
+}
+
+func TestDecoderEmbedded(t *testing.T) {
+	input := `{"id": 1, "Kind": "k", "Version": 2, "Data": {"a": 1}, "level": 3, "who": "me", "source": "s"}`
+
+	var got, want event
+	if got.Payload != nil || got.Deep != nil {
+		t.Fatalf("expected nil embedded pointers before decoding")
+	}
+	check(t, NewDecoder([]byte(input)).Decode(&got))
+	check(t, json.Unmarshal([]byte(input), &want))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected: %+v, got: %+v", want, got)
+	}
+	if got.Payload == nil || got.Data["a"] != 1 || got.Deep == nil || got.Level != 3 {
+		t.Fatalf("expected embedded pointers to be allocated, got: %+v", got)
+	}
+	if got.Meta.ID != 1 || got.Label != "k" || got.Payload.Kind != "" {
+		t.Fatalf("expected the tagged field to win, got: %+v %+v", got.Meta, *got.Payload)
+	}
+	if got.Meta.Version != 0 || got.Payload.Version != 0 {
+		t.Fatalf("expected the ambiguous field to be dropped, got: %+v %+v", got.Meta, *got.Payload)
+	}
+	if got.Audit.Who != "me" || got.Deep.Who != "" {
+		t.Fatalf("expected the shallower field to win, got: %+v %+v", got.Audit, *got.Deep)
+	}
+
+	// fields of the same name at the same depth without a tag cancel out.
+	type A struct{ X, Y int }
+	type B struct{ X, Z int }
+	type ambiguous struct {
+		A
+		B
+	}
+	var amb, ambWant ambiguous
+	input = `{"X": 1, "Y": 2, "Z": 3}`
+	check(t, NewDecoder([]byte(input)).Decode(&amb))
+	check(t, json.Unmarshal([]byte(input), &ambWant))
+	if amb != ambWant || amb.A.X != 0 || amb.B.X != 0 || amb.Y != 2 || amb.Z != 3 {
+		t.Fatalf("expected: %+v, got: %+v", ambWant, amb)
+	}
+
+	// an embedded pointer to an unexported struct type cannot be allocated.
+	type hidden struct{ X int }
+	type outer struct{ *hidden }
+	var o outer
+	if err := NewDecoder([]byte(`{"X": 1}`)).Decode(&o); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+// uuid is a map key type implementing encoding.TextUnmarshaler.
+type uuid [16]byte
+
+func (u *uuid) UnmarshalText(text []byte) error {
+	h := bytes.ReplaceAll(text, []byte("-"), nil)
+	if len(h) != 32 {
+		return fmt.Errorf("invalid uuid length %d", len(text))
+	}
+	_, err := hexenc.Decode(u[:], h)
+	return err
+}
+
+func (u uuid) MarshalText() ([]byte, error) {
+	h := hexenc.EncodeToString(u[:])
+	return []byte(h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]), nil
+}
+
+func TestDecoderMapKeys(t *testing.T) {
+	type id int16
+	tests := []struct {
+		input string
+		v     interface{}
+	}{
+		{`{"1": "a", "2": "b", "-3": "c"}`, new(map[int]string)},
+		{`{"127": 1, "-128": 2}`, new(map[int8]int)},
+		{`{"65535": true}`, new(map[uint16]bool)},
+		{`{"18446744073709551615": 1}`, new(map[uint64]int)},
+		{`{"7": [1]}`, new(map[id][]int)},
+		{`{"123e4567-e89b-12d3-a456-426614174000": 1, "00000000-0000-0000-0000-000000000000": 2}`, new(map[uuid]int)},
+	}
+	for _, tc := range tests {
+		got := reflect.New(reflect.TypeOf(tc.v).Elem()).Interface()
+		check(t, NewDecoder([]byte(tc.input)).Decode(got))
+		check(t, json.Unmarshal([]byte(tc.input), tc.v))
+		if !reflect.DeepEqual(got, tc.v) {
+			t.Errorf("decode %s: expected: %v, got: %v", tc.input, tc.v, got)
+		}
+		// and back.
+		out, err := Marshal(got)
+		check(t, err)
+		var roundtrip interface{}
+		check(t, json.Unmarshal(out, &roundtrip))
+		var orig interface{}
+		check(t, json.Unmarshal([]byte(tc.input), &orig))
+		if !reflect.DeepEqual(roundtrip, orig) {
+			t.Errorf("marshal %v: expected: %s, got: %s", got, tc.input, out)
+		}
+	}
+
+	errs := []struct {
+		input string
+		v     interface{}
+		err   string
+	}{
+		{`{"1": 1, "200": 2}`, new(map[int8]int), `cannot decode key "200" into int8 at offset 9`},
+		{`{"-1": 1}`, new(map[uint]int), `cannot decode key "-1" into uint at offset 1`},
+		{`{"x": 1}`, new(map[int]int), `cannot decode key "x" into int at offset 1`},
+		{`{"1.5": 1}`, new(map[int]int), `cannot decode key "1.5" into int at offset 1`},
+		{`{"not-a-uuid": 1}`, new(map[uuid]int), `cannot decode key "not-a-uuid" into json.uuid at offset 1: invalid uuid length`},
+		{`{"a": 1}`, new(map[bool]int), `cannot decode object into Go value of type map[bool]int`},
+	}
+	for _, tc := range errs {
+		err := NewDecoder([]byte(tc.input)).Decode(tc.v)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("decode %s into %T: expected error containing %q, got: %v", tc.input, tc.v, tc.err, err)
+		}
+	}
+}
+
+func TestNewDecoderString(t *testing.T) {
+	docs := []string{
+		``, `   `, `null`, `"abc"`, `"a\u00e9\n"`, "\xef\xbb\xbf[1, 2]", `1 "two" [3] {"four": 4}`,
+		`{"a": [1, "b", {"c": null}], "d": true, "e": -1.5e3}`, `[1, 2`, `{"a" 1}`, `[1, 2] x`,
+		`"\ud800"`, `{"a": {"b": [[], {}]}, "c": "\"]"}`,
+	}
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(t, tc.path))
+		check(t, err)
+		docs = append(docs, string(data))
+	}
+	// each op is run to the end of the input on decoders from both
+	// constructors, recording what it returns.
+	ops := map[string]func(d *Decoder) (string, error){
+		"NextToken": func(d *Decoder) (string, error) {
+			tok, err := d.NextToken()
+			return string(tok), err
+		},
+		"Token": func(d *Decoder) (string, error) {
+			tok, err := d.Token()
+			return fmt.Sprint(tok), err
+		},
+		"Decode": func(d *Decoder) (string, error) {
+			var v interface{}
+			err := d.Decode(&v)
+			return fmt.Sprint(v), err
+		},
+		"NextAsBytes": func(d *Decoder) (string, error) {
+			b, err := d.NextAsBytes()
+			return string(b), err
+		},
+		"Skip": func(d *Decoder) (string, error) {
+			return "", d.Skip()
+		},
+	}
+	trace := func(d *Decoder, op func(d *Decoder) (string, error)) []string {
+		var out []string
+		for len(out) < 1<<20 {
+			v, err := op(d)
+			out = append(out, fmt.Sprintf("%s %v %d %d", v, err, d.InputOffset(), d.Depth()))
+			if err != nil {
+				break
+			}
+		}
+		return out
+	}
+	for _, in := range docs {
+		for name, op := range ops {
+			want := trace(NewDecoder([]byte(in), AllowLoneSurrogates()), op)
+			got := trace(NewDecoderString(in, AllowLoneSurrogates()), op)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s(%.40q): got %.200q, want %.200q", name, in, got, want)
+			}
+		}
+	}
+
+	// the input is not copied.
+	in := `{"a": 1}`
+	if d := NewDecoderString(in); unsafe.SliceData(d.scanner.data) != unsafe.StringData(in) {
+		t.Errorf("NewDecoderString: input copied")
+	}
+}
+
+func TestDecoder_Reset(t *testing.T) {
+	dec := NewDecoder([]byte(`[[{"a": [1, 2`))
+	var v interface{}
+	if err := dec.Decode(&v); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	dec.Reset([]byte(`[3]`))
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(v, []interface{}{3.0}) {
+		t.Fatalf("expected: %v, got: %v", []interface{}{3.0}, v)
+	}
+
+	if err := dec.ResetReader(strings.NewReader(`{"b": [true]}`)); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := map[string]interface{}{"b": []interface{}{true}}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("expected: %v, got: %v", want, v)
+	}
+
+	// a second ResetReader reuses the buffer from the first.
+	buf := dec.readBuf()
+	if err := dec.ResetReader(strings.NewReader(`"c"`)); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if &dec.scanner.data[:1][0] != &buf[:1][0] {
+		t.Fatalf("expected ResetReader to reuse its buffer")
+	}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if v != "c" {
+		t.Fatalf("expected: %v, got: %v", "c", v)
+	}
+}
+
+func TestDecoderDecodeStream(t *testing.T) {
+	type record struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	input := `{"a": 1, "b": "one"}{"a": 2, "b": "two"}
+	{"a": 3, "b": "three"}
+`
+	want := []record{{1, "one"}, {2, "two"}, {3, "three"}}
+
+	dec := NewDecoder([]byte(input))
+	var got []record
+	for {
+		var r record
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		got = append(got, r)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
+}
+
+func TestDecoderStreamGarbage(t *testing.T) {
+	dec := NewDecoder([]byte(`{"a":1} x {"a":2}`))
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	err := dec.Decode(&v)
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected *SyntaxError, got: %v", err)
+	}
+	if serr.Offset != 8 {
+		t.Fatalf("expected offset 8, got %v", serr.Offset)
+	}
+}
+
+func TestDecoderDisallowTrailingData(t *testing.T) {
+	tests := []struct {
+		json   string
+		offset int64 // of the first extra byte, or -1 if valid
+	}{
+		{json: `{"a":1}`, offset: -1},
+		{json: "{\"a\":1} \n\t ", offset: -1},
+		{json: `{"a":1},`, offset: 7},
+		{json: `{"a":1} x`, offset: 8},
+		{json: `{"a":1} {"a":2}`, offset: 8},
+		{json: `1 2`, offset: 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			dec := NewDecoder([]byte(tc.json))
+			dec.DisallowTrailingData()
+			var v interface{}
+			err := dec.Decode(&v)
+			if tc.offset < 0 {
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				return
+			}
+			var serr *SyntaxError
+			if !errors.As(err, &serr) {
+				t.Fatalf("expected *SyntaxError, got: %v", err)
+			}
+			if serr.Offset != tc.offset {
+				t.Fatalf("expected offset %v, got %v", tc.offset, serr.Offset)
+			}
+		})
+	}
+}
+
+func TestDecoderDisallowTrailingDataNested(t *testing.T) {
+	// a Decode of a value inside the document does not look past it.
+	dec := NewDecoder([]byte(`{"a": 1, "b": [2, 3]} `), (*Decoder).DisallowTrailingData)
+	got := map[string]interface{}{}
+	check(t, dec.Object(func(key []byte) error {
+		var v interface{}
+		err := dec.Decode(&v)
+		got[string(key)] = v
+		return err
+	}))
+	if want := map[string]interface{}{"a": 1.0, "b": []interface{}{2.0, 3.0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Object: got %v, want %v", got, want)
+	}
+
+	dec = NewDecoder([]byte(`[{"a": 1}, 2, "x"] `), (*Decoder).DisallowTrailingData)
+	var elems []interface{}
+	check(t, dec.Array(func(int) error {
+		var v interface{}
+		err := dec.Decode(&v)
+		elems = append(elems, v)
+		return err
+	}))
+	if want := []interface{}{map[string]interface{}{"a": 1.0}, 2.0, "x"}; !reflect.DeepEqual(elems, want) {
+		t.Errorf("Array: got %v, want %v", elems, want)
+	}
+	if _, err := dec.NextToken(); err != io.EOF {
+		t.Errorf("after Array: got %v, want io.EOF", err)
+	}
+
+	// nor does one in a DecodeFunc under Unmarshal, for a nested value or
+	// for the top-level value itself, while the trailing data of the
+	// document is still reported.
+	type pair struct{ A, B int }
+	RegisterDecodeFunc(reflect.TypeOf(pair{}), func(dec *Decoder, v reflect.Value) error {
+		var ab []int
+		if err := dec.Decode(&ab); err != nil {
+			return err
+		}
+		if len(ab) != 2 {
+			return fmt.Errorf("got %d numbers, want 2", len(ab))
+		}
+		v.Set(reflect.ValueOf(pair{ab[0], ab[1]}))
+		return nil
+	})
+	defer RegisterDecodeFunc(reflect.TypeOf(pair{}), nil)
+	var pairs []pair
+	check(t, Unmarshal([]byte(`[[1, 2], [3, 4]] `), &pairs))
+	if want := []pair{{1, 2}, {3, 4}}; !reflect.DeepEqual(pairs, want) {
+		t.Errorf("Unmarshal: got %v, want %v", pairs, want)
+	}
+	var p pair
+	check(t, Unmarshal([]byte(` [5, 6] `), &p))
+	if p != (pair{5, 6}) {
+		t.Errorf("Unmarshal: got %v, want {5 6}", p)
+	}
+	var serr *SyntaxError
+	if err := Unmarshal([]byte(`[5, 6] [7, 8]`), &p); !errors.As(err, &serr) || serr.Offset != 7 {
+		t.Errorf("Unmarshal with trailing data: got %v, want a *SyntaxError at offset 7", err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	type record struct {
+		A int               `json:"a"`
+		B []interface{}     `json:"b"`
+		C map[string]string `json:"c"`
+		D *bool             `json:"d"`
+	}
+	var r record
+	if err := Unmarshal([]byte(` {"a": 1, "b": [true, "x"], "c": {"k": "v"}, "d": false} `), &r); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	f := false
+	want := record{A: 1, B: []interface{}{true, "x"}, C: map[string]string{"k": "v"}, D: &f}
+	if !reflect.DeepEqual(want, r) {
+		t.Fatalf("expected: %+v, got: %+v", want, r)
+	}
+
+	for _, input := range []string{``, ` `, `{"a": 1} {"a": 2}`, `{"a": 1},`, `[1, 2`} {
+		var v interface{}
+		if err := Unmarshal([]byte(input), &v); err == nil {
+			t.Errorf("unmarshal %q: expected error, got nil", input)
+		}
+	}
+}
+
+func TestDecoderUnmarshalTypeError(t *testing.T) {
+	type item struct {
+		Price int    `json:"price"`
+		Name  string `json:"name"`
+	}
+	type order struct {
+		Items []item `json:"items"`
+		Owner struct {
+			Age uint8
+		} `json:"owner"`
+	}
+	tests := []struct {
+		input string
+		v     func() interface{}
+		want  UnmarshalTypeError
+	}{
+		{`{"items": [{"price": 1}, {"price": "x"}]}`, func() interface{} { return new(order) },
+			UnmarshalTypeError{Value: "string", Offset: 35, Path: "$.items[1].price", Struct: "item", Field: "items.price"}},
+		{`{"owner": {"Age": 300}}`, func() interface{} { return new(order) },
+			UnmarshalTypeError{Value: "number 300", Offset: 18, Path: "$.owner.Age", Struct: "", Field: "owner.Age"}},
+		{`{"items": [{"name": 5}]}`, func() interface{} { return new(order) },
+			UnmarshalTypeError{Value: "number", Offset: 20, Path: "$.items[0].name", Struct: "item", Field: "items.name"}},
+		{`{"items": {}}`, func() interface{} { return new(order) },
+			UnmarshalTypeError{Value: "object", Offset: 10, Path: "$.items", Struct: "order", Field: "items"}},
+		{`[1, 2, 1.5]`, func() interface{} { return new([]int) },
+			UnmarshalTypeError{Value: "number 1.5", Offset: 7, Path: "$[2]"}},
+		{`[[true], [false, "x"]]`, func() interface{} { return new([][]bool) },
+			UnmarshalTypeError{Value: "string", Offset: 17, Path: "$[1][1]"}},
+		{`{"a": {"b c": [1]}}`, func() interface{} { return new(map[string]map[string]string) },
+			UnmarshalTypeError{Value: "array", Offset: 14, Path: `$.a["b c"]`}},
+		{`{"a": 1e400}`, func() interface{} { return new(interface{}) },
+			UnmarshalTypeError{Value: "number 1e400", Offset: 6, Path: "$.a"}},
+		{`"x"`, func() interface{} { return new(bool) },
+			UnmarshalTypeError{Value: "string", Offset: 0, Path: "$"}},
+	}
+	for _, tc := range tests {
+		err := Unmarshal([]byte(tc.input), tc.v())
+		var got *UnmarshalTypeError
+		if !errors.As(err, &got) {
+			t.Errorf("Unmarshal(%s): got %v, want *UnmarshalTypeError", tc.input, err)
+			continue
+		}
+		want := tc.want
+		want.Type = got.Type
+		if *got != want {
+			t.Errorf("Unmarshal(%s): got %+v, want %+v", tc.input, *got, want)
+		}
+
+		// the JSON value and the Go type agree with encoding/json.
+		var stdErr *json.UnmarshalTypeError
+		if err := json.Unmarshal([]byte(tc.input), tc.v()); errors.As(err, &stdErr) {
+			if got.Value != stdErr.Value || got.Type != stdErr.Type {
+				t.Errorf("Unmarshal(%s): got %s into %v, encoding/json reports %s into %v", tc.input, got.Value, got.Type, stdErr.Value, stdErr.Type)
+			}
+		}
+	}
+
+	err := Unmarshal([]byte(`{"items": [{"price": "x"}]}`), new(order))
+	if want := `json: cannot decode string into Go struct field item.items.price of type int at offset 21, path $.items[0].price`; err == nil || err.Error() != want {
+		t.Errorf("Unmarshal: got %v, want %s", err, want)
+	}
+}
+
+func TestDecodeStructConcurrent(t *testing.T) {
+	type record struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+		C bool
+		d int
+	}
+	data := []byte(`{"a": 1, "b": "x", "C": true, "d": 4, "e": [5]}`)
+	want := record{A: 1, B: "x", C: true}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var r record
+				if err := Unmarshal(data, &r); err != nil {
+					errs <- err
+					return
+				}
+				if r != want {
+					errs <- fmt.Errorf("expected: %+v, got: %+v", want, r)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestDecoder_NextAsBytes(t *testing.T) {
+	tests := []struct {
+		json   string
+		tokens []string
+		next   []byte
+		after  []string // tokens following the element; "" for io.EOF
+	}{
+		{json: `{"a":"test"}`, tokens: []string{"{", `"a"`}, next: []byte(`"test"`)},
+		{json: `{"a":  "test"}`, tokens: []string{"{", `"a"`}, next: []byte(`"test"`)},
+		{json: `{"a":  [1, 2, 3]}`, tokens: []string{"{", `"a"`}, next: []byte(`[1, 2, 3]`)},
+		{json: `{"obj": {"some": "key"}}`, tokens: []string{"{", `"obj"`}, next: []byte(`{"some": "key"}`)},
+
+		// the last member of an object.
+		{json: `{"a": {"x": 1}}`, tokens: []string{"{", `"a"`}, next: []byte(`{"x": 1}`), after: []string{"}", ""}},
+		{json: `{"a": 1, "b": [true] }`, tokens: []string{"{", `"a"`, "1", `"b"`}, next: []byte(`[true]`), after: []string{"}", ""}},
+		{json: `{"a": {"x": 1}, "b": 2}`, tokens: []string{"{", `"a"`}, next: []byte(`{"x": 1}`), after: []string{`"b"`, "2", "}", ""}},
+
+		// elements of an array.
+		{json: `[{"x": 1}, [2], 3]`, tokens: []string{"["}, next: []byte(`{"x": 1}`), after: []string{"[", "2", "]", "3", "]", ""}},
+		{json: `[1, {"x": [2]}]`, tokens: []string{"[", "1"}, next: []byte(`{"x": [2]}`), after: []string{"]", ""}},
+
+		// top-level values, before any call to NextToken.
+		{json: `{"a": [1, 2]}`, next: []byte(`{"a": [1, 2]}`), after: []string{""}},
+		{json: ` [1] `, next: []byte(`[1]`), after: []string{""}},
+		{json: `[1] {"b": 2}`, next: []byte(`[1]`), after: []string{"{", `"b"`, "2", "}", ""}},
+
+		// scalars followed by whitespace.
+		{json: "  42 \n\t", next: []byte(`42`), after: []string{""}},
+		{json: `"str"   `, next: []byte(`"str"`), after: []string{""}},
+		{json: "{\"a\": true  \n}", tokens: []string{"{", `"a"`}, next: []byte(`true`), after: []string{"}", ""}},
+		{json: `[null , 1]`, tokens: []string{"["}, next: []byte(`null`), after: []string{"1", "]", ""}},
+		{json: `["a\"b\u00e9" ,1]`, tokens: []string{"["}, next: []byte(`"a\"b\u00e9"`), after: []string{"1", "]", ""}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			data := []byte(tc.json)
+			dec, spanDec := NewDecoder(data), NewDecoder(data)
+			for n, want := range tc.tokens {
+				got, err := dec.NextToken()
+				if string(got) != want {
+					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+				}
+				spanDec.NextToken()
+			}
+			got, err := dec.NextAsBytes()
+			if !bytes.Equal(got, tc.next) {
+				t.Fatalf("expected: %q, got: %q, %v", tc.next, got, err)
+			}
+			start, end, err := spanDec.NextValueSpan()
+			if err != nil || &data[start] != &got[0] || end-start != len(got) {
+				t.Fatalf("NextValueSpan: got %d, %d, %v, want the span of %q", start, end, err, got)
+			}
+			if spanDec.getOffset() != dec.getOffset() {
+				t.Fatalf("NextValueSpan: left the decoder at offset %d, want %d", spanDec.getOffset(), dec.getOffset())
+			}
+			for _, want := range tc.after {
+				got, err := dec.NextToken()
+				if want == "" {
+					if err != io.EOF {
+						t.Fatalf("expected io.EOF, got: %q, %v", got, err)
+					}
+					continue
+				}
+				if string(got) != want {
+					t.Fatalf("after NextAsBytes: expected: %q, got: %q, %v", want, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoderNextValueSpanErrors(t *testing.T) {
+	for _, tc := range []struct {
+		json string
+		opts []Option
+	}{
+		{json: ``},
+		{json: `]`},
+		{json: `[1, 2`},
+		{json: `{"a": [1}`},
+		{json: `[[[1]]]`, opts: []Option{func(d *Decoder) { d.SetMaxDepth(2) }}},
+		{json: `[1, 2, 3]`, opts: []Option{func(d *Decoder) { d.SetMaxValueBytes(4) }}},
+	} {
+		_, wantErr := NewDecoder([]byte(tc.json), tc.opts...).NextAsBytes()
+		start, end, err := NewDecoder([]byte(tc.json), tc.opts...).NextValueSpan()
+		if wantErr == nil || err == nil || start != 0 || end != 0 {
+			t.Errorf("%s: got %d, %d, %v, want the error %v", tc.json, start, end, err, wantErr)
+		} else if got, want := strings.TrimPrefix(err.Error(), "NextValueSpan"), strings.TrimPrefix(wantErr.Error(), "NextAsBytes"); got != want {
+			t.Errorf("%s: got %v, want %v", tc.json, err, wantErr)
+		}
+	}
+}
+
+func TestDecoderNextAsBytesCompact(t *testing.T) {
+	tests := []struct {
+		json   string
+		tokens []string
+		want   string
+		after  string // the token following the element; "" for io.EOF
+	}{
+		{json: ` 42 `, want: `42`},
+		{json: " \"a b\" ", want: `"a b"`},
+		{json: "{ \"a\" : [ 1 , { \"b\" :\ttrue } , [ ] ] ,\n \"c\": {} }", want: `{"a":[1,{"b":true},[]],"c":{}}`},
+		{json: `{"obj": {"some": "key", "x": [1, 2]}}`, tokens: []string{"{", `"obj"`}, want: `{"some":"key","x":[1,2]}`, after: "}"},
+		{json: `[ [ 1 , 2 ] , 3 ]`, tokens: []string{"["}, want: `[1,2]`, after: "3"},
+		{json: `{"a": 1, "b": "x y"}`, tokens: []string{"{", `"a"`, "1", `"b"`}, want: `"x y"`, after: "}"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			dec := NewDecoder([]byte(tc.json))
+			for _, want := range tc.tokens {
+				if got, err := dec.NextToken(); string(got) != want {
+					t.Fatalf("expected: %q, got: %q, %v", want, got, err)
+				}
+			}
+			got, err := dec.NextAsBytesCompact([]byte("prefix:"))
+			check(t, err)
+			if want := "prefix:" + tc.want; string(got) != want {
+				t.Fatalf("expected: %s, got: %s", want, got)
+			}
+			tok, err := dec.NextToken()
+			if tc.after == "" {
+				if err != io.EOF {
+					t.Fatalf("expected io.EOF, got: %q, %v", tok, err)
+				}
+			} else if string(tok) != tc.after {
+				t.Fatalf("expected: %q, got: %q, %v", tc.after, tok, err)
+			}
+		})
+	}
+
+	// the fixtures agree with encoding/json.
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(t, tc.path))
+		check(t, err)
+		got, err := NewDecoder(data).NextAsBytesCompact(nil)
+		check(t, err)
+		var want bytes.Buffer
+		check(t, json.Compact(&want, data))
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("%s: result differs from json.Compact", tc.path)
+		}
+	}
+}
+
+func TestDecoderNextAsBytesCompactErrors(t *testing.T) {
+	for _, tc := range []struct {
+		json   string
+		tokens int
+		msg    string
+	}{
+		{json: `{"a": [1 2]}`, msg: `invalid character '2' after array element`},
+		{json: `{"a": [1, tru]}`, msg: `invalid character ']' in literal true (expecting 'e')`},
+		{json: `{"a": "\q"}`, msg: `invalid character 'q' in string escape code`},
+		{json: `[1, 2]`, tokens: 3, msg: `invalid character ']' looking for beginning of value`},
+		{json: `{"a": {"b": 1}`, msg: `unexpected EOF`},
+	} {
+		dec := NewDecoder([]byte(tc.json))
+		for i := 0; i < tc.tokens; i++ {
+			dec.NextToken()
+		}
+		_, err := dec.NextAsBytesCompact(nil)
+		if err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s: got %v, want error containing %q", tc.json, err, tc.msg)
+		}
+	}
+}
+
+func TestDecoderCopyValue(t *testing.T) {
+	input := ` {"keep": {"a": [1, 2], "b": "x\"y"}, "n": 12.5, "arr": [ true , null ]} `
+	for _, reader := range []bool{false, true} {
+		dec := NewDecoder([]byte(input))
+		if reader {
+			check(t, dec.ResetReader(strings.NewReader(input)))
+		}
+		var buf bytes.Buffer
+		if _, err := dec.NextToken(); err != nil {
+			t.Fatal(err)
+		}
+		for dec.More() {
+			key, err := dec.NextToken()
+			check(t, err)
+			buf.Write(key)
+			buf.WriteByte('=')
+			n, err := dec.CopyValue(&buf)
+			check(t, err)
+			buf.WriteByte(';')
+			if n == 0 {
+				t.Fatalf("CopyValue: wrote 0 bytes")
+			}
+		}
+		if tok, err := dec.NextToken(); err != nil || string(tok) != "}" {
+			t.Fatalf("NextToken: got %q, %v", tok, err)
+		}
+		want := `"keep"={"a": [1, 2], "b": "x\"y"};"n"=12.5;"arr"=[ true , null ];`
+		if buf.String() != want {
+			t.Errorf("reader %v: got %s, want %s", reader, buf.String(), want)
+		}
+	}
+
+	// a value far larger than the bufio default, from a reader.
+	var big bytes.Buffer
+	big.WriteString(`{"big": [`)
+	for i := 0; i < 100000; i++ {
+		if i > 0 {
+			big.WriteByte(',')
+		}
+		fmt.Fprintf(&big, `{"i": %d}`, i)
+	}
+	big.WriteString(`]}`)
+	dec := NewDecoder(nil)
+	check(t, dec.ResetReader(bytes.NewReader(big.Bytes())))
+	check(t, dec.Seek("big"))
+	var out bytes.Buffer
+	n, err := dec.CopyValue(&out)
+	check(t, err)
+	if want := big.Bytes()[8 : big.Len()-1]; n != int64(len(want)) || !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("CopyValue: wrote %d bytes, want %d", n, len(want))
+	}
+
+	// read a byte at a time, a value much larger than the buffer is written
+	// in chunks as it is read, and its input discarded.
+	in := `{"big": ` + big.String() + `, "after": [1]}`
+	dec = NewDecoder(nil)
+	check(t, dec.ResetReader(iotest.OneByteReader(strings.NewReader(in))))
+	check(t, dec.Seek("big"))
+	w := &countingWriter{}
+	n, err = dec.CopyValue(w)
+	check(t, err)
+	if n != int64(big.Len()) || !bytes.Equal(w.Bytes(), big.Bytes()) {
+		t.Errorf("CopyValue: wrote %d bytes, want %d", n, big.Len())
+	}
+	if w.writes < big.Len()/copyChunk || cap(dec.readBuf()) > 4*copyChunk {
+		t.Errorf("CopyValue: wrote %d times from a buffer of %d bytes", w.writes, cap(dec.readBuf()))
+	}
+	var after []int
+	if key, err := dec.NextToken(); err != nil || string(key) != `"after"` {
+		t.Fatalf("NextToken: got %q, %v", key, err)
+	}
+	if err := dec.Decode(&after); err != nil || len(after) != 1 || dec.Path() != "$.after" {
+		t.Errorf("Decode after CopyValue: got %v at %s, %v", after, dec.Path(), err)
+	}
+}
+
+// countingWriter is a bytes.Buffer which counts the calls to Write.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) { return 0, io.ErrShortWrite }
+
+func TestDecoderCopyValueErrors(t *testing.T) {
+	var buf bytes.Buffer
+	dec := NewDecoder([]byte(`[1, {"a": [1 2]}]`))
+	dec.NextToken()
+	check(t, func() error { _, err := dec.CopyValue(&buf); return err }())
+	if _, err := dec.CopyValue(&buf); err == nil || !strings.Contains(err.Error(), "after array element") {
+		t.Errorf("CopyValue: got %v, want syntax error", err)
+	}
+	if buf.String() != "1" {
+		t.Errorf("CopyValue: wrote %q, want only the valid value", buf.String())
+	}
+
+	dec = NewDecoder([]byte(`[]`))
+	dec.NextToken()
+	if _, err := dec.CopyValue(&buf); err == nil {
+		t.Errorf("CopyValue at the end of an array: expected error")
+	}
+
+	dec = NewDecoder([]byte(`{"a": 1}`))
+	if _, err := dec.CopyValue(errWriter{}); err != io.ErrShortWrite {
+		t.Errorf("CopyValue: got %v, want the write error", err)
+	}
+
+	// from a reader, what was read of an invalid value before the error
+	// has been written, and the path of the error is still known.
+	in := `{"k": [` + strings.Repeat(`"x", `, 10000) + `1 2]}`
+	dec = NewDecoder(nil)
+	check(t, dec.ResetReader(strings.NewReader(in)))
+	buf.Reset()
+	if n, err := dec.CopyValue(&buf); err == nil || n == 0 || int64(buf.Len()) != n || !strings.HasPrefix(in, buf.String()) {
+		t.Errorf("CopyValue: wrote %d bytes, got %v, want a syntax error", n, err)
+	}
+	if path := dec.Path(); path != "$.k[10000]" {
+		t.Errorf("Path: got %s, want $.k[10000]", path)
+	}
+}
+
+func TestDecoderAllowComments(t *testing.T) {
+	input := `/* header */ {
+	// line comment with "quotes" and ] brackets
+	"a": [1, /* } */ 2] /* after */,
+	"b" /* before colon */ : { "c": "not // a comment" },
+	"d": 3 // trailing
+}
+// end`
+	dec := NewDecoder([]byte(input), AllowComments())
+	var toks []string
+	for {
+		tok, err := dec.NextToken()
+		if err == io.EOF {
+			break
+		}
+		check(t, err)
+		toks = append(toks, string(tok))
+	}
+	want := []string{"{", `"a"`, "[", "1", "2", "]", `"b"`, "{", `"c"`, `"not // a comment"`, "}", `"d"`, "3", "}"}
+	if !reflect.DeepEqual(toks, want) {
+		t.Errorf("NextToken: got %q, want %q", toks, want)
+	}
+
+	// Decode, Skip, More and the Read methods look past comments too.
+	var v map[string]interface{}
+	dec = NewDecoder(nil, AllowComments())
+	dec.Reset([]byte(input))
+	check(t, dec.Decode(&v))
+	if v["d"] != 3.0 || v["b"].(map[string]interface{})["c"] != "not // a comment" {
+		t.Errorf("Decode: got %v", v)
+	}
+	dec = NewDecoder([]byte(input))
+	dec.AllowComments()
+	dec.NextToken()
+	dec.NextToken()
+	check(t, dec.Skip())
+	if !dec.More() || dec.PeekKind() != KindString {
+		t.Errorf("More, PeekKind: got %v, %v after Skip", dec.More(), dec.PeekKind())
+	}
+	if key, err := dec.ReadString(); err != nil || key != "b" {
+		t.Fatalf("ReadString: got %q, %v", key, err)
+	}
+	check(t, dec.Seek("c"))
+	if s, err := dec.ReadString(); err != nil || s != "not // a comment" {
+		t.Errorf("ReadString: got %q, %v", s, err)
+	}
+	dec.NextToken()
+	dec.NextToken()
+	if i, err := dec.ReadInt64(); err != nil || i != 3 {
+		t.Errorf("ReadInt64: got %d, %v", i, err)
+	}
+	if tok, err := dec.NextToken(); err != nil || string(tok) != "}" {
+		t.Errorf("NextToken: got %q, %v at the end of the object", tok, err)
+	}
+	if _, err := dec.NextToken(); err != io.EOF {
+		t.Errorf("NextToken: got %v after the trailing comment, want io.EOF", err)
+	}
+
+	for _, in := range []string{`[1, /* c */ ]`, `{"a": 1, // c` + "\n}", `[1 /* c ]`, `[/* c */`} {
+		dec := NewDecoder([]byte(in), AllowComments())
+		var v interface{}
+		if err := dec.Decode(&v); err == nil {
+			t.Errorf("Decode(%q): expected error", in)
+		}
+	}
+	// Skip does not validate, but does not look for brackets in comments.
+	for _, in := range []string{`[1 /* c ]`, `[/* ] */`} {
+		if err := NewDecoder([]byte(in), AllowComments()).Skip(); err == nil {
+			t.Errorf("Skip(%q): expected error", in)
+		}
+	}
+
+	// the default is strict.
+	if err := NewDecoder([]byte(`[1 /* c */]`)).Decode(new(interface{})); err == nil {
+		t.Errorf("Decode without AllowComments: expected error")
+	}
+}
+
+func TestDecoderAllowTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{`[1,]`, `[1]`},
+		{`{"a": 1,}`, `{"a": 1}`},
+		{`[1, [2, 3,], 4,]`, `[1, [2, 3], 4]`},
+		{`{"a": {"b": [1, {"c": null,},],}, "d": [],}`, `{"a": {"b": [1, {"c": null}]}, "d": []}`},
+		{`[[[[1,],],],]`, `[[[[1]]]]`},
+		{"[1 ,\n\t]", `[1]`},
+		{`[1, 2]`, `[1, 2]`},
+	}
+	tokens := func(in string) []string {
+		dec := NewDecoder([]byte(in))
+		dec.AllowTrailingCommas()
+		var toks []string
+		for {
+			tok, err := dec.NextToken()
+			if err == io.EOF {
+				return toks
+			}
+			check(t, err)
+			toks = append(toks, string(tok))
+		}
+	}
+	for _, tc := range tests {
+		var got, want interface{}
+		check(t, json.Unmarshal([]byte(tc.want), &want))
+		dec := NewDecoder([]byte(tc.input), AllowTrailingCommas())
+		dec.DisallowTrailingData()
+		if err := dec.Decode(&got); err != nil {
+			t.Errorf("Decode(%s): %v", tc.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode(%s): got %v, want %v", tc.input, got, want)
+		}
+
+		// the token stream is that of the input without trailing commas.
+		if got, want := tokens(tc.input), tokens(tc.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("NextToken(%s): got %q, want %q", tc.input, got, want)
+		}
+
+		dec = NewDecoder([]byte(tc.input+` 1`), AllowTrailingCommas())
+		check(t, dec.Skip())
+		if n, err := dec.ReadInt64(); err != nil || n != 1 {
+			t.Errorf("Skip(%s): got %d, %v after it, want 1", tc.input, n, err)
+		}
+		if err := Validate([]byte(tc.input), AllowTrailingCommas()); err != nil {
+			t.Errorf("Validate(%s): %v", tc.input, err)
+		}
+		if tc.input != tc.want && Valid([]byte(tc.input)) {
+			t.Errorf("Valid(%s): got true without AllowTrailingCommas", tc.input)
+		}
+	}
+
+	for _, in := range []string{`[,]`, `{,}`, `[1,,]`, `{"a": 1,,}`, `[1,,2]`, `[,1]`, `{"a",}`, `{"a":,}`, `[1,] ,`, `[1,`} {
+		dec := NewDecoder([]byte(in), AllowTrailingCommas())
+		dec.DisallowTrailingData()
+		if err := dec.Decode(new(interface{})); err == nil {
+			t.Errorf("Decode(%s): expected error", in)
+		}
+		if err := Validate([]byte(in), AllowTrailingCommas()); err == nil {
+			t.Errorf("Validate(%s): expected error", in)
+		}
+	}
+}
+
+func TestDecoderAllowRelaxedStrings(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{`'abc'`, `"abc"`},
+		{`''`, `""`},
+		{`'it\'s'`, `"it's"`},
+		{`'say "hi"'`, `"say \"hi\""`},
+		{`'\"\\\/\b\f\n\r\té'`, `"\"\\\/\b\f\n\r\té"`},
+		{`{key: 'value'}`, `{"key": "value"}`},
+		{`{_a1: 1, $b: 2, c$_9: 3}`, `{"_a1": 1, "$b": 2, "c$_9": 3}`},
+		{`{null: null, true: true, false: false, nullable: 1}`, `{"null": null, "true": true, "false": false, "nullable": 1}`},
+		{`{a : 1, b` + "\n" + `: 2}`, `{"a": 1, "b": 2}`},
+		// mixed quote styles in one document.
+		{`{name: 'x', "std": "y", 'single': ['a', "b", 'c"d'], nested: {'k': "v", k2: 'w'}}`,
+			`{"name": "x", "std": "y", "single": ["a", "b", "c\"d"], "nested": {"k": "v", "k2": "w"}}`},
+		{`['[', '{', "]", '\'}']`, `["[", "{", "]", "'}"]`},
+	}
+	for _, tc := range tests {
+		var got, want interface{}
+		check(t, json.Unmarshal([]byte(tc.want), &want))
+		dec := NewDecoder([]byte(tc.input), AllowRelaxedStrings())
+		dec.DisallowTrailingData()
+		if err := dec.Decode(&got); err != nil {
+			t.Errorf("Decode(%s): %v", tc.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode(%s): got %v, want %v", tc.input, got, want)
+		}
+
+		// Token sees standard strings.
+		dec = NewDecoder([]byte(tc.input), AllowRelaxedStrings())
+		std := json.NewDecoder(strings.NewReader(tc.want))
+		for {
+			tok, err := dec.Token()
+			wantTok, wantErr := std.Token()
+			if err != wantErr || tok != wantTok {
+				t.Errorf("Token(%s): got %v, %v, want %v, %v", tc.input, tok, err, wantTok, wantErr)
+				break
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		// Skip finds the end of containers holding single-quoted brackets.
+		dec = NewDecoder([]byte(tc.input+` 1`), AllowRelaxedStrings())
+		check(t, dec.Skip())
+		if n, err := dec.ReadInt64(); err != nil || n != 1 {
+			t.Errorf("Skip(%s): got %d, %v after it, want 1", tc.input, n, err)
+		}
+		if err := Validate([]byte(tc.input), AllowRelaxedStrings()); err != nil {
+			t.Errorf("Validate(%s): %v", tc.input, err)
+		}
+		if Valid([]byte(tc.input)) {
+			t.Errorf("Valid(%s): got true without AllowRelaxedStrings", tc.input)
+		}
+	}
+
+	for _, in := range []string{
+		`abc`, `[abc]`, `{a: b}`, `{a b: 1}`, `{1a: 1}`, `{a-b: 1}`, `{'a' 1}`,
+		`'abc`, `'a\'`, `'\q'`, `'\u12x4'`, "'a\nb'", `{a: 1, b}`, `{"a": 1} b`,
+	} {
+		if err := Validate([]byte(in), AllowRelaxedStrings()); err == nil {
+			t.Errorf("Validate(%s): expected error", in)
+		}
+	}
+}
+
+func TestDecoderRelaxedStringsReaders(t *testing.T) {
+	dec := NewDecoder([]byte(`{null: 'a\'b', items: [{id: 1}], 'q"k': true}`), AllowRelaxedStrings())
+	dec.DisallowDuplicateKeys()
+	var keys, paths []string
+	err := dec.Object(func(key []byte) error {
+		keys = append(keys, string(key))
+		switch dec.PeekKind() {
+		case KindString:
+			s, err := dec.ReadString()
+			paths = append(paths, dec.Path()+"="+s)
+			return err
+		case KindArrayStart:
+			return dec.Array(func(i int) error {
+				return dec.Object(func(key []byte) error {
+					_, err := dec.ReadInt64()
+					paths = append(paths, dec.Path())
+					return err
+				})
+			})
+		}
+		paths = append(paths, dec.Path())
+		return dec.Skip()
+	})
+	check(t, err)
+	if want := []string{"null", "items", `q"k`}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Object: got keys %q, want %q", keys, want)
+	}
+	if want := []string{"$.null=a'b", "$.items[0].id", `$["q\"k"]`}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Path: got %q, want %q", paths, want)
+	}
+
+	dec = NewDecoder([]byte(`{a: 1, 'a': 2}`), AllowRelaxedStrings())
+	dec.DisallowDuplicateKeys()
+	if err := dec.Decode(new(interface{})); err == nil || !strings.Contains(err.Error(), `duplicate key "a"`) {
+		t.Errorf("Decode: got %v, want a duplicate key error", err)
+	}
+}
+
+func TestDecoderBOM(t *testing.T) {
+	input := "\xef\xbb\xbf{\"a\": [1, 2]}"
+	var v struct{ A []int }
+	check(t, NewDecoder([]byte(input)).Decode(&v))
+	if !reflect.DeepEqual(v.A, []int{1, 2}) {
+		t.Errorf("Decode: got %v", v.A)
+	}
+	check(t, Unmarshal([]byte(input), &v))
+	if !Valid([]byte(input)) {
+		t.Errorf("Valid: got false, want true")
+	}
+
+	dec := NewDecoder(nil)
+	check(t, dec.ResetReader(strings.NewReader(input)))
+	tok, err := dec.NextToken()
+	check(t, err)
+	if string(tok) != "{" || dec.InputOffset() != 4 {
+		t.Errorf("ResetReader: got %q at offset %d, want \"{\" at 4", tok, dec.InputOffset())
+	}
+	if tok := NewScanner([]byte(input)).Next(); string(tok) != "{" {
+		t.Errorf("Scanner.Next: got %q, want \"{\"", tok)
+	}
+
+	for _, in := range []string{"\xef\xbb\xbf", "\xef\xbb\xbf \n"} {
+		if _, err := NewDecoder([]byte(in)).NextToken(); err != io.EOF {
+			t.Errorf("NextToken(%q): got %v, want io.EOF", in, err)
+		}
+	}
+
+	// offsets are relative to the input, including the byte order mark.
+	var serr *SyntaxError
+	err = Unmarshal([]byte("\xef\xbb\xbf{\"a\" 1}"), &v)
+	if !errors.As(err, &serr) || serr.Offset != 8 {
+		t.Errorf("Unmarshal: got %v, want *SyntaxError at offset 8", err)
+	}
+
+	for _, in := range []string{"\xef\xbb\xbf\xef\xbb\xbf{}", " \xef\xbb\xbf{}", "[\xef\xbb\xbf1]", "{}\xef\xbb\xbf", "\xef\xbb{}"} {
+		if err := Validate([]byte(in)); !errors.As(err, &serr) {
+			t.Errorf("Validate(%q): got %v, want *SyntaxError", in, err)
+		}
+	}
+}
+
+func TestDecoderValidateUTF8(t *testing.T) {
+	invalid := []string{
+		"\xc0\xaf",         // overlong /
+		"\xe0\x80\xaf",     // overlong /
+		"\xf0\x80\x80\xaf", // overlong /
+		"\xed\xa0\x80",     // surrogate half
+		"\x80",             // unexpected continuation byte
+		"\xc3",             // truncated
+		"\xe2\x82",         // truncated
+		"\xf0\x9f\x98",     // truncated
+		"\xf5", "\xf8\x88\x80\x80\x80", "\xfe", "\xff",
+	}
+	for _, bad := range invalid {
+		in := `{"k": ["ok", "ab` + bad + `cd"]}`
+		check(t, Validate([]byte(in)))
+
+		err := Validate([]byte(in), ValidateUTF8())
+		var serr *SyntaxError
+		if !errors.As(err, &serr) || serr.Offset != 16 {
+			t.Errorf("Validate(%q, ValidateUTF8()): got %v, want *SyntaxError at offset 16", in, err)
+		}
+
+		var got, want interface{}
+		check(t, json.Unmarshal([]byte(in), &want))
+		check(t, NewDecoder([]byte(in), ReplaceInvalidUTF8()).Decode(&got))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode(%q) with ReplaceInvalidUTF8: got %q, encoding/json: %q", in, got, want)
+		}
+	}
+
+	valid := "\"é ∑ 😀 \ufffd \xef\xbf\xbd\""
+	for _, opt := range []Option{ValidateUTF8(), ReplaceInvalidUTF8()} {
+		var got string
+		check(t, NewDecoder([]byte(valid), opt).Decode(&got))
+		if want := "é ∑ 😀 \ufffd \ufffd"; got != want {
+			t.Errorf("Decode(%q): got %q, want %q", valid, got, want)
+		}
+	}
+
+	// keys, tokens and relaxed strings.
+	in := "{\"k\xff\": 'v\xc0', \"k\xff\": 1}"
+	var m map[string]interface{}
+	check(t, NewDecoder([]byte(in), AllowRelaxedStrings(), ReplaceInvalidUTF8()).Decode(&m))
+	if want := map[string]interface{}{"k\ufffd": 1.0}; !reflect.DeepEqual(m, want) {
+		t.Errorf("Decode(%q): got %q, want %q", in, m, want)
+	}
+	dec := NewDecoder([]byte(in), AllowRelaxedStrings(), ReplaceInvalidUTF8())
+	dec.DisallowDuplicateKeys()
+	if err := dec.Decode(&m); err == nil || !strings.Contains(err.Error(), "duplicate key") {
+		t.Errorf("Decode(%q) with DisallowDuplicateKeys: got %v, want duplicate key error", in, err)
+	}
+	dec = NewDecoder([]byte(in), AllowRelaxedStrings(), ValidateUTF8())
+	dec.NextToken()
+	if _, err := dec.Token(); err == nil || !strings.Contains(err.Error(), "invalid UTF-8 byte 0xff") {
+		t.Errorf("Token: got %v, want invalid UTF-8 error", err)
+	}
+	var serr *SyntaxError
+	if err := Validate([]byte(`['ok', 'a`+"\xfe"+`']`), AllowRelaxedStrings(), ValidateUTF8()); !errors.As(err, &serr) || serr.Offset != 9 {
+		t.Errorf("Validate single-quoted: got %v, want *SyntaxError at offset 9", err)
+	}
+}
+
+func TestDecoderLoneSurrogates(t *testing.T) {
+	var s string
+	check(t, Unmarshal([]byte(`"smile \ud83d\ude00"`), &s))
+	if s != "smile \U0001f600" {
+		t.Errorf("Unmarshal: got %q, want a surrogate pair decoded", s)
+	}
+
+	tests := []struct {
+		in      string
+		offset  int64
+		escape  string
+		lenient string
+	}{
+		{in: `"a\ud800"`, offset: 2, escape: `\ud800`, lenient: "a\ufffd"},
+		{in: `"a\uDC00b"`, offset: 2, escape: `\uDC00`, lenient: "a\ufffdb"},
+		{in: `"\ud800x\ude00"`, offset: 1, escape: `\ud800`, lenient: "\ufffdx\ufffd"},
+		{in: `"\ud800\n"`, offset: 1, escape: `\ud800`, lenient: "\ufffd\n"},
+		{in: `"\ud800\ud800\udc00"`, offset: 1, escape: `\ud800`, lenient: "\ufffd\U00010000"},
+	}
+	for _, tc := range tests {
+		check(t, Validate([]byte(tc.in)))
+
+		for _, v := range []interface{}{new(string), new(interface{}), new([]string), new(map[string]string)} {
+			in := tc.in
+			switch v.(type) {
+			case *[]string:
+				in = "[" + in + "]"
+			case *map[string]string:
+				in = `{"k": ` + in + "}"
+			}
+			err := Unmarshal([]byte(in), v)
+			var serr *SyntaxError
+			if !errors.As(err, &serr) || !strings.Contains(err.Error(), "unpaired surrogate "+tc.escape) {
+				t.Errorf("Unmarshal(%s, %T): got %v, want unpaired surrogate error", in, v, err)
+				continue
+			}
+			if want := tc.offset + int64(strings.Index(in, tc.in)); serr.Offset != want {
+				t.Errorf("Unmarshal(%s, %T): got offset %d, want %d", in, v, serr.Offset, want)
+			}
+
+			check(t, NewDecoder([]byte(in), AllowLoneSurrogates()).Decode(v))
+		}
+
+		var got string
+		check(t, NewDecoder([]byte(tc.in), AllowLoneSurrogates()).Decode(&got))
+		var want string
+		check(t, json.Unmarshal([]byte(tc.in), &want))
+		if got != tc.lenient || got != want {
+			t.Errorf("Decode(%s) with AllowLoneSurrogates: got %q, want %q, encoding/json: %q", tc.in, got, tc.lenient, want)
+		}
+	}
+
+	// keys, tokens and the Read methods.
+	for _, in := range []string{`{"\udc00": 1}`, `{"k\ud800": true}`} {
+		var v struct{ K bool }
+		if err := Unmarshal([]byte(in), &v); err == nil {
+			t.Errorf("Unmarshal(%s) into struct: expected error", in)
+		}
+		if err := Unmarshal([]byte(in), new(map[string]int)); err == nil {
+			t.Errorf("Unmarshal(%s) into map: expected error", in)
+		}
+	}
+	dec := NewDecoder([]byte(`["\ud800"]`))
+	dec.NextToken()
+	if _, err := dec.Token(); err == nil {
+		t.Errorf("Token: expected error")
+	}
+	dec = NewDecoder([]byte(`["\ud800"]`))
+	dec.NextToken()
+	if _, err := dec.ReadString(); err == nil {
+		t.Errorf("ReadString: expected error")
+	}
+	dec = NewDecoder([]byte(`["\ud800"]`), AllowLoneSurrogates())
+	dec.NextToken()
+	if s, err := dec.ReadString(); err != nil || s != "\ufffd" {
+		t.Errorf("ReadString with AllowLoneSurrogates: got %q, %v", s, err)
+	}
+}
+
+func TestDecoderAllowNaNInf(t *testing.T) {
+	input := `{"a": NaN, "b": Infinity, "c": -Infinity, "d": [NaN, -Infinity, 1.5]}`
+	var v struct {
+		A, B float64
+		C    float32
+		D    []float64
+	}
+	check(t, NewDecoder([]byte(input), AllowNaNInf()).Decode(&v))
+	if !math.IsNaN(v.A) || !math.IsInf(v.B, 1) || !math.IsInf(float64(v.C), -1) {
+		t.Errorf("Decode: got %v", v)
+	}
+	if len(v.D) != 3 || !math.IsNaN(v.D[0]) || !math.IsInf(v.D[1], -1) || v.D[2] != 1.5 {
+		t.Errorf("Decode: got %v", v.D)
+	}
+
+	var fs []float64
+	check(t, NewDecoder([]byte(`[Infinity, NaN]`), AllowNaNInf()).Decode(&fs))
+	if len(fs) != 2 || !math.IsInf(fs[0], 1) || !math.IsNaN(fs[1]) {
+		t.Errorf("Decode into []float64: got %v", fs)
+	}
+
+	var x interface{}
+	check(t, NewDecoder([]byte(input), AllowNaNInf()).Decode(&x))
+	m := x.(map[string]interface{})
+	if !math.IsNaN(m["a"].(float64)) || !math.IsInf(m["b"].(float64), 1) || !math.IsInf(m["c"].(float64), -1) || !math.IsNaN(m["d"].([]interface{})[0].(float64)) {
+		t.Errorf("Decode into interface{}: got %v", x)
+	}
+
+	dec := NewDecoder([]byte(`[NaN, -Infinity, Infinity]`), AllowNaNInf())
+	dec.NextToken()
+	for _, want := range []float64{math.NaN(), math.Inf(-1), math.Inf(1)} {
+		if k := dec.PeekKind(); k != KindNumber {
+			t.Errorf("PeekKind: got %v, want number", k)
+		}
+		tok, err := dec.Token()
+		check(t, err)
+		if f, ok := tok.(float64); !ok || f != want && !(math.IsNaN(f) && math.IsNaN(want)) {
+			t.Errorf("Token: got %v, want %v", tok, want)
+		}
+	}
+
+	for _, in := range []string{`NaN`, `Infinity`, `-Infinity`, `[1, NaN]`} {
+		if err := Validate([]byte(in)); err == nil {
+			t.Errorf("Validate(%s): expected error without AllowNaNInf", in)
+		}
+		if err := Validate([]byte(in), AllowNaNInf()); err != nil {
+			t.Errorf("Validate(%s, AllowNaNInf()): %v", in, err)
+		}
+		if err := Unmarshal([]byte(in), new(interface{})); err == nil {
+			t.Errorf("Unmarshal(%s): expected error", in)
+		}
+	}
+	for _, in := range []string{`nan`, `NaNx`, `Inf`, `-Inf`, `Infinityy`, `+Infinity`, `[NaN1]`, `-NaN`, `Infinit`} {
+		if err := Validate([]byte(in), AllowNaNInf()); err == nil {
+			t.Errorf("Validate(%s, AllowNaNInf()): expected error", in)
+		}
+	}
+
+	// not integers.
+	var i int
+	err := NewDecoder([]byte(`NaN`), AllowNaNInf()).Decode(&i)
+	var terr *UnmarshalTypeError
+	if !errors.As(err, &terr) || terr.Value != "number NaN" {
+		t.Errorf("Decode into int: got %v, want *UnmarshalTypeError", err)
+	}
+
+	// the encoder stays strict.
+	if _, err := Marshal(math.Inf(1)); err == nil {
+		t.Errorf("Marshal(+Inf): expected error")
+	}
+}
+
+func TestDecoderTime(t *testing.T) {
+	type event struct {
+		At    time.Time            `json:"at"`
+		Until *time.Time           `json:"until"`
+		Times map[string]time.Time `json:"times"`
+	}
+	input := `{
+		"at": "2024-02-29T13:45:30.123456789+05:30",
+		"until": "1999-12-31T23:59:59Z",
+		"times": {"a": "2024-01-01T00:00:00.5-08:00"}
+	}`
+	var e event
+	check(t, NewDecoder([]byte(input)).Decode(&e))
+	want := time.Date(2024, 2, 29, 13, 45, 30, 123456789, time.FixedZone("", 5*3600+1800))
+	if !e.At.Equal(want) {
+		t.Errorf("at: got %v, want %v", e.At, want)
+	}
+	if _, offset := e.At.Zone(); offset != 5*3600+1800 {
+		t.Errorf("at: got zone offset %d, want %d", offset, 5*3600+1800)
+	}
+	if e.Until == nil || !e.Until.Equal(time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)) {
+		t.Errorf("until: got %v", e.Until)
+	}
+	if got := e.Times["a"]; !got.Equal(time.Date(2024, 1, 1, 8, 0, 0, 5e8, time.UTC)) {
+		t.Errorf("times: got %v", got)
+	}
+
+	// null leaves a time.Time unchanged and sets a *time.Time to nil.
+	check(t, NewDecoder([]byte(`{"at": null, "until": null}`)).Decode(&e))
+	if !e.At.Equal(want) || e.Until != nil {
+		t.Errorf("null: got %v, %v, want %v, nil", e.At, e.Until, want)
+	}
+
+	// The encoder writes RFC 3339 strings, which decode to the same time.
+	b, err := Marshal(e)
+	check(t, err)
+	var e2 event
+	check(t, Unmarshal(b, &e2))
+	if !e2.At.Equal(e.At) || e2.At.String() != e.At.String() {
+		t.Errorf("round trip through %s: got %v, want %v", b, e2.At, e.At)
+	}
+	if want := `"2024-02-29T13:45:30.123456789+05:30"`; !strings.Contains(string(b), want) {
+		t.Errorf("Marshal: got %s, want it to contain %s", b, want)
+	}
+
+	for _, tc := range []struct{ in, err string }{
+		{`{"at": "2024-02-30T00:00:00Z"}`, `json: cannot decode string "2024-02-30T00:00:00Z" into Go value of type time.Time at offset 7, path $.at: parsing time "2024-02-30T00:00:00Z": day out of range`},
+		{`{"at": "yesterday"}`, `json: cannot decode string "yesterday" into Go value of type time.Time at offset 7, path $.at: parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterday" as "2006"`},
+		{`{"at": "2024-01-01 00:00:00Z"}`, `cannot parse " 00:00:00Z" as "T"`},
+		{`{"at": 1700000000}`, `json: cannot decode number into Go struct field event.at of type time.Time at offset 7, path $.at`},
+		{`{"at": {}}`, `json: cannot decode object into Go struct field event.at of type time.Time at offset 7, path $.at`},
+	} {
+		err := NewDecoder([]byte(tc.in)).Decode(new(event))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Decode(%s): got %v, want %s", tc.in, err, tc.err)
+		}
+	}
+	var perr *time.ParseError
+	if err := Unmarshal([]byte(`"x"`), new(time.Time)); !errors.As(err, &perr) {
+		t.Errorf("got %v, want a *time.ParseError", err)
+	}
+}
+
+func TestDecoderDuration(t *testing.T) {
+	type timeout struct {
+		D time.Duration  `json:"d"`
+		P *time.Duration `json:"p"`
+	}
+	var v timeout
+	check(t, NewDecoder([]byte(`{"d": 1500000000, "p": 42}`)).Decode(&v))
+	if v.D != 1500*time.Millisecond || v.P == nil || *v.P != 42 {
+		t.Errorf("got %v, %v", v.D, v.P)
+	}
+	if err := NewDecoder([]byte(`{"d": "1h30m"}`)).Decode(&v); err == nil {
+		t.Errorf("got %v, want error for a string without AllowDurationStrings", v.D)
+	}
+
+	check(t, NewDecoder([]byte(`{"d": "1h30m", "p": "-1.5s"}`), AllowDurationStrings()).Decode(&v))
+	if v.D != 90*time.Minute || *v.P != -1500*time.Millisecond {
+		t.Errorf("got %v, %v, want 1h30m0s, -1.5s", v.D, *v.P)
+	}
+	check(t, NewDecoder([]byte(`{"d": 7}`), AllowDurationStrings()).Decode(&v))
+	if v.D != 7 {
+		t.Errorf("got %v, want 7ns", v.D)
+	}
+	err := NewDecoder([]byte(`{"d": "soon"}`), AllowDurationStrings()).Decode(&v)
+	if want := `json: cannot decode string "soon" into Go value of type time.Duration at offset 6, path $.d: time: invalid duration "soon"`; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+
+	// Other int64 types still reject strings.
+	var n int64
+	if err := NewDecoder([]byte(`"1s"`), AllowDurationStrings()).Decode(&n); err == nil {
+		t.Errorf("int64: got %v, want error", n)
+	}
+}
+
+func TestDecoderBytes(t *testing.T) {
+	type blob struct {
+		Data []byte  `json:"data"`
+		Ptr  *[]byte `json:"ptr"`
+	}
+	binary := []byte{0, 0xff, 0xfe, 0, 1, 0x80, 0x7f, 0xfb, 0xef, 0}
+	for n := 0; n <= len(binary); n++ {
+		in := blob{Data: binary[:n], Ptr: &binary}
+		b, err := Marshal(in)
+		check(t, err)
+		want, err := json.Marshal(in)
+		check(t, err)
+		if !bytes.Equal(b, want) {
+			t.Errorf("Marshal: got %s, want %s", b, want)
+		}
+		var out blob
+		check(t, Unmarshal(b, &out))
+		if !bytes.Equal(out.Data, in.Data) || out.Data == nil || out.Ptr == nil || !bytes.Equal(*out.Ptr, binary) {
+			t.Errorf("round trip through %s: got %v, %v, want %v", b, out.Data, out.Ptr, in.Data)
+		}
+	}
+
+	tests := []struct {
+		in   string
+		want []byte
+	}{
+		{`"+/8="`, []byte{0xfb, 0xff}},
+		{`"-_8="`, []byte{0xfb, 0xff}},
+		{`"-_8"`, []byte{0xfb, 0xff}},
+		{`"+/8"`, []byte{0xfb, 0xff}},
+		{`"AP8A"`, []byte{0, 0xff, 0}},
+		{`"AP\u0038A"`, []byte{0, 0xff, 0}},
+		{`""`, []byte{}},
+		{`[1, 2]`, []byte{1, 2}},
+	}
+	for _, tc := range tests {
+		var got []byte
+		if err := Unmarshal([]byte(tc.in), &got); err != nil || !bytes.Equal(got, tc.want) || got == nil {
+			t.Errorf("Unmarshal(%s): got %#v, %v, want %#v", tc.in, got, err, tc.want)
+		}
+	}
+
+	got := []byte("x")
+	check(t, Unmarshal([]byte(`null`), &got))
+	if got != nil {
+		t.Errorf("null: got %#v, want nil", got)
+	}
+
+	for _, tc := range []struct{ in, err string }{
+		{`{"data": "AP8A!"}`, `json: cannot decode string "AP8A!" into Go value of type []uint8 at offset 9, path $.data: illegal base64 data at input byte 4`},
+		{`{"data": "+/-_"}`, `illegal base64 data at input byte 0`},
+		{`{"data": "A"}`, `illegal base64 data at input byte 0`},
+		{`{"data": "` + strings.Repeat("A", 100) + `!"}`, `json: cannot decode string "` + strings.Repeat("A", 64) + `"... into Go value of type []uint8 at offset 9, path $.data: illegal base64 data at input byte 100`},
+	} {
+		err := Unmarshal([]byte(tc.in), new(blob))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Unmarshal(%s): got %v, want %s", tc.in, err, tc.err)
+		}
+	}
+	var cerr base64.CorruptInputError
+	if err := Unmarshal([]byte(`"?"`), new([]byte)); !errors.As(err, &cerr) {
+		t.Errorf("got %v, want a base64.CorruptInputError", err)
+	}
+	if err := Unmarshal([]byte(`"AA=="`), new(json.RawMessage)); err == nil {
+		t.Errorf("RawMessage: got nil error")
+	}
+}
+
+func TestDecoderLenient(t *testing.T) {
+	type record struct {
+		I  int               `json:"i"`
+		U  uint8             `json:"u"`
+		F  float64           `json:"f"`
+		B  bool              `json:"b"`
+		S  string            `json:"s"`
+		P  *int              `json:"p"`
+		X  interface{}       `json:"x"`
+		N  Number            `json:"n"`
+		D  time.Duration     `json:"d"`
+		Is []int             `json:"is"`
+		Ss []string          `json:"ss"`
+		Ms map[string]string `json:"ms"`
+	}
+	tests := []struct {
+		in   string
+		want record
+	}{
+		{`{"i": "42"}`, record{I: 42}},
+		{`{"i": "-7"}`, record{I: -7}},
+		{`{"u": "255"}`, record{U: 255}},
+		{`{"f": "1.5"}`, record{F: 1.5}},
+		{`{"f": "\u0031"}`, record{F: 1}},
+		{`{"b": "true"}`, record{B: true}},
+		{`{"b": "false"}`, record{B: false}},
+		{`{"b": "1"}`, record{B: true}},
+		{`{"b": "0"}`, record{B: false}},
+		{`{"b": 1}`, record{B: true}},
+		{`{"b": 0}`, record{B: false}},
+		{`{"s": 12}`, record{S: "12"}},
+		{`{"s": -1.50e3}`, record{S: "-1.50e3"}},
+		{`{"p": "3"}`, record{P: new(int)}},
+		{`{"x": "3"}`, record{X: "3"}},
+		{`{"x": 3}`, record{X: 3.0}},
+		{`{"n": 3}`, record{N: "3"}},
+		{`{"is": ["1", 2, "3"]}`, record{Is: []int{1, 2, 3}}},
+		{`{"ss": ["a", 2, 3.5]}`, record{Ss: []string{"a", "2", "3.5"}}},
+		{`{"ms": {"a": 1, "b": "c"}}`, record{Ms: map[string]string{"a": "1", "b": "c"}}},
+	}
+	for _, tc := range tests {
+		var got record
+		if err := NewDecoder([]byte(tc.in), Lenient()).Decode(&got); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tc.in, err)
+			continue
+		}
+		if tc.want.P != nil {
+			*tc.want.P = 3
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Unmarshal(%s): got %+v, want %+v", tc.in, got, tc.want)
+		}
+		var strict record
+		if err := Unmarshal([]byte(tc.in), &strict); err == nil && !reflect.DeepEqual(strict, tc.want) {
+			t.Errorf("Unmarshal(%s) without Lenient: got %+v, want an error or %+v", tc.in, strict, tc.want)
+		}
+	}
+
+	var i int
+	var s string
+	var b bool
+	var is []int
+	check(t, NewDecoder([]byte(`"5"`), Lenient()).Decode(&i))
+	check(t, NewDecoder([]byte(`5`), Lenient()).Decode(&s))
+	check(t, NewDecoder([]byte(`"1"`), Lenient()).Decode(&b))
+	check(t, NewDecoder([]byte(`["1", "2"]`), Lenient()).Decode(&is))
+	if i != 5 || s != "5" || !b || !reflect.DeepEqual(is, []int{1, 2}) {
+		t.Errorf("got %d, %q, %t, %v, want 5, \"5\", true, [1 2]", i, s, b, is)
+	}
+
+	for _, tc := range []struct{ in, err string }{
+		{`{"i": "abc"}`, "json: cannot decode string into Go struct field record.i of type int"},
+		{`{"i": ""}`, "json: cannot decode string into Go struct field record.i of type int"},
+		{`{"i": " 1"}`, "json: cannot decode string into Go struct field record.i of type int"},
+		{`{"i": "1.5"}`, "json: cannot decode number 1.5 into Go struct field record.i of type int"},
+		{`{"u": "256"}`, "json: cannot decode number 256 into Go struct field record.u of type uint8"},
+		{`{"u": "-1"}`, "json: cannot decode number -1 into Go struct field record.u of type uint8"},
+		{`{"b": "yes"}`, "json: cannot decode string into Go struct field record.b of type bool"},
+		{`{"b": 2}`, "json: cannot decode number into Go struct field record.b of type bool"},
+		{`{"b": 1.0}`, "json: cannot decode number into Go struct field record.b of type bool"},
+		{`{"s": true}`, "json: cannot decode bool into Go struct field record.s of type string"},
+		{`{"d": "1s"}`, "json: cannot decode string into Go struct field record.d of type time.Duration"},
+		{`{"is": ["1", "x"]}`, "json: cannot decode string into Go struct field record.is of type int"},
+		{`{"ms": {"a": true}}`, "json: cannot decode bool into Go struct field record.ms of type string at offset 13, path $.ms.a"},
+	} {
+		err := NewDecoder([]byte(tc.in), Lenient()).Decode(new(record))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Unmarshal(%s): got %v, want %s", tc.in, err, tc.err)
+		}
+	}
+	var d time.Duration
+	check(t, NewDecoder([]byte(`"1s"`), Lenient(), AllowDurationStrings()).Decode(&d))
+	check(t, NewDecoder([]byte(`"2000"`), Lenient()).Decode(&d))
+	if d != 2000 {
+		t.Errorf("got %v, want 2000ns", d)
+	}
+}
+
+func TestDecoderInternKeys(t *testing.T) {
+	input := []byte(`[{"id": 1, "msg": "a"}, {"id": 2, "m\u0073g": "b"}]`)
+	sameKeys := func(ms []map[string]interface{}) bool {
+		var ids, msgs []string
+		for _, m := range ms {
+			for k := range m {
+				if k == "id" {
+					ids = append(ids, k)
+				} else {
+					msgs = append(msgs, k)
+				}
+			}
+		}
+		return unsafe.StringData(ids[0]) == unsafe.StringData(ids[1]) &&
+			unsafe.StringData(msgs[0]) == unsafe.StringData(msgs[1])
+	}
+
+	var ms []map[string]interface{}
+	check(t, NewDecoder(input).Decode(&ms))
+	if sameKeys(ms) {
+		t.Errorf("Decode: keys shared without InternKeys")
+	}
+	dec := NewDecoder(input, InternKeys())
+	check(t, dec.Decode(&ms))
+	if !sameKeys(ms) {
+		t.Errorf("Decode: keys not shared with InternKeys")
+	}
+
+	// Reset keeps the interned keys.
+	first := ms
+	dec.Reset(input)
+	ms = nil
+	check(t, dec.Decode(&ms))
+	if unsafe.StringData(keyOf(first[0], "id")) != unsafe.StringData(keyOf(ms[0], "id")) {
+		t.Errorf("Decode after Reset: keys not shared")
+	}
+	dec.ClearInternedKeys()
+	dec.Reset(input)
+	ms = nil
+	check(t, dec.Decode(&ms))
+	if unsafe.StringData(keyOf(first[0], "id")) == unsafe.StringData(keyOf(ms[0], "id")) || !sameKeys(ms) {
+		t.Errorf("Decode after ClearInternedKeys: keys not interned afresh")
+	}
+
+	// maps decoded by reflection, and maps of strings.
+	type key string
+	var mk []map[key]int
+	input = []byte(`[{"a": 1}, {"a": 2}]`)
+	check(t, NewDecoder(input, InternKeys()).Decode(&mk))
+	var ka []string
+	for _, m := range mk {
+		for k := range m {
+			ka = append(ka, string(k))
+		}
+	}
+	if unsafe.StringData(ka[0]) != unsafe.StringData(ka[1]) {
+		t.Errorf("Decode into map[key]int: keys not shared")
+	}
+	var mss []map[string]string
+	check(t, NewDecoder([]byte(`[{"a": "x"}, {"a": "y"}]`), InternKeys()).Decode(&mss))
+	if unsafe.StringData(keyOf(mss[0], "a")) != unsafe.StringData(keyOf(mss[1], "a")) {
+		t.Errorf("Decode into map[string]string: keys not shared")
+	}
+
+	// beyond MaxInternedKeys, new keys are decoded without being interned.
+	var buf bytes.Buffer
+	buf.WriteString(`{`)
+	for i := 0; i < MaxInternedKeys+10; i++ {
+		fmt.Fprintf(&buf, `"k%d": %d, `, i, i)
+	}
+	buf.WriteString(`"last": 0}`)
+	dec = NewDecoder(buf.Bytes(), InternKeys())
+	var m map[string]int
+	check(t, dec.Decode(&m))
+	if len(m) != MaxInternedKeys+11 || m["k1030"] != 1030 || len(dec.keys) != MaxInternedKeys {
+		t.Errorf("Decode: got %d members, %d interned keys", len(m), len(dec.keys))
+	}
+}
+
+// keyOf returns the key of m equal to k, as stored in the map.
+func keyOf[V any](m map[string]V, k string) string {
+	for mk := range m {
+		if mk == k {
+			return mk
+		}
+	}
+	return ""
+}
+
+func TestDecoderDrain(t *testing.T) {
+	tests := []struct {
+		input string
+		skip  int // tokens read before Drain
+		err   string
+	}{
+		{`{"a": 1, "b": [2, {"c": "d"}]}`, 0, ""},
+		{`{"a": 1, "b": [2, {"c": "d"}]}  `, 4, ""},
+		{`{"a": 1, "b": [2, {"c": "d"}]}`, 10, ""},
+		{`"str"`, 1, ""},
+		{``, 0, ""},
+		{`{"a": 1, "b": [2, {"c": "d"}]`, 3, "unexpected end of JSON input at line 1, column 30 (offset 29)"},
+		{`{"a": 1, "b": [2 {"c": "d"}]}`, 3, "invalid character '{' after array element"},
+		{`{"a": 1, "b": [2, "\x"]}`, 3, "invalid character 'x' in string escape code"},
+		{`{"a": 1, "b": [2, {"c": "d"}]}`, 0, ""},
+		{`{"a": 1} {"b": 2}`, 2, "invalid character '{' after top-level value"},
+		{`{"a": 1} {"b": 2}`, 5, ""},
+		{`{"a": 1} {"b": 2} 3`, 5, "invalid character '3' after top-level value"},
+		{`[1, 2] x`, 1, "invalid character 'x' after top-level value"},
+		{`[1, 2] // c`, 1, "invalid character '/' after top-level value"},
+	}
+	for _, tc := range tests {
+		d := NewDecoder([]byte(tc.input))
+		for i := 0; i < tc.skip; i++ {
+			if _, err := d.NextToken(); err != nil {
+				break
+			}
+		}
+		err := d.Drain()
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("Drain(%s) after %d tokens: got %v, want %q", tc.input, tc.skip, err, tc.err)
+		}
+	}
+
+	// the Decoder is left reading a stream of values.
+	d := NewDecoder([]byte(`[1] 2`))
+	d.NextToken()
+	if err := d.Drain(); err == nil || d.disallowTrailingData {
+		t.Errorf("Drain: got %v, disallowTrailingData %v", err, d.disallowTrailingData)
+	}
+	check(t, NewDecoder([]byte(`[1, /* c */ 2] // c`), AllowComments()).Drain())
+	if err := NewDecoder([]byte(`["abcdef"]`), func(d *Decoder) { d.SetMaxStringLen(3) }).Drain(); err == nil {
+		t.Errorf("Drain: expected *LimitError")
+	}
+}
+
+func TestDecoder_Skip(t *testing.T) {
+	tests := []struct {
+		json      string
+		tokens    []string
+		nextToken string
+	}{
+		{
+			json:      `{"a": "some long string", "b": true}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `"b"`,
+		},
+		{
+			json:      `{"a": {"key": "value"}, "b": true}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `"b"`,
+		},
+		{
+			json:      `{"a": [1, 2, 3]}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `}`,
+		},
+		{
+			json:      `{"a": [1, "][", 2], "b": true}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `"b"`,
+		},
+		{
+			json:      `{"a": [1, "strings starts here\" and continue after escape sequence][", 2]}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `}`,
+		},
+		{
+			json:      `{"a": [1, "strings starts here\" and continue after escape sequence][", 2]}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `}`,
+		},
+		{
+			json:      `{"a": [1, "strings starts here\" and continue after escape sequence][", 2]}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `}`,
+		},
+		{
+			json:      `{"a": {"b": 1}, "c": 3.1}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `"c"`,
+		},
+		{
+			json:      `{"a": {"b": [ "one", {"g": 1}, [true, false] ]}, "c": 3.1}`,
+			tokens:    []string{`{`, `"a"`},
+			nextToken: `"c"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			dec := NewDecoder([]byte(tc.json))
+			for n, want := range tc.tokens {
+				got, err := dec.NextToken()
+				if string(got) != want {
+					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+				}
+				t.Logf("token: %q, stack: %v", got, dec.stack)
+			}
+			if err := dec.Skip(); err != nil {
+				t.Fatalf("skip: %v", err)
+			}
+			got, err := dec.NextToken()
+			if string(got) != tc.nextToken {
+				t.Fatalf("expected: %q, got: %q, %v", tc.nextToken, string(got), err)
+			}
+		})
+	}
+}
+
+func TestDecoder_SkipValue(t *testing.T) {
+	tests := []struct {
+		json   string
+		before []string
+		after  []string
+	}{
+		{json: `42`},
+		{json: `"hello"`},
+		{json: `true`},
+		{json: `[1, [2, 3], {"a": 4}]`},
+		{json: `{"a": [1, 2]}`},
+		{json: `[1, {"x":2}, 3]`, before: []string{`[`, `1`}, after: []string{`3`, `]`}},
+		{json: `[1, [2], 3]`, before: []string{`[`, `1`}, after: []string{`3`, `]`}},
+		{json: `[1, "two", 3]`, before: []string{`[`, `1`}, after: []string{`3`, `]`}},
+		{json: `[{"a": 1}, {"b": 2}]`, before: []string{`[`}, after: []string{`{`, `"b"`, `2`, `}`, `]`}},
+		{json: `{"a": [1], "b": 2}`, before: []string{`{`, `"a"`}, after: []string{`"b"`, `2`, `}`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			dec := NewDecoder([]byte(tc.json))
+			for n, want := range tc.before {
+				got, err := dec.NextToken()
+				if string(got) != want {
+					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+				}
+			}
+			if err := dec.Skip(); err != nil {
+				t.Fatalf("skip: %v", err)
+			}
+			for n, want := range tc.after {
+				got, err := dec.NextToken()
+				if string(got) != want {
+					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+				}
+			}
+			if _, err := dec.NextToken(); err != io.EOF {
+				t.Fatalf("expected: %v, got: %v", io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestDecoder_More(t *testing.T) {
+	tests := []struct {
+		json   string
+		tokens []string
+		more   bool
+	}{
+		{json: ``, more: false},
+		{json: `  `, more: false},
+		{json: ` 1`, more: true},
+		{json: `[]`, tokens: []string{`[`}, more: false},
+		{json: `[ ]`, tokens: []string{`[`}, more: false},
+		{json: `[1]`, tokens: []string{`[`}, more: true},
+		{json: `[1]`, tokens: []string{`[`, `1`}, more: false},
+		{json: `[1, 2]`, tokens: []string{`[`, `1`}, more: true},
+		{json: `[1 ,  2]`, tokens: []string{`[`, `1`}, more: true},
+		{json: `{}`, tokens: []string{`{`}, more: false},
+		{json: `{"a": 1}`, tokens: []string{`{`}, more: true},
+		{json: `{"a": 1}`, tokens: []string{`{`, `"a"`, `1`}, more: false},
+		{json: `{"a": 1, "b": 2}`, tokens: []string{`{`, `"a"`, `1`}, more: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			dec := NewDecoder([]byte(tc.json))
+			for n, want := range tc.tokens {
+				got, err := dec.NextToken()
+				if string(got) != want {
+					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+				}
+			}
+			if got := dec.More(); got != tc.more {
+				t.Fatalf("expected: %v, got: %v", tc.more, got)
+			}
+			// More must not consume anything.
+			if got := dec.More(); got != tc.more {
+				t.Fatalf("expected: %v, got: %v", tc.more, got)
+			}
+		})
+	}
+}
+
+func TestDecoder_MoreIterate(t *testing.T) {
+	dec := NewDecoder([]byte(`[1, {"x": [2]}, "three", [4, 5]]`))
+	if _, err := dec.NextToken(); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for dec.More() {
+		if err := dec.Skip(); err != nil {
+			t.Fatalf("skip: %v", err)
+		}
+		n++
+	}
+	if n != 4 {
+		t.Fatalf("expected 4 elements, got %v", n)
+	}
+	tok, err := dec.NextToken()
+	if string(tok) != `]` {
+		t.Fatalf("expected: %q, got: %q, %v", `]`, tok, err)
+	}
+}
+
+func TestDecoder_PeekKind(t *testing.T) {
+	input := `{"a" : [1, "two", true, null, {}], "b": false }`
+	want := []Kind{
+		KindObjectStart,
+		KindString,
+		KindArrayStart,
+		KindNumber, KindString, KindBool, KindNull,
+		KindObjectStart, KindObjectEnd,
+		KindArrayEnd,
+		KindString, KindBool,
+		KindObjectEnd,
+	}
+	dec := NewDecoder([]byte(input))
+	for n, kind := range want {
+		if got := dec.PeekKind(); got != kind {
+			t.Fatalf("%v: expected: %v, got: %v", n+1, kind, got)
+		}
+		offset := dec.getOffset()
+		depth := dec.len()
+		if got := dec.PeekKind(); got != kind {
+			t.Fatalf("%v: second peek: expected: %v, got: %v", n+1, kind, got)
+		}
+		if dec.getOffset() != offset || dec.len() != depth {
+			t.Fatalf("%v: PeekKind advanced the decoder", n+1)
+		}
+		tok, err := dec.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := kinds[tok[0]]; got != kind {
+			t.Fatalf("%v: expected token of kind %v, got %q", n+1, kind, tok)
+		}
+	}
+	if got := dec.PeekKind(); got != KindInvalid {
+		t.Fatalf("expected: %v, got: %v", KindInvalid, got)
+	}
+}
+
+func TestDecoder_SkipUnterminated(t *testing.T) {
+	tests := []struct {
+		json   string
+		tokens []string
+	}{
+		{json: `{"a": [1, 2`, tokens: []string{`{`, `"a"`}},
+		{json: `{"a": {"b": 1`, tokens: []string{`{`, `"a"`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			dec := NewDecoder([]byte(tc.json))
+			for n, want := range tc.tokens {
+				got, err := dec.NextToken()
+				if string(got) != want {
+					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+				}
+			}
+			err := dec.Skip()
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("expected: %v, got: %v", io.ErrUnexpectedEOF, err)
+			}
+			if off := dec.getOffset(); off > len(tc.json) {
+				t.Fatalf("offset %v past end of input %v", off, len(tc.json))
+			}
+		})
+	}
+}
+
+func TestDecoder_SkipMismatched(t *testing.T) {
+	tests := []struct {
+		json   string
+		tokens []string
+	}{
+		{json: `{"a": [1, }, 2]}`, tokens: []string{`{`, `"a"`}},
+		{json: `{"a": [ { ] } ]}`, tokens: []string{`{`, `"a"`}},
+		{json: `{"a": {"b": [1}]}`, tokens: []string{`{`, `"a"`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.json, func(t *testing.T) {
+			dec := NewDecoder([]byte(tc.json))
+			for n, want := range tc.tokens {
+				got, err := dec.NextToken()
+				if string(got) != want {
+					t.Fatalf("%v: expected: %q, got: %q, %v", n+1, want, string(got), err)
+				}
+			}
+			if err := dec.Skip(); err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+		})
+	}
+}
+
+func BenchmarkDecoder_Skip(b *testing.B) {
 	input := []byte(`{"a": 1,"b": 123.456, "c": [null]}`)
 	dec := NewDecoder(input)
 	b.ResetTimer()
@@ -333,3 +3568,82 @@ func BenchmarkDecoder_Skip(b *testing.B) {
 		dec.Reset(input)
 	}
 }
+
+// FuzzDecoder checks that Unmarshal agrees with encoding/json on whether the
+// input is valid and, if it is, on the value decoded into an interface{}.
+func FuzzDecoder(f *testing.F) {
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(f, tc.path))
+		check(f, err)
+		if len(data) <= 64<<10 {
+			f.Add(data)
+			continue
+		}
+		// large inputs slow fuzzing to a crawl, seed with their members.
+		v := Parse(data)
+		var members []Value
+		switch v.Kind() {
+		case KindObjectStart:
+			for _, m := range v.Map() {
+				members = append(members, m)
+			}
+		case KindArrayStart:
+			members = v.Array()
+		}
+		for _, m := range members {
+			if len(m.Raw()) <= 4<<10 {
+				f.Add(m.Raw())
+			}
+		}
+	}
+	for _, tc := range invalidJSON {
+		f.Add([]byte(tc.json))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// a leading byte order mark is skipped, encoding/json rejects it.
+		std := bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+		var want interface{}
+		wantErr := json.Unmarshal(std, &want)
+		var got interface{}
+		// encoding/json decodes lone surrogates as U+FFFD.
+		dec := NewDecoder(data, AllowLoneSurrogates())
+		dec.DisallowTrailingData()
+		gotErr := dec.Decode(&got)
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Fatalf("Unmarshal: got error %v, encoding/json: %v", gotErr, wantErr)
+		}
+		if valid := Valid(data); valid != json.Valid(std) {
+			t.Fatalf("Valid: got %v, encoding/json: %v", valid, !valid)
+		}
+		if gotErr != nil {
+			return
+		}
+		if !utf8.Valid(data) {
+			// encoding/json replaces invalid UTF-8 in strings with U+FFFD,
+			// this package passes it through.
+			return
+		}
+		if !reflect.DeepEqual(normalizeNumbers(got), normalizeNumbers(want)) {
+			t.Fatalf("Unmarshal: got %#v, encoding/json: %#v", got, want)
+		}
+	})
+}
+
+// normalizeNumbers converts the numbers in a decoded interface{} value to
+// float64, whichever representation the decoder chose for them.
+func normalizeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeNumbers(e)
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
@@ -0,0 +1,271 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A SetOption changes how Set modifies a document.
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	createParents bool
+}
+
+// CreateParents makes Set create the missing objects along the path, rather
+// than failing. Every missing element of the path becomes an object key,
+// even one that looks like an array index.
+func CreateParents() SetOption {
+	return func(o *setOptions) { o.createParents = true }
+}
+
+// Set returns a copy of data with value stored at path, which is followed
+// as described for Decoder.Seek: an existing member or element is replaced,
+// a missing member is added at the end of its object, and the array index
+// "-", or the index of the element past the last, appends to an array. The
+// parent of the value must exist unless CreateParents is given. An empty
+// path replaces the whole document.
+//
+// Both data and value must be valid. Only the bytes of the value replaced,
+// or the separators around the one inserted, change: the rest of data is
+// copied byte for byte. If an object holds the key more than once, the first
+// member is replaced.
+func Set(data []byte, path []string, value []byte, opts ...SetOption) ([]byte, error) {
+	var o setOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := Validate(data); err != nil {
+		return nil, err
+	}
+	if err := Validate(value); err != nil {
+		return nil, err
+	}
+	value = bytes.TrimSpace(value)
+	if len(path) == 0 {
+		return append([]byte(nil), value...), nil
+	}
+	b, err := set(data, path, value, o.createParents)
+	if err != nil {
+		return nil, fmt.Errorf("Set %s: %w", strings.Join(path, "."), err)
+	}
+	return b, nil
+}
+
+// set stores value at the non-empty path of data, creating its missing
+// parents if create is set.
+func set(data []byte, path []string, value []byte, create bool) ([]byte, error) {
+	loc, err := locate(data, path, false)
+	if err != nil {
+		if !create || len(path) == 1 || !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		// Only a parent that is missing is created, not one that exists
+		// but is not an object or array.
+		parent := path[:len(path)-1]
+		if ploc, perr := locate(data, parent, false); perr == nil && ploc.found {
+			return nil, err
+		}
+		member := append([]byte{ObjectStart}, appendMember(nil, path[len(path)-1], value)...)
+		return set(data, parent, append(member, ObjectEnd), create)
+	}
+	switch {
+	case loc.found:
+		return splice(data, loc.value, value), nil
+	case loc.obj:
+		return loc.insert(data, appendMember(nil, path[len(path)-1], value)), nil
+	case loc.index > loc.n:
+		return nil, fmt.Errorf("%w: index out of range", ErrNotFound)
+	default:
+		return loc.insert(data, value), nil
+	}
+}
+
+// Delete returns a copy of data without the member or array element at
+// path, which is followed as described for Decoder.Seek, along with the
+// comma separating it from its neighbors. The rest of data is copied byte
+// for byte. If the path does not exist, Delete returns an error wrapping
+// ErrNotFound.
+//
+// data must be valid. If an object holds the key more than once, the first
+// member is deleted.
+func Delete(data []byte, path ...string) ([]byte, error) {
+	if err := Validate(data); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return nil, errors.New("json: cannot delete the whole document")
+	}
+	loc, err := locate(data, path, false)
+	if err == nil && !loc.found {
+		err = fmt.Errorf("%w: no such member or element", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Delete %s: %w", strings.Join(path, "."), err)
+	}
+	return splice(data, loc.remove), nil
+}
+
+// span is the range [start, end) of bytes in a document.
+type span struct {
+	start, end int
+}
+
+// location describes where a member of an object, or an element of an
+// array, lies in a document, or where one would be inserted.
+type location struct {
+	obj   bool // whether the parent is an object
+	found bool
+
+	// index is the array index looked up, or -1 for "-".
+	index int
+
+	// value is the span of the value found, and remove the span to cut to
+	// remove it along with the comma separating it from its neighbors.
+	value, remove span
+
+	// at is the offset of the key or element found, before which a new
+	// element is inserted.
+	at int
+
+	// n is the number of members or elements of the parent, open the
+	// offset just past its opening bracket, and last the end of its last
+	// value.
+	n, open, last int
+}
+
+// locate finds the member or element of data at path, which must not be
+// empty, reporting where it is or where it would be inserted. The parents of
+// path must exist. If strict is set, array indexes must follow the rules of
+// RFC 6901, though the final element may be "-".
+//
+// If an object holds the key more than once, the first member is found, as
+// for Seek. The members of the parent are skipped without validation, so
+// data should be known to be valid.
+func locate(data []byte, path []string, strict bool) (location, error) {
+	loc := location{index: -1, last: -1}
+	d := GetDecoder(data)
+	defer PutDecoder(d)
+	parent, elem := path[:len(path)-1], path[len(path)-1]
+	for _, e := range parent {
+		if err := d.seekElem(e, strict); err != nil {
+			return loc, err
+		}
+	}
+	switch kind := d.PeekKind(); kind {
+	case KindObjectStart:
+		loc.obj = true
+	case KindArrayStart:
+		if elem != "-" {
+			n, err := locateIndex(elem, strict)
+			if err != nil {
+				return loc, err
+			}
+			loc.index = n
+		}
+	case KindInvalid:
+		_, err := d.NextToken()
+		return loc, err
+	default:
+		return loc, fmt.Errorf("%w: %v is not an object or array", ErrNotFound, kind)
+	}
+	if _, err := d.NextToken(); err != nil {
+		return loc, err
+	}
+	loc.open = d.scanner.offset
+	end := KindArrayEnd
+	if loc.obj {
+		end = KindObjectEnd
+	}
+	for ; d.PeekKind() != end; loc.n++ {
+		start := d.peekOffset()
+		match := false
+		if loc.obj {
+			key, err := d.ReadStringBytes()
+			if err != nil {
+				return loc, err
+			}
+			match = !loc.found && string(key) == elem
+		} else {
+			match = loc.n == loc.index
+		}
+		value, err := d.NextAsBytes()
+		if err != nil {
+			return loc, err
+		}
+		stop := d.scanner.offset
+		if loc.found && loc.remove.end < 0 {
+			// The member found was the first, so it is removed along with
+			// the comma after it.
+			loc.remove.end = start
+		}
+		if match {
+			loc.found = true
+			loc.at = start
+			loc.value = span{stop - len(value), stop}
+			if loc.last < 0 {
+				loc.remove = span{start, -1}
+			} else {
+				loc.remove = span{loc.last, stop}
+			}
+		}
+		loc.last = stop
+	}
+	if loc.found && loc.remove.end < 0 {
+		loc.remove.end = loc.value.end
+	}
+	return loc, nil
+}
+
+// locateIndex parses the array index elem.
+func locateIndex(elem string, strict bool) (int, error) {
+	if strict {
+		if err := checkPointerIndex(elem); err != nil {
+			return 0, err
+		}
+	}
+	n, err := strconv.Atoi(elem)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: not an array index", ErrNotFound)
+	}
+	return n, nil
+}
+
+// insert returns a copy of data with member, which is either a value or a
+// key and value, added to the parent described by loc: before the element
+// found, or after the last member.
+func (loc *location) insert(data, member []byte) []byte {
+	switch {
+	case loc.found:
+		return splice(data, span{loc.at, loc.at}, member, []byte{Comma})
+	case loc.n == 0:
+		return splice(data, span{loc.open, loc.open}, member)
+	default:
+		return splice(data, span{loc.last, loc.last}, []byte{Comma}, member)
+	}
+}
+
+// appendMember appends key and value, as an object member, to b.
+func appendMember(b []byte, key string, value []byte) []byte {
+	b = appendString(b, key, false)
+	b = append(b, Colon)
+	return append(b, value...)
+}
+
+// splice returns a copy of data with the bytes in s replaced by the
+// concatenation of parts.
+func splice(data []byte, s span, parts ...[]byte) []byte {
+	n := len(data) - (s.end - s.start)
+	for _, p := range parts {
+		n += len(p)
+	}
+	b := make([]byte, 0, n)
+	b = append(b, data[:s.start]...)
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return append(b, data[s.end:]...)
+}
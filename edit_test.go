@@ -0,0 +1,140 @@
+package json
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		data  string
+		path  []string
+		value string
+		want  string
+	}{
+		{`{}`, []string{"a"}, `1`, `{"a":1}`},
+		{`{ }`, []string{"a"}, `1`, `{"a":1 }`},
+		{`{"a": 1}`, []string{"a"}, `[2]`, `{"a": [2]}`},
+		{`{"a": 1}`, []string{"b"}, `2`, `{"a": 1,"b":2}`},
+		{`{"a": 1, "a": 2}`, []string{"a"}, `3`, `{"a": 3, "a": 2}`},
+		{"{\n\t\"a\": {\"b\": null},\n\t\"c\": 3\n}", []string{"a", "b"}, `"x"`, "{\n\t\"a\": {\"b\": \"x\"},\n\t\"c\": 3\n}"},
+		{`{"a": [1, 2]}`, []string{"a", "0"}, `0`, `{"a": [0, 2]}`},
+		{`{"a": [1, 2]}`, []string{"a", "2"}, `3`, `{"a": [1, 2,3]}`},
+		{`{"a": [1, 2]}`, []string{"a", "-"}, `3`, `{"a": [1, 2,3]}`},
+		{`{"a": []}`, []string{"a", "0"}, `1`, `{"a": [1]}`},
+		{`[]`, []string{"-"}, ` {"b": 1} `, `[{"b": 1}]`},
+		{`{"a": 1}`, nil, ` true `, `true`},
+		{`{"a\"": 1}`, []string{"b<"}, `2`, `{"a\"": 1,"b<":2}`},
+	}
+	for _, tc := range tests {
+		data := []byte(tc.data)
+		got, err := Set(data, tc.path, []byte(tc.value))
+		if err != nil || string(got) != tc.want {
+			t.Errorf("Set(%s, %q, %s): got %s, %v, want %s", tc.data, tc.path, tc.value, got, err, tc.want)
+		}
+		if string(data) != tc.data {
+			t.Errorf("Set(%s, %q, %s) modified its input: %s", tc.data, tc.path, tc.value, data)
+		}
+	}
+}
+
+func TestSetCreateParents(t *testing.T) {
+	tests := []struct {
+		data string
+		path []string
+		want string
+	}{
+		{`{}`, []string{"a", "b", "c"}, `{"a":{"b":{"c":1}}}`},
+		{`{"a": {}}`, []string{"a", "b", "c"}, `{"a": {"b":{"c":1}}}`},
+		{`{"a": {"x": 0}}`, []string{"a", "b", "0"}, `{"a": {"x": 0,"b":{"0":1}}}`},
+		{`{"a": {"b": 2}}`, []string{"a", "b"}, `{"a": {"b": 1}}`},
+		{`{"a": []}`, []string{"a", "0", "b"}, `{"a": [{"b":1}]}`},
+	}
+	for _, tc := range tests {
+		got, err := Set([]byte(tc.data), tc.path, []byte("1"), CreateParents())
+		if err != nil || string(got) != tc.want {
+			t.Errorf("Set(%s, %q, CreateParents()): got %s, %v, want %s", tc.data, tc.path, got, err, tc.want)
+		}
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []struct {
+		data   string
+		path   []string
+		value  string
+		create bool
+		err    string
+	}{
+		{`{}`, []string{"a", "b"}, `1`, false, "Set a.b: json: path not found: no such key"},
+		{`{"a": 1}`, []string{"a", "b"}, `1`, false, "Set a.b: json: path not found: number is not an object or array"},
+		{`{"a": 1}`, []string{"a", "b"}, `1`, true, "Set a.b: json: path not found: number is not an object or array"},
+		{`{"a": [1]}`, []string{"a", "2"}, `1`, false, "Set a.2: json: path not found: index out of range"},
+		{`{"a": [1]}`, []string{"a", "x"}, `1`, true, "Set a.x: json: path not found: not an array index"},
+		{`{"a": 1`, []string{"a"}, `1`, false, "unexpected end of JSON input"},
+		{`{"a": 1}`, []string{"a"}, `[1`, false, "unexpected end of JSON input"},
+		{`{"a": 1}`, []string{"a"}, ``, false, "unexpected end of JSON input"},
+	}
+	for _, tc := range tests {
+		var opts []SetOption
+		if tc.create {
+			opts = append(opts, CreateParents())
+		}
+		got, err := Set([]byte(tc.data), tc.path, []byte(tc.value), opts...)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Set(%s, %q, %s): got %s, %v, want error %q", tc.data, tc.path, tc.value, got, err, tc.err)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		data string
+		path []string
+		want string
+	}{
+		{`{"a": 1}`, []string{"a"}, `{}`},
+		{`{ "a": 1 }`, []string{"a"}, `{  }`},
+		{`{"a": 1, "b": 2, "c": 3}`, []string{"a"}, `{"b": 2, "c": 3}`},
+		{`{"a": 1, "b": 2, "c": 3}`, []string{"b"}, `{"a": 1, "c": 3}`},
+		{`{"a": 1, "b": 2, "c": 3}`, []string{"c"}, `{"a": 1, "b": 2}`},
+		{`{"a": 1, "a": 2}`, []string{"a"}, `{"a": 2}`},
+		{`[1]`, []string{"0"}, `[]`},
+		{`[1, 2, 3]`, []string{"2"}, `[1, 2]`},
+		{`[1, [2, 3]]`, []string{"1", "0"}, `[1, [3]]`},
+		{"{\n  \"a\": {\"b\": [true]},\n  \"c\": null\n}", []string{"a", "b"}, "{\n  \"a\": {},\n  \"c\": null\n}"},
+		{"{\n  \"a\": {\"b\": [true]},\n  \"c\": null\n}", []string{"c"}, "{\n  \"a\": {\"b\": [true]}\n}"},
+	}
+	for _, tc := range tests {
+		data := []byte(tc.data)
+		got, err := Delete(data, tc.path...)
+		if err != nil || string(got) != tc.want {
+			t.Errorf("Delete(%s, %q): got %s, %v, want %s", tc.data, tc.path, got, err, tc.want)
+		}
+		if string(data) != tc.data {
+			t.Errorf("Delete(%s, %q) modified its input: %s", tc.data, tc.path, data)
+		}
+	}
+
+	for _, tc := range []struct {
+		data string
+		path []string
+	}{
+		{`{"a": 1}`, []string{"b"}},
+		{`{"a": 1}`, []string{"a", "b"}},
+		{`[1]`, []string{"1"}},
+		{`[1]`, []string{"-"}},
+		{`{}`, []string{"a"}},
+	} {
+		if got, err := Delete([]byte(tc.data), tc.path...); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete(%s, %q): got %s, %v, want ErrNotFound", tc.data, tc.path, got, err)
+		}
+	}
+	if got, err := Delete([]byte(`{"a": 1}`)); err == nil {
+		t.Errorf("Delete with no path: got %s, want error", got)
+	}
+	if got, err := Delete([]byte(`{"a": 1,}`), "a"); err == nil {
+		t.Errorf("Delete of invalid data: got %s, want error", got)
+	}
+}
@@ -0,0 +1,657 @@
+package json
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// Marshal returns the JSON encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	return MarshalAppend(nil, v)
+}
+
+// MarshalAppend appends the JSON encoding of v to dst and returns the
+// extended buffer. If dst has sufficient capacity MarshalAppend does not
+// allocate for scalars and strings. If v cannot be encoded the contents of
+// the returned buffer beyond len(dst) are unspecified.
+func MarshalAppend(dst []byte, v interface{}) ([]byte, error) {
+	e := encodeState{escapeHTML: true}
+	if e.hasEncodeFuncs() {
+		// the fast paths would bypass an EncodeFunc registered for their types.
+		return e.appendValue(dst, reflect.ValueOf(v))
+	}
+	switch v := v.(type) {
+	case string:
+		return appendString(dst, v, true), nil
+	case bool:
+		return strconv.AppendBool(dst, v), nil
+	case int:
+		return strconv.AppendInt(dst, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(dst, v, 10), nil
+	case float64:
+		return AppendFloat(dst, v, 64)
+	default:
+		return e.appendValue(dst, reflect.ValueOf(v))
+	}
+}
+
+// AppendString appends s to dst as a quoted and escaped JSON string.
+// As with Marshal, the HTML characters <, > and & are escaped.
+func AppendString(dst []byte, s string) []byte {
+	return appendString(dst, s, true)
+}
+
+// AppendQuote is like AppendString but quotes the contents of a []byte,
+// without converting it to a string first.
+func AppendQuote(dst []byte, s []byte) []byte {
+	return appendString(dst, bytesToString(s), true)
+}
+
+// AppendInt appends the JSON encoding of the integer i to dst.
+func AppendInt(dst []byte, i int64) []byte {
+	return strconv.AppendInt(dst, i, 10)
+}
+
+// AppendUint appends the JSON encoding of the unsigned integer u to dst.
+func AppendUint(dst []byte, u uint64) []byte {
+	return strconv.AppendUint(dst, u, 10)
+}
+
+// AppendBool appends true or false to dst.
+func AppendBool(dst []byte, b bool) []byte {
+	return strconv.AppendBool(dst, b)
+}
+
+// AppendFloat appends the JSON encoding of the floating-point number f,
+// as generated by strconv.ParseFloat with the given bitSize, to dst. The
+// output is the same as encoding/json's: the shortest text that round-trips,
+// in exponent notation only for very large or very small magnitudes.
+// NaN and infinities cannot be represented in JSON and are reported as an
+// *UnsupportedValueError.
+func AppendFloat(dst []byte, f float64, bitSize int) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return dst, &UnsupportedValueError{Value: reflect.ValueOf(f), Str: strconv.FormatFloat(f, 'g', -1, bitSize)}
+	}
+
+	// Convert as if by ES6 number to string conversion. This matches most
+	// other JSON generators, and encoding/json byte for byte: use the
+	// shortest representation that round-trips, switching to exponent
+	// notation only below 1e-6 or at 1e21 and above.
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bitSize == 64 && (abs < 1e-6 || abs >= 1e21) || bitSize == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, bitSize)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst, nil
+}
+
+// An Encoder writes JSON values to an output stream.
+type Encoder struct {
+	w   io.Writer
+	buf []byte // scratch buffer, retained across calls to Encode
+	encodeState
+
+	indentPrefix string
+	indentValue  string
+	indentBuf    []byte // scratch buffer for indented output
+
+	// state of the streaming writer API, see writer.go.
+	frames []writeFrame
+	wbuf   []byte
+
+	// set on the Encoder passed to an EncodeFunc, which collects the one
+	// value written to it in wbuf rather than writing it out.
+	collect   bool
+	collected bool
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, encodeState: encodeState{escapeHTML: true}}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a newline
+// character. Nothing is written if v cannot be encoded.
+func (e *Encoder) Encode(v interface{}) error {
+	if e.collect {
+		return e.WriteValue(v)
+	}
+	if len(e.frames) > 0 {
+		return errIncompleteDocument
+	}
+	b, err := e.appendValue(e.buf[:0], reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	e.buf = b
+	if e.indenting() {
+		if b, err = appendIndent(e.indentBuf[:0], b, e.indentPrefix, e.indentValue); err != nil {
+			return err
+		}
+		e.indentBuf = b
+	}
+	b = append(b, '\n')
+	_, err = e.w.Write(b)
+	return err
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be
+// escaped inside JSON quoted strings. The default behavior is to escape
+// &, <, and > to \u0026, \u003c, and \u003e to avoid certain safety problems
+// that can arise when embedding JSON in HTML.
+//
+// In non-HTML settings where the escaping interferes with the readability
+// of the output, SetEscapeHTML(false) disables this behavior.
+func (e *Encoder) SetEscapeHTML(on bool) {
+	e.escapeHTML = on
+}
+
+// SetIndent instructs the encoder to format each subsequent encoded
+// value as if indented by the package-level function Indent(dst, src, prefix, indent).
+// Calling SetIndent("", "") disables indentation. The indentation applies to
+// values written with the streaming writer API too, and should only be
+// changed between top-level values.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.indentPrefix = prefix
+	e.indentValue = indent
+}
+
+// An UnsupportedTypeError is returned by Marshal when attempting
+// to encode an unsupported value type.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "json: unsupported type: " + e.Type.String()
+}
+
+// An UnsupportedValueError is returned by Marshal when attempting
+// to encode an unsupported value, such as a NaN float.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "json: unsupported value: " + e.Str
+}
+
+// encodeState holds the scratch space used while encoding a value.
+type encodeState struct {
+	keys       []mapKey // map keys being sorted, used as a stack by nested maps
+	escapeHTML bool     // escape <, > and & in strings
+
+	// Keep track of what pointers we've seen in the current recursive call
+	// path, to avoid cycles that could lead to a stack overflow. Only do the
+	// relatively expensive map operations if ptrLevel is larger than
+	// startDetectingCyclesAfter, so that we skip the work if we're within a
+	// reasonable amount of nested pointers deep.
+	ptrLevel uint
+	ptrSeen  map[any]struct{}
+
+	// functions registered with RegisterEncodeFunc, overriding the
+	// package-level ones, and the Encoder passed to them.
+	encodeFuncs map[reflect.Type]EncodeFunc
+	funcEnc     *Encoder
+}
+
+// startDetectingCyclesAfter is the nesting depth of pointers, maps and slices
+// past which the encoder starts checking for cycles, as encoding/json does.
+const startDetectingCyclesAfter = 1000
+
+// enter records that the encoder descends into the pointer, map or slice v.
+// It returns an UnsupportedValueError if v is already being encoded further
+// up the current path. Every successful enter must be paired with a leave.
+func (e *encodeState) enter(v reflect.Value) error {
+	e.ptrLevel++
+	if e.ptrLevel <= startDetectingCyclesAfter {
+		return nil
+	}
+	ptr := cycleKey(v)
+	if _, ok := e.ptrSeen[ptr]; ok {
+		e.ptrLevel--
+		return &UnsupportedValueError{Value: v, Str: "encountered a cycle via " + v.Type().String()}
+	}
+	if e.ptrSeen == nil {
+		e.ptrSeen = make(map[any]struct{})
+	}
+	e.ptrSeen[ptr] = struct{}{}
+	return nil
+}
+
+// leave undoes the matching call to enter.
+func (e *encodeState) leave(v reflect.Value) {
+	if e.ptrLevel > startDetectingCyclesAfter {
+		delete(e.ptrSeen, cycleKey(v))
+	}
+	e.ptrLevel--
+}
+
+// cycleKey returns the identity of the pointer, map or slice v. A slice is
+// identified by its length as well as its data pointer, since different
+// subslices of the same array may be encoded without forming a cycle.
+func cycleKey(v reflect.Value) any {
+	if v.Kind() == reflect.Slice {
+		return struct {
+			ptr unsafe.Pointer
+			len int
+		}{v.UnsafePointer(), v.Len()}
+	}
+	return v.UnsafePointer()
+}
+
+// mapKey is a map entry along with the encoded string form of its key,
+// used for sorting.
+type mapKey struct {
+	name  string
+	value reflect.Value
+}
+
+// Marshaler is the interface implemented by types that can marshal
+// themselves into valid JSON.
+type Marshaler interface {
+	MarshalJSON() ([]byte, error)
+}
+
+// A MarshalerError represents an error from calling a MarshalJSON or
+// MarshalText method.
+type MarshalerError struct {
+	Type       reflect.Type
+	Err        error
+	sourceFunc string
+}
+
+func (e *MarshalerError) Error() string {
+	return "json: error calling " + e.sourceFunc + " for type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *MarshalerError) Unwrap() error { return e.Err }
+
+var (
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// appendValue appends the JSON encoding of v to b.
+func (e *encodeState) appendValue(b []byte, v reflect.Value) ([]byte, error) {
+	if v.IsValid() {
+		if e.hasEncodeFuncs() {
+			if b, ok, err := e.callEncodeFunc(b, v); ok {
+				return b, err
+			}
+		}
+		if t := v.Type(); isBig(t) || t.Kind() == reflect.Ptr && isBig(t.Elem()) {
+			return appendBig(b, v)
+		}
+		if v.Type() == orderedMapType {
+			return e.appendOrderedMap(b, v)
+		}
+		if m, ok := marshalerFor(v, marshalerType); ok {
+			if !m.IsValid() {
+				return append(b, "null"...), nil
+			}
+			return appendMarshalJSON(b, m)
+		}
+		if m, ok := marshalerFor(v, textMarshalerType); ok {
+			if !m.IsValid() {
+				return append(b, "null"...), nil
+			}
+			return e.appendMarshalText(b, m)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		// a nil interface{}.
+		return append(b, "null"...), nil
+	case reflect.Bool:
+		return strconv.AppendBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(b, v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(b, v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return appendFloat(b, v)
+	case reflect.String:
+		if v.Type() == numberType {
+			return appendNumber(b, v)
+		}
+		return appendString(b, v.String(), e.escapeHTML), nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return append(b, "null"...), nil
+		}
+		return e.appendValue(b, v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return append(b, "null"...), nil
+		}
+		if err := e.enter(v); err != nil {
+			return b, err
+		}
+		b, err := e.appendValue(b, v.Elem())
+		e.leave(v)
+		return b, err
+	case reflect.Map:
+		if err := e.enter(v); err != nil {
+			return b, err
+		}
+		b, err := e.appendMap(b, v)
+		e.leave(v)
+		return b, err
+	case reflect.Slice:
+		if v.IsNil() {
+			return append(b, "null"...), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendBytes(b, v.Bytes()), nil
+		}
+		if err := e.enter(v); err != nil {
+			return b, err
+		}
+		b, err := e.appendArray(b, v)
+		e.leave(v)
+		return b, err
+	case reflect.Array:
+		return e.appendArray(b, v)
+	case reflect.Struct:
+		return e.appendStruct(b, v)
+	default:
+		return b, &UnsupportedTypeError{Type: v.Type()}
+	}
+}
+
+func appendFloat(b []byte, v reflect.Value) ([]byte, error) {
+	b, err := AppendFloat(b, v.Float(), v.Type().Bits())
+	if err, ok := err.(*UnsupportedValueError); ok {
+		err.Value = v
+	}
+	return b, err
+}
+
+// appendNumber appends v, a Number, to b verbatim, so that a number decoded
+// with UseNumber is encoded as it was written. As in encoding/json, an empty
+// Number is 0, and one which is not a valid JSON number is an error.
+func appendNumber(b []byte, v reflect.Value) ([]byte, error) {
+	s := v.String()
+	if s == "" {
+		s = "0"
+	}
+	if !isNumber(s) {
+		return b, fmt.Errorf("json: invalid number literal %q", s)
+	}
+	return append(b, s...), nil
+}
+
+// marshalerFor reports whether v, or a pointer to v if v is addressable,
+// implements the interface type it. The returned value holds the receiver to
+// call the method on, or is invalid if that receiver is a nil pointer or
+// interface, which encodes as null.
+func marshalerFor(v reflect.Value, it reflect.Type) (reflect.Value, bool) {
+	t := v.Type()
+	switch {
+	case t.Implements(it):
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return reflect.Value{}, true
+		}
+		return v, true
+	case t.Kind() != reflect.Ptr && v.CanAddr() && reflect.PointerTo(t).Implements(it):
+		return v.Addr(), true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// appendMarshalJSON appends the output of m's MarshalJSON method, which is
+// validated and compacted so that a misbehaving method cannot corrupt the
+// surrounding document.
+func appendMarshalJSON(b []byte, m reflect.Value) ([]byte, error) {
+	out, err := m.Interface().(Marshaler).MarshalJSON()
+	if err != nil {
+		return b, &MarshalerError{Type: m.Type(), Err: err, sourceFunc: "MarshalJSON"}
+	}
+	n := len(b)
+	if b, err = appendCompact(b, out); err != nil {
+		return b[:n], &MarshalerError{Type: m.Type(), Err: err, sourceFunc: "MarshalJSON"}
+	}
+	return b, nil
+}
+
+// appendMarshalText appends the output of m's MarshalText method as a JSON
+// string.
+func (e *encodeState) appendMarshalText(b []byte, m reflect.Value) ([]byte, error) {
+	text, err := m.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return b, &MarshalerError{Type: m.Type(), Err: err, sourceFunc: "MarshalText"}
+	}
+	return appendString(b, bytesToString(text), e.escapeHTML), nil
+}
+
+func (e *encodeState) appendMap(b []byte, v reflect.Value) ([]byte, error) {
+	if v.IsNil() {
+		return append(b, "null"...), nil
+	}
+	kt := v.Type().Key()
+	switch kt.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		if !kt.Implements(textMarshalerType) {
+			return b, &UnsupportedTypeError{Type: v.Type()}
+		}
+	}
+
+	// Keys are sorted by their encoded form. Nested maps push their keys on
+	// top of ours, so only refer to e.keys by index while encoding values.
+	base := len(e.keys)
+	defer func() { e.keys = e.keys[:base] }()
+	iter := v.MapRange()
+	for iter.Next() {
+		name, err := mapKeyString(iter.Key())
+		if err != nil {
+			return b, err
+		}
+		e.keys = append(e.keys, mapKey{name: name, value: iter.Value()})
+	}
+	slices.SortFunc(e.keys[base:], func(a, b mapKey) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	b = append(b, '{')
+	for i := base; i < len(e.keys); i++ {
+		if i > base {
+			b = append(b, ',')
+		}
+		b = appendString(b, e.keys[i].name, e.escapeHTML)
+		b = append(b, ':')
+		var err error
+		if b, err = e.appendValue(b, e.keys[i].value); err != nil {
+			return b, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+// mapKeyString returns the object key for the map key k, which must be of
+// string or integer kind, or implement encoding.TextMarshaler. As with
+// encoding/json, string kinds are used as is even if they implement
+// encoding.TextMarshaler.
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		text, err := tm.MarshalText()
+		if err != nil {
+			return "", &MarshalerError{Type: k.Type(), Err: err, sourceFunc: "MarshalText"}
+		}
+		return string(text), nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	default:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+}
+
+func (e *encodeState) appendArray(b []byte, v reflect.Value) ([]byte, error) {
+	b = append(b, '[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		var err error
+		if b, err = e.appendValue(b, v.Index(i)); err != nil {
+			return b, err
+		}
+	}
+	return append(b, ']'), nil
+}
+
+func (e *encodeState) appendStruct(b []byte, v reflect.Value) ([]byte, error) {
+	b = append(b, '{')
+	first := true
+	fs := structFields(v.Type()).list
+	for i := range fs {
+		f := &fs[i]
+		fv, ok := f.value(v)
+		if !ok || f.omit(fv) {
+			// a field promoted through a nil embedded pointer is left out.
+			continue
+		}
+		if !first {
+			b = append(b, ',')
+		}
+		first = false
+		b = appendString(b, f.name, e.escapeHTML)
+		b = append(b, ':')
+		var err error
+		if b, err = e.appendValue(b, fv); err != nil {
+			return b, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+func appendBytes(b []byte, p []byte) []byte {
+	b = append(b, '"')
+	n := base64.StdEncoding.EncodedLen(len(p))
+	b = slices.Grow(b, n)
+	base64.StdEncoding.Encode(b[len(b):len(b)+n], p)
+	b = b[:len(b)+n]
+	return append(b, '"')
+}
+
+const hex = "0123456789abcdef"
+
+// safeSet holds true for the ASCII bytes that can appear in a JSON string
+// without escaping. Bytes at or above utf8.RuneSelf are false so that they
+// are checked for valid UTF-8.
+var safeSet = func() (t [256]bool) {
+	for c := ' '; c < utf8.RuneSelf; c++ {
+		t[c] = c != '"' && c != '\\'
+	}
+	return t
+}()
+
+// htmlSafeSet is like safeSet but also requires <, > and & to be escaped.
+var htmlSafeSet = func() (t [256]bool) {
+	t = safeSet
+	t['<'], t['>'], t['&'] = false, false, false
+	return t
+}()
+
+// appendString appends s to b as a quoted JSON string. Quotes, backslashes
+// and control characters are always escaped, as are U+2028 and U+2029;
+// <, > and & are escaped if escapeHTML is set. Invalid UTF-8 is replaced by
+// U+FFFD.
+func appendString(b []byte, s string, escapeHTML bool) []byte {
+	safe := &safeSet
+	if escapeHTML {
+		safe = &htmlSafeSet
+	}
+	b = append(b, '"')
+
+	// fast path: nothing to escape, copy s in one go.
+	i := 0
+	for i < len(s) && safe[s[i]] {
+		i++
+	}
+	if i == len(s) {
+		b = append(b, s...)
+		return append(b, '"')
+	}
+
+	start := 0
+	for i < len(s) {
+		c := s[i]
+		if safe[c] {
+			i++
+			continue
+		}
+		if c < utf8.RuneSelf {
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				// control characters and, if escapeHTML is set, <, > and &.
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+		case r == '\u2028' || r == '\u2029':
+			// valid JSON, but not valid JavaScript inside a string literal.
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,598 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEncoderEncode(t *testing.T) {
+	one := 1
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{v: nil, want: `null`},
+		{v: true, want: `true`},
+		{v: int8(-8), want: `-8`},
+		{v: uint64(math.MaxUint64), want: `18446744073709551615`},
+		{v: 1.5, want: `1.5`},
+		{v: float32(0.1), want: `0.1`},
+		{v: "hello", want: `"hello"`},
+		{v: "a\"b\\c\nd\x01", want: `"a\"b\\c\nd\u0001"`},
+		{v: "\xff", want: "\"\ufffd\""},
+		{v: &one, want: `1`},
+		{v: (*int)(nil), want: `null`},
+		{v: []int(nil), want: `null`},
+		{v: []int{}, want: `[]`},
+		{v: []interface{}{1, "two", nil}, want: `[1,"two",null]`},
+		{v: [2]bool{true, false}, want: `[true,false]`},
+		{v: []byte("hi"), want: `"aGk="`},
+		{v: map[string]int(nil), want: `null`},
+		{v: map[string]int{"a": 1}, want: `{"a":1}`},
+		{v: map[int]string{-1: "x"}, want: `{"-1":"x"}`},
+		{v: struct {
+			A int    `json:"a"`
+			B string `json:"-"`
+			C *int
+			d int
+		}{A: 1, B: "b", d: 4}, want: `{"a":1,"C":null}`},
+	}
+
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(tc.v); err != nil {
+			t.Errorf("encode %#v: %v", tc.v, err)
+			continue
+		}
+		if got := buf.String(); got != tc.want+"\n" {
+			t.Errorf("encode %#v: expected: %q, got: %q", tc.v, tc.want+"\n", got)
+		}
+	}
+}
+
+func TestEncoderUnsupported(t *testing.T) {
+	tests := []interface{}{
+		make(chan int),
+		func() {},
+		complex(1, 2),
+		[]interface{}{1, make(chan int)},
+		map[bool]int{true: 1},
+	}
+	for _, v := range tests {
+		var buf bytes.Buffer
+		err := NewEncoder(&buf).Encode(v)
+		var uerr *UnsupportedTypeError
+		if !errors.As(err, &uerr) {
+			t.Errorf("encode %T: expected *UnsupportedTypeError, got: %v", v, err)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("encode %T: expected no output, got: %q", v, buf.String())
+		}
+	}
+
+	var uerr *UnsupportedValueError
+	if _, err := Marshal(math.NaN()); !errors.As(err, &uerr) {
+		t.Errorf("expected *UnsupportedValueError, got: %v", err)
+	}
+}
+
+type cycleNode struct {
+	Name string
+	Next *cycleNode
+}
+
+func TestEncoderCycle(t *testing.T) {
+	a := &cycleNode{Name: "a"}
+	b := &cycleNode{Name: "b", Next: a}
+	a.Next = b
+
+	s := []interface{}{nil}
+	s[0] = s
+
+	m := map[string]interface{}{}
+	m["m"] = m
+
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{a, "encountered a cycle via *json.cycleNode"},
+		{s, "encountered a cycle via []interface {}"},
+		{m, "encountered a cycle via map[string]interface {}"},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		err := enc.Encode(tc.v)
+		var uerr *UnsupportedValueError
+		if !errors.As(err, &uerr) {
+			t.Fatalf("encode %T: expected *UnsupportedValueError, got: %v", tc.v, err)
+		}
+		if uerr.Str != tc.want {
+			t.Fatalf("expected: %q, got: %q", tc.want, uerr.Str)
+		}
+		// the encoder must be usable again after the error.
+		if err := enc.Encode([]int{1}); err != nil || buf.String() != "[1]\n" {
+			t.Fatalf("encode after cycle: %q, %v", buf.String(), err)
+		}
+	}
+}
+
+func TestEncoderDeepNoCycle(t *testing.T) {
+	// shared pointers that do not form a cycle must still encode.
+	leaf := &cycleNode{Name: "leaf"}
+	var v interface{} = []*cycleNode{leaf, leaf}
+	for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+		v = []interface{}{v}
+	}
+	got, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, _ := json.Marshal(v)
+	if string(got) != string(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+}
+
+func TestEncoderEmbedded(t *testing.T) {
+	type A struct{ X, Y int }
+	type B struct{ X, Z int }
+	type ambiguous struct {
+		A
+		B
+	}
+	tests := []interface{}{
+		event{},
+		event{Meta: Meta{ID: 1, Label: "m", Version: 2}, Payload: &Payload{Kind: "k", Version: 3}, Source: "s"},
+		event{Audit: Audit{Deep: &Deep{Who: "d", Level: 2}, Who: "w"}},
+		ambiguous{A{1, 2}, B{3, 4}},
+	}
+	for _, v := range tests {
+		got, err := Marshal(v)
+		check(t, err)
+		want, err := json.Marshal(v)
+		check(t, err)
+		if string(got) != string(want) {
+			t.Errorf("expected: %s, got: %s", want, got)
+		}
+	}
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	type inner struct {
+		Name  string            `json:"name"`
+		Tags  []string          `json:"tags"`
+		Attrs map[string]string `json:"attrs"`
+	}
+	type outer struct {
+		ID       int64     `json:"id"`
+		Ratio    float64   `json:"ratio"`
+		OK       bool      `json:"ok"`
+		Inner    inner     `json:"inner"`
+		Ptr      *inner    `json:"ptr"`
+		Nil      *inner    `json:"nil"`
+		Scores   []float32 `json:"scores"`
+		Unsigned uint16    `json:"unsigned"`
+	}
+	in := outer{
+		ID:       -42,
+		Ratio:    0.25,
+		OK:       true,
+		Inner:    inner{Name: "a", Tags: []string{"x", "y"}, Attrs: map[string]string{"k": "v"}},
+		Ptr:      &inner{Name: "b", Tags: []string{}},
+		Scores:   []float32{1.5, -2},
+		Unsigned: 7,
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(in); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := enc.Encode(&in); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	dec := NewDecoder(buf.Bytes())
+	for i := 0; i < 2; i++ {
+		var out outer
+		if err := dec.Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Fatalf("expected: %+v, got: %+v", in, out)
+		}
+	}
+
+	var v1 interface{}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := Unmarshal(b, &v1); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	b2, err := Marshal(v1)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var v2 interface{}
+	if err := Unmarshal(b2, &v2); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(v1, v2) {
+		t.Fatalf("expected: %v, got: %v", v1, v2)
+	}
+}
+
+func TestMarshalAppend(t *testing.T) {
+	dst := []byte(`prefix:`)
+	got, err := MarshalAppend(dst, map[string][]int{"a": {1, 2}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if want := `prefix:{"a":[1,2]}`; string(got) != want {
+		t.Fatalf("expected: %q, got: %q", want, got)
+	}
+
+	got = AppendString(got[:0], "a\tb")
+	got = append(got, ',')
+	got = AppendQuote(got, []byte(`"c"`))
+	got = append(got, ',')
+	got = AppendInt(got, -3)
+	got = append(got, ',')
+	got = AppendUint(got, 4)
+	got = append(got, ',')
+	got = AppendBool(got, false)
+	got = append(got, ',')
+	got, err = AppendFloat(got, 0.5, 64)
+	if err != nil {
+		t.Fatalf("append float: %v", err)
+	}
+	if want := `"a\tb","\"c\"",-3,4,false,0.5`; string(got) != want {
+		t.Fatalf("expected: %q, got: %q", want, got)
+	}
+	if _, err := AppendFloat(nil, math.Inf(1), 64); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestEncoderFloat(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{0.0, `0`},
+		{math.Copysign(0, -1), `-0`},
+		{1.0, `1`},
+		{-2.5, `-2.5`},
+		{1e20, `100000000000000000000`},
+		{1e21, `1e+21`},
+		{-1e21, `-1e+21`},
+		{1e-6, `0.000001`},
+		{5e-7, `5e-7`},
+		{1.5e-10, `1.5e-10`},
+		{123456789.125, `123456789.125`},
+		{math.MaxFloat64, `1.7976931348623157e+308`},
+		{math.SmallestNonzeroFloat64, `5e-324`},
+		{float32(0.1), `0.1`},
+		{float64(float32(0.1)), `0.10000000149011612`},
+		{float32(3.4028235e38), `3.4028235e+38`},
+		{float32(1e21), `1e+21`},
+		{float32(1e20), `100000000000000000000`},
+		{float32(5e-7), `5e-7`},
+		{float32(16777217), `16777216`},
+	}
+	for _, tc := range tests {
+		got, err := Marshal(tc.v)
+		if err != nil {
+			t.Fatalf("marshal %v: %v", tc.v, err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("marshal %T(%v): expected: %s, got: %s", tc.v, tc.v, tc.want, got)
+		}
+		want, _ := json.Marshal(tc.v)
+		if string(got) != string(want) {
+			t.Errorf("marshal %T(%v): encoding/json: %s, got: %s", tc.v, tc.v, want, got)
+		}
+	}
+
+	for _, v := range []interface{}{math.NaN(), math.Inf(1), math.Inf(-1), float32(math.Inf(1)), []float64{1, math.NaN()}} {
+		var uerr *UnsupportedValueError
+		if _, err := Marshal(v); !errors.As(err, &uerr) {
+			t.Errorf("marshal %v: expected *UnsupportedValueError, got: %v", v, err)
+		}
+	}
+}
+
+func TestEncoderNumber(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{Number("1.50"), `1.50`},
+		{Number("-0"), `-0`},
+		{Number("2.0e3"), `2.0e3`},
+		{Number("123456789012345678901234567890"), `123456789012345678901234567890`},
+		{Number(""), `0`},
+		{[]Number{"1E+2", "0.10"}, `[1E+2,0.10]`},
+		{map[string]Number{"a": "1e-07"}, `{"a":1e-07}`},
+		{struct{ N *Number }{new(Number)}, `{"N":0}`},
+	}
+	for _, tc := range tests {
+		got, err := Marshal(tc.v)
+		if err != nil || string(got) != tc.want {
+			t.Errorf("Marshal(%v): got %s, %v, want %s", tc.v, got, err, tc.want)
+		}
+		want, _ := json.Marshal(tc.v)
+		if string(got) != string(want) {
+			t.Errorf("Marshal(%v): encoding/json: %s, got: %s", tc.v, want, got)
+		}
+	}
+	for _, n := range []Number{"abc", "1 2", " 1", "01", "1.", ".5", "+1", "NaN", "1e", `"1"`} {
+		if got, err := Marshal(n); err == nil || err.Error() != "json: invalid number literal "+strconv.Quote(string(n)) {
+			t.Errorf("Marshal(%q): got %s, %v, want an invalid number literal error", n, got, err)
+		}
+	}
+}
+
+func TestEncoderNumberRoundTrip(t *testing.T) {
+	data, err := io.ReadAll(fixture(t, "numbers"))
+	check(t, err)
+	var want bytes.Buffer
+	check(t, json.Compact(&want, data))
+
+	// untouched numbers keep their formatting through decode and encode,
+	// so only the whitespace changes.
+	var v interface{}
+	check(t, NewDecoder(data, UseNumber(), UseOrderedMaps()).Decode(&v))
+	got, err := Marshal(v)
+	check(t, err)
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("got %s, want %s", got, want.Bytes())
+	}
+
+	m := v.(OrderedMap)
+	m.Set("name", "edited")
+	got, err = Marshal(m)
+	check(t, err)
+	wantEdited := bytes.Replace(want.Bytes(), []byte(`"quirky numbers"`), []byte(`"edited"`), 1)
+	if !bytes.Equal(got, wantEdited) {
+		t.Errorf("after an edit: got %s, want %s", got, wantEdited)
+	}
+}
+
+func TestMarshalAppendAllocs(t *testing.T) {
+	dst := make([]byte, 0, 64)
+	values := []interface{}{"hello, world", 12345, int64(-1), 3.25, true, uint8(7), float32(1.5)}
+	for _, v := range values {
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, err := MarshalAppend(dst, v); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("MarshalAppend(%T): expected 0 allocs, got %v", v, allocs)
+		}
+	}
+}
+
+func BenchmarkMarshalAppend(b *testing.B) {
+	values := []struct {
+		name string
+		v    interface{}
+	}{
+		{"string", "a string without any escapes"},
+		{"int", 1234567},
+		{"float", 123.456},
+		{"bool", true},
+	}
+	for _, tc := range values {
+		b.Run(tc.name, func(b *testing.B) {
+			dst := make([]byte, 0, 64)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var err error
+				dst, err = MarshalAppend(dst[:0], tc.v)
+				check(b, err)
+			}
+		})
+	}
+}
+
+func BenchmarkAppendString(b *testing.B) {
+	s := "a string without any escapes"
+	dst := make([]byte, 0, 64)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	for i := 0; i < b.N; i++ {
+		dst = AppendString(dst[:0], s)
+	}
+}
+
+func TestEncoderSortedMapKeys(t *testing.T) {
+	m := make(map[string]map[int]bool)
+	for i := 0; i < 100; i++ {
+		m[strconv.Itoa(i*7919%1000)] = map[int]bool{i: true, -i: false, 10 * i: true}
+	}
+	first, err := Marshal(m)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		again, err := Marshal(m)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if !bytes.Equal(first, again) {
+			t.Fatalf("expected identical output:\n%s\n%s", first, again)
+		}
+	}
+
+	got, err := Marshal(map[int]string{9: "a", 10: "b", -1: "c"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if want := `{"-1":"c","10":"b","9":"a"}`; string(got) != want {
+		t.Fatalf("expected: %q, got: %q", want, got)
+	}
+}
+
+type rawMarshaler struct{ out string }
+
+func (m rawMarshaler) MarshalJSON() ([]byte, error) { return []byte(m.out), nil }
+
+type ptrMarshaler struct{ n int }
+
+func (m *ptrMarshaler) MarshalJSON() ([]byte, error) { return []byte(strconv.Itoa(m.n)), nil }
+
+type textKey struct{ a, b string }
+
+func (k textKey) MarshalText() ([]byte, error) { return []byte(k.a + "<" + k.b), nil }
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) { return nil, errors.New("boom") }
+
+func TestEncoderMarshaler(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{v: rawMarshaler{"{ \"a\" :\n [ 1 , 2 ] ,\t\"b\": \"x y\" }"}, want: `{"a":[1,2],"b":"x y"}`},
+		{v: &rawMarshaler{` true `}, want: `true`},
+		{v: (*rawMarshaler)(nil), want: `null`},
+		{v: []rawMarshaler{{`1`}, {` "two" `}}, want: `[1,"two"]`},
+		{v: struct{ M ptrMarshaler }{ptrMarshaler{7}}, want: `{"M":{}}`},
+		{v: &struct{ M ptrMarshaler }{ptrMarshaler{7}}, want: `{"M":7}`},
+		{v: struct{ I interface{} }{rawMarshaler{`[ ]`}}, want: `{"I":[]}`},
+		{v: struct{ M Marshaler }{}, want: `{"M":null}`},
+		{v: textKey{"a", "b"}, want: `"a\u003cb"`},
+		{v: map[textKey]int{{"x", "y"}: 1, {"a", "b"}: 2}, want: `{"a\u003cb":2,"x\u003cy":1}`},
+	}
+	for _, tc := range tests {
+		got, err := Marshal(tc.v)
+		if err != nil {
+			t.Errorf("marshal %#v: %v", tc.v, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("marshal %#v: expected: %q, got: %q", tc.v, tc.want, got)
+		}
+		var v interface{}
+		if err := Unmarshal(got, &v); err != nil {
+			t.Errorf("marshal %#v: output %q is not valid: %v", tc.v, got, err)
+		}
+	}
+
+	for _, v := range []interface{}{
+		rawMarshaler{`{"a": }`},
+		rawMarshaler{`1 2`},
+		rawMarshaler{``},
+		[]interface{}{failingMarshaler{}},
+	} {
+		_, err := Marshal(v)
+		var merr *MarshalerError
+		if !errors.As(err, &merr) {
+			t.Errorf("marshal %#v: expected *MarshalerError, got: %v", v, err)
+		}
+	}
+}
+
+func TestEncoderOmitEmpty(t *testing.T) {
+	type point struct{ X, Y int }
+	type record struct {
+		Bool      bool              `json:"bool,omitempty"`
+		Int       int               `json:"int,omitempty"`
+		Float     float64           `json:"float,omitempty"`
+		String    string            `json:"string,omitempty"`
+		Ptr       *int              `json:"ptr,omitempty"`
+		Slice     []int             `json:"slice,omitempty"`
+		Map       map[string]int    `json:"map,omitempty"`
+		Struct    point             `json:"struct,omitempty"`
+		ZeroInt   int               `json:"zero_int,omitzero"`
+		ZeroSlice []int             `json:"zero_slice,omitzero"`
+		ZeroPoint point             `json:"zero_point,omitzero"`
+		ZeroTime  time.Time         `json:"zero_time,omitzero"`
+		ZeroMap   map[string]string `json:"zero_map,omitzero"`
+		Both      string            `json:",omitempty,omitzero"`
+	}
+	tests := []struct {
+		v    record
+		want string
+	}{
+		{v: record{}, want: `{"struct":{"X":0,"Y":0}}`},
+		{
+			v: record{
+				Slice:     []int{},
+				ZeroSlice: []int{},
+				ZeroMap:   map[string]string{},
+			},
+			want: `{"struct":{"X":0,"Y":0},"zero_slice":[],"zero_map":{}}`,
+		},
+		{
+			v: record{
+				Bool:      true,
+				Int:       1,
+				Ptr:       new(int),
+				Slice:     []int{1},
+				ZeroPoint: point{Y: 1},
+				ZeroTime:  time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+				Both:      "x",
+			},
+			want: `{"bool":true,"int":1,"ptr":0,"slice":[1],"struct":{"X":0,"Y":0},"zero_point":{"X":0,"Y":1},"zero_time":"2020-01-02T03:04:05Z","Both":"x"}`,
+		},
+	}
+	for _, tc := range tests {
+		got, err := Marshal(tc.v)
+		if err != nil {
+			t.Errorf("marshal %+v: %v", tc.v, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("marshal %+v:\nexpected: %s\ngot:      %s", tc.v, tc.want, got)
+		}
+	}
+}
+
+func TestEncoderEscapeHTML(t *testing.T) {
+	tests := []struct {
+		in       string
+		html     string
+		withHTML string
+	}{
+		{in: `</script>`, html: `"\u003c/script\u003e"`, withHTML: `"</script>"`},
+		{in: `a && b`, html: `"a \u0026\u0026 b"`, withHTML: `"a && b"`},
+		{in: "héllo, 世界 🌍", html: `"héllo, 世界 🌍"`, withHTML: `"héllo, 世界 🌍"`},
+		{in: "\x00\x1f\t\"<", html: `"\u0000\u001f\t\"\u003c"`, withHTML: `"\u0000\u001f\t\"<"`},
+		{in: "\u2028\u2029", html: `"\u2028\u2029"`, withHTML: `"\u2028\u2029"`},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		check(t, enc.Encode(tc.in))
+		enc.SetEscapeHTML(false)
+		check(t, enc.Encode(tc.in))
+		if want := tc.html + "\n" + tc.withHTML + "\n"; buf.String() != want {
+			t.Errorf("encode %q: expected: %q, got: %q", tc.in, want, buf.String())
+		}
+
+		// the output must agree with encoding/json.
+		var std bytes.Buffer
+		senc := json.NewEncoder(&std)
+		check(t, senc.Encode(tc.in))
+		senc.SetEscapeHTML(false)
+		check(t, senc.Encode(tc.in))
+		if buf.String() != std.String() {
+			t.Errorf("encode %q: expected: %q, got: %q", tc.in, std.String(), buf.String())
+		}
+	}
+}
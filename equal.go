@@ -0,0 +1,174 @@
+package json
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Equal reports whether a and b hold the same JSON value, ignoring
+// insignificant whitespace and the order of object members. Arrays are
+// equal if their elements are equal in order, strings if their unescaped
+// contents are equal, and numbers if they have the same decimal value, so
+// 1.0 equals 1 and 1e2 equals 100. Both must be valid, as for Validate, and
+// an object holding the same key twice is an error rather than being
+// compared by its last member.
+//
+// The values are compared token by token without being decoded, except that
+// the members of an object in a are indexed by key to look up those of the
+// object in b; the index refers to a rather than copying its values.
+func Equal(a, b []byte) (bool, error) {
+	for _, data := range [][]byte{a, b} {
+		if err := Validate(data, (*Decoder).DisallowDuplicateKeys); err != nil {
+			return false, err
+		}
+	}
+	return equalValues(a, b)
+}
+
+// equalValues reports whether the valid values a and b are equal.
+func equalValues(a, b []byte) (bool, error) {
+	da, db := GetDecoder(a), GetDecoder(b)
+	defer PutDecoder(da)
+	defer PutDecoder(db)
+	return equalNext(da, db)
+}
+
+// equalNext consumes the next value from each of da and db, as far as
+// needed to tell whether they are equal.
+func equalNext(da, db *Decoder) (bool, error) {
+	ta, err := da.NextToken()
+	if err != nil {
+		return false, err
+	}
+	tb, err := db.NextToken()
+	if err != nil {
+		return false, err
+	}
+	if tokenKind(ta) != tokenKind(tb) {
+		return false, nil
+	}
+	switch ta[0] {
+	case ArrayStart:
+		return equalArrays(da, db)
+	case ObjectStart:
+		return equalObjects(da, db)
+	case String:
+		sa, err := da.unquoteBytes(ta)
+		if err != nil {
+			return false, err
+		}
+		sb, err := db.unquoteBytes(tb)
+		return bytes.Equal(sa, sb), err
+	case True, False:
+		return ta[0] == tb[0], nil
+	case Null:
+		return true, nil
+	}
+	return numbersEqual(ta, tb), nil
+}
+
+// equalArrays compares the elements of the arrays just opened in da and db.
+func equalArrays(da, db *Decoder) (bool, error) {
+	for {
+		enda, endb := da.PeekKind() == KindArrayEnd, db.PeekKind() == KindArrayEnd
+		if enda || endb {
+			if !enda || !endb {
+				return false, nil
+			}
+			if _, err := da.NextToken(); err != nil {
+				return false, err
+			}
+			_, err := db.NextToken()
+			return err == nil, err
+		}
+		if eq, err := equalNext(da, db); !eq || err != nil {
+			return false, err
+		}
+	}
+}
+
+// equalObjects compares the members of the objects just opened in da and
+// db, which hold no duplicate keys.
+func equalObjects(da, db *Decoder) (bool, error) {
+	members := make(map[string][]byte)
+	for da.PeekKind() != KindObjectEnd {
+		key, err := da.ReadStringBytes()
+		if err != nil {
+			return false, err
+		}
+		if members[string(key)], err = da.NextAsBytes(); err != nil {
+			return false, err
+		}
+	}
+	if _, err := da.NextToken(); err != nil {
+		return false, err
+	}
+	n := 0
+	for ; db.PeekKind() != KindObjectEnd; n++ {
+		key, err := db.ReadStringBytes()
+		if err != nil {
+			return false, err
+		}
+		va, ok := members[string(key)]
+		if !ok {
+			return false, nil
+		}
+		vb, err := db.NextAsBytes()
+		if err != nil {
+			return false, err
+		}
+		if eq, err := equalValues(va, vb); !eq || err != nil {
+			return false, err
+		}
+	}
+	if _, err := db.NextToken(); err != nil {
+		return false, err
+	}
+	return n == len(members), nil
+}
+
+// numbersEqual reports whether the number tokens a and b have the same
+// decimal value.
+func numbersEqual(a, b []byte) bool {
+	var bufa, bufb [32]byte
+	nega, da, pa, oka := decimal(bufa[:0], a)
+	negb, db, pb, okb := decimal(bufb[:0], b)
+	if !oka || !okb {
+		// an exponent too large to compare exactly.
+		fa, _ := strconv.ParseFloat(bytesToString(a), 64)
+		fb, _ := strconv.ParseFloat(bytesToString(b), 64)
+		return fa == fb
+	}
+	if len(da) == 0 || len(db) == 0 {
+		// zero, whatever its sign and exponent.
+		return len(da) == len(db)
+	}
+	return nega == negb && pa == pb && bytes.Equal(da, db)
+}
+
+// decimal returns the value of the number token tok as 0.digits × 10^point,
+// with digits appended to dst without leading or trailing zeros, so that
+// they are empty for zero. ok is false if the exponent is out of range.
+func decimal(dst, tok []byte) (neg bool, digits []byte, point int, ok bool) {
+	if neg = tok[0] == '-'; neg {
+		tok = tok[1:]
+	}
+	if i := bytes.IndexAny(tok, "eE"); i >= 0 {
+		exp, err := strconv.ParseInt(bytesToString(tok[i+1:]), 10, 32)
+		if err != nil {
+			return neg, nil, 0, false
+		}
+		point = int(exp)
+		tok = tok[:i]
+	}
+	intPart, frac := tok, tok[len(tok):]
+	if i := bytes.IndexByte(tok, '.'); i >= 0 {
+		intPart, frac = tok[:i], tok[i+1:]
+	}
+	digits = append(append(dst, intPart...), frac...)
+	point += len(intPart)
+	n := len(digits)
+	digits = bytes.TrimLeft(digits, "0")
+	point -= n - len(digits)
+	return neg, bytes.TrimRight(digits, "0"), point, true
+}
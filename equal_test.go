@@ -0,0 +1,76 @@
+package json
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{`{"a": 1, "b": [true, null, "x"]}`, "{\n\t\"b\": [ true,null , \"x\" ],\n\t\"a\": 1\n}", true},
+		{`{"a": 1, "b": 2}`, `{"a": 1}`, false},
+		{`{"a": 1}`, `{"a": 1, "b": 2}`, false},
+		{`{"a": 1}`, `{"b": 1}`, false},
+		{`{}`, ` { } `, true},
+		{`{"a": {"b": {"c": [1, 2]}, "d": 3}}`, `{"a": {"d": 3.0, "b": {"c": [1, 2e0]}}}`, true},
+		{`{"a": {"b": {"c": [1, 2]}}}`, `{"a": {"b": {"c": [2, 1]}}}`, false},
+		{`[1, 2, 3]`, `[1, 2]`, false},
+		{`[1, 2]`, `[1, 2, 3]`, false},
+		{`[[], {}]`, `[[], {}]`, true},
+		{`[[]]`, `[{}]`, false},
+		{`"A\n"`, `"A\u000a"`, true},
+		{`"a"`, `"b"`, false},
+		{`true`, `true`, true},
+		{`true`, `false`, false},
+		{`null`, `null`, true},
+		{`null`, `0`, false},
+		{`"1"`, `1`, false},
+		{`1`, `1.0`, true},
+		{`1e2`, `100`, true},
+		{`1E+2`, `100.000`, true},
+		{`0.05`, `5e-2`, true},
+		{`-12.5`, `-1250e-2`, true},
+		{`-12.5`, `12.5`, false},
+		{`0`, `-0.0e10`, true},
+		{`0`, `0.0001`, false},
+		{`10`, `1`, false},
+		{`9007199254740993`, `9007199254740992`, false},
+		{`123456789012345678901234567890`, `1.23456789012345678901234567890e29`, true},
+		{`1e999999999999`, `2e999999999999`, true}, // both +Inf as floats
+	}
+	for _, tc := range tests {
+		for _, swap := range []bool{false, true} {
+			a, b := tc.a, tc.b
+			if swap {
+				a, b = b, a
+			}
+			got, err := Equal([]byte(a), []byte(b))
+			if err != nil || got != tc.want {
+				t.Errorf("Equal(%s, %s): got %v, %v, want %v", a, b, got, err, tc.want)
+			}
+		}
+	}
+
+	for _, tc := range []struct{ a, b, err string }{
+		{`{"a": 1}`, `{"a": 1`, "unexpected end of JSON input"},
+		{`[1, 2`, `[1, 3]`, "unexpected end of JSON input"},
+		{`[1] x`, `[1]`, "after top-level value"},
+		{`[1]`, `[2] [1]`, "after top-level value"},
+		{`{"a": 1, "a": 2}`, `{"a": 2}`, `duplicate key "a"`},
+		{`{"a": {"b": 1}}`, `{"a": {"b": 1, "b": 1}}`, `duplicate key "b"`},
+		{`{"a": 1, "\u0061": 2}`, `{"a": 2}`, `duplicate key "a"`},
+		{`{"a": 2}`, `{"\/": 1, "a": 2, "/": 1}`, `duplicate key "/"`},
+	} {
+		if _, err := Equal([]byte(tc.a), []byte(tc.b)); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Equal(%s, %s): got %v, want error %q", tc.a, tc.b, err, tc.err)
+		}
+	}
+	var serr *SyntaxError
+	if _, err := Equal([]byte(`[1]`), []byte(`[1,]`)); !errors.As(err, &serr) {
+		t.Errorf("Equal: got %v, want *SyntaxError", err)
+	}
+}
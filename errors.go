@@ -0,0 +1,136 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ErrMaxDepthExceeded is returned, wrapped with the offset of the offending
+// bracket, when the input nests arrays and objects deeper than the Decoder's
+// maximum depth.
+var ErrMaxDepthExceeded = errors.New("json: maximum nesting depth exceeded")
+
+// ErrInputTooLarge is returned, wrapped with the limit, when the Decoder
+// needs to read past the maximum input size set by SetMaxInputBytes.
+var ErrInputTooLarge = errors.New("json: input exceeds the maximum size")
+
+// inputTooLargeError returns an error wrapping ErrInputTooLarge for input
+// longer than max bytes.
+func inputTooLargeError(max int) error {
+	return fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, max)
+}
+
+// A LimitError is returned when a string or value in the input exceeds a
+// limit set on the Decoder by SetMaxStringLen or SetMaxValueBytes. For input
+// read from an io.Reader, Len may be only the size read before the limit
+// was found to be exceeded.
+type LimitError struct {
+	What   string // "string" or "value"
+	Len    int    // size of the string contents or value, in bytes
+	Limit  int    // the limit exceeded
+	Offset int64  // byte offset of the start of the string or value
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("json: %s of %d bytes at offset %d exceeds limit of %d bytes", e.What, e.Len, e.Offset, e.Limit)
+}
+
+// depthError returns an error wrapping ErrMaxDepthExceeded for the bracket at
+// offset, which would nest deeper than max levels.
+func depthError(max, offset int) error {
+	return fmt.Errorf("%w: more than %d levels at offset %d", ErrMaxDepthExceeded, max, offset)
+}
+
+// A SyntaxError is a description of a JSON syntax error, including the
+// position of the offending byte in the input.
+type SyntaxError struct {
+	msg    string // description of error
+	Offset int64  // byte offset of the offending byte
+	Line   int    // 1-based line of the offending byte; a CRLF is one line break
+	Column int    // 1-based column, in bytes, of the offending byte
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d (offset %d)", e.msg, e.Line, e.Column, e.Offset)
+}
+
+// newSyntaxError returns a SyntaxError for the byte at offset in data.
+// The line and column are only computed here, on the error path, so
+// scanning does not pay for tracking them.
+func newSyntaxError(data []byte, offset int, msg string) *SyntaxError {
+	prefix := data[:offset]
+	return &SyntaxError{
+		msg:    msg,
+		Offset: int64(offset),
+		Line:   1 + bytes.Count(prefix, []byte{'\n'}),
+		Column: offset - bytes.LastIndexByte(prefix, '\n'),
+	}
+}
+
+// quoteChar formats c as a quoted character literal.
+func quoteChar(c byte) string {
+	// special cases - different from quoted strings
+	if c == '\'' {
+		return `'\''`
+	}
+	if c == '"' {
+		return `'"'`
+	}
+
+	// use quoted string with different quotation marks
+	s := strconv.Quote(string(c))
+	return "'" + s[1:len(s)-1] + "'"
+}
+
+// A KindError is returned by the Decoder's Read methods when the next token
+// is not of the kind the method reads. The token is not consumed.
+type KindError struct {
+	Want   Kind  // kind the method reads
+	Got    Kind  // kind of the next token
+	Offset int64 // byte offset of the next token
+}
+
+func (e *KindError) Error() string {
+	return fmt.Sprintf("json: expected %v, found %v at offset %d", e.Want, e.Got, e.Offset)
+}
+
+// An UnmarshalTypeError is returned by Decode when a JSON value cannot be
+// stored in the Go value it is decoded into, because it is of the wrong kind
+// or, for a number, out of range or not an integer.
+type UnmarshalTypeError struct {
+	Value  string       // the JSON value: "string", "object", "number 1.5", ...
+	Type   reflect.Type // type of the Go value it could not be stored in
+	Offset int64        // byte offset of the value in the input
+	Path   string       // location of the value, as returned by Decoder.Path
+	Struct string       // name of the innermost struct type holding the value
+	Field  string       // dotted keys of the struct fields leading to the value
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Struct != "" || e.Field != "" {
+		return fmt.Sprintf("json: cannot decode %s into Go struct field %s.%s of type %v at offset %d, path %s", e.Value, e.Struct, e.Field, e.Type, e.Offset, e.Path)
+	}
+	return fmt.Sprintf("json: cannot decode %s into Go value of type %v at offset %d, path %s", e.Value, e.Type, e.Offset, e.Path)
+}
+
+// ErrStop may be returned by the callback passed to Decoder.Object or
+// Decoder.Array to stop iterating. The rest of the container is skipped and
+// the iteration method returns nil.
+var ErrStop = errors.New("json: stop iteration")
+
+// ErrNoDiscriminator is returned, wrapped with the offset of the object
+// and the reason, by Decoder.DecodeUnion when an object has no string
+// member to choose its type by.
+var ErrNoDiscriminator = errors.New("json: no union discriminator")
+
+// ErrUnknownKind is returned, wrapped with the discriminator and the error
+// of the factory, by Decoder.DecodeUnion when the factory rejects the kind
+// of an object.
+var ErrUnknownKind = errors.New("json: unknown union kind")
+
+// ErrNotFound is returned, wrapped with the path element that could not be
+// found, by Decoder.Seek.
+var ErrNotFound = errors.New("json: path not found")
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 
 	"github.com/xsandr/json"
 )
@@ -18,10 +19,9 @@ func ExampleScanner_Next() {
 		}
 		fmt.Printf("%s\n", tok)
 	}
-	// Fixme: think about having Error method
-	//if err := sc.Error(); err != nil && err != io.EOF {
-	//	log.Fatal(err)
-	//}
+	if err := sc.Error(); err != nil && err != io.EOF {
+		log.Fatal(err)
+	}
 
 	// Output:
 	// {
@@ -41,6 +41,36 @@ func ExampleScanner_Next() {
 	// }
 }
 
+func ExampleScanner_NextKind() {
+	input := `{"a": 1,"b": 123.456, "c": [null]}`
+	sc := json.NewScanner([]byte(input))
+	for {
+		kind, tok := sc.NextKind()
+		switch kind {
+		case json.KindInvalid:
+			return
+		case json.KindColon, json.KindComma:
+			continue
+		case json.KindString, json.KindNumber:
+			fmt.Printf("%v %s\n", kind, tok)
+		default:
+			fmt.Printf("%v\n", kind)
+		}
+	}
+
+	// Output:
+	// object start
+	// string "a"
+	// number 1
+	// string "b"
+	// number 123.456
+	// string "c"
+	// array start
+	// null
+	// array end
+	// object end
+}
+
 func ExampleDecoder_Token() {
 	input := `{"a": 1,"b": 123.456, "c": [null]}`
 	dec := json.NewDecoder([]byte(input))
@@ -106,3 +136,150 @@ func ExampleDecoder_Decode() {
 
 	// Output: map[a:1 b:123.456 c:[<nil>]]
 }
+
+func ExampleDecoder_Object() {
+	input := `{"name": "Gopher", "age": 13, "address": {"city": "Sydney"}, "tags": ["a", "b"]}`
+	dec := json.NewDecoder([]byte(input))
+	var (
+		name string
+		age  int64
+	)
+	err := dec.Object(func(key []byte) error {
+		var err error
+		switch string(key) {
+		case "name":
+			name, err = dec.ReadString()
+		case "age":
+			age, err = dec.ReadInt64()
+		default:
+			err = dec.Skip()
+		}
+		return err
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(name, age)
+
+	// Output:
+	// Gopher 13
+}
+
+func ExampleDecoder_Array() {
+	input := `[{"id": 1}, {"id": 2}, {"id": 3}]`
+	dec := json.NewDecoder([]byte(input))
+	ids := make(chan int64, 3)
+	err := dec.Array(func(i int) error {
+		return dec.Object(func(key []byte) error {
+			if string(key) != "id" {
+				return dec.Skip()
+			}
+			id, err := dec.ReadInt64()
+			ids <- id
+			return err
+		})
+	})
+	close(ids)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for id := range ids {
+		fmt.Println(id)
+	}
+
+	// Output:
+	// 1
+	// 2
+	// 3
+}
+
+func ExampleDecoder_Seek() {
+	input := `{"status": "ok", "response": {"data": {"items": [{"id": 1}, {"id": 2}]}}}`
+	dec := json.NewDecoder([]byte(input))
+	if err := dec.Seek("response", "data", "items", "1"); err != nil {
+		log.Fatal(err)
+	}
+	var item map[string]interface{}
+	if err := dec.Decode(&item); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(item)
+
+	// Output: map[id:2]
+}
+
+func ExampleParse() {
+	v := json.Parse([]byte(`{"user": {"name": "Gopher", "langs": ["go", "c"]}, "stars": 42}`))
+	fmt.Println(v.Get("user", "name").Str())
+	fmt.Println(v.Get("stars").Int())
+	for _, lang := range v.Get("user", "langs").Array() {
+		fmt.Println(lang.Str())
+	}
+	fmt.Println(v.Get("user", "age").Exists())
+	if err := v.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// Gopher
+	// 42
+	// go
+	// c
+	// false
+}
+
+func ExampleDecoder_Token_typeSwitch() {
+	input := `{"name": "Gopher", "tags": ["a", "b"], "age": 13, "admin": false, "boss": null}`
+	dec := json.NewDecoder([]byte(input))
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			fmt.Printf("delim %v\n", tok)
+		case string:
+			fmt.Printf("string %q\n", tok)
+		case json.Number:
+			fmt.Printf("number %v\n", tok)
+		case bool:
+			fmt.Printf("bool %v\n", tok)
+		case nil:
+			fmt.Println("null")
+		}
+	}
+
+	// Output:
+	// delim {
+	// string "name"
+	// string "Gopher"
+	// string "tags"
+	// delim [
+	// string "a"
+	// string "b"
+	// delim ]
+	// string "age"
+	// number 13
+	// string "admin"
+	// bool false
+	// string "boss"
+	// null
+	// delim }
+}
+
+func ExampleFilter() {
+	body := `{"user": "gopher", "password": "hunter2", "profile": {"ssn": "078-05-1120", "city": "Sydney"}}`
+	redact := func(path []string, key []byte) bool {
+		return string(key) == "password" || string(key) == "ssn"
+	}
+	if err := json.Filter(os.Stdout, []byte(body), redact); err != nil {
+		log.Fatal(err)
+	}
+
+	// Output: {"user":"gopher","profile":{"city":"Sydney"}}
+}
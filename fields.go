@@ -0,0 +1,287 @@
+package json
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// field describes a struct field that maps to an object member.
+type field struct {
+	name      string       // object key, from the json tag or the Go field name
+	index     []int        // index path of the field, through any embedded structs
+	typ       reflect.Type // type of the field
+	tagged    bool         // the name comes from the json tag
+	omitEmpty bool         // the omitempty tag option is set
+	omitZero  bool         // the omitzero tag option is set
+}
+
+// fields is the plan for encoding and decoding a struct type: its fields in
+// declaration order, and an index of them by name for decoding.
+type fields struct {
+	list   []field
+	byName map[string]int // index into list by field name
+}
+
+var fieldCache sync.Map // map[reflect.Type]*fields
+
+// structFields returns the encodable and decodable fields of the struct
+// type t. Unexported fields and fields tagged `json:"-"` are ignored. The
+// fields of embedded structs are promoted, following the rules of
+// encoding/json. The result is computed once per type and shared by all
+// callers, so it must not be modified.
+func structFields(t reflect.Type) *fields {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.(*fields)
+	}
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fs.(*fields)
+}
+
+// typeFields builds the fields of the struct type t. Embedded structs are
+// walked breadth first, so that of several fields with the same name the
+// shallowest is found first, as in encoding/json.
+func typeFields(t reflect.Type) *fields {
+	var list []field
+
+	// embedded structs to explore at the current and the next depth, with
+	// the number of times each type occurs at that depth.
+	current := []field{}
+	next := []field{{typ: t}}
+	var count, nextCount map[reflect.Type]int
+	visited := map[reflect.Type]bool{}
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, f := range current {
+			if visited[f.typ] {
+				continue
+			}
+			visited[f.typ] = true
+
+			for i := 0; i < f.typ.NumField(); i++ {
+				sf := f.typ.Field(i)
+				if sf.Anonymous {
+					et := sf.Type
+					if et.Kind() == reflect.Ptr {
+						et = et.Elem()
+					}
+					// the exported fields of an unexported embedded
+					// struct are still promoted.
+					if !sf.IsExported() && et.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := make([]int, len(f.index)+1)
+				copy(index, f.index)
+				index[len(f.index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					nf := field{name: name, index: index, typ: sf.Type, tagged: name != ""}
+					if nf.name == "" {
+						nf.name = sf.Name
+					}
+					for opts != "" {
+						var opt string
+						opt, opts, _ = strings.Cut(opts, ",")
+						switch opt {
+						case "omitempty":
+							nf.omitEmpty = true
+						case "omitzero":
+							nf.omitZero = true
+						}
+					}
+					list = append(list, nf)
+					if count[f.typ] > 1 {
+						// the same type embedded twice at one depth: its
+						// fields annihilate each other. Add a second copy
+						// so the dominance check below drops them.
+						list = append(list, nf)
+					}
+					continue
+				}
+
+				// an untagged embedded struct: explore it at the next depth.
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, field{name: ft.Name(), index: index, typ: ft})
+				}
+			}
+		}
+	}
+
+	// Sort by name, breaking ties by depth, then by the presence of a tag,
+	// then by index sequence, and keep only the dominant field of each name.
+	slices.SortFunc(list, func(a, b field) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(len(a.index), len(b.index)); c != 0 {
+			return c
+		}
+		if a.tagged != b.tagged {
+			if a.tagged {
+				return -1
+			}
+			return 1
+		}
+		return slices.Compare(a.index, b.index)
+	})
+	out := list[:0]
+	for advance, i := 0, 0; i < len(list); i += advance {
+		name := list[i].name
+		for advance = 1; i+advance < len(list); advance++ {
+			if list[i+advance].name != name {
+				break
+			}
+		}
+		if advance == 1 {
+			out = append(out, list[i])
+			continue
+		}
+		if dominant, ok := dominantField(list[i : i+advance]); ok {
+			out = append(out, dominant)
+		}
+	}
+
+	// back to declaration order.
+	list = out
+	slices.SortFunc(list, func(a, b field) int {
+		return slices.Compare(a.index, b.index)
+	})
+
+	fs := &fields{list: list, byName: make(map[string]int, len(list))}
+	for i := range list {
+		fs.byName[list[i].name] = i
+	}
+	return fs
+}
+
+// dominantField returns the field that wins among fields of the same name,
+// which are sorted by depth and then by tag. The shallowest field wins, if
+// it is alone at its depth or the only tagged one there; otherwise the name
+// is ambiguous and none of the fields is used.
+func dominantField(fs []field) (field, bool) {
+	if len(fs) > 1 && len(fs[0].index) == len(fs[1].index) && fs[0].tagged == fs[1].tagged {
+		return field{}, false
+	}
+	return fs[0], true
+}
+
+// field returns the field named key, or nil. key is the raw bytes of the
+// object key; looking it up does not allocate.
+func (fs *fields) field(key []byte) *field {
+	if i, ok := fs.byName[string(key)]; ok {
+		return &fs.list[i]
+	}
+	return nil
+}
+
+// foldField returns the first field whose name matches key under Unicode
+// case-folding, or nil. Like field, it does not allocate.
+func (fs *fields) foldField(key []byte) *field {
+	k := bytesToString(key)
+	for i := range fs.list {
+		if strings.EqualFold(fs.list[i].name, k) {
+			return &fs.list[i]
+		}
+	}
+	return nil
+}
+
+// value returns the field f of the struct v, following f's index path
+// through embedded structs. If an embedded pointer on the way is nil, value
+// returns false.
+func (f *field) value(v reflect.Value) (reflect.Value, bool) {
+	for i, x := range f.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// settableValue is like value, but allocates nil embedded pointers on the
+// way. Pointers to unexported struct types cannot be allocated, and are
+// reported as an error.
+func (f *field) settableValue(v reflect.Value) (reflect.Value, error) {
+	for i, x := range f.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("json: cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// omit reports whether the value v of field f should be left out of the
+// encoded object.
+func (f *field) omit(v reflect.Value) bool {
+	return f.omitEmpty && isEmptyValue(v) || f.omitZero && isZeroValue(v)
+}
+
+// isEmptyValue reports whether v is empty in the sense of the omitempty
+// option: false, 0, a nil pointer or interface, or an empty array, map,
+// slice or string.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// isZeroValue reports whether v is zero in the sense of the omitzero option:
+// its IsZero method, if it has one, reports true, or it is the zero value of
+// its type.
+func isZeroValue(v reflect.Value) bool {
+	if v.Type().Implements(isZeroerType) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return true
+		}
+		return v.Interface().(isZeroer).IsZero()
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(isZeroerType) {
+		return v.Addr().Interface().(isZeroer).IsZero()
+	}
+	return v.IsZero()
+}
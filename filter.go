@@ -0,0 +1,99 @@
+package json
+
+import (
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Filter copies the JSON document src to dst, leaving out the object members
+// for which drop returns true. drop is called with the key of each member,
+// unescaped, and the path to the object holding it, as a list of the keys and
+// decimal array indexes leading to it from the top-level value; neither may
+// be retained after drop returns. The members of a dropped member's value are
+// not visited, though the value is still checked to be valid.
+//
+// The output is written compactly through an Encoder's streaming writer API,
+// so it is followed by a newline, and keys are written with their escape
+// sequences normalized. If src is not valid JSON, Filter returns an error and
+// part of the output may already have been written to dst.
+func Filter(dst io.Writer, src []byte, drop func(path []string, key []byte) bool) error {
+	d := GetDecoder(src)
+	defer PutDecoder(d)
+	enc := NewEncoder(dst)
+	enc.SetEscapeHTML(false)
+	f := filter{d: d, enc: enc, drop: drop}
+	if err := f.value(); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return newSyntaxError(src, len(src), "unexpected end of JSON input")
+		}
+		return err
+	}
+	if d.scanner.peek(); d.scanner.offset < len(src) {
+		return d.scanner.trailingError()
+	}
+	return nil
+}
+
+// filter holds the state of a call to Filter.
+type filter struct {
+	d    *Decoder
+	enc  *Encoder
+	drop func(path []string, key []byte) bool
+	path []string
+}
+
+// value copies the next value from f.d to f.enc, filtering any objects
+// within it.
+func (f *filter) value() error {
+	switch f.d.PeekKind() {
+	case KindObjectStart:
+		if err := f.enc.WriteObjectStart(); err != nil {
+			return err
+		}
+		err := f.d.Object(func(key []byte) error {
+			if f.drop(f.path, key) {
+				// unlike Skip, CopyValue checks the whole of the value.
+				_, err := f.d.CopyValue(io.Discard)
+				return err
+			}
+			k := string(key)
+			if err := f.enc.WriteKey(k); err != nil {
+				return err
+			}
+			return f.member(k)
+		})
+		if err != nil {
+			return err
+		}
+		return f.enc.WriteObjectEnd()
+	case KindArrayStart:
+		if err := f.enc.WriteArrayStart(); err != nil {
+			return err
+		}
+		err := f.d.Array(func(i int) error {
+			return f.member(strconv.Itoa(i))
+		})
+		if err != nil {
+			return err
+		}
+		return f.enc.WriteArrayEnd()
+	}
+	tok, err := f.d.NextToken()
+	if err != nil {
+		return err
+	}
+	if tok[0] == ArrayEnd || tok[0] == ObjectEnd {
+		return f.d.syntaxError(tok, "looking for beginning of value")
+	}
+	return f.enc.writeRaw("WriteRaw", tok)
+}
+
+// member copies the value of the member or element elem of the current
+// container.
+func (f *filter) member(elem string) error {
+	f.path = append(f.path, elem)
+	err := f.value()
+	f.path = f.path[:len(f.path)-1]
+	return err
+}
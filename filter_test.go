@@ -0,0 +1,108 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	dropKeys := func(keys ...string) func([]string, []byte) bool {
+		return func(path []string, key []byte) bool {
+			for _, k := range keys {
+				if string(key) == k {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	tests := []struct {
+		input string
+		drop  func([]string, []byte) bool
+		want  string
+	}{
+		{`{"a": 1, "b": 2, "c": 3}`, dropKeys("a"), `{"b":2,"c":3}`},
+		{`{"a": 1, "b": 2, "c": 3}`, dropKeys("b"), `{"a":1,"c":3}`},
+		{`{"a": 1, "b": 2, "c": 3}`, dropKeys("c"), `{"a":1,"b":2}`},
+		{`{"a": 1, "b": 2, "c": 3}`, dropKeys("a", "c"), `{"b":2}`},
+		{`{"a": 1, "b": 2, "c": 3}`, dropKeys("a", "b", "c"), `{}`},
+		{`{"a": 1, "b": 2, "a": 3}`, dropKeys("a"), `{"b":2}`},
+		{`{}`, dropKeys("a"), `{}`},
+		{`[]`, dropKeys("a"), `[]`},
+		{` "str" `, dropKeys("a"), `"str"`},
+		{`{"user": {"name": "x", "password": "secret", "tags": ["a", "b"]}, "password": {"nested": [1, {"password": 2}]}}`,
+			dropKeys("password"), `{"user":{"name":"x","tags":["a","b"]}}`},
+		{`[{"ssn": 1, "id": 1}, {"id": 2, "ssn": 2}, {"ssn": 3}]`, dropKeys("ssn"), `[{"id":1},{"id":2},{}]`},
+		{`{"password": "secret", "k": "v\"<é>"}`, dropKeys("password"), `{"k":"v\"<é>"}`},
+		{`{"aé<": [1.5e3, true, null, false]}`, dropKeys(), `{"aé<":[1.5e3,true,null,false]}`},
+		// the key is only dropped at the given path.
+		{`{"a": {"id": 1, "x": 2}, "b": {"id": 3}, "id": 4}`, func(path []string, key []byte) bool {
+			return len(path) == 1 && path[0] == "a" && string(key) == "id"
+		}, `{"a":{"x":2},"b":{"id":3},"id":4}`},
+		{`{"a": [{"id": 1}, {"id": 2}]}`, func(path []string, key []byte) bool {
+			return len(path) == 2 && path[1] == "1"
+		}, `{"a":[{"id":1},{}]}`},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		if err := Filter(&buf, []byte(tc.input), tc.drop); err != nil {
+			t.Errorf("Filter(%s): %v", tc.input, err)
+			continue
+		}
+		if got := buf.String(); got != tc.want+"\n" {
+			t.Errorf("Filter(%s): got %q, want %q", tc.input, got, tc.want+"\n")
+		}
+	}
+}
+
+func TestFilterPath(t *testing.T) {
+	input := `{"a": [{"b": 1}, [{"c": {"d": 2}}]], "e": {"f": 3}}`
+	var got []string
+	err := Filter(io.Discard, []byte(input), func(path []string, key []byte) bool {
+		got = append(got, strings.Join(append(path, string(key)), "."))
+		return string(key) == "e"
+	})
+	check(t, err)
+	want := []string{"a", "a.0.b", "a.1.0.c", "a.1.0.c.d", "e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter: visited %q, want %q", got, want)
+	}
+}
+
+func TestFilterFixtures(t *testing.T) {
+	for _, tc := range inputs {
+		t.Run(tc.path, func(t *testing.T) {
+			data, err := io.ReadAll(fixture(t, tc.path))
+			check(t, err)
+			var buf bytes.Buffer
+			check(t, Filter(&buf, data, func([]string, []byte) bool { return false }))
+			var want, got interface{}
+			check(t, json.Unmarshal(data, &want))
+			check(t, json.Unmarshal(buf.Bytes(), &got))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Filter: output differs from the input")
+			}
+		})
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	for _, input := range []string{``, ` `, `{`, `{"a": 1,}`, `[1, 2`, `[1 2]`, `{"a" 1}`, `{1: 2}`, `]`, `[}`, `{"a": 1} x`, `1 2`, `{"a": tru}`, `"\x"`} {
+		err := Filter(io.Discard, []byte(input), func([]string, []byte) bool { return false })
+		var serr *SyntaxError
+		var kerr *KindError
+		if !errors.As(err, &serr) && !errors.As(err, &kerr) {
+			t.Errorf("Filter(%s): got %v, want a syntax error", input, err)
+		}
+	}
+	// invalid input within a dropped member is still reported.
+	err := Filter(io.Discard, []byte(`{"a": [1 2], "b": 1}`), func([]string, []byte) bool { return true })
+	if err == nil {
+		t.Errorf("Filter: expected an error for invalid dropped member")
+	}
+}
@@ -0,0 +1,158 @@
+package json
+
+import "strconv"
+
+// Get returns the raw bytes of the value found by following path from the
+// top-level value of data, as described for Decoder.Seek. The result refers
+// to data. Only as much of data as precedes the value is read, and only as
+// much of it is validated as is needed to skip over it.
+//
+// If the path does not exist Get returns an error wrapping ErrNotFound; if
+// data is not valid JSON up to the value it returns a *SyntaxError.
+func Get(data []byte, path ...string) ([]byte, error) {
+	d := GetDecoder(data)
+	defer PutDecoder(d)
+	if err := d.Seek(path...); err != nil {
+		return nil, err
+	}
+	return d.NextAsBytes()
+}
+
+// GetMany returns the raw bytes of the values found by following each of
+// paths from the top-level value of data, as Get does, but reads data only
+// once, however many paths there are. The results are in the order of paths;
+// the result for a path that does not exist is nil. Reading stops once every
+// path has been found.
+//
+// If a key appears more than once in an object, its first value is used.
+func GetMany(data []byte, paths ...[]string) ([][]byte, error) {
+	x := extractor{
+		d:       GetDecoder(data),
+		paths:   paths,
+		results: make([][]byte, len(paths)),
+		left:    len(paths),
+	}
+	defer PutDecoder(x.d)
+	active := make([]int, len(paths))
+	for i := range active {
+		active[i] = i
+	}
+	if err := x.extract(active, 0); err != nil {
+		return nil, err
+	}
+	return x.results, nil
+}
+
+// extractor walks a document once for GetMany.
+type extractor struct {
+	d       *Decoder
+	paths   [][]string
+	results [][]byte
+	left    int // number of paths not yet found
+	index   []byte
+}
+
+// extract consumes the next value, which is at depth along each of the
+// paths indexed by active, and records it as the result of those of them
+// that end there.
+func (x *extractor) extract(active []int, depth int) error {
+	d := x.d
+	start := d.peekOffset()
+	var err error
+	switch kind := d.PeekKind(); {
+	case !x.deeper(active, depth):
+		err = d.Skip()
+	case kind == KindObjectStart:
+		err = x.extractObject(active, depth)
+	case kind == KindArrayStart:
+		err = x.extractArray(active, depth)
+	default:
+		err = d.Skip()
+	}
+	if err != nil || x.left == 0 {
+		return err
+	}
+	for _, i := range active {
+		if len(x.paths[i]) == depth && x.results[i] == nil {
+			x.results[i] = d.scanner.data[start:d.scanner.offset]
+			x.left--
+		}
+	}
+	return nil
+}
+
+// deeper reports whether any of the active paths continues past depth.
+func (x *extractor) deeper(active []int, depth int) bool {
+	for _, i := range active {
+		if len(x.paths[i]) > depth && x.results[i] == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the active paths which continue past depth with elem.
+func (x *extractor) match(active []int, depth int, elem []byte) []int {
+	var sub []int
+	for _, i := range active {
+		if p := x.paths[i]; len(p) > depth && p[depth] == string(elem) && x.results[i] == nil {
+			sub = append(sub, i)
+		}
+	}
+	return sub
+}
+
+func (x *extractor) extractObject(active []int, depth int) error {
+	d := x.d
+	if _, err := d.NextToken(); err != nil {
+		return err
+	}
+	for d.PeekKind() != KindObjectEnd {
+		key, err := d.ReadStringBytes()
+		if err != nil {
+			return err
+		}
+		if sub := x.match(active, depth, key); sub != nil {
+			err = x.extract(sub, depth+1)
+		} else {
+			err = d.Skip()
+		}
+		if err != nil || x.left == 0 {
+			return err
+		}
+	}
+	_, err := d.NextToken()
+	return err
+}
+
+func (x *extractor) extractArray(active []int, depth int) error {
+	d := x.d
+	if _, err := d.NextToken(); err != nil {
+		return err
+	}
+	for i := 0; d.PeekKind() != KindArrayEnd; i++ {
+		x.index = strconv.AppendInt(x.index[:0], int64(i), 10)
+		var err error
+		if sub := x.match(active, depth, x.index); sub != nil {
+			err = x.extract(sub, depth+1)
+		} else {
+			err = d.Skip()
+		}
+		if err != nil || x.left == 0 {
+			return err
+		}
+	}
+	_, err := d.NextToken()
+	return err
+}
+
+// GetPointer is like Get, but the path is given as a JSON pointer, as
+// described for Decoder.SeekPointer.
+func GetPointer(data []byte, pointer string) ([]byte, error) {
+	d := GetDecoder(data)
+	defer PutDecoder(d)
+	if err := d.SeekPointer(pointer); err != nil {
+		return nil, err
+	}
+	return d.NextAsBytes()
+}
@@ -0,0 +1,107 @@
+package json
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	data := []byte(`{"a": {"b": [10, {"c": "x"}, [true]]}, "n": null, "s": "str"}`)
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{nil, string(data)},
+		{[]string{"a"}, `{"b": [10, {"c": "x"}, [true]]}`},
+		{[]string{"a", "b", "0"}, `10`},
+		{[]string{"a", "b", "1"}, `{"c": "x"}`},
+		{[]string{"a", "b", "1", "c"}, `"x"`},
+		{[]string{"a", "b", "2", "0"}, `true`},
+		{[]string{"n"}, `null`},
+		{[]string{"s"}, `"str"`},
+	}
+	for _, tc := range tests {
+		got, err := Get(data, tc.path...)
+		if err != nil {
+			t.Errorf("Get(%q): %v", tc.path, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("Get(%q): got %s, want %s", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	data := []byte(`{"a": {"b": [10]}}`)
+	for _, path := range [][]string{{"x"}, {"a", "c"}, {"a", "b", "1"}, {"a", "b", "0", "c"}} {
+		if _, err := Get(data, path...); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q): got %v, want ErrNotFound", path, err)
+		}
+	}
+
+	var serr *SyntaxError
+	for _, input := range []string{`{"a" 1}`, `{"x": tru, "a": 1}`, `{"x": [}, "a": 1}`} {
+		_, err := Get([]byte(input), "a")
+		if errors.Is(err, ErrNotFound) || !errors.As(err, &serr) {
+			t.Errorf("Get(%s): got %v, want *SyntaxError", input, err)
+		}
+	}
+}
+
+func TestGetAllocs(t *testing.T) {
+	data, err := io.ReadAll(fixture(t, "twitter"))
+	check(t, err)
+	Get(data, "statuses", "3", "user", "screen_name") // warm the pool
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := Get(data, "statuses", "3", "user", "screen_name"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Get: got %v allocs, want 0", allocs)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	data := []byte(`{"a": {"b": [10, {"c": "x"}, [true]]}, "n": null, "a": "dup", "s": "str"}`)
+	paths := [][]string{
+		{"s"},
+		{"a", "b", "1", "c"},
+		{"missing"},
+		{"a"},
+		{"a", "b", "0"},
+		{"s", "x"},
+		{"a", "b", "1", "c"},
+		nil,
+	}
+	got, err := GetMany(data, paths...)
+	check(t, err)
+	want := []string{`"str"`, `"x"`, "", `{"b": [10, {"c": "x"}, [true]]}`, `10`, "", `"x"`, string(data)}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if string(got[i]) != want[i] || (got[i] == nil) != (want[i] == "") {
+			t.Errorf("GetMany %q: got %q, want %q", paths[i], got[i], want[i])
+		}
+		if got[i] == nil {
+			continue
+		}
+		// the results agree with Get.
+		if one, err := Get(data, paths[i]...); err != nil || string(one) != string(got[i]) {
+			t.Errorf("Get(%q): got %s, %v, want %s", paths[i], one, err, got[i])
+		}
+	}
+
+	// reading stops once all the paths are found, before the syntax error.
+	got, err = GetMany([]byte(`{"a": 1, "b": [2], "c": }`), []string{"b", "0"}, []string{"a"})
+	check(t, err)
+	if string(got[0]) != "2" || string(got[1]) != "1" {
+		t.Errorf("GetMany: got %q, want [2 1]", got)
+	}
+	if _, err := GetMany([]byte(`{"a": 1, "b": [2], "c": }`), []string{"c"}); err == nil {
+		t.Errorf("GetMany on invalid input: expected error")
+	}
+}
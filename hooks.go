@@ -0,0 +1,237 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// A DecodeFunc decodes the next value from dec into v, which is settable and
+// of the type the function is registered for. dec is positioned at the value,
+// and the function may read it with any of the Decoder's methods, such as
+// ReadString, NextAsBytes or Decode, but it must consume exactly that value.
+type DecodeFunc func(dec *Decoder, v reflect.Value) error
+
+var (
+	decodeFuncs    sync.Map // map[reflect.Type]DecodeFunc
+	numDecodeFuncs atomic.Int32
+)
+
+// RegisterDecodeFunc registers fn to decode values of type t, for every
+// Decoder, in place of the built-in decoding, so that types which cannot
+// implement an interface, such as those of other packages, can be decoded
+// without a wrapper type. A nil fn removes the function registered for t.
+// RegisterDecodeFunc may be called concurrently with itself and with
+// decoding, which sees each registration from its next value on.
+//
+// Decoder.RegisterDecodeFunc overrides the functions registered here for
+// one Decoder.
+func RegisterDecodeFunc(t reflect.Type, fn DecodeFunc) {
+	if fn == nil {
+		if _, ok := decodeFuncs.LoadAndDelete(t); ok {
+			numDecodeFuncs.Add(-1)
+		}
+		return
+	}
+	if _, ok := decodeFuncs.Swap(t, fn); !ok {
+		numDecodeFuncs.Add(1)
+	}
+}
+
+// RegisterDecodeFunc registers fn to decode values of type t for d, taking
+// precedence over a function registered for t with the package-level
+// RegisterDecodeFunc. A nil fn makes d decode values of type t with the
+// built-in decoding even if a package-level function is registered for it.
+//
+// A value is passed to the function for the first type along its chain of
+// pointers which has one, so a function registered for T also decodes the
+// value of a *T that is not null, after allocating the T if the pointer is
+// nil. If the function returns without having consumed exactly one value,
+// Decode returns an error.
+func (d *Decoder) RegisterDecodeFunc(t reflect.Type, fn DecodeFunc) {
+	if d.decodeFuncs == nil {
+		d.decodeFuncs = make(map[reflect.Type]DecodeFunc)
+	}
+	d.decodeFuncs[t] = fn
+}
+
+// hasDecodeFuncs reports whether a DecodeFunc may be registered for d.
+func (d *Decoder) hasDecodeFuncs() bool {
+	return d.decodeFuncs != nil || numDecodeFuncs.Load() > 0
+}
+
+// decodeFunc returns the DecodeFunc registered for t, or nil.
+func (d *Decoder) decodeFunc(t reflect.Type) DecodeFunc {
+	if fn, ok := d.decodeFuncs[t]; ok {
+		return fn
+	}
+	if fn, ok := decodeFuncs.Load(t); ok {
+		return fn.(DecodeFunc)
+	}
+	return nil
+}
+
+// callDecodeFunc decodes the next value into v with the DecodeFunc
+// registered for the type of v, or for the type a chain of pointers in v
+// leads to, and reports whether there was one. A null value for a pointer is
+// left to the built-in decoding, which sets it to nil.
+func (d *Decoder) callDecodeFunc(v reflect.Value) (bool, error) {
+	var fn DecodeFunc
+	ptrs := 0
+	for t := v.Type(); ; t = t.Elem() {
+		if fn = d.decodeFunc(t); fn != nil {
+			break
+		}
+		if t.Kind() != reflect.Ptr {
+			return false, nil
+		}
+		ptrs++
+	}
+	if ptrs > 0 && d.PeekKind() == KindNull {
+		return false, nil
+	}
+	for ; ptrs > 0; ptrs-- {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	depth, start, want := d.len(), d.peekOffset(), d.position()
+	if depth == 0 || !d.top().obj {
+		// consuming the value begins the next top-level value or element.
+		want++
+	}
+	// a top-level value is checked for trailing data by the Decode that
+	// reached fn, not by a Decode that fn calls for the value itself.
+	disallow := d.disallowTrailingData
+	d.disallowTrailingData = false
+	err := fn(d, v)
+	d.disallowTrailingData = disallow
+	if err != nil {
+		return true, err
+	}
+	if d.len() != depth || d.getOffset() <= start || d.position() != want {
+		return true, fmt.Errorf("json: decode func for %v did not consume exactly the value at offset %d", v.Type(), start)
+	}
+	return true, nil
+}
+
+// position returns the index of the current element of the innermost
+// array, the offset of the current key of the innermost object, or at the
+// top level the number of values begun, so that a DecodeFunc can be checked
+// to have consumed exactly one value without rescanning the input, which
+// Skip may have discarded.
+func (d *Decoder) position() int {
+	if d.len() == 0 {
+		return d.values
+	}
+	f := d.top()
+	if f.obj {
+		return f.key
+	}
+	return f.index
+}
+
+// An EncodeFunc encodes v, which is of the type the function is registered
+// for, by writing exactly one value to enc with the streaming writer API,
+// such as WriteString, WriteObjectStart or WriteValue, which check that the
+// output is valid JSON.
+type EncodeFunc func(enc *Encoder, v reflect.Value) error
+
+var (
+	encodeFuncs    sync.Map // map[reflect.Type]EncodeFunc
+	numEncodeFuncs atomic.Int32
+)
+
+// RegisterEncodeFunc registers fn to encode values of type t, for Marshal
+// and every Encoder, in place of the built-in encoding and taking precedence
+// over a MarshalJSON or MarshalText method of t. A nil fn removes the
+// function registered for t. RegisterEncodeFunc may be called concurrently
+// with itself and with encoding, which sees each registration from its next
+// value on.
+//
+// Encoder.RegisterEncodeFunc overrides the functions registered here for
+// one Encoder.
+func RegisterEncodeFunc(t reflect.Type, fn EncodeFunc) {
+	if fn == nil {
+		if _, ok := encodeFuncs.LoadAndDelete(t); ok {
+			numEncodeFuncs.Add(-1)
+		}
+		return
+	}
+	if _, ok := encodeFuncs.Swap(t, fn); !ok {
+		numEncodeFuncs.Add(1)
+	}
+}
+
+// RegisterEncodeFunc registers fn to encode values of type t for e, taking
+// precedence over a function registered for t with the package-level
+// RegisterEncodeFunc. A nil fn makes e encode values of type t with the
+// built-in encoding even if a package-level function is registered for it.
+//
+// The function is passed an Encoder of its own, which inherits e's
+// functions and SetEscapeHTML setting; its output is indented with the rest
+// of the value if e's is. A function registered for T also encodes the T a
+// *T points to, and is not called for a nil *T, which is null. If the
+// function returns without having written exactly one value, the encoding
+// fails with an error.
+func (e *Encoder) RegisterEncodeFunc(t reflect.Type, fn EncodeFunc) {
+	if e.encodeFuncs == nil {
+		e.encodeFuncs = make(map[reflect.Type]EncodeFunc)
+	}
+	e.encodeFuncs[t] = fn
+}
+
+// hasEncodeFuncs reports whether an EncodeFunc may be registered for e.
+func (e *encodeState) hasEncodeFuncs() bool {
+	return e.encodeFuncs != nil || numEncodeFuncs.Load() > 0
+}
+
+// encodeFunc returns the EncodeFunc registered for t, or nil.
+func (e *encodeState) encodeFunc(t reflect.Type) EncodeFunc {
+	if fn, ok := e.encodeFuncs[t]; ok {
+		return fn
+	}
+	if fn, ok := encodeFuncs.Load(t); ok {
+		return fn.(EncodeFunc)
+	}
+	return nil
+}
+
+// callEncodeFunc appends the value written by the EncodeFunc registered for
+// the type of v, or for the type a chain of pointers in v leads to, to b,
+// and reports whether there was one. A nil pointer on the way is null.
+func (e *encodeState) callEncodeFunc(b []byte, v reflect.Value) ([]byte, bool, error) {
+	var fn EncodeFunc
+	ptrs := 0
+	for t := v.Type(); ; t = t.Elem() {
+		if fn = e.encodeFunc(t); fn != nil {
+			break
+		}
+		if t.Kind() != reflect.Ptr {
+			return b, false, nil
+		}
+		ptrs++
+	}
+	for ; ptrs > 0; ptrs-- {
+		if v.IsNil() {
+			return append(b, "null"...), true, nil
+		}
+		v = v.Elem()
+	}
+	enc := e.funcEnc
+	if enc == nil {
+		enc = &Encoder{collect: true}
+		e.funcEnc = enc
+	}
+	enc.escapeHTML, enc.encodeFuncs = e.escapeHTML, e.encodeFuncs
+	enc.frames, enc.wbuf, enc.collected = enc.frames[:0], enc.wbuf[:0], false
+	if err := fn(enc, v); err != nil {
+		return b, true, err
+	}
+	if !enc.collected {
+		return b, true, fmt.Errorf("json: encode func for %v did not write a complete value", v.Type())
+	}
+	return append(b, enc.wbuf...), true, nil
+}
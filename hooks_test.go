@@ -0,0 +1,353 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixedPoint stands in for a type of another package, which cannot be given an
+// UnmarshalJSON method.
+type fixedPoint struct {
+	units int64
+	scale int
+}
+
+func (x fixedPoint) String() string { return fmt.Sprintf("%de-%d", x.units, x.scale) }
+
+// decodeFixedPoint decodes a number, or a string holding one, as a fixedPoint.
+func decodeFixedPoint(dec *Decoder, v reflect.Value) error {
+	var s string
+	switch dec.PeekKind() {
+	case KindString:
+		var err error
+		if s, err = dec.ReadString(); err != nil {
+			return err
+		}
+	default:
+		tok, err := dec.NextToken()
+		if err != nil {
+			return err
+		}
+		s = string(tok)
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return fmt.Errorf("fixed point %q: %w", s, err)
+	}
+	v.Set(reflect.ValueOf(fixedPoint{units: units, scale: len(frac)}))
+	return nil
+}
+
+var fixedPointType = reflect.TypeOf(fixedPoint{})
+
+func TestDecodeFunc(t *testing.T) {
+	type order struct {
+		Price  fixedPoint            `json:"price"`
+		Ptr    *fixedPoint           `json:"ptr"`
+		Null   *fixedPoint           `json:"null"`
+		Items  []fixedPoint          `json:"items"`
+		ByName map[string]fixedPoint `json:"by_name"`
+		Rest   interface{}           `json:"rest"`
+	}
+	in := `{"price": 12.50, "ptr": "0.3", "null": null, "items": [1, "2.25"], "by_name": {"a": 7}, "rest": {"b": 1}}`
+	d := NewDecoder([]byte(in))
+	d.RegisterDecodeFunc(fixedPointType, decodeFixedPoint)
+	var got order
+	check(t, d.Decode(&got))
+	want := order{
+		Price:  fixedPoint{1250, 2},
+		Ptr:    &fixedPoint{3, 1},
+		Items:  []fixedPoint{{1, 0}, {225, 2}},
+		ByName: map[string]fixedPoint{"a": {7, 0}},
+		Rest:   map[string]interface{}{"b": 1.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// a DecodeFunc for a type with a fast path takes precedence over it.
+	d = NewDecoder([]byte(`["one", "two"]`))
+	d.RegisterDecodeFunc(reflect.TypeOf(""), func(dec *Decoder, v reflect.Value) error {
+		s, err := dec.ReadString()
+		v.SetString(strings.ToUpper(s))
+		return err
+	})
+	var strs []string
+	check(t, d.Decode(&strs))
+	if fmt.Sprint(strs) != "[ONE TWO]" {
+		t.Errorf("got %v, want [ONE TWO]", strs)
+	}
+
+	// a DecodeFunc may decode the value with Decode, for the types it holds.
+	type wrapped struct{ N int }
+	d = NewDecoder([]byte(`[{"n": 1}, {"n": 2}]`))
+	d.RegisterDecodeFunc(reflect.TypeOf(wrapped{}), func(dec *Decoder, v reflect.Value) error {
+		var m map[string]int
+		err := dec.Decode(&m)
+		v.Set(reflect.ValueOf(wrapped{m["n"] * 10}))
+		return err
+	})
+	var ws []wrapped
+	check(t, d.Decode(&ws))
+	if fmt.Sprint(ws) != "[{10} {20}]" {
+		t.Errorf("got %v, want [{10} {20}]", ws)
+	}
+}
+
+func TestDecodeFuncRegistry(t *testing.T) {
+	RegisterDecodeFunc(fixedPointType, decodeFixedPoint)
+	defer RegisterDecodeFunc(fixedPointType, nil)
+
+	var x fixedPoint
+	check(t, Unmarshal([]byte(`"1.5"`), &x))
+	if x != (fixedPoint{15, 1}) {
+		t.Errorf("got %v, want 15e-1", x)
+	}
+
+	// a Decoder's own function overrides the package-level one, and nil
+	// restores the built-in decoding.
+	d := NewDecoder([]byte(`1`))
+	d.RegisterDecodeFunc(fixedPointType, func(dec *Decoder, v reflect.Value) error {
+		v.Set(reflect.ValueOf(fixedPoint{units: -1}))
+		return dec.Skip()
+	})
+	check(t, d.Decode(&x))
+	if x != (fixedPoint{units: -1}) {
+		t.Errorf("got %v, want the Decoder's function used", x)
+	}
+	d = NewDecoder([]byte(`1`))
+	d.RegisterDecodeFunc(fixedPointType, nil)
+	if err := d.Decode(&x); err == nil || !strings.Contains(err.Error(), "cannot decode number into Go value of type json.fixedPoint") {
+		t.Errorf("got %v, want the built-in error", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				RegisterDecodeFunc(fixedPointType, decodeFixedPoint)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var x fixedPoint
+				if err := Unmarshal([]byte(`2.5`), &x); err != nil || x != (fixedPoint{25, 1}) {
+					t.Errorf("got %v, %v, want 25e-1", x, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDecodeFuncConsumed(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   string
+		fn   DecodeFunc
+		err  string
+	}{
+		{
+			name: "nothing",
+			in:   `{"a": 1}`,
+			fn:   func(*Decoder, reflect.Value) error { return nil },
+			err:  "json: decode func for json.fixedPoint did not consume exactly the value at offset 6",
+		},
+		{
+			name: "too much",
+			in:   `[1, 2]`,
+			fn: func(dec *Decoder, v reflect.Value) error {
+				dec.NextToken()
+				_, err := dec.NextToken()
+				return err
+			},
+			err: "json: decode func for json.fixedPoint did not consume exactly the value at offset 1",
+		},
+		{
+			name: "nothing but a peek",
+			in:   `{"a" :  1}`,
+			fn: func(dec *Decoder, v reflect.Value) error {
+				dec.PeekKind()
+				return nil
+			},
+			err: "json: decode func for json.fixedPoint did not consume exactly the value at offset 8",
+		},
+		{
+			name: "two top-level values",
+			in:   `1 2`,
+			fn: func(dec *Decoder, v reflect.Value) error {
+				dec.NextToken()
+				_, err := dec.NextToken()
+				return err
+			},
+			err: "json: decode func for json.fixedPoint did not consume exactly the value at offset 0",
+		},
+		{
+			name: "part of a container",
+			in:   `[[1, 2]]`,
+			fn: func(dec *Decoder, v reflect.Value) error {
+				_, err := dec.NextToken()
+				return err
+			},
+			err: "json: decode func for json.fixedPoint did not consume exactly the value at offset 1",
+		},
+		{
+			name: "error",
+			in:   `"x"`,
+			fn:   decodeFixedPoint,
+			err:  `fixed point "x": strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+	} {
+		d := NewDecoder([]byte(tc.in))
+		d.RegisterDecodeFunc(fixedPointType, tc.fn)
+		var v interface{}
+		switch tc.in[0] {
+		case '{':
+			v = new(map[string]fixedPoint)
+		case '[':
+			v = new([]fixedPoint)
+		default:
+			v = new(fixedPoint)
+		}
+		if err := d.Decode(v); err == nil || err.Error() != tc.err {
+			t.Errorf("%s: got %v, want %s", tc.name, err, tc.err)
+		}
+	}
+}
+
+// epochTime wraps time.Time, so it has its MarshalJSON method.
+type epochTime struct{ time.Time }
+
+var epochTimeType = reflect.TypeOf(epochTime{})
+
+// encodeEpochMillis encodes an epochTime as milliseconds since the epoch.
+func encodeEpochMillis(enc *Encoder, v reflect.Value) error {
+	return enc.WriteInt(v.Interface().(epochTime).UnixMilli())
+}
+
+func TestEncodeFunc(t *testing.T) {
+	type event struct {
+		At    epochTime   `json:"at"`
+		Ptr   *epochTime  `json:"ptr"`
+		Nil   *epochTime  `json:"nil"`
+		Times []epochTime `json:"times"`
+		Any   interface{} `json:"any"`
+	}
+	at := epochTime{time.Date(2024, 5, 1, 12, 0, 0, 500e6, time.UTC)}
+	in := event{At: at, Ptr: &at, Times: []epochTime{at, {time.Unix(0, 0)}}, Any: at}
+
+	// without a function, the MarshalJSON method is used.
+	b, err := Marshal(in)
+	check(t, err)
+	const builtin = `{"at":"2024-05-01T12:00:00.5Z","ptr":"2024-05-01T12:00:00.5Z","nil":null,"times":["2024-05-01T12:00:00.5Z","1970-01-01T00:00:00Z"],"any":"2024-05-01T12:00:00.5Z"}`
+	if string(b) != builtin {
+		t.Errorf("got %s, want %s", b, builtin)
+	}
+
+	const millis = `{"at":1714564800500,"ptr":1714564800500,"nil":null,"times":[1714564800500,0],"any":1714564800500}`
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.RegisterEncodeFunc(epochTimeType, encodeEpochMillis)
+	check(t, enc.Encode(in))
+	if buf.String() != millis+"\n" {
+		t.Errorf("got %s, want %s", buf.String(), millis)
+	}
+
+	RegisterEncodeFunc(epochTimeType, encodeEpochMillis)
+	b, err = Marshal(in)
+	check(t, err)
+	if string(b) != millis {
+		t.Errorf("Marshal: got %s, want %s", b, millis)
+	}
+	buf.Reset()
+	enc = NewEncoder(&buf)
+	enc.RegisterEncodeFunc(epochTimeType, nil)
+	check(t, enc.Encode(in))
+	RegisterEncodeFunc(epochTimeType, nil)
+	if buf.String() != builtin+"\n" {
+		t.Errorf("with the function removed for the Encoder: got %s, want %s", buf.String(), builtin)
+	}
+	if b, err = Marshal(at); err != nil || string(b) != `"2024-05-01T12:00:00.5Z"` {
+		t.Errorf("after removing the function: got %s, %v", b, err)
+	}
+}
+
+func TestEncodeFuncWriter(t *testing.T) {
+	// a function for a type with a fast path takes precedence over it.
+	RegisterEncodeFunc(reflect.TypeOf(""), func(enc *Encoder, v reflect.Value) error {
+		return enc.WriteString(strings.ToUpper(v.String()))
+	})
+	b, err := Marshal("<a>")
+	RegisterEncodeFunc(reflect.TypeOf(""), nil)
+	if err != nil || string(b) != `"\u003cA\u003e"` {
+		t.Errorf("got %s, %v, want %s", b, err, `"\u003cA\u003e"`)
+	}
+
+	type span struct{ From, To epochTime }
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	enc.RegisterEncodeFunc(epochTimeType, encodeEpochMillis)
+	enc.RegisterEncodeFunc(reflect.TypeOf(span{}), func(enc *Encoder, v reflect.Value) error {
+		s := v.Interface().(span)
+		enc.WriteObjectStart()
+		enc.WriteKey("<from>")
+		enc.Encode(s.From)
+		enc.WriteKey("length")
+		enc.WriteValue(s.To.Sub(s.From.Time).String())
+		return enc.WriteObjectEnd()
+	})
+	check(t, enc.Encode([]span{{epochTime{time.UnixMilli(1000)}, epochTime{time.UnixMilli(61000)}}}))
+	want := "[\n  {\n    \"<from>\": 1000,\n    \"length\": \"1m0s\"\n  }\n]\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	for _, tc := range []struct {
+		name string
+		fn   EncodeFunc
+		err  string
+	}{
+		{
+			name: "nothing",
+			fn:   func(*Encoder, reflect.Value) error { return nil },
+			err:  "json: encode func for json.epochTime did not write a complete value",
+		},
+		{
+			name: "unterminated",
+			fn:   func(enc *Encoder, _ reflect.Value) error { return enc.WriteArrayStart() },
+			err:  "json: encode func for json.epochTime did not write a complete value",
+		},
+		{
+			name: "two values",
+			fn: func(enc *Encoder, _ reflect.Value) error {
+				enc.WriteInt(1)
+				return enc.WriteInt(2)
+			},
+			err: "json: WriteInt: an encode func may only write one value",
+		},
+		{
+			name: "key",
+			fn:   func(enc *Encoder, _ reflect.Value) error { return enc.WriteKey("a") },
+			err:  "json: WriteKey: not inside an object",
+		},
+	} {
+		enc := NewEncoder(new(bytes.Buffer))
+		enc.RegisterEncodeFunc(epochTimeType, tc.fn)
+		if err := enc.Encode([]epochTime{{}}); err == nil || err.Error() != tc.err {
+			t.Errorf("%s: got %v, want %s", tc.name, err, tc.err)
+		}
+	}
+}
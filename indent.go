@@ -0,0 +1,220 @@
+package json
+
+import (
+	"bytes"
+	"io"
+)
+
+// Indent appends to dst an indented form of the JSON-encoded src.
+// Each element in a JSON object or array begins on a new,
+// indented line beginning with prefix followed by one or more
+// copies of indent according to the indentation nesting.
+// The data appended to dst does not begin with the prefix nor
+// any indentation, to make it easier to embed inside other formatted JSON data.
+// Only whitespace is changed; numbers, strings and key order are copied from
+// src exactly. If src is not valid JSON, Indent returns a *SyntaxError and
+// dst is left unchanged. opts are applied as for NewDecoder; comments allowed
+// by AllowComments are dropped.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string, opts ...Option) error {
+	b, err := appendIndent(dst.AvailableBuffer(), src, prefix, indent, opts...)
+	if err != nil {
+		return err
+	}
+	dst.Write(b)
+	return nil
+}
+
+// Compact appends to dst the JSON-encoded src with insignificant whitespace
+// elided. Whitespace inside strings is preserved. src is validated as it is
+// copied; if it is not a single valid JSON value, Compact returns a
+// *SyntaxError, or io.ErrUnexpectedEOF for truncated input, and dst is left
+// unchanged. opts are applied as for NewDecoder; comments allowed by
+// AllowComments are dropped.
+func Compact(dst *bytes.Buffer, src []byte, opts ...Option) error {
+	b, err := appendCompact(dst.AvailableBuffer(), src, opts...)
+	if err != nil {
+		return err
+	}
+	dst.Write(b)
+	return nil
+}
+
+// A StripOption changes how StripComments removes comments.
+type StripOption func(*stripOptions)
+
+type stripOptions struct {
+	keepOffsets bool
+}
+
+// KeepOffsets makes StripComments replace each comment with as many spaces
+// as it has bytes, keeping its line breaks, so that the rest of the input
+// stays at the same offset, line and column. Errors found in the output can
+// then be mapped back to the input.
+func KeepOffsets() StripOption {
+	return func(o *stripOptions) { o.keepOffsets = true }
+}
+
+// StripComments appends src to dst with its // line and /* block */
+// comments removed, as allowed by AllowComments, and everything else,
+// whitespace included, copied as is. The newline ending a line comment is
+// kept. Comment markers inside strings are part of the string, not comments.
+//
+// src must hold a single value, valid apart from its comments, so that the
+// result is strict JSON; otherwise StripComments returns a *SyntaxError. On
+// error the contents of the returned buffer beyond len(dst) are
+// unspecified.
+func StripComments(dst, src []byte, opts ...StripOption) ([]byte, error) {
+	var o stripOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	d := GetDecoder(src, AllowComments(), (*Decoder).DisallowTrailingData)
+	defer PutDecoder(d)
+	s := &d.scanner
+
+	// A byte order mark is dropped, or blanked with the comments.
+	prev := bomLen(src)
+	if o.keepOffsets {
+		dst = append(dst, "   "[:prev]...)
+	}
+	for {
+		_, err := d.NextToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return dst, endOfInput(src, err)
+		}
+		dst = s.appendStripped(dst, prev, s.start, o.keepOffsets)
+		dst = append(dst, src[s.start:s.offset]...)
+		prev = s.offset
+	}
+	if prev == bomLen(src) {
+		return dst, endOfInput(src, io.EOF)
+	}
+	return s.appendStripped(dst, prev, len(src), o.keepOffsets), nil
+}
+
+// appendStripped appends the data in [i, end), which holds only whitespace,
+// separators and comments, to dst without its comments. If keep is set,
+// comments are replaced by spaces, keeping their line breaks.
+func (s *Scanner) appendStripped(dst []byte, i, end int, keep bool) []byte {
+	for i < end {
+		j := bytes.IndexByte(s.data[i:end], '/')
+		if j < 0 {
+			return append(dst, s.data[i:end]...)
+		}
+		dst = append(dst, s.data[i:i+j]...)
+		i += j
+		j = s.commentEnd(i)
+		comment := s.data[i:j]
+		switch {
+		case keep:
+			for _, c := range comment {
+				if c != '\n' && c != '\r' {
+					c = ' '
+				}
+				dst = append(dst, c)
+			}
+		case comment[1] == '/' && comment[len(comment)-1] == '\n':
+			if bytes.HasSuffix(comment, []byte("\r\n")) {
+				dst = append(dst, '\r')
+			}
+			dst = append(dst, '\n')
+		}
+		i = j
+	}
+	return dst
+}
+
+// appendCompact appends the single JSON value in src to dst with all
+// insignificant whitespace removed. src is validated as it is copied; on
+// error the contents of the returned buffer beyond len(dst) are unspecified.
+func appendCompact(dst, src []byte, opts ...Option) ([]byte, error) {
+	return reformat(dst, src, "", "", false, opts)
+}
+
+// appendIndent is like appendCompact but lays the value out as Indent does.
+func appendIndent(dst, src []byte, prefix, indent string, opts ...Option) ([]byte, error) {
+	return reformat(dst, src, prefix, indent, true, opts)
+}
+
+// reformat appends the single JSON value in src to dst, discarding its
+// original whitespace. If pretty is set, elements of arrays and objects are
+// placed on their own lines, indented by prefix and then indent once per
+// level of nesting, and colons are followed by a space. opts configure the
+// Decoder reading src.
+func reformat(dst, src []byte, prefix, indent string, pretty bool, opts []Option) ([]byte, error) {
+	d := NewDecoder(src, opts...)
+	d.DisallowTrailingData()
+
+	// frame records, for each open array or object, how many tokens have
+	// been written into it, from which the separator needed before the next
+	// token follows.
+	type frame struct {
+		obj bool
+		n   int
+	}
+	var buf [32]frame
+	frames := buf[:0]
+	empty := true
+	for {
+		tok, err := d.NextToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return dst, err
+		}
+		empty = false
+		switch tok[0] {
+		case ObjectEnd, ArrayEnd:
+			f := frames[len(frames)-1]
+			frames = frames[:len(frames)-1]
+			if pretty && f.n > 0 {
+				dst = appendNewline(dst, prefix, indent, len(frames))
+			}
+			dst = append(dst, tok[0])
+			continue
+		}
+		if n := len(frames); n > 0 {
+			f := &frames[n-1]
+			if f.obj && f.n%2 == 1 {
+				dst = append(dst, Colon)
+				if pretty {
+					dst = append(dst, ' ')
+				}
+			} else {
+				if f.n > 0 {
+					dst = append(dst, Comma)
+				}
+				if pretty {
+					dst = appendNewline(dst, prefix, indent, n)
+				}
+			}
+			f.n++
+		}
+		dst = append(dst, tok...)
+		switch tok[0] {
+		case ObjectStart:
+			frames = append(frames, frame{obj: true})
+		case ArrayStart:
+			frames = append(frames, frame{obj: false})
+		}
+	}
+	if empty {
+		return dst, io.ErrUnexpectedEOF
+	}
+	return dst, nil
+}
+
+// appendNewline appends a newline followed by prefix and depth copies of
+// indent.
+func appendNewline(dst []byte, prefix, indent string, depth int) []byte {
+	dst = append(dst, '\n')
+	dst = append(dst, prefix...)
+	for i := 0; i < depth; i++ {
+		dst = append(dst, indent...)
+	}
+	return dst
+}
@@ -0,0 +1,234 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `1`, want: `1`},
+		{in: ` "a b" `, want: `"a b"`},
+		{in: `{}`, want: `{}`},
+		{in: `[ ]`, want: `[]`},
+		{in: `{"a": [1.50, -2e10, {}], "b" : {"c": null}}`, want: `{
+>	"a": [
+>		1.50,
+>		-2e10,
+>		{}
+>	],
+>	"b": {
+>		"c": null
+>	}
+>}`},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		buf.WriteString("prefix ")
+		if err := Indent(&buf, []byte(tc.in), ">", "\t"); err != nil {
+			t.Errorf("indent %q: %v", tc.in, err)
+			continue
+		}
+		if got := buf.String(); got != "prefix "+tc.want {
+			t.Errorf("indent %q:\nexpected: %s\ngot:      %s", tc.in, "prefix "+tc.want, got)
+		}
+	}
+}
+
+func TestIndentInvalid(t *testing.T) {
+	tests := []struct {
+		in     string
+		offset int64
+	}{
+		{in: `{"a": 1,, "b": 2}`, offset: 8},
+		{in: `[1, 2] 3`, offset: 7},
+		{in: `[1, 2`, offset: -1},
+		{in: ``, offset: -1},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		buf.WriteString("unchanged")
+		err := Indent(&buf, []byte(tc.in), "", "  ")
+		if buf.String() != "unchanged" {
+			t.Errorf("indent %q: dst modified: %q", tc.in, buf.String())
+		}
+		if tc.offset < 0 {
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("indent %q: expected: %v, got: %v", tc.in, io.ErrUnexpectedEOF, err)
+			}
+			continue
+		}
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("indent %q: expected *SyntaxError, got: %v", tc.in, err)
+			continue
+		}
+		if serr.Offset != tc.offset {
+			t.Errorf("indent %q: expected offset %v, got %v", tc.in, tc.offset, serr.Offset)
+		}
+	}
+}
+
+func TestIndentFixtures(t *testing.T) {
+	for _, tc := range inputs {
+		t.Run(tc.path, func(t *testing.T) {
+			data, err := io.ReadAll(fixture(t, tc.path))
+			check(t, err)
+			var want, got bytes.Buffer
+			check(t, json.Indent(&want, data, "", "  "))
+			check(t, Indent(&got, data, "", "  "))
+			if !bytes.Equal(bytes.TrimSpace(want.Bytes()), got.Bytes()) {
+				t.Fatalf("output differs from encoding/json")
+			}
+		})
+	}
+}
+
+func TestEncoderSetIndent(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	check(t, enc.Encode(map[string]interface{}{"a": []int{1, 2}, "b": map[string]int{}}))
+	enc.SetIndent("", "")
+	check(t, enc.Encode([]int{3}))
+	want := `{
+  "a": [
+    1,
+    2
+  ],
+  "b": {}
+}
+[3]
+`
+	if buf.String() != want {
+		t.Fatalf("expected: %q, got: %q", want, buf.String())
+	}
+}
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: ` 1 `, want: `1`},
+		{in: "{\n\t\"a b\" : [ 1 , \"c\\\" d\" ],\r\n \"e\": { } }", want: `{"a b":[1,"c\" d"],"e":{}}`},
+		{in: `[ "  spaces  inside  " , true , null ]`, want: `["  spaces  inside  ",true,null]`},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		if err := Compact(&buf, []byte(tc.in)); err != nil {
+			t.Errorf("compact %q: %v", tc.in, err)
+			continue
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("compact %q: expected: %q, got: %q", tc.in, tc.want, got)
+		}
+	}
+
+	for _, in := range []string{`{"a" 1}`, `[1, 2`, `[1] [2]`, `tru`} {
+		var buf bytes.Buffer
+		if err := Compact(&buf, []byte(in)); err == nil {
+			t.Errorf("compact %q: expected error, got nil", in)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("compact %q: expected no output, got: %q", in, buf.String())
+		}
+	}
+}
+
+func TestCompactComments(t *testing.T) {
+	in := "// settings\n{\n\t\"a\": 1, // the a\n\t/* \"b\": 2, */\n\t\"c\": \"/* kept */\"\n} /* done */\n"
+	var buf bytes.Buffer
+	check(t, Compact(&buf, []byte(in), AllowComments()))
+	if want := `{"a":1,"c":"/* kept */"}`; buf.String() != want {
+		t.Errorf("compact: expected: %q, got: %q", want, buf.String())
+	}
+	buf.Reset()
+	check(t, Indent(&buf, []byte(in), "", "  ", AllowComments()))
+	if want := "{\n  \"a\": 1,\n  \"c\": \"/* kept */\"\n}"; buf.String() != want {
+		t.Errorf("indent: expected: %q, got: %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := Compact(&buf, []byte(in)); err == nil || buf.Len() > 0 {
+		t.Errorf("compact without AllowComments: got %q, %v, want an error", buf.String(), err)
+	}
+}
+
+func TestCompactFixtures(t *testing.T) {
+	for _, tc := range inputs {
+		t.Run(tc.path, func(t *testing.T) {
+			data, err := io.ReadAll(fixture(t, tc.path))
+			check(t, err)
+			var want, got bytes.Buffer
+			check(t, json.Compact(&want, data))
+			check(t, Compact(&got, data))
+			if !bytes.Equal(want.Bytes(), got.Bytes()) {
+				t.Fatalf("output differs from encoding/json")
+			}
+		})
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		in, want, keep string
+	}{
+		{
+			"{\n  // name\n  \"a\": 1, /* one */\n  \"b\": [2 /* two */, 3] // end\n}",
+			"{\n  \n  \"a\": 1, \n  \"b\": [2 , 3] \n}",
+			"{\n         \n  \"a\": 1,          \n  \"b\": [2          , 3]       \n}",
+		},
+		{
+			`{"url": "http://x/*y*/", "c": "//"}`,
+			`{"url": "http://x/*y*/", "c": "//"}`,
+			`{"url": "http://x/*y*/", "c": "//"}`,
+		},
+		{
+			"/* multi\nline */[1]// no newline",
+			"[1]",
+			"        \n       [1]             ",
+		},
+		{"[1]// crlf\r\n", "[1]\r\n", "[1]       \r\n"},
+		{"\xef\xbb\xbf[1]", "[1]", "   [1]"},
+		{"1", "1", "1"},
+		{"/**/ /***/ 1 //", "  1 ", "           1   "},
+	}
+	for _, tc := range tests {
+		got, err := StripComments([]byte("prefix:"), []byte(tc.in))
+		if err != nil || string(got) != "prefix:"+tc.want {
+			t.Errorf("StripComments(%q): got %q, %v, want %q", tc.in, got, err, "prefix:"+tc.want)
+		}
+		if err == nil && !Valid(got[len("prefix:"):]) {
+			t.Errorf("StripComments(%q): got invalid JSON %q", tc.in, got)
+		}
+		got, err = StripComments(nil, []byte(tc.in), KeepOffsets())
+		if err != nil || string(got) != tc.keep {
+			t.Errorf("StripComments(%q, KeepOffsets()): got %q, %v, want %q", tc.in, got, err, tc.keep)
+		}
+		if len(got) != len(tc.in) {
+			t.Errorf("StripComments(%q, KeepOffsets()): got %d bytes, want %d", tc.in, len(got), len(tc.in))
+		}
+	}
+
+	for _, in := range []string{
+		``,
+		`// only a comment`,
+		`[1, /* unterminated ]`,
+		`[1,] // trailing comma`,
+		`{"a": 1} {"b": 2}`,
+		`[1 / 2]`,
+	} {
+		if got, err := StripComments(nil, []byte(in)); err == nil {
+			t.Errorf("StripComments(%q): got %q, want error", in, got)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("StripComments(%q): got %T, want *SyntaxError", in, err)
+		}
+	}
+}
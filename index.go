@@ -0,0 +1,208 @@
+package json
+
+import (
+	"math"
+	"strconv"
+)
+
+// An Index records the path and span of the values of a document, so that
+// repeated lookups in it do not rescan it as Get does. It is built once by
+// BuildIndex, in a single pass over the document, and may then be used
+// concurrently.
+//
+// The Index refers to the data it was built from rather than copying it, and
+// returns slices of it. Changing the data, or reusing its buffer, while the
+// Index is in use makes the results meaningless; the Index cannot detect it.
+type Index struct {
+	data     []byte
+	entries  []indexEntry       // the values, in the order they appear
+	keys     string             // the keys of all object members, unescaped
+	children map[indexKey]int32 // index of the entry for each member or element
+	maxDepth int
+}
+
+// indexEntry is the entry of an Index for one value.
+type indexEntry struct {
+	start, end int   // span of the value in data
+	parent     int32 // entry of the enclosing array or object, or -1
+	next       int32 // entry following the value and those nested in it
+	key        int32 // offset of the member's key in keys, or the element's index
+	keyLen     int32 // length of the key, or -1 for an array element
+}
+
+// indexKey identifies a member or element of the container at entry parent:
+// the member with key if index is -1, or else the element at index.
+type indexKey struct {
+	parent int32
+	index  int32
+	key    string
+}
+
+// BuildIndex reads data, which must hold a single JSON value, and returns an
+// Index of the values in it nested up to maxDepth levels deep, the top-level
+// value being at level 0, or of all the values if maxDepth is 0 or less.
+// Every value is checked as NextToken checks it, except those nested deeper
+// than maxDepth, which are only checked as far as needed to skip them.
+//
+// If a key appears more than once in an object, its first value is indexed,
+// as Get would find it.
+func BuildIndex(data []byte, maxDepth int) (*Index, error) {
+	idx := &Index{data: data, maxDepth: maxDepth}
+	d := GetDecoder(data)
+	defer PutDecoder(d)
+	var keys []byte
+	if err := idx.build(d, &keys, -1, -1, -1); err != nil {
+		return nil, endOfInput(data, err)
+	}
+	if err := d.Drain(); err != nil {
+		return nil, err
+	}
+	idx.keys = string(keys)
+	idx.children = make(map[indexKey]int32, len(idx.entries)-1)
+	for i, e := range idx.entries[1:] {
+		k := indexKey{parent: e.parent, index: e.key}
+		if e.keyLen >= 0 {
+			k = indexKey{parent: e.parent, index: -1, key: idx.keys[e.key : e.key+e.keyLen]}
+		}
+		if _, ok := idx.children[k]; !ok {
+			idx.children[k] = int32(i + 1)
+		}
+	}
+	return idx, nil
+}
+
+// build consumes the next value, which is a member or element of the
+// container at entry parent, and adds entries for it and for the values
+// nested in it to idx.
+func (idx *Index) build(d *Decoder, keys *[]byte, parent, key, keyLen int32) error {
+	i := int32(len(idx.entries))
+	idx.entries = append(idx.entries, indexEntry{parent: parent, key: key, keyLen: keyLen})
+	kind := d.PeekKind()
+	if kind != KindObjectStart && kind != KindArrayStart || idx.maxDepth > 0 && d.len() >= idx.maxDepth {
+		start, end, err := d.NextValueSpan()
+		if err != nil {
+			return err
+		}
+		idx.entries[i].start, idx.entries[i].end, idx.entries[i].next = start, end, i+1
+		return nil
+	}
+	if _, err := d.NextToken(); err != nil {
+		return err
+	}
+	idx.entries[i].start = d.scanner.start
+	for n := int32(0); ; n++ {
+		switch d.PeekKind() {
+		case KindObjectEnd, KindArrayEnd:
+			if _, err := d.NextToken(); err != nil {
+				return err
+			}
+			idx.entries[i].end, idx.entries[i].next = d.getOffset(), int32(len(idx.entries))
+			return nil
+		}
+		key, keyLen := n, int32(-1)
+		if kind == KindObjectStart {
+			k, err := d.ReadStringBytes()
+			if err != nil {
+				return err
+			}
+			key, keyLen = int32(len(*keys)), int32(len(k))
+			*keys = append(*keys, k...)
+		}
+		if err := idx.build(d, keys, i, key, keyLen); err != nil {
+			return err
+		}
+	}
+}
+
+// Get returns the raw bytes of the value found by following path from the
+// top-level value, as described for Decoder.Seek, and whether there is one.
+// The value of a path within the indexed levels is found in time independent
+// of the size of the document; the rest of a longer path is followed by
+// scanning the deepest value indexed on the way, as Get does.
+func (idx *Index) Get(path ...string) ([]byte, bool) {
+	i, n := idx.find(path)
+	if i < 0 {
+		return nil, false
+	}
+	e := &idx.entries[i]
+	value := idx.data[e.start:e.end]
+	if n < len(path) {
+		v, err := Get(value, path[n:]...)
+		return v, err == nil
+	}
+	return value, true
+}
+
+// find returns the entry of the deepest value indexed along path and the
+// number of elements of path leading to it, or -1 if path leads nowhere
+// within the indexed levels.
+func (idx *Index) find(path []string) (int32, int) {
+	if len(idx.entries) == 0 {
+		return -1, 0
+	}
+	i := int32(0)
+	for n, elem := range path {
+		if idx.maxDepth > 0 && n >= idx.maxDepth {
+			return i, n
+		}
+		k := indexKey{parent: i, index: -1, key: elem}
+		switch idx.data[idx.entries[i].start] {
+		case ObjectStart:
+		case ArrayStart:
+			index, err := strconv.Atoi(elem)
+			if err != nil || index < 0 || index > math.MaxInt32 {
+				return -1, 0
+			}
+			k = indexKey{parent: i, index: int32(index)}
+		default:
+			return -1, 0
+		}
+		j, ok := idx.children[k]
+		if !ok {
+			return -1, 0
+		}
+		i = j
+	}
+	return i, len(path)
+}
+
+// Range calls fn, in the order they appear in the document, with the path
+// and the raw bytes of the indexed value found by following prefix, as Get
+// does, and of each of the indexed values nested in it, including those of
+// duplicate keys, which Get does not find. Nothing is called if the value
+// is not indexed. The path is only valid during the call. An error
+// returned by fn stops the iteration and is returned as is, except ErrStop,
+// for which Range returns nil.
+func (idx *Index) Range(fn func(path []string, value []byte) error, prefix ...string) error {
+	first, n := idx.find(prefix)
+	if first < 0 || n < len(prefix) {
+		return nil
+	}
+	path := append([]string(nil), prefix...)
+	// parents holds the entries of the values along path, from first on.
+	parents := []int32{first}
+	for i := first; i < idx.entries[first].next; i++ {
+		e := &idx.entries[i]
+		if i > first {
+			for parents[len(parents)-1] != e.parent {
+				parents, path = parents[:len(parents)-1], path[:len(path)-1]
+			}
+			parents, path = append(parents, i), append(path, idx.elem(e))
+		}
+		if err := fn(path, idx.data[e.start:e.end]); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// elem returns the last element of the path of the value of e.
+func (idx *Index) elem(e *indexEntry) string {
+	if e.keyLen < 0 {
+		return strconv.Itoa(int(e.key))
+	}
+	return idx.keys[e.key : e.key+e.keyLen]
+}
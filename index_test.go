@@ -0,0 +1,126 @@
+package json
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	data := []byte(` {"a": {"b": [1, {"c": "x"}, [true]]}, "kéy": null, "a": 2, "": {"": 3}} `)
+	for _, maxDepth := range []int{0, 1, 2, 3} {
+		idx, err := BuildIndex(data, maxDepth)
+		check(t, err)
+		for _, path := range [][]string{
+			nil,
+			{"a"},
+			{"a", "b"},
+			{"a", "b", "1"},
+			{"a", "b", "1", "c"},
+			{"a", "b", "2", "0"},
+			{"a", "b", "3"},
+			{"a", "b", "-1"},
+			{"a", "b", "x"},
+			{"a", "c"},
+			{"a", "b", "0", "c"},
+			{"kéy"},
+			{"k\\u00e9y"},
+			{"", ""},
+			{"x", "y"},
+		} {
+			want, err := Get(data, path...)
+			got, ok := idx.Get(path...)
+			if ok != (err == nil) || string(got) != string(want) {
+				t.Errorf("maxDepth %d: Get(%q): got %s, %t, want %s, %v", maxDepth, path, got, ok, want, err)
+			}
+		}
+	}
+
+	idx, err := BuildIndex(data, 0)
+	check(t, err)
+	var got []string
+	check(t, idx.Range(func(path []string, value []byte) error {
+		got = append(got, strings.Join(path, ".")+"="+string(value))
+		return nil
+	}, "a"))
+	want := []string{
+		`a={"b": [1, {"c": "x"}, [true]]}`,
+		`a.b=[1, {"c": "x"}, [true]]`,
+		`a.b.0=1`,
+		`a.b.1={"c": "x"}`,
+		`a.b.1.c="x"`,
+		`a.b.2=[true]`,
+		`a.b.2.0=true`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Range(a): got %q, want %q", got, want)
+	}
+
+	// Range stops at ErrStop, and only visits the indexed values, including
+	// those of duplicate keys.
+	idx, err = BuildIndex(data, 2)
+	check(t, err)
+	got = got[:0]
+	check(t, idx.Range(func(path []string, value []byte) error {
+		got = append(got, strings.Join(path, "."))
+		if len(got) == 5 {
+			return ErrStop
+		}
+		return nil
+	}))
+	if want := []string{"", "a", "a.b", "kéy", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Range: got %q, want %q", got, want)
+	}
+	errFn := errors.New("fn")
+	if err := idx.Range(func([]string, []byte) error { return errFn }, "a"); err != errFn {
+		t.Errorf("Range: got %v, want the error of fn", err)
+	}
+	if err := idx.Range(func([]string, []byte) error { return errFn }, "a", "b", "0"); err != nil {
+		t.Errorf("Range of a value deeper than the index: got %v, want nil", err)
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	for _, in := range []string{
+		``,
+		`{"a": [1, 2}`,
+		`{"a": [1, 2]`,
+		`{"a": 1} 2`,
+		`{"a": tru}`,
+		`[1,]`,
+	} {
+		if idx, err := BuildIndex([]byte(in), 0); err == nil {
+			t.Errorf("BuildIndex(%s): got %v, want error", in, idx)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("BuildIndex(%s): got %T, want *SyntaxError", in, err)
+		}
+	}
+}
+
+func TestIndexFixtures(t *testing.T) {
+	for _, tc := range inputs {
+		t.Run(tc.path, func(t *testing.T) {
+			data, err := io.ReadAll(fixture(t, tc.path))
+			check(t, err)
+			idx, err := BuildIndex(data, 0)
+			check(t, err)
+			n := 0
+			check(t, idx.Range(func(path []string, value []byte) error {
+				// Get rescans the document, so only check a sample of paths.
+				if n++; n%1009 == 1 {
+					want, err := Get(data, path...)
+					check(t, err)
+					if got, ok := idx.Get(path...); !ok || string(got) != string(want) {
+						t.Fatalf("Get(%q): got %.40s, %t, want %.40s", path, got, ok, want)
+					}
+				}
+				return nil
+			}))
+			if n != len(idx.entries) {
+				t.Errorf("Range visited %d values, want %d", n, len(idx.entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package json
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Object consumes the next value, which must be an object, calling fn with
+// each of its keys in turn. The key is unescaped and only valid until the
+// next call to the Decoder. fn must consume exactly the key's value, with
+// Decode, Skip, NextAsBytes or one of the Read methods; Object returns an
+// error if it did not, whether it stopped short of the value's end or read
+// on into the members after it, which it tells from where fn left the
+// Decoder rather than by scanning the value beforehand.
+//
+// If fn returns ErrStop, Object skips the rest of the object and returns nil.
+// Any other error from fn is returned as is. If the next token is not an
+// object start, Object returns a *KindError and does not consume it.
+func (d *Decoder) Object(fn func(key []byte) error) error {
+	if _, err := d.readToken(KindObjectStart); err != nil {
+		return err
+	}
+	depth := d.len()
+	for {
+		if d.PeekKind() == KindObjectEnd {
+			_, err := d.NextToken()
+			return err
+		}
+		key, err := d.ReadStringBytes()
+		if err != nil {
+			return err
+		}
+		start, offset := d.scanner.start, d.scanner.offset
+		if err := fn(key); err != nil {
+			if err == ErrStop {
+				return d.skipOut(depth)
+			}
+			return err
+		}
+		if err := d.checkConsumed(depth, offset, start); err != nil {
+			return fmt.Errorf("json: Object: value of key at offset %d: %w", start, err)
+		}
+	}
+}
+
+// Array consumes the next value, which must be an array, calling fn with the
+// index of each of its elements in turn. fn must consume exactly the element,
+// with Decode, Skip, NextAsBytes or one of the Read methods; Array returns an
+// error if it did not, which it tells from where fn left the Decoder rather
+// than by scanning the element beforehand. That error, and any returned by
+// fn, is wrapped with the index of the element.
+//
+// If fn returns ErrStop, Array skips the rest of the array and returns nil.
+// If the next token is not an array start, Array returns a *KindError and
+// does not consume it.
+func (d *Decoder) Array(fn func(i int) error) error {
+	if _, err := d.readToken(KindArrayStart); err != nil {
+		return err
+	}
+	depth := d.len()
+	for i := 0; ; i++ {
+		if d.PeekKind() == KindArrayEnd {
+			_, err := d.NextToken()
+			return err
+		}
+		offset := d.scanner.offset
+		err := fn(i)
+		if err == ErrStop {
+			return d.skipOut(depth)
+		}
+		if err == nil {
+			err = d.checkConsumed(depth, offset, i)
+		}
+		if err != nil {
+			return fmt.Errorf("json: Array: element %d: %w", i, err)
+		}
+	}
+}
+
+// Keys consumes the next value, which must be an object, and returns its
+// keys, unescaped, in the order they appear, including any duplicates. The
+// values are skipped as Skip skips them, without being decoded, which makes
+// Keys a cheap way to decide what type to decode a document into. If the
+// next token is not an object start, Keys returns a *KindError and does not
+// consume it.
+func (d *Decoder) Keys() ([]string, error) {
+	var keys []string
+	err := d.Object(func(key []byte) error {
+		keys = append(keys, string(key))
+		return d.Skip()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ArrayEach calls fn with the index and the raw bytes of each element of the
+// array data holds, in turn, without decoding them. Nested arrays and
+// objects are passed whole. Every element is checked as it is reached, and
+// a malformed one stops the iteration with an error wrapping a *SyntaxError
+// and naming the element's index; fn has been called for the elements before
+// it. An error returned by fn stops the iteration and is returned wrapped
+// with the index of the element, except ErrStop, for which ArrayEach returns
+// nil. If data is not an array, ArrayEach returns a *KindError.
+func ArrayEach(data []byte, fn func(i int, value []byte) error) error {
+	d := GetDecoder(data)
+	defer PutDecoder(d)
+	err := d.Array(func(i int) error {
+		value, err := d.nextValue()
+		if err != nil {
+			return endOfInput(data, err)
+		}
+		return fn(i, value)
+	})
+	if err == nil {
+		err = d.Drain()
+	}
+	return endOfInput(data, err)
+}
+
+// ObjectEach calls fn with the key and the raw bytes of the value of each
+// member of the object data holds, in turn, without decoding the values. The
+// key is unescaped and, like the value, only valid during the call. Every
+// member is checked as it is reached, and a malformed one stops the
+// iteration with a *SyntaxError; fn has been called for the members before
+// it. An error returned by fn stops the iteration and is returned as is,
+// except ErrStop, for which ObjectEach returns nil. If data is not an
+// object, ObjectEach returns a *KindError.
+func ObjectEach(data []byte, fn func(key, value []byte) error) error {
+	d := GetDecoder(data)
+	defer PutDecoder(d)
+	err := d.Object(func(key []byte) error {
+		value, err := d.nextValue()
+		if err != nil {
+			return endOfInput(data, err)
+		}
+		return fn(key, value)
+	})
+	if err == nil {
+		err = d.Drain()
+	}
+	return endOfInput(data, err)
+}
+
+// nextValue is like NextAsBytes, but checks every token of the value as
+// NextToken does rather than only matching brackets.
+func (d *Decoder) nextValue() ([]byte, error) {
+	tok, err := d.NextToken()
+	if err != nil {
+		return nil, err
+	}
+	start, depth := d.scanner.start, d.len()
+	if tok[0] == ObjectStart || tok[0] == ArrayStart {
+		for d.len() >= depth {
+			if _, err := d.NextToken(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return d.scanner.span(start, d.scanner.offset), nil
+}
+
+// endOfInput returns err, unless it is io.EOF or io.ErrUnexpectedEOF, for
+// which it returns the *SyntaxError for data ending early.
+func endOfInput(data []byte, err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return newSyntaxError(data, len(data), "unexpected end of JSON input")
+	}
+	return err
+}
+
+// Seek consumes the input up to the value found by following path from the
+// next value, so that the value can be read with Decode, NextAsBytes,
+// NextToken or one of the Read methods. Each element of path is an object
+// key, or the decimal index of an array element. Members and elements
+// before the one sought are skipped.
+//
+// If the path does not exist, Seek returns an error wrapping ErrNotFound and
+// the position of the Decoder is unspecified.
+func (d *Decoder) Seek(path ...string) error {
+	for n, elem := range path {
+		if err := d.seekElem(elem, false); err != nil {
+			return fmt.Errorf("Seek %s: %w", strings.Join(path[:n+1], "."), err)
+		}
+	}
+	return nil
+}
+
+// seekElem consumes the next value, which must be an object or array, up to
+// the member or element elem. If strict is set, array indexes must follow
+// the rules of RFC 6901.
+func (d *Decoder) seekElem(elem string, strict bool) error {
+	switch kind := d.PeekKind(); kind {
+	case KindObjectStart:
+		return d.seekKey(elem)
+	case KindArrayStart:
+		if strict {
+			if err := checkPointerIndex(elem); err != nil {
+				return err
+			}
+		}
+		return d.seekIndex(elem)
+	case KindInvalid:
+		_, err := d.NextToken()
+		return err
+	default:
+		return fmt.Errorf("%w: %v is not an object or array", ErrNotFound, kind)
+	}
+}
+
+// seekKey consumes the next object up to the value of key.
+func (d *Decoder) seekKey(key string) error {
+	if _, err := d.NextToken(); err != nil {
+		return err
+	}
+	for d.PeekKind() != KindObjectEnd {
+		k, err := d.ReadStringBytes()
+		if err != nil {
+			return err
+		}
+		if string(k) == key {
+			return nil
+		}
+		if err := d.Skip(); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: no such key", ErrNotFound)
+}
+
+// seekIndex consumes the next array up to the element at index.
+func (d *Decoder) seekIndex(index string) error {
+	n, err := strconv.Atoi(index)
+	if err != nil || n < 0 {
+		return fmt.Errorf("%w: not an array index", ErrNotFound)
+	}
+	if _, err := d.NextToken(); err != nil {
+		return err
+	}
+	for i := 0; d.PeekKind() != KindArrayEnd; i++ {
+		if i == n {
+			return nil
+		}
+		if err := d.Skip(); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: index out of range", ErrNotFound)
+}
+
+// checkConsumed returns an error if a callback, called with the Decoder at
+// offset, did not consume exactly the member or element of the container
+// at depth that followed offset. That value is then the one at pos in the
+// container: the offset of its key for an object, or its index for an
+// array. Stopping short of its end leaves the Decoder deeper, or where it
+// was; going past it leaves the Decoder shallower, or at a later position.
+func (d *Decoder) checkConsumed(depth, offset, pos int) error {
+	if d.len() != depth {
+		return fmt.Errorf("callback left the decoder at depth %d, want %d", d.len(), depth)
+	}
+	f := d.top()
+	cur := f.index
+	if f.obj {
+		cur = f.key
+	}
+	if d.scanner.offset == offset || cur != pos {
+		return fmt.Errorf("callback did not consume exactly one value")
+	}
+	return nil
+}
+
+// skipOut consumes the rest of the container at depth, whatever has been
+// read of it, including its closing bracket.
+func (d *Decoder) skipOut(depth int) error {
+	for d.len() >= depth {
+		tok, err := d.NextToken()
+		if err != nil {
+			return err
+		}
+		switch tok[0] {
+		case ObjectStart, ArrayStart:
+			start := d.scanner.start
+			if err := d.scanner.skipContainer(tok[0], d.remainingDepth()); err != nil {
+				return fmt.Errorf("container at offset %d: %w", start, err)
+			}
+			d.closeContainer()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,555 @@
+package json
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoderObject(t *testing.T) {
+	d := NewDecoder([]byte(`{"id": 7, "name": "x", "tags": ["a", "b"], "inner": {"ok": true}, "skip": {"a": [1, {}]}} 42`))
+	var (
+		id    int64
+		name  string
+		tags  []string
+		ok    bool
+		order []string
+	)
+	err := d.Object(func(key []byte) error {
+		order = append(order, string(key))
+		var err error
+		switch string(key) {
+		case "id":
+			id, err = d.ReadInt64()
+		case "name":
+			name, err = d.ReadString()
+		case "tags":
+			err = d.Decode(&tags)
+		case "inner":
+			err = d.Object(func(key []byte) error {
+				ok, err = d.ReadBool()
+				return err
+			})
+		default:
+			err = d.Skip()
+		}
+		return err
+	})
+	check(t, err)
+	if id != 7 || name != "x" || strings.Join(tags, ",") != "a,b" || !ok {
+		t.Errorf("got id=%d name=%q tags=%q ok=%v", id, name, tags, ok)
+	}
+	if got := strings.Join(order, ","); got != "id,name,tags,inner,skip" {
+		t.Errorf("got keys %s", got)
+	}
+	// the decoder is positioned after the object.
+	if i, err := d.ReadInt64(); err != nil || i != 42 {
+		t.Errorf("ReadInt64 after Object: got %d, %v, want 42", i, err)
+	}
+}
+
+func TestDecoderObjectEmpty(t *testing.T) {
+	d := NewDecoder([]byte(`[{}, { }]`))
+	d.NextToken()
+	for d.More() {
+		err := d.Object(func(key []byte) error {
+			t.Fatalf("unexpected key %q", key)
+			return nil
+		})
+		check(t, err)
+	}
+	if tok, err := d.NextToken(); err != nil || string(tok) != "]" {
+		t.Fatalf("NextToken: got %q, %v", tok, err)
+	}
+}
+
+func TestDecoderObjectStop(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fn   func(d *Decoder, key []byte) error
+	}{
+		{"before value", func(d *Decoder, key []byte) error {
+			return ErrStop
+		}},
+		{"after value", func(d *Decoder, key []byte) error {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			return ErrStop
+		}},
+		{"inside value", func(d *Decoder, key []byte) error {
+			if _, err := d.NextToken(); err != nil {
+				return err
+			}
+			if _, err := d.NextToken(); err != nil {
+				return err
+			}
+			return ErrStop
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDecoder([]byte(`[{"a": [1, {"b": 2}], "c": {"d": [3]}}, "after"]`))
+			d.NextToken()
+			calls := 0
+			err := d.Object(func(key []byte) error {
+				calls++
+				return tc.fn(d, key)
+			})
+			check(t, err)
+			if calls != 1 {
+				t.Errorf("got %d calls, want 1", calls)
+			}
+			if s, err := d.ReadString(); err != nil || s != "after" {
+				t.Fatalf("ReadString after Object: got %q, %v", s, err)
+			}
+			if tok, err := d.NextToken(); err != nil || string(tok) != "]" {
+				t.Fatalf("NextToken: got %q, %v", tok, err)
+			}
+		})
+	}
+}
+
+func TestDecoderObjectErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	for _, tc := range []struct {
+		name  string
+		input string
+		fn    func(d *Decoder, key []byte) error
+		want  string
+	}{
+		{"callback error", `{"a": 1}`, func(d *Decoder, key []byte) error {
+			return errBoom
+		}, "boom"},
+		{"value not consumed", `{"a": 1, "b": 2}`, func(d *Decoder, key []byte) error {
+			return nil
+		}, "did not consume exactly one value"},
+		{"value partly consumed", `{"a": [1, 2]}`, func(d *Decoder, key []byte) error {
+			_, err := d.NextToken()
+			return err
+		}, "left the decoder at depth 2, want 1"},
+		{"next key consumed", `{"a": 1, "b": 2}`, func(d *Decoder, key []byte) error {
+			d.Skip()
+			_, err := d.NextToken()
+			return err
+		}, "did not consume exactly one value"},
+		{"next member consumed", `{"a": 1, "b": 2, "c": 3}`, func(d *Decoder, key []byte) error {
+			d.Skip()
+			d.NextToken()
+			return d.Skip()
+		}, `value of key at offset 1: callback did not consume exactly one value`},
+		{"nested next member consumed", `{"a": {"x": [1]}, "b": {"y": 2}}`, func(d *Decoder, key []byte) error {
+			d.Skip()
+			d.NextToken()
+			return d.Skip()
+		}, "did not consume exactly one value"},
+		{"object end consumed", `{"a": 1}`, func(d *Decoder, key []byte) error {
+			d.Skip()
+			_, err := d.NextToken()
+			return err
+		}, "left the decoder at depth 0, want 1"},
+		{"syntax error", `{"a": 1 "b": 2}`, func(d *Decoder, key []byte) error {
+			return d.Skip()
+		}, "invalid character"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDecoder([]byte(tc.input))
+			err := d.Object(func(key []byte) error { return tc.fn(d, key) })
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("got %v, want error containing %q", err, tc.want)
+			}
+		})
+	}
+
+	d := NewDecoder([]byte(`[1]`))
+	var kerr *KindError
+	if err := d.Object(nil); !errors.As(err, &kerr) || kerr.Got != KindArrayStart {
+		t.Fatalf("Object on an array: got %v, want *KindError", err)
+	}
+}
+
+func TestDecoderArrayIter(t *testing.T) {
+	d := NewDecoder([]byte(`[1, "two", [3], {"four": 4}, null] true`))
+	var got []string
+	err := d.Array(func(i int) error {
+		got = append(got, d.PeekKind().String())
+		return d.Skip()
+	})
+	check(t, err)
+	if want := `number|string|array start|object start|null`; strings.Join(got, "|") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, "|"), want)
+	}
+	if b, err := d.ReadBool(); err != nil || !b {
+		t.Errorf("ReadBool after Array: got %v, %v, want true", b, err)
+	}
+
+	// nested arrays, and an array inside an object.
+	d = NewDecoder([]byte(`{"m": [[1, 2], [], [3]]}`))
+	var sum int64
+	err = d.Object(func(key []byte) error {
+		return d.Array(func(i int) error {
+			return d.Array(func(j int) error {
+				n, err := d.ReadInt64()
+				sum += n
+				return err
+			})
+		})
+	})
+	check(t, err)
+	if sum != 6 {
+		t.Errorf("got sum %d, want 6", sum)
+	}
+}
+
+func TestDecoderArrayStop(t *testing.T) {
+	d := NewDecoder([]byte(`[[0, [1]], 1, {"a": [2]}, 3] "after"`))
+	var seen []int
+	err := d.Array(func(i int) error {
+		seen = append(seen, i)
+		if i == 1 {
+			// stop partway into the next element.
+			if err := d.Skip(); err != nil {
+				return err
+			}
+			if _, err := d.NextToken(); err != nil {
+				return err
+			}
+			return ErrStop
+		}
+		return d.Skip()
+	})
+	check(t, err)
+	if len(seen) != 2 {
+		t.Errorf("got calls for %v, want [0 1]", seen)
+	}
+	if s, err := d.ReadString(); err != nil || s != "after" {
+		t.Fatalf("ReadString after Array: got %q, %v", s, err)
+	}
+}
+
+func TestDecoderArrayErrors(t *testing.T) {
+	d := NewDecoder([]byte(`[1, 2, "three"]`))
+	err := d.Array(func(i int) error {
+		_, err := d.ReadInt64()
+		return err
+	})
+	var kerr *KindError
+	if !errors.As(err, &kerr) || !strings.HasPrefix(err.Error(), "json: Array: element 2: ") {
+		t.Fatalf("got %v, want *KindError wrapped with the element index", err)
+	}
+
+	d = NewDecoder([]byte(`[1, 2]`))
+	err = d.Array(func(i int) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "element 0: callback did not consume exactly one value") {
+		t.Fatalf("got %v, want error for unconsumed element", err)
+	}
+
+	// a callback reading two elements would otherwise skip every other one.
+	d = NewDecoder([]byte(`[1, 2, 3, 4]`))
+	calls := 0
+	err = d.Array(func(i int) error {
+		calls++
+		d.ReadInt64()
+		_, err := d.ReadInt64()
+		return err
+	})
+	if err == nil || calls != 1 || !strings.Contains(err.Error(), "element 0: callback did not consume exactly one value") {
+		t.Fatalf("got %v after %d calls, want error for an element consumed past its end", err, calls)
+	}
+	d = NewDecoder([]byte(`[[1], {"a": [2]}, 3]`))
+	err = d.Array(func(i int) error {
+		if i == 1 {
+			d.Skip()
+		}
+		return d.Skip()
+	})
+	if err == nil || !strings.Contains(err.Error(), "element 1: callback did not consume exactly one value") {
+		t.Fatalf("got %v, want error for an element consumed past its end", err)
+	}
+
+	d = NewDecoder([]byte(`[[1, 2]]`))
+	err = d.Array(func(i int) error {
+		_, err := d.NextToken()
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "left the decoder at depth 2, want 1") {
+		t.Fatalf("got %v, want error for partly consumed element", err)
+	}
+
+	d = NewDecoder([]byte(`{}`))
+	if err := d.Array(nil); !errors.As(err, &kerr) || kerr.Got != KindObjectStart {
+		t.Fatalf("Array on an object: got %v, want *KindError", err)
+	}
+}
+
+func TestDecoderArrayAllocs(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`{"id": 1, "ok": true}`)
+	}
+	sb.WriteByte(']')
+	data := []byte(sb.String())
+	d := NewDecoder(nil)
+	var n int64
+	allocs := testing.AllocsPerRun(10, func() {
+		d.Reset(data)
+		err := d.Array(func(i int) error {
+			return d.Object(func(key []byte) error {
+				if string(key) == "id" {
+					id, err := d.ReadInt64()
+					n += id
+					return err
+				}
+				return d.Skip()
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocs, want 0", allocs)
+	}
+}
+
+func TestDecoderKeys(t *testing.T) {
+	d := NewDecoder([]byte(`{"type": "a", "b\u00e9": {"nested": [1, {"x": 2}]}, "": null, "type": 3} {} [1]`))
+	keys, err := d.Keys()
+	check(t, err)
+	if want := []string{"type", "bé", "", "type"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %q, want %q", keys, want)
+	}
+	keys, err = d.Keys()
+	if err != nil || len(keys) != 0 {
+		t.Errorf("empty object: got %q, %v", keys, err)
+	}
+	var kerr *KindError
+	if _, err := d.Keys(); !errors.As(err, &kerr) || kerr.Got != KindArrayStart || kerr.Offset != 76 {
+		t.Errorf("array: got %v, want a *KindError at offset 76", err)
+	}
+	// the array was not consumed.
+	var a []int
+	check(t, d.Decode(&a))
+
+	for _, tc := range []struct{ in, err string }{
+		{`{"a": 1, "b" 2}`, "invalid character '2' after object key at line 1, column 14 (offset 13)"},
+		{`{"a": [1, 2}`, "Skip: container at offset 6: invalid character '}' in mismatched container at line 1, column 12 (offset 11)"},
+		{`{"a": "b\x"}`, "invalid character 'x' in string escape code at line 1, column 10 (offset 9)"},
+		{`{"a": 1`, "unexpected EOF"},
+	} {
+		if keys, err := NewDecoder([]byte(tc.in)).Keys(); err == nil || err.Error() != tc.err || keys != nil {
+			t.Errorf("Keys(%s): got %q, %v, want %s", tc.in, keys, err, tc.err)
+		}
+	}
+}
+
+func TestArrayEach(t *testing.T) {
+	var got []string
+	err := ArrayEach([]byte(` [1, "two", {"a": [3, "]"]}, [], null ] `), func(i int, value []byte) error {
+		if i != len(got) {
+			t.Errorf("ArrayEach: got index %d, want %d", i, len(got))
+		}
+		got = append(got, string(value))
+		return nil
+	})
+	check(t, err)
+	if want := []string{`1`, `"two"`, `{"a": [3, "]"]}`, `[]`, `null`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ArrayEach: got %q, want %q", got, want)
+	}
+
+	// stopping early.
+	n := 0
+	errFn := errors.New("fn")
+	err = ArrayEach([]byte(`[1, 2, 3, 4]`), func(i int, value []byte) error {
+		if n++; i == 1 {
+			return errFn
+		}
+		return nil
+	})
+	if !errors.Is(err, errFn) || n != 2 {
+		t.Errorf("ArrayEach: got %v after %d calls, want fn error after 2", err, n)
+	}
+	n = 0
+	err = ArrayEach([]byte(`[1, 2, {"a": [3, "]"]}]`), func(i int, value []byte) error {
+		n++
+		return ErrStop
+	})
+	if err != nil || n != 1 {
+		t.Errorf("ArrayEach: got %v after %d calls, want nil after 1", err, n)
+	}
+
+	tests := []struct {
+		input string
+		calls int
+		err   string
+	}{
+		{`[1, {"a" 2}, 3]`, 1, "json: Array: element 1: invalid character '2' after object key"},
+		{`[1, [2, 3}]`, 1, "json: Array: element 1: invalid character '}'"},
+		{`[1, "\x"]`, 1, "json: Array: element 1: invalid character 'x' in string escape code"},
+		{`[1, {"a": [2`, 1, "json: Array: element 1: unexpected end of JSON input"},
+		{`[1, 2`, 2, "unexpected end of JSON input"},
+		{`[1 2]`, 1, "invalid character '2' after array element"},
+		{`[1] 2`, 1, "invalid character '2' after top-level value"},
+		{``, 0, "unexpected end of JSON input"},
+	}
+	for _, tc := range tests {
+		n := 0
+		err := ArrayEach([]byte(tc.input), func(i int, value []byte) error {
+			n++
+			return nil
+		})
+		var serr *SyntaxError
+		if !errors.As(err, &serr) || !strings.Contains(err.Error(), tc.err) || n != tc.calls {
+			t.Errorf("ArrayEach(%s): got %v after %d calls, want *SyntaxError %q after %d", tc.input, err, n, tc.err, tc.calls)
+		}
+	}
+	var kerr *KindError
+	if err := ArrayEach([]byte(`{"a": 1}`), func(int, []byte) error { return nil }); !errors.As(err, &kerr) || kerr.Got != KindObjectStart {
+		t.Errorf("ArrayEach(object): got %v, want *KindError", err)
+	}
+}
+
+func TestObjectEach(t *testing.T) {
+	var got []string
+	err := ObjectEach([]byte(` {"type": "click", "t\u0073": 12, "data": {"x": [1, "}"]}, "e": []} `), func(key, value []byte) error {
+		got = append(got, string(key)+"="+string(value))
+		return nil
+	})
+	check(t, err)
+	if want := []string{`type="click"`, `ts=12`, `data={"x": [1, "}"]}`, `e=[]`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ObjectEach: got %q, want %q", got, want)
+	}
+
+	// routing on one member.
+	var typ string
+	err = ObjectEach([]byte(`{"type": "click", "data": {"a": [}`), func(key, value []byte) error {
+		typ = string(value)
+		return ErrStop
+	})
+	if err == nil || typ != `"click"` {
+		t.Errorf("ObjectEach: got %v, %s, want error after the type", err, typ)
+	}
+	errFn := errors.New("fn")
+	n := 0
+	err = ObjectEach([]byte(`{"a": 1, "b": 2, "c": 3}`), func(key, value []byte) error {
+		if n++; string(key) == "b" {
+			return errFn
+		}
+		return nil
+	})
+	if err != errFn || n != 2 {
+		t.Errorf("ObjectEach: got %v after %d calls, want fn error after 2", err, n)
+	}
+
+	tests := []struct {
+		input string
+		calls int
+		err   string
+	}{
+		{`{"a": 1, "b": {"c" 2}}`, 1, "invalid character '2' after object key"},
+		{`{"a": 1, "b" 2}`, 1, "invalid character '2' after object key"},
+		{`{"a": 1, "b": [2`, 1, "unexpected end of JSON input"},
+		{`{"a": 1`, 1, "unexpected end of JSON input"},
+		{`{"a": 1} x`, 1, "invalid character 'x' after top-level value"},
+	}
+	for _, tc := range tests {
+		n := 0
+		err := ObjectEach([]byte(tc.input), func(key, value []byte) error {
+			n++
+			return nil
+		})
+		var serr *SyntaxError
+		if !errors.As(err, &serr) || !strings.Contains(err.Error(), tc.err) || n != tc.calls {
+			t.Errorf("ObjectEach(%s): got %v after %d calls, want *SyntaxError %q after %d", tc.input, err, n, tc.err, tc.calls)
+		}
+	}
+	var kerr *KindError
+	if err := ObjectEach([]byte(`[1]`), func(key, value []byte) error { return nil }); !errors.As(err, &kerr) || kerr.Want != KindObjectStart {
+		t.Errorf("ObjectEach(array): got %v, want *KindError", err)
+	}
+
+	data := []byte(`{"type": "click", "id": 7, "data": {"x": [1, 2]}}`)
+	allocs := testing.AllocsPerRun(100, func() {
+		check(t, ObjectEach(data, func(key, value []byte) error { return nil }))
+	})
+	if allocs != 0 {
+		t.Errorf("ObjectEach: got %v allocs, want 0", allocs)
+	}
+}
+
+func TestDecoderSeek(t *testing.T) {
+	data := []byte(`{
+		"status": "ok",
+		"response": {
+			"meta": {"count": 3, "skip": [1, {"items": "no"}]},
+			"data": {
+				"items": [
+					{"id": 1, "tags": ["a"]},
+					{"id": 2, "tags": ["b", "c"]},
+					{"id": 3, "tags": []}
+				]
+			}
+		}
+	}`)
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{nil, string(data)},
+		{[]string{"status"}, `"ok"`},
+		{[]string{"response", "meta", "count"}, `3`},
+		{[]string{"response", "data", "items", "0", "id"}, `1`},
+		{[]string{"response", "data", "items", "1", "tags", "1"}, `"c"`},
+		{[]string{"response", "data", "items", "2"}, `{"id": 3, "tags": []}`},
+		{[]string{"response", "data", "items", "2", "tags"}, `[]`},
+	}
+	for _, tc := range tests {
+		d := NewDecoder(data)
+		if err := d.Seek(tc.path...); err != nil {
+			t.Errorf("Seek(%q): %v", tc.path, err)
+			continue
+		}
+		var got, want interface{}
+		check(t, d.Decode(&got))
+		check(t, Unmarshal([]byte(tc.want), &want))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Seek(%q): decoded %v, want %v", tc.path, got, want)
+		}
+	}
+
+	// the located value can be read with the Read methods too.
+	d := NewDecoder(data)
+	check(t, d.Seek("response", "data", "items", "1", "id"))
+	if id, err := d.ReadInt64(); err != nil || id != 2 {
+		t.Errorf("ReadInt64 after Seek: got %d, %v, want 2", id, err)
+	}
+}
+
+func TestDecoderSeekNotFound(t *testing.T) {
+	data := []byte(`{"a": {"b": [10, 20]}, "s": "str"}`)
+	for _, path := range [][]string{
+		{"x"},
+		{"a", "c"},
+		{"a", "b", "2"},
+		{"a", "b", "-1"},
+		{"a", "b", "first"},
+		{"a", "b", "0", "c"},
+		{"s", "0"},
+	} {
+		d := NewDecoder(data)
+		err := d.Seek(path...)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Seek(%q): got %v, want ErrNotFound", path, err)
+		}
+	}
+
+	// syntax errors are not reported as ErrNotFound.
+	d := NewDecoder([]byte(`{"a": 1 "b": 2}`))
+	if err := d.Seek("b"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Seek on invalid input: got %v, want syntax error", err)
+	}
+}
@@ -0,0 +1,195 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// A LineError is returned by LinesDecoder.Decode when the value on a line
+// is malformed or cannot be decoded.
+type LineError struct {
+	Line int // 1-based line number in the stream
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("json: line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// A LinesDecoder reads newline-delimited JSON (also called JSON Lines or
+// NDJSON) from an input stream: one value per line, each line ended by
+// '\n', or by "\r\n" since '\r' is whitespace. Blank lines are skipped, and
+// the last line need not end with a newline.
+//
+// Unlike a Decoder reading values one after another, a LinesDecoder requires
+// every value to be complete on its line: a value continued on the next line
+// is an error, as is a line holding more than one value. Only the current
+// line is held in memory.
+type LinesDecoder struct {
+	r    io.Reader
+	d    *Decoder
+	buf  []byte
+	pos  int   // start of the unread input in buf
+	scan int   // length of the unread input searched for a newline so far
+	err  error // error returned by r, io.EOF at the end of the input
+
+	line   int   // number of the line last read
+	offset int64 // offset in the stream of buf[0]
+}
+
+// NewLinesDecoder returns a new LinesDecoder reading from r, which decodes
+// each line as a Decoder configured by opts would.
+func NewLinesDecoder(r io.Reader, opts ...Option) *LinesDecoder {
+	d := NewDecoder(nil, opts...)
+	d.DisallowTrailingData()
+	return &LinesDecoder{r: r, d: d}
+}
+
+// Decode reads the next line holding a value and stores the value in v, as
+// Decoder.Decode does. At the end of the input it returns io.EOF.
+//
+// An error in a value is returned as a *LineError holding the line number;
+// the offset of a *SyntaxError or *UnmarshalTypeError it wraps is relative
+// to the whole stream. The rest of the line is discarded, so the next call
+// to Decode continues with the following line. Errors reading from the
+// underlying reader are returned as is.
+func (l *LinesDecoder) Decode(v interface{}) error {
+	for {
+		line, start, err := l.readLine()
+		if err != nil {
+			return err
+		}
+		l.d.Reset(line)
+		err = l.d.Decode(v)
+		if err == io.EOF {
+			continue // a blank line
+		}
+		if err != nil {
+			err = endOfInput(line, err)
+			var serr *SyntaxError
+			var terr *UnmarshalTypeError
+			if errors.As(err, &serr) {
+				serr.Offset += start
+				serr.Line = l.line
+			} else if errors.As(err, &terr) {
+				terr.Offset += start
+			}
+			return &LineError{Line: l.line, Err: err}
+		}
+		return nil
+	}
+}
+
+// Line returns the number of the line read by the last call to Decode.
+func (l *LinesDecoder) Line() int {
+	return l.line
+}
+
+// readLine returns the next line of input, without its newline, along with
+// its offset in the stream. The line refers to the buffer of l, and is only
+// valid until the next call.
+func (l *LinesDecoder) readLine() ([]byte, int64, error) {
+	for {
+		start := l.offset + int64(l.pos)
+		if i := bytes.IndexByte(l.buf[l.pos+l.scan:], '\n'); i >= 0 {
+			return l.takeLine(l.scan+i, 1), start, nil
+		}
+		l.scan = len(l.buf) - l.pos
+		if l.err != nil {
+			if l.scan == 0 {
+				return nil, 0, l.err
+			}
+			return l.takeLine(l.scan, 0), start, nil
+		}
+		if l.pos > 0 {
+			l.offset += int64(l.pos)
+			l.buf = l.buf[:copy(l.buf, l.buf[l.pos:])]
+			l.pos = 0
+		}
+		if len(l.buf) == cap(l.buf) {
+			l.buf = append(l.buf, make([]byte, max(512, len(l.buf)))...)[:len(l.buf)]
+		}
+		var n int
+		n, l.err = l.r.Read(l.buf[len(l.buf):cap(l.buf)])
+		l.buf = l.buf[:len(l.buf)+n]
+	}
+}
+
+// takeLine consumes the n bytes of the next line and the skip bytes after
+// it, and returns the line.
+func (l *LinesDecoder) takeLine(n, skip int) []byte {
+	line := l.buf[l.pos : l.pos+n]
+	l.pos += n + skip
+	l.scan = 0
+	l.line++
+	return line
+}
+
+// A LinesEncoder writes newline-delimited JSON to an output stream: each
+// value compact, on a line of its own. Newlines inside strings are always
+// escaped, so a value never spans more than one line.
+//
+// By default each value is written to the underlying io.Writer as soon as
+// it is encoded, so that a reader tailing the output sees whole records.
+// After SetAutoFlush(false), values are buffered and written by Flush, or
+// once the buffer grows large.
+type LinesEncoder struct {
+	w   io.Writer
+	buf []byte // encoded values not yet written
+	encodeState
+
+	noAutoFlush bool
+}
+
+// NewLinesEncoder returns a new LinesEncoder that writes to w.
+func NewLinesEncoder(w io.Writer) *LinesEncoder {
+	return &LinesEncoder{w: w, encodeState: encodeState{escapeHTML: true}}
+}
+
+// Encode writes the JSON encoding of v, as Encoder.Encode would without
+// indentation, followed by a newline character. Nothing is written if v
+// cannot be encoded.
+func (l *LinesEncoder) Encode(v interface{}) error {
+	n := len(l.buf)
+	b, err := l.appendValue(l.buf, reflect.ValueOf(v))
+	if err != nil {
+		l.buf = b[:n]
+		return err
+	}
+	l.buf = append(b, '\n')
+	if !l.noAutoFlush || len(l.buf) >= writerFlushSize {
+		return l.Flush()
+	}
+	return nil
+}
+
+// Flush writes any buffered values to the underlying io.Writer. If the
+// write fails, the values not written remain buffered.
+func (l *LinesEncoder) Flush() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	n, err := l.w.Write(l.buf)
+	if n < len(l.buf) && err == nil {
+		err = io.ErrShortWrite
+	}
+	l.buf = l.buf[:copy(l.buf, l.buf[n:])]
+	return err
+}
+
+// SetAutoFlush specifies whether Encode writes each value as soon as it is
+// encoded, which is the default, or leaves it buffered for Flush.
+func (l *LinesEncoder) SetAutoFlush(on bool) {
+	l.noAutoFlush = !on
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be
+// escaped inside JSON quoted strings, as for Encoder.SetEscapeHTML.
+func (l *LinesEncoder) SetEscapeHTML(on bool) {
+	l.escapeHTML = on
+}
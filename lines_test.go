@@ -0,0 +1,250 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type lineRecord struct {
+	N    int    `json:"n"`
+	Name string `json:"name"`
+}
+
+func TestLinesDecoder(t *testing.T) {
+	const n = 100000
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		switch i % 1000 {
+		case 1:
+			buf.WriteString("\n")
+		case 2:
+			buf.WriteString(" \t\r\n")
+		}
+		fmt.Fprintf(&buf, `{"n": %d, "name": "%s"}`, i, strings.Repeat("x", i%97))
+		if i%3 == 0 {
+			buf.WriteString("\r")
+		}
+		if i < n-1 {
+			buf.WriteString("\n")
+		}
+	}
+	readers := map[string]io.Reader{
+		"HalfReader":    iotest.HalfReader(bytes.NewReader(buf.Bytes())),
+		"DataErrReader": iotest.DataErrReader(bytes.NewReader(buf.Bytes())),
+		"OneByteReader": iotest.OneByteReader(bytes.NewReader(buf.Bytes())),
+	}
+	for name, r := range readers {
+		l := NewLinesDecoder(r)
+		for i := 0; ; i++ {
+			var rec lineRecord
+			err := l.Decode(&rec)
+			if err == io.EOF {
+				if i != n {
+					t.Errorf("%s: got io.EOF after %d records, want %d", name, i, n)
+				}
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: record %d: %v", name, i, err)
+			}
+			if rec.N != i || len(rec.Name) != i%97 {
+				t.Fatalf("%s: got %+v, want record %d", name, rec, i)
+			}
+		}
+		if err := l.Decode(new(lineRecord)); err != io.EOF {
+			t.Errorf("%s: Decode after the end: got %v, want io.EOF", name, err)
+		}
+	}
+}
+
+func TestLinesDecoderErrors(t *testing.T) {
+	input := strings.Join([]string{
+		`{"n": 1}`,
+		``,
+		`{"n": 2,`,
+		`"name": "a"}`,
+		`{"n": 3} {"n": 4}`,
+		`{"n": "5"}`,
+		`{"n": 6}`,
+	}, "\n")
+	want := []struct {
+		n    int
+		line int
+		err  string
+	}{
+		{n: 1},
+		{line: 3, err: "json: line 3: unexpected end of JSON input at line 3, column 9 (offset 18)"},
+		{line: 4, err: "json: line 4: json: cannot decode string into Go value of type json.lineRecord at offset 19, path $"},
+		{line: 5, err: "json: line 5: invalid character '{' after top-level value at line 5, column 10 (offset 41)"},
+		{line: 6, err: "json: line 6: json: cannot decode string into Go struct field lineRecord.n of type int at offset 56, path $.n"},
+		{n: 6},
+	}
+	l := NewLinesDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	for _, w := range want {
+		var rec lineRecord
+		err := l.Decode(&rec)
+		if w.err == "" {
+			if err != nil || rec.N != w.n {
+				t.Errorf("got %+v, %v, want record %d", rec, err, w.n)
+			}
+			continue
+		}
+		var lerr *LineError
+		if !errors.As(err, &lerr) || lerr.Line != w.line || err.Error() != w.err {
+			t.Errorf("got %v, want %s", err, w.err)
+		}
+		if l.Line() != w.line {
+			t.Errorf("Line: got %d, want %d", l.Line(), w.line)
+		}
+	}
+	if err := l.Decode(new(lineRecord)); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+
+	r := iotest.TimeoutReader(strings.NewReader("{}\n{}\n"))
+	l = NewLinesDecoder(r)
+	for i := 0; i < 2; i++ {
+		if err := l.Decode(new(lineRecord)); err != nil {
+			t.Errorf("got %v, want nil", err)
+		}
+	}
+	if err := l.Decode(new(lineRecord)); err != iotest.ErrTimeout {
+		t.Errorf("got %v, want %v", err, iotest.ErrTimeout)
+	}
+}
+
+func TestLinesDecoderOptions(t *testing.T) {
+	l := NewLinesDecoder(strings.NewReader("[1, 2,]\n// note\n[3]"), AllowTrailingCommas(), AllowComments())
+	var got [][]int
+	for {
+		var v []int
+		err := l.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if fmt.Sprint(got) != "[[1 2] [3]]" {
+		t.Errorf("got %v, want [[1 2] [3]]", got)
+	}
+}
+
+type indentedMarshaler struct{}
+
+func (indentedMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte("{\n  \"a\": [\n    1\n  ]\n}"), nil
+}
+
+func TestLinesEncoder(t *testing.T) {
+	values := []interface{}{
+		"line\nbreak",
+		"carriage\rreturn\r\n",
+		"separators\u2028\u2029",
+		map[string]string{"key\nwith newline": "value\n"},
+		[]interface{}{"a\n", map[string]interface{}{"b": "\n\n"}},
+		indentedMarshaler{},
+		json.RawMessage("[\n1,\n2\n]"),
+		struct {
+			S string `json:"s\n"`
+		}{"\n"},
+		nil,
+	}
+	var buf bytes.Buffer
+	l := NewLinesEncoder(&buf)
+	for _, v := range values {
+		check(t, l.Encode(v))
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != len(values)+1 || lines[len(values)] != "" {
+		t.Fatalf("got %d lines, want %d records and a final newline:\n%s", len(lines)-1, len(values), buf.String())
+	}
+	for i, line := range lines[:len(values)] {
+		want, err := Marshal(values[i])
+		check(t, err)
+		if line != string(want) {
+			t.Errorf("line %d: got %s, want %s", i+1, line, want)
+		}
+		if strings.ContainsAny(line, "\r\u2028\u2029") {
+			t.Errorf("line %d: got %q, want line terminators escaped", i+1, line)
+		}
+	}
+
+	d := NewLinesDecoder(&buf)
+	for i := range values {
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			t.Errorf("record %d: %v", i, err)
+		}
+	}
+	if err := d.Decode(new(interface{})); err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+}
+
+func TestLinesEncoderFlush(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLinesEncoder(&buf)
+	check(t, l.Encode(1))
+	if buf.String() != "1\n" {
+		t.Errorf("got %q, want the record written", buf.String())
+	}
+
+	l.SetAutoFlush(false)
+	check(t, l.Encode(2))
+	if err := l.Encode(math.NaN()); err == nil {
+		t.Errorf("Encode(NaN): got nil error")
+	}
+	check(t, l.Encode(3))
+	if buf.String() != "1\n" {
+		t.Errorf("got %q, want records buffered", buf.String())
+	}
+	check(t, l.Flush())
+	if buf.String() != "1\n2\n3\n" {
+		t.Errorf("got %q, want %q", buf.String(), "1\n2\n3\n")
+	}
+
+	buf.Reset()
+	s := strings.Repeat("x", 1000)
+	for i := 0; buf.Len() == 0; i++ {
+		if i == writerFlushSize/len(s)+1 {
+			t.Fatalf("no output after %d records", i)
+		}
+		check(t, l.Encode(s))
+	}
+
+	w := &shortWriter{n: 3}
+	l = NewLinesEncoder(w)
+	l.SetAutoFlush(false)
+	check(t, l.Encode("abcdef"))
+	if err := l.Flush(); err != io.ErrShortWrite {
+		t.Errorf("got %v, want io.ErrShortWrite", err)
+	}
+	w.n = 100
+	check(t, l.Flush())
+	if w.buf.String() != "\"abcdef\"\n" {
+		t.Errorf("got %q, want %q", w.buf.String(), "\"abcdef\"\n")
+	}
+}
+
+// shortWriter writes at most n bytes per call, without an error.
+type shortWriter struct {
+	buf bytes.Buffer
+	n   int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		p = p[:w.n]
+	}
+	return w.buf.Write(p)
+}
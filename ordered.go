@@ -0,0 +1,173 @@
+package json
+
+import "reflect"
+
+// A Member is a member of an OrderedMap.
+type Member struct {
+	Key   string
+	Value interface{}
+}
+
+// An OrderedMap is a JSON object which keeps its members in the order they
+// were decoded or set, so that a document can be decoded, edited and
+// encoded again without reordering it. Decode fills an OrderedMap
+// destination with the members of an object, and with UseOrderedMaps the
+// objects nested in it, and in any interface{}, are decoded as OrderedMaps
+// too. The Encoder writes the members in order; a nil OrderedMap is null.
+//
+// An object with duplicate keys is decoded into a single member, which has
+// the last value given for the key at the position of the first.
+//
+// Get and Set search the members in order, which is fast for the small
+// objects typical of configuration but linear in the number of members.
+type OrderedMap []Member
+
+var orderedMapType = reflect.TypeOf(OrderedMap(nil))
+
+// Get returns the value of the member with the given key, and whether there
+// is one.
+func (m OrderedMap) Get(key string) (interface{}, bool) {
+	if i := m.index(key); i >= 0 {
+		return m[i].Value, true
+	}
+	return nil, false
+}
+
+// Set sets the value of the member with the given key, which keeps its
+// position, or appends a new member if there is none.
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if i := m.index(key); i >= 0 {
+		(*m)[i].Value = value
+		return
+	}
+	*m = append(*m, Member{Key: key, Value: value})
+}
+
+// Delete removes the member with the given key, if there is one, keeping
+// the order of the others.
+func (m *OrderedMap) Delete(key string) {
+	if i := m.index(key); i >= 0 {
+		*m = append((*m)[:i], (*m)[i+1:]...)
+	}
+}
+
+// Keys returns the keys of the members in order.
+func (m OrderedMap) Keys() []string {
+	keys := make([]string, len(m))
+	for i, mem := range m {
+		keys[i] = mem.Key
+	}
+	return keys
+}
+
+func (m OrderedMap) index(key string) int {
+	for i := range m {
+		if m[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// UseOrderedMaps causes Decode to store an object decoded into an
+// interface{} as an OrderedMap rather than a map[string]interface{}, which
+// applies to the objects nested in an OrderedMap too.
+func (d *Decoder) UseOrderedMaps() { d.orderedMaps = true }
+
+// UseOrderedMaps returns an Option which calls Decoder.UseOrderedMaps.
+func UseOrderedMaps() Option { return (*Decoder).UseOrderedMaps }
+
+// decodeObjectAny decodes the members of an object, whose opening brace has
+// already been consumed, into a new map[string]interface{}, or OrderedMap if
+// UseOrderedMaps has been called.
+func (d *Decoder) decodeObjectAny() (interface{}, error) {
+	if d.orderedMaps {
+		m, err := d.decodeOrderedMap(nil)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	m, err := d.decodeMapAny(nil)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// orderedIndexMin is the number of members past which decodeOrderedMap
+// finds duplicate keys with a map rather than by searching the members.
+const orderedIndexMin = 16
+
+// decodeOrderedMap decodes the members of an object, whose opening brace
+// has already been consumed, into m, as Set would add them. If m is nil a
+// new OrderedMap is allocated, so that an empty object is not null.
+func (d *Decoder) decodeOrderedMap(m OrderedMap) (OrderedMap, error) {
+	if m == nil {
+		m = OrderedMap{}
+	}
+	var index map[string]int
+	for {
+		tok, err := d.NextToken()
+		if err != nil {
+			return nil, err
+		}
+		if tok[0] == ObjectEnd {
+			return m, nil
+		}
+		key, err := d.unquoteKey(tok)
+		if err != nil {
+			return nil, err
+		}
+		val, err := d.decodeValueAny()
+		if err != nil {
+			return nil, err
+		}
+		if index == nil && len(m) >= orderedIndexMin {
+			index = make(map[string]int, 2*len(m))
+			for i := len(m) - 1; i >= 0; i-- {
+				index[m[i].Key] = i
+			}
+		}
+		i := -1
+		if index != nil {
+			if j, ok := index[key]; ok {
+				i = j
+			} else {
+				index[key] = len(m)
+			}
+		} else {
+			i = m.index(key)
+		}
+		if i >= 0 {
+			m[i].Value = val
+		} else {
+			m = append(m, Member{Key: key, Value: val})
+		}
+	}
+}
+
+// appendOrderedMap appends v, an OrderedMap, to b as an object.
+func (e *encodeState) appendOrderedMap(b []byte, v reflect.Value) ([]byte, error) {
+	if v.IsNil() {
+		return append(b, "null"...), nil
+	}
+	if err := e.enter(v); err != nil {
+		return b, err
+	}
+	defer e.leave(v)
+	m := v.Interface().(OrderedMap)
+	b = append(b, '{')
+	for i, mem := range m {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendString(b, mem.Key, e.escapeHTML)
+		b = append(b, ':')
+		var err error
+		if b, err = e.appendValue(b, reflect.ValueOf(mem.Value)); err != nil {
+			return b, err
+		}
+	}
+	return append(b, '}'), nil
+}
@@ -0,0 +1,147 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	in := `{"z": 1, "a": {"y": [{"q": null, "p": true}], "b": "x"}, "m": [], "z": 2}`
+	var m OrderedMap
+	check(t, Unmarshal([]byte(in), &m))
+	want := OrderedMap{
+		{"z", 2.0},
+		{"a", map[string]interface{}{"y": []interface{}{map[string]interface{}{"q": nil, "p": true}}, "b": "x"}},
+		{"m", []interface{}{}},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+
+	var v interface{}
+	check(t, NewDecoder([]byte(in), UseOrderedMaps()).Decode(&v))
+	want = OrderedMap{
+		{"z", 2.0},
+		{"a", OrderedMap{{"y", []interface{}{OrderedMap{{"q", nil}, {"p", true}}}}, {"b", "x"}}},
+		{"m", []interface{}{}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("UseOrderedMaps: got %v, want %v", v, want)
+	}
+	b, err := Marshal(v)
+	check(t, err)
+	if got := string(b); got != `{"z":2,"a":{"y":[{"q":null,"p":true}],"b":"x"},"m":[]}` {
+		t.Errorf("Marshal: got %s", got)
+	}
+
+	m = OrderedMap{{"b", 1}, {"a", 2}}
+	m.Set("c", 3)
+	m.Set("b", 4)
+	m.Delete("a")
+	m.Delete("x")
+	if x, ok := m.Get("b"); !ok || x != 4 {
+		t.Errorf("Get(b): got %v, %t, want 4", x, ok)
+	}
+	if _, ok := m.Get("a"); ok {
+		t.Errorf("Get(a): got a deleted member")
+	}
+	if b, err := Marshal(m); err != nil || string(b) != `{"b":4,"c":3}` {
+		t.Errorf("Marshal: got %s, %v, want %s", b, err, `{"b":4,"c":3}`)
+	}
+
+	// decoding into an OrderedMap adds to its members, as for a map.
+	m = OrderedMap{{"x", 1.0}, {"y", 2.0}}
+	check(t, Unmarshal([]byte(`{"y": 3, "w": 4}`), &m))
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"x", "y", "w"}) || m[1].Value != 3.0 {
+		t.Errorf("got %v, want x, y=3, w", m)
+	}
+
+	// with many members, duplicates are found through an index.
+	var big bytes.Buffer
+	big.WriteString("{")
+	for i := 0; i < 3*orderedIndexMin; i++ {
+		big.WriteString(`"k` + strconv.Itoa(i) + `": 1, `)
+	}
+	big.WriteString(`"k0": 2, "kz": 3, "kz": 4}`)
+	m = nil
+	check(t, Unmarshal(big.Bytes(), &m))
+	if len(m) != 3*orderedIndexMin+1 || m[0].Value != 2.0 || m[len(m)-1] != (Member{"kz", 4.0}) {
+		t.Errorf("got %v", m)
+	}
+
+	var s struct {
+		M  OrderedMap  `json:"m"`
+		P  *OrderedMap `json:"p"`
+		N  OrderedMap  `json:"n"`
+		Is []int       `json:"is"`
+	}
+	check(t, Unmarshal([]byte(`{"m": {"b": 1, "a": 2}, "p": {"c": 3}, "n": null}`), &s))
+	if s.M.Keys()[0] != "b" || s.P == nil || (*s.P)[0].Key != "c" || s.N != nil {
+		t.Errorf("got %+v", s)
+	}
+	if b, err := Marshal(s); err != nil || string(b) != `{"m":{"b":1,"a":2},"p":{"c":3},"n":null,"is":null}` {
+		t.Errorf("Marshal: got %s, %v", b, err)
+	}
+	if err := Unmarshal([]byte(`[1]`), &m); err == nil {
+		t.Errorf("Unmarshal of an array: got nil error")
+	}
+}
+
+func TestOrderedMapRoundTrip(t *testing.T) {
+	// the numbers and strings of these fixtures are written as the Encoder
+	// writes them, so only whitespace may change.
+	verbatim := map[string]bool{"twitter": true, "code": true}
+	for _, tc := range inputs {
+		t.Run(tc.path, func(t *testing.T) {
+			data, err := io.ReadAll(fixture(t, tc.path))
+			check(t, err)
+			var v interface{}
+			check(t, NewDecoder(data, UseOrderedMaps()).Decode(&v))
+			var got bytes.Buffer
+			enc := NewEncoder(&got)
+			enc.SetEscapeHTML(false)
+			check(t, enc.Encode(v))
+
+			// the numbers are rounded to float64 both ways.
+			var gotValue, wantValue interface{}
+			check(t, json.Unmarshal(got.Bytes(), &gotValue))
+			check(t, json.Unmarshal(data, &wantValue))
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Fatalf("got a different document")
+			}
+			if !reflect.DeepEqual(keyOrder(t, got.Bytes()), keyOrder(t, data)) {
+				t.Errorf("key order differs")
+			}
+			if verbatim[tc.path] {
+				var want bytes.Buffer
+				check(t, json.Compact(&want, data))
+				want.WriteByte('\n')
+				if !bytes.Equal(got.Bytes(), want.Bytes()) {
+					t.Errorf("got output differing from the input by more than whitespace")
+				}
+			}
+		})
+	}
+}
+
+// keyOrder returns the object keys of data in the order they appear.
+func keyOrder(t *testing.T, data []byte) []string {
+	var keys []string
+	d := NewDecoder(data)
+	for {
+		tok, err := d.NextToken()
+		if err == io.EOF {
+			return keys
+		}
+		check(t, err)
+		if tok[0] == String && d.len() > 0 && d.top().obj && d.top().keyEnd == d.getOffset() {
+			key, err := d.unquote(tok)
+			check(t, err)
+			keys = append(keys, key)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTestFailed is returned, wrapped in a *PatchError, when a JSON Patch
+// "test" operation finds a value different from the one given.
+var ErrTestFailed = errors.New("json: patch test failed")
+
+// A PatchError is returned by ApplyPatch when an operation of the patch is
+// malformed or cannot be applied.
+type PatchError struct {
+	Index int    // index of the operation in the patch
+	Op    string // the operation, such as "add", if known
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	if e.Op == "" {
+		return fmt.Sprintf("json: patch operation %d: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("json: patch operation %d (%s): %v", e.Index, e.Op, e.Err)
+}
+
+func (e *PatchError) Unwrap() error { return e.Err }
+
+// ApplyPatch applies the JSON Patch patch, as defined by RFC 6902, to the
+// document doc and returns the result. The operations add, remove, replace,
+// move, copy and test are supported, with their paths given as JSON
+// pointers; the path "/a/-" of add refers to the end of the array a.
+//
+// The patch is atomic: if any operation fails, ApplyPatch returns a
+// *PatchError holding its index and the reason, and no result. A failed test
+// operation wraps ErrTestFailed, and a path that does not exist wraps
+// ErrNotFound.
+//
+// Both doc and patch must be valid. Each operation splices bytes into a copy
+// of the document, so the parts of doc it does not touch are kept byte for
+// byte, along with their whitespace, and doc itself is never modified.
+func ApplyPatch(doc, patch []byte) ([]byte, error) {
+	if err := Validate(doc); err != nil {
+		return nil, err
+	}
+	if err := Validate(patch); err != nil {
+		return nil, err
+	}
+	err := ArrayEach(patch, func(i int, raw []byte) error {
+		var op patchOp
+		err := op.parse(raw)
+		if err == nil {
+			doc, err = op.apply(doc)
+		}
+		if err != nil {
+			return &PatchError{Index: i, Op: op.op, Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// patchOp is an operation of a JSON Patch.
+type patchOp struct {
+	op         string
+	path, from []string
+	value      []byte // nil if missing
+
+	pointer, fromPointer string
+	hasPath, hasFrom     bool
+}
+
+// parse reads the operation from the object raw. Unknown members are
+// ignored, as RFC 6902 requires.
+func (op *patchOp) parse(raw []byte) error {
+	if raw[0] != ObjectStart {
+		return errors.New("operation is not an object")
+	}
+	seen := map[string]bool{}
+	err := ObjectEach(raw, func(key, value []byte) error {
+		k := string(key)
+		switch k {
+		case "op", "path", "from", "value":
+		default:
+			return nil
+		}
+		if seen[k] {
+			return fmt.Errorf("duplicate member %q", k)
+		}
+		seen[k] = true
+		var err error
+		switch k {
+		case "op":
+			err = Unmarshal(value, &op.op)
+		case "path":
+			if err = Unmarshal(value, &op.pointer); err == nil {
+				op.path, err = parsePointer(op.pointer)
+				op.hasPath = true
+			}
+		case "from":
+			if err = Unmarshal(value, &op.fromPointer); err == nil {
+				op.from, err = parsePointer(op.fromPointer)
+				op.hasFrom = true
+			}
+		case "value":
+			op.value = value
+		}
+		if err != nil {
+			return fmt.Errorf("member %q: %w", k, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	switch op.op {
+	case "add", "replace", "test":
+		if op.value == nil {
+			return errors.New(`missing member "value"`)
+		}
+	case "move", "copy":
+		if !op.hasFrom {
+			return errors.New(`missing member "from"`)
+		}
+	case "remove":
+	case "":
+		return errors.New(`missing member "op"`)
+	default:
+		return fmt.Errorf("unknown operation %q", op.op)
+	}
+	if !op.hasPath {
+		return errors.New(`missing member "path"`)
+	}
+	return nil
+}
+
+// apply returns the result of applying op to doc.
+func (op *patchOp) apply(doc []byte) ([]byte, error) {
+	switch op.op {
+	case "add":
+		return patchAdd(doc, op.path, op.value)
+	case "remove":
+		return patchRemove(doc, op.path)
+	case "replace":
+		if len(op.path) == 0 {
+			return append([]byte(nil), op.value...), nil
+		}
+		loc, err := patchLocate(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		return splice(doc, loc.value, op.value), nil
+	case "move":
+		if op.fromPointer == op.pointer {
+			return doc, nil
+		}
+		if strings.HasPrefix(op.pointer, op.fromPointer+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		value, err := GetPointer(doc, op.fromPointer)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = patchRemove(doc, op.from); err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, op.path, value)
+	case "copy":
+		value, err := GetPointer(doc, op.fromPointer)
+		if err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, op.path, value)
+	default: // test
+		value, err := GetPointer(doc, op.pointer)
+		if err != nil {
+			return nil, err
+		}
+		if ok, err := Equal(value, op.value); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, fmt.Errorf("%w: value at %q differs", ErrTestFailed, op.pointer)
+		}
+		return doc, nil
+	}
+}
+
+// patchLocate finds the existing member or element of doc at path.
+func patchLocate(doc []byte, path []string) (location, error) {
+	loc, err := locate(doc, path, true)
+	if err != nil {
+		return loc, err
+	}
+	if !loc.found {
+		if !loc.obj && loc.index < 0 {
+			return loc, ErrPointerPastEnd
+		}
+		if loc.obj {
+			return loc, fmt.Errorf("%w: no such key", ErrNotFound)
+		}
+		return loc, fmt.Errorf("%w: index out of range", ErrNotFound)
+	}
+	return loc, nil
+}
+
+// patchAdd returns doc with value added at path: replacing an object
+// member, or inserted into an array before the element at path.
+func patchAdd(doc []byte, path []string, value []byte) ([]byte, error) {
+	if len(path) == 0 {
+		return append([]byte(nil), value...), nil
+	}
+	loc, err := locate(doc, path, true)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case loc.obj && loc.found:
+		return splice(doc, loc.value, value), nil
+	case loc.obj:
+		return loc.insert(doc, appendMember(nil, path[len(path)-1], value)), nil
+	case !loc.found && loc.index > loc.n:
+		return nil, fmt.Errorf("%w: index out of range", ErrNotFound)
+	default:
+		return loc.insert(doc, value), nil
+	}
+}
+
+// patchRemove returns doc with the member or element at path removed.
+func patchRemove(doc []byte, path []string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	loc, err := patchLocate(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	return splice(doc, loc.remove), nil
+}
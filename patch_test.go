@@ -0,0 +1,180 @@
+package json
+
+import (
+	"errors"
+	"testing"
+)
+
+// rfc6902Tests are the examples of RFC 6902, Appendix A. An empty want means
+// that the patch fails.
+var rfc6902Tests = []struct {
+	name, doc, patch, want string
+}{
+	{"A.1", `{"foo": "bar"}`,
+		`[{"op": "add", "path": "/baz", "value": "qux"}]`,
+		`{"baz": "qux", "foo": "bar"}`},
+	{"A.2", `{"foo": ["bar", "baz"]}`,
+		`[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+		`{"foo": ["bar", "qux", "baz"]}`},
+	{"A.3", `{"baz": "qux", "foo": "bar"}`,
+		`[{"op": "remove", "path": "/baz"}]`,
+		`{"foo": "bar"}`},
+	{"A.4", `{"foo": ["bar", "qux", "baz"]}`,
+		`[{"op": "remove", "path": "/foo/1"}]`,
+		`{"foo": ["bar", "baz"]}`},
+	{"A.5", `{"baz": "qux", "foo": "bar"}`,
+		`[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+		`{"baz": "boo", "foo": "bar"}`},
+	{"A.6", `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+		`[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+		`{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`},
+	{"A.7", `{"foo": ["all", "grass", "cows", "eat"]}`,
+		`[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+		`{"foo": ["all", "cows", "eat", "grass"]}`},
+	{"A.8", `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		`[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+		`{"baz": "qux", "foo": ["a", 2, "c"]}`},
+	{"A.9", `{"baz": "qux"}`,
+		`[{"op": "test", "path": "/baz", "value": "bar"}]`,
+		``},
+	{"A.10", `{"foo": "bar"}`,
+		`[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+		`{"foo": "bar", "child": {"grandchild": {}}}`},
+	{"A.11", `{"foo": "bar"}`,
+		`[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+		`{"foo": "bar", "baz": "qux"}`},
+	{"A.12", `{"foo": "bar"}`,
+		`[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+		``},
+	{"A.13", `{"foo": "bar"}`,
+		`[{"op": "add", "path": "/baz", "value": "qux", "op": "remove"}]`,
+		``},
+	{"A.14", `{"/": 9, "~1": 10}`,
+		`[{"op": "test", "path": "/~01", "value": 10}]`,
+		`{"/": 9, "~1": 10}`},
+	{"A.15", `{"/": 9, "~1": 10}`,
+		`[{"op": "test", "path": "/~01", "value": "10"}]`,
+		``},
+	{"A.16", `{"foo": ["bar"]}`,
+		`[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+		`{"foo": ["bar", ["abc", "def"]]}`},
+}
+
+func TestApplyPatchRFC6902(t *testing.T) {
+	for _, tc := range rfc6902Tests {
+		got, err := ApplyPatch([]byte(tc.doc), []byte(tc.patch))
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s: got %s, want error", tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if ok, err := Equal(got, []byte(tc.want)); err != nil || !ok {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		// Untouched parts of the document keep their formatting.
+		{"{\n  \"a\": [ 1, 2 ],\n  \"b\": {}\n}",
+			`[{"op": "add", "path": "/b/c", "value": true}]`,
+			"{\n  \"a\": [ 1, 2 ],\n  \"b\": {\"c\":true}\n}"},
+		{"{\n  \"a\": [ 1, 2 ],\n  \"b\": {}\n}",
+			`[{"op": "remove", "path": "/a/0"}]`,
+			"{\n  \"a\": [ 2 ],\n  \"b\": {}\n}"},
+		{"{\n  \"a\": [ 1, 2 ],\n  \"b\": {}\n}",
+			`[{"op": "remove", "path": "/a/1"}]`,
+			"{\n  \"a\": [ 1 ],\n  \"b\": {}\n}"},
+		{"{\n  \"a\": [ 1, 2 ],\n  \"b\": {}\n}",
+			`[{"op": "remove", "path": "/b"}]`,
+			"{\n  \"a\": [ 1, 2 ]\n}"},
+		{"{\n  \"a\": [ 1, 2 ],\n  \"b\": {}\n}",
+			`[{"op": "remove", "path": "/a"}]`,
+			"{\n  \"b\": {}\n}"},
+		{`{ "a": 1 }`, `[{"op": "remove", "path": "/a"}]`, `{  }`},
+		{`{"a": [1, 2]}`, `[{"op": "add", "path": "/a/2", "value": 3}]`, `{"a": [1, 2,3]}`},
+		{`{"a": [1, 2]}`, `[{"op": "add", "path": "/a/0", "value": 0}]`, `{"a": [0,1, 2]}`},
+		{`{"a": []}`, `[{"op": "add", "path": "/a/-", "value": 0}]`, `{"a": [0]}`},
+		{`{"a": []}`, `[{"op": "add", "path": "/a/0", "value": 0}]`, `{"a": [0]}`},
+		{`{"a": 1}`, `[{"op": "add", "path": "/a", "value": 2}]`, `{"a": 2}`},
+		{`{}`, `[{"op": "add", "path": "/a\"b", "value": 2}]`, `{"a\"b":2}`},
+		{`{"a": 1}`, `[{"op": "add", "path": "", "value": [1]}]`, `[1]`},
+		{`{"a": 1}`, `[{"op": "replace", "path": "", "value": null}]`, `null`},
+		{`{"a": {"b": 1}}`, `[{"op": "copy", "from": "/a", "path": "/c"}]`, `{"a": {"b": 1},"c":{"b": 1}}`},
+		{`{"a": 1}`, `[{"op": "move", "from": "/a", "path": "/a"}]`, `{"a": 1}`},
+		{`{"a": 1, "b": 2}`, `[{"op": "move", "from": "/a", "path": "/b"}]`, `{"b": 1}`},
+		{`[1, 2]`, `[{"op": "test", "path": "", "value": [1, 2.0]}]`, `[1, 2]`},
+		{`[1, 2]`, `[]`, `[1, 2]`},
+		{`{"a": {"b": [1, {"c": 2}]}}`,
+			`[{"op": "replace", "path": "/a/b/1/c", "value": 3}, {"op": "remove", "path": "/a/b/0"}]`,
+			`{"a": {"b": [{"c": 3}]}}`},
+	}
+	for _, tc := range tests {
+		doc := []byte(tc.doc)
+		got, err := ApplyPatch(doc, []byte(tc.patch))
+		if err != nil || string(got) != tc.want {
+			t.Errorf("ApplyPatch(%s, %s): got %s, %v, want %s", tc.doc, tc.patch, got, err, tc.want)
+		}
+		if string(doc) != tc.doc {
+			t.Errorf("ApplyPatch(%s, %s) modified the document: %s", tc.doc, tc.patch, doc)
+		}
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		doc, patch string
+		index      int
+		op         string
+		err        error
+	}{
+		{`{"a": 1}`, `[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/a"}]`, 1, "remove", ErrNotFound},
+		{`{"a": 1}`, `[{"op": "add", "path": "/b", "value": 2}, {"op": "test", "path": "/b", "value": 3}]`, 1, "test", ErrTestFailed},
+		{`{"a": [1]}`, `[{"op": "add", "path": "/a/2", "value": 2}]`, 0, "add", ErrNotFound},
+		{`{"a": [1]}`, `[{"op": "add", "path": "/a/01", "value": 2}]`, 0, "add", ErrNotFound},
+		{`{"a": [1]}`, `[{"op": "replace", "path": "/a/-", "value": 2}]`, 0, "replace", ErrPointerPastEnd},
+		{`{"a": [1]}`, `[{"op": "remove", "path": "/a/-"}]`, 0, "remove", ErrPointerPastEnd},
+		{`{"a": 1}`, `[{"op": "add", "path": "/a/b", "value": 2}]`, 0, "add", ErrNotFound},
+		{`{"a": 1}`, `[{"op": "copy", "from": "/b", "path": "/c"}]`, 0, "copy", ErrNotFound},
+		{`{"a": {}}`, `[{"op": "move", "from": "/a", "path": "/a/b"}]`, 0, "move", nil},
+		{`{"a": 1}`, `[{"op": "remove", "path": ""}]`, 0, "remove", nil},
+		{`{"a": 1}`, `[{"op": "add", "path": "/b"}]`, 0, "add", nil},
+		{`{"a": 1}`, `[{"op": "move", "path": "/b"}]`, 0, "move", nil},
+		{`{"a": 1}`, `[{"op": "remove"}]`, 0, "remove", nil},
+		{`{"a": 1}`, `[{"path": "/a"}]`, 0, "", nil},
+		{`{"a": 1}`, `[{"op": "frobnicate", "path": "/a"}]`, 0, "frobnicate", nil},
+		{`{"a": 1}`, `[{"op": "remove", "path": "a"}]`, 0, "remove", nil},
+		{`{"a": 1}`, `[{"op": 1, "path": "/a"}]`, 0, "", nil},
+		{`{"a": 1}`, `[[]]`, 0, "", nil},
+	}
+	for _, tc := range tests {
+		got, err := ApplyPatch([]byte(tc.doc), []byte(tc.patch))
+		var pe *PatchError
+		if !errors.As(err, &pe) {
+			t.Errorf("ApplyPatch(%s, %s): got %s, %v, want *PatchError", tc.doc, tc.patch, got, err)
+			continue
+		}
+		if got != nil || pe.Index != tc.index || pe.Op != tc.op || tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("ApplyPatch(%s, %s): got %s, %v (index %d, op %q), want index %d, op %q, %v",
+				tc.doc, tc.patch, got, err, pe.Index, pe.Op, tc.index, tc.op, tc.err)
+		}
+	}
+
+	for _, tc := range []struct{ doc, patch string }{
+		{`{"a": 1`, `[]`},
+		{`{"a": 1}`, `[{"op": "remove", "path": "/a"}`},
+		{`{"a": 1}`, `{"op": "remove", "path": "/a"}`},
+	} {
+		if got, err := ApplyPatch([]byte(tc.doc), []byte(tc.patch)); err == nil {
+			t.Errorf("ApplyPatch(%s, %s): got %s, want error", tc.doc, tc.patch, got)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package json
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Depth returns the number of arrays and objects the Decoder is inside,
+// after the most recently returned token. An array or object start counts
+// the container it opens, and an end does not count the one it closes.
+func (d *Decoder) Depth() int {
+	return d.len()
+}
+
+// Path returns the location of the most recently returned token, as a
+// JSONPath expression such as $.items[3].user.name, where $ is the top-level
+// value. The location of an object key is that of its value, and the
+// location of an array or object start or end is that of the container.
+// Keys which are not identifiers are written in brackets, as in
+// $["content-type"].
+//
+// The Decoder tracks its position without allocating; only Path itself
+// allocates. After Skip or NextAsBytes the path is that of the value
+// skipped.
+func (d *Decoder) Path() string {
+	b := make([]byte, 0, 64)
+	b = append(b, '$')
+	for i, f := range d.stack {
+		switch {
+		case f.obj && f.keyEnd > 0:
+			if f.key < d.scanner.base {
+				// Skip has discarded the input holding the key.
+				b = appendPathKey(b, d.savedKeys[i].tok)
+				break
+			}
+			b = appendPathKey(b, d.keyToken(f))
+		case !f.obj && f.index >= 0:
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(f.index), 10)
+			b = append(b, ']')
+		}
+	}
+	return string(b)
+}
+
+// keyToken returns the current key token of the object f as a standard
+// string token, rescanning it if it was written in a relaxed form.
+func (d *Decoder) keyToken(f frame) []byte {
+	tok := d.scanner.span(f.key, f.keyEnd)
+	if tok[0] == String {
+		return tok
+	}
+	s := Scanner{data: d.scanner.data, base: d.scanner.base, offset: f.key, flags: d.scanner.flags}
+	return s.Next()
+}
+
+// A savedKey is a copy of the key token at offset in the input.
+type savedKey struct {
+	offset int
+	tok    []byte
+}
+
+// saveKeys copies the current key tokens of the open objects which are not
+// copied yet, before the input holding them may be discarded.
+func (d *Decoder) saveKeys() {
+	for len(d.savedKeys) < d.len() {
+		d.savedKeys = append(d.savedKeys, savedKey{})
+	}
+	for i, f := range d.stack {
+		if k := &d.savedKeys[i]; f.obj && f.keyEnd > 0 && f.key >= d.scanner.base && k.offset != f.key {
+			k.offset, k.tok = f.key, append(k.tok[:0], d.keyToken(f)...)
+		}
+	}
+}
+
+// appendPathKey appends the path element for the key token tok to b.
+func appendPathKey(b, tok []byte) []byte {
+	key := tok[1 : len(tok)-1]
+	if bytes.IndexByte(key, '\\') >= 0 {
+		key, _ = unescape(nil, tok, true)
+	}
+	if !isIdentifier(key) {
+		b = append(b, '[')
+		b = appendString(b, bytesToString(key), false)
+		return append(b, ']')
+	}
+	b = append(b, '.')
+	return append(b, key...)
+}
+
+// isIdentifier reports whether key can be written after a dot in a path:
+// an ASCII letter or underscore followed by letters, digits or underscores.
+func isIdentifier(key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for i, c := range key {
+		switch {
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecoderPath(t *testing.T) {
+	input := `{"items": [1, {"user": {"name": "x"}}, [], [true, {}]], "content-type": "a", "b": null}`
+	want := []string{
+		`{ $ 1`,
+		`"items" $.items 1`,
+		`[ $.items 2`,
+		`1 $.items[0] 2`,
+		`{ $.items[1] 3`,
+		`"user" $.items[1].user 3`,
+		`{ $.items[1].user 4`,
+		`"name" $.items[1].user.name 4`,
+		`"x" $.items[1].user.name 4`,
+		`} $.items[1].user 3`,
+		`} $.items[1] 2`,
+		`[ $.items[2] 3`,
+		`] $.items[2] 2`,
+		`[ $.items[3] 3`,
+		`true $.items[3][0] 3`,
+		`{ $.items[3][1] 4`,
+		`} $.items[3][1] 3`,
+		`] $.items[3] 2`,
+		`] $.items 1`,
+		`"content-type" $["content-type"] 1`,
+		`"a" $["content-type"] 1`,
+		`"b" $.b 1`,
+		`null $.b 1`,
+		`} $ 0`,
+	}
+	d := NewDecoder([]byte(input))
+	if got := d.Path(); got != "$" || d.Depth() != 0 {
+		t.Errorf("before the first token: got %s %d, want $ 0", got, d.Depth())
+	}
+	for _, w := range want {
+		tok, err := d.NextToken()
+		check(t, err)
+		if got := strings.Join([]string{string(tok), d.Path(), string(rune('0' + d.Depth()))}, " "); got != w {
+			t.Errorf("got %s, want %s", got, w)
+		}
+	}
+}
+
+func TestDecoderPathSkip(t *testing.T) {
+	d := NewDecoder([]byte(`[{"a": [1, 2]}, {"b": 1}]`))
+	d.NextToken()
+	check(t, d.Skip())
+	if got := d.Path(); got != "$[0]" {
+		t.Errorf("after Skip: got %s, want $[0]", got)
+	}
+	check(t, d.Seek("b"))
+	if got := d.Path(); got != "$[1].b" {
+		t.Errorf("after Seek: got %s, want $[1].b", got)
+	}
+	if _, err := d.NextAsBytes(); err != nil || d.Path() != "$[1].b" {
+		t.Errorf("after NextAsBytes: got %s, %v, want $[1].b", d.Path(), err)
+	}
+}
+
+func TestDecoderPathUnread(t *testing.T) {
+	d := NewDecoder([]byte(`{"a": [1, 1.5], "b\x": 1}`))
+	d.NextToken()
+	d.ReadString()
+	d.NextToken()
+	if _, err := d.ReadInt64(); err != nil || d.Path() != "$.a[0]" {
+		t.Fatalf("ReadInt64: got %s, %v, want $.a[0]", d.Path(), err)
+	}
+	// a number which is not converted is left unread, and so is its index.
+	if _, err := d.ReadInt64(); err == nil || d.Path() != "$.a[0]" {
+		t.Fatalf("ReadInt64: got %s, %v, want $.a[0] and an error", d.Path(), err)
+	}
+	d.NextToken()
+	d.NextToken()
+	// likewise a key with an invalid escape.
+	if _, err := d.ReadString(); err == nil || d.Path() != "$.a" {
+		t.Fatalf("ReadString: got %s, %v, want $.a and an error", d.Path(), err)
+	}
+}
+
+func TestDecoderPathAllocs(t *testing.T) {
+	data := []byte(`{"items": [{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": []}]}`)
+	d := NewDecoder(nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		d.Reset(data)
+		for {
+			if _, err := d.NextToken(); err != nil {
+				break
+			}
+			_ = d.Depth()
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("NextToken: got %v allocs, want 0", allocs)
+	}
+}
+
+func TestAppendPathKey(t *testing.T) {
+	tests := []struct {
+		tok, want string
+	}{
+		{`"name"`, `.name`},
+		{`"_x1"`, `._x1`},
+		{`"n\u0061me"`, `.name`},
+		{`"1st"`, `["1st"]`},
+		{`""`, `[""]`},
+		{`"a.b"`, `["a.b"]`},
+		{`"café"`, `["café"]`},
+		{`"q\"<"`, `["q\"<"]`},
+	}
+	for _, tc := range tests {
+		if got := string(appendPathKey(nil, []byte(tc.tok))); got != tc.want {
+			t.Errorf("appendPathKey(%s): got %s, want %s", tc.tok, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPointerPastEnd is returned, wrapped, when a JSON pointer uses the array
+// index "-", which refers to the nonexistent element after the last one.
+var ErrPointerPastEnd = errors.New("json: pointer refers to the element past the end of an array")
+
+// SeekPointer is like Seek, but the path is given as a JSON pointer, as
+// defined by RFC 6901, such as "/foo/0/a~1b". The empty pointer refers to
+// the next value itself.
+//
+// An array index must be a decimal number without leading zeros. The index
+// "-" results in an error wrapping ErrPointerPastEnd.
+func (d *Decoder) SeekPointer(pointer string) error {
+	path, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	for n, elem := range path {
+		if err := d.seekElem(elem, true); err != nil {
+			return fmt.Errorf("SeekPointer %s: %w", pointer[:pointerPrefix(pointer, n+1)], err)
+		}
+	}
+	return nil
+}
+
+// parsePointer splits the JSON pointer p into its reference tokens, with
+// ~1 and ~0 decoded.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("json: invalid JSON pointer %q: must start with /", p)
+	}
+	path := strings.Split(p[1:], "/")
+	for i, tok := range path {
+		if !strings.Contains(tok, "~") {
+			continue
+		}
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || tok[j+1] != '0' && tok[j+1] != '1') {
+				return nil, fmt.Errorf("json: invalid JSON pointer %q: ~ must be followed by 0 or 1", p)
+			}
+		}
+		path[i] = pointerUnescaper.Replace(tok)
+	}
+	return path, nil
+}
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// pointerPrefix returns the length of the part of pointer p made up of its
+// first n reference tokens.
+func pointerPrefix(p string, n int) int {
+	i := 0
+	for ; n > 0; n-- {
+		j := strings.IndexByte(p[i+1:], '/')
+		if j < 0 {
+			return len(p)
+		}
+		i += 1 + j
+	}
+	return i
+}
+
+// checkPointerIndex returns an error if tok is not an array index as
+// allowed by RFC 6901.
+func checkPointerIndex(tok string) error {
+	if tok == "-" {
+		return ErrPointerPastEnd
+	}
+	if tok == "" || len(tok) > 1 && tok[0] == '0' {
+		return fmt.Errorf("%w: invalid array index %q", ErrNotFound, tok)
+	}
+	for i := 0; i < len(tok); i++ {
+		if tok[i] < '0' || tok[i] > '9' {
+			return fmt.Errorf("%w: invalid array index %q", ErrNotFound, tok)
+		}
+	}
+	return nil
+}
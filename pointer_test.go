@@ -0,0 +1,101 @@
+package json
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// rfc6901Doc is the example document from section 5 of RFC 6901.
+const rfc6901Doc = `{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8
+}`
+
+func TestGetPointer(t *testing.T) {
+	tests := []struct {
+		pointer, want string
+	}{
+		{"", rfc6901Doc},
+		{"/foo", `["bar", "baz"]`},
+		{"/foo/0", `"bar"`},
+		{"/", `0`},
+		{"/a~1b", `1`},
+		{"/c%d", `2`},
+		{"/e^f", `3`},
+		{"/g|h", `4`},
+		{"/i\\j", `5`},
+		{"/k\"l", `6`},
+		{"/ ", `7`},
+		{"/m~0n", `8`},
+	}
+	for _, tc := range tests {
+		got, err := GetPointer([]byte(rfc6901Doc), tc.pointer)
+		if err != nil {
+			t.Errorf("GetPointer(%q): %v", tc.pointer, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("GetPointer(%q): got %s, want %s", tc.pointer, got, tc.want)
+		}
+	}
+}
+
+func TestSeekPointer(t *testing.T) {
+	data := []byte(`{"a": [{"~/": [10, 11, {"": "deep"}]}]}`)
+	d := NewDecoder(data)
+	check(t, d.SeekPointer("/a/0/~0~1/2/"))
+	if s, err := d.ReadString(); err != nil || s != "deep" {
+		t.Errorf("ReadString after SeekPointer: got %q, %v", s, err)
+	}
+
+	// ~01 is ~1, not /.
+	got, err := GetPointer([]byte(`{"~1": 1, "/": 2}`), "/~01")
+	if err != nil || string(got) != "1" {
+		t.Errorf(`GetPointer("/~01"): got %s, %v, want 1`, got, err)
+	}
+}
+
+func TestGetPointerErrors(t *testing.T) {
+	data := []byte(`{"a": [10, 11], "b": {"0": "zero"}}`)
+	for _, tc := range []struct {
+		pointer string
+		want    error
+		msg     string
+	}{
+		{"/x", ErrNotFound, "SeekPointer /x: "},
+		{"/a/2", ErrNotFound, "SeekPointer /a/2: "},
+		{"/a/01", ErrNotFound, "invalid array index"},
+		{"/a/+1", ErrNotFound, "invalid array index"},
+		{"/a/1e0", ErrNotFound, "invalid array index"},
+		{"/a/", ErrNotFound, "invalid array index"},
+		{"/a/0/x", ErrNotFound, "SeekPointer /a/0/x: "},
+		{"/a/-", ErrPointerPastEnd, "SeekPointer /a/-: "},
+		{"/b/00", ErrNotFound, "no such key"},
+	} {
+		_, err := GetPointer(data, tc.pointer)
+		if !errors.Is(err, tc.want) || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("GetPointer(%q): got %v, want %v containing %q", tc.pointer, err, tc.want, tc.msg)
+		}
+	}
+
+	// object keys that look like indexes are not subject to the array rules.
+	if got, err := GetPointer(data, "/b/0"); err != nil || string(got) != `"zero"` {
+		t.Errorf(`GetPointer("/b/0"): got %s, %v`, got, err)
+	}
+
+	for _, p := range []string{"a", "/a~", "/a~2"} {
+		_, err := GetPointer(data, p)
+		if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "invalid JSON pointer") {
+			t.Errorf("GetPointer(%q): got %v, want invalid pointer error", p, err)
+		}
+	}
+}
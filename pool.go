@@ -0,0 +1,66 @@
+package json
+
+import "sync"
+
+// decoderPool holds Decoders with the default options, for GetDecoder and
+// the package-level functions, so that they do not allocate one per call.
+var decoderPool = sync.Pool{
+	New: func() interface{} { return NewDecoder(nil) },
+}
+
+// GetDecoder returns a Decoder reading data, configured by opts, as
+// NewDecoder does, but taken from a pool of Decoders released by PutDecoder,
+// so that its stack and buffers are reused rather than allocated. To decode
+// from an io.Reader, pass nil data and call ResetReader; the buffer it reads
+// into is pooled too.
+func GetDecoder(data []byte, opts ...Option) *Decoder {
+	d := decoderPool.Get().(*Decoder)
+	d.Reset(data)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// maxPooledBuffer is the largest capacity of a buffer PutDecoder keeps for
+// reuse. As in the encoders of encoding/json, a larger one is dropped, so
+// that decoding one large document does not pin its memory in the pool.
+const maxPooledBuffer = 64 << 10
+
+// PutDecoder returns d to the pool used by GetDecoder. d drops its
+// references to the input and its options are restored to their defaults.
+// Neither d, nor any []byte returned by its methods which does not refer to
+// the data d was given, may be used after the call; in particular the input
+// read by ResetReader and the strings unescaped by ReadStringBytes are
+// zeroed when d is released, and their buffers are reused unless larger
+// than 64 KiB.
+func PutDecoder(d *Decoder) {
+	// the recorded keys refer to the input.
+	clear(d.keyStack[:cap(d.keyStack)])
+	clear(d.seenKeys)
+	*d = Decoder{
+		scanner: Scanner{
+			quoted:   reusable(d.scanner.quoted),
+			brackets: d.scanner.brackets[:0],
+		},
+		state:    (*Decoder).stateValue,
+		stack:    d.stack[:0],
+		buf:      reusable(d.readBuf()),
+		scratch:  reusable(d.scratch),
+		maxDepth: DefaultMaxDepth,
+		seenKeys: d.seenKeys,
+		keyStack: d.keyStack[:0],
+	}
+	decoderPool.Put(d)
+}
+
+// reusable returns b emptied for reuse by a pooled Decoder, with what it
+// held zeroed so that no input outlives its Decoder's use, or nil if b is
+// too large to keep.
+func reusable(b []byte) []byte {
+	if cap(b) > maxPooledBuffer {
+		return nil
+	}
+	clear(b[:cap(b)])
+	return b[:0]
+}
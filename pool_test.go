@@ -0,0 +1,72 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetDecoder(t *testing.T) {
+	var v map[string]interface{}
+	d := GetDecoder([]byte(`{"a": /* one */ 1}`), AllowComments(), (*Decoder).DisallowDuplicateKeys)
+	check(t, d.Decode(&v))
+	if v["a"] != 1.0 {
+		t.Errorf("Decode: got %v", v)
+	}
+	check(t, d.ResetReader(strings.NewReader(`{"b": [2]}`)))
+	check(t, d.Decode(&v))
+	if len(v) != 2 {
+		t.Errorf("Decode after ResetReader: got %v", v)
+	}
+	PutDecoder(d)
+
+	// a released Decoder holds no references to the input, and has the
+	// default options.
+	if d.scanner.data != nil || d.len() != 0 || len(d.buf) != 0 || len(d.seenKeys) != 0 || d.scanner.flags != 0 ||
+		d.disallowDuplicateKeys || d.maxDepth != DefaultMaxDepth {
+		t.Errorf("PutDecoder: got %+v", d)
+	}
+	for _, k := range d.keyStack[:cap(d.keyStack)] {
+		if k.name != "" {
+			t.Errorf("PutDecoder: key %q retained", k.name)
+		}
+	}
+
+	// whether or not it is reused, a Decoder from the pool is like a new one.
+	for i := 0; i < 3; i++ {
+		d := GetDecoder([]byte(`{"a": /* one */ 1}`))
+		if err := d.Decode(&v); err == nil {
+			t.Errorf("Decode: expected error for comment without AllowComments")
+		}
+		PutDecoder(d)
+	}
+}
+
+func TestPutDecoderBuffers(t *testing.T) {
+	// the buffers holding input and unescaped strings are zeroed.
+	d := GetDecoder(nil)
+	check(t, d.ResetReader(strings.NewReader(`["secret\n"]`)))
+	check(t, d.Array(func(int) error {
+		_, err := d.ReadStringBytes()
+		return err
+	}))
+	buf, scratch := d.readBuf(), d.scratch[:cap(d.scratch)]
+	buf = buf[:cap(buf)]
+	PutDecoder(d)
+	for _, b := range [][]byte{buf, scratch} {
+		if strings.Contains(string(b), "secret") {
+			t.Errorf("PutDecoder: buffer still holds %q", b)
+		}
+	}
+
+	// buffers larger than maxPooledBuffer are dropped.
+	d = GetDecoder(nil)
+	big := `"` + strings.Repeat("\\t", maxPooledBuffer) + `"`
+	check(t, d.ResetReader(strings.NewReader(big)))
+	if _, err := d.ReadStringBytes(); err != nil {
+		t.Fatal(err)
+	}
+	PutDecoder(d)
+	if d.buf != nil || d.scratch != nil {
+		t.Errorf("PutDecoder: kept buffers of capacity %d and %d", cap(d.buf), cap(d.scratch))
+	}
+}
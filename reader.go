@@ -0,0 +1,162 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// ReadInt64 consumes the next value, which must be a number, and returns it
+// as an int64. If the next token is not a number, ReadInt64 returns a
+// *KindError and does not consume it. If the number is not an integer or
+// overflows an int64, ReadInt64 returns an error and does not consume it.
+func (d *Decoder) ReadInt64() (int64, error) {
+	m := d.mark()
+	tok, err := d.readToken(KindNumber)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(bytesToString(tok), 10, 64)
+	if err != nil {
+		return 0, d.unreadToken(m, err)
+	}
+	return i, nil
+}
+
+// ReadUint64 consumes the next value, which must be a number, and returns it
+// as a uint64. It reports errors as ReadInt64 does.
+func (d *Decoder) ReadUint64() (uint64, error) {
+	m := d.mark()
+	tok, err := d.readToken(KindNumber)
+	if err != nil {
+		return 0, err
+	}
+	u, err := strconv.ParseUint(bytesToString(tok), 10, 64)
+	if err != nil {
+		return 0, d.unreadToken(m, err)
+	}
+	return u, nil
+}
+
+// ReadFloat64 consumes the next value, which must be a number, and returns
+// it as a float64. It reports errors as ReadInt64 does.
+func (d *Decoder) ReadFloat64() (float64, error) {
+	m := d.mark()
+	tok, err := d.readToken(KindNumber)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(bytesToString(tok), 64)
+	if err != nil {
+		return 0, d.unreadToken(m, err)
+	}
+	return f, nil
+}
+
+// ReadBool consumes the next value, which must be true or false, and returns
+// it. If the next token is not a bool, ReadBool returns a *KindError and does
+// not consume it.
+func (d *Decoder) ReadBool() (bool, error) {
+	tok, err := d.readToken(KindBool)
+	if err != nil {
+		return false, err
+	}
+	return tok[0] == True, nil
+}
+
+// ReadString consumes the next string, which may be a value or an object
+// key, and returns its contents with escape sequences decoded. If the next
+// token is not a string, ReadString returns a *KindError and does not
+// consume it.
+func (d *Decoder) ReadString() (string, error) {
+	b, err := d.ReadStringBytes()
+	return string(b), err
+}
+
+// ReadStringBytes is like ReadString but returns the contents of the string
+// without converting them to a string. If the string contains no escape
+// sequences the result refers to the input; otherwise it refers to a buffer
+// the Decoder reuses. Either way it is only valid until the next call to the
+// Decoder.
+func (d *Decoder) ReadStringBytes() ([]byte, error) {
+	m := d.mark()
+	tok, err := d.readToken(KindString)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(tok, '\\') < 0 {
+		return tok[1 : len(tok)-1], nil
+	}
+	d.scratch, err = d.unescapeToken(d.scratch[:0], tok)
+	if err != nil {
+		d.rewind(m)
+		return nil, err
+	}
+	return d.scratch, nil
+}
+
+// readToken consumes and returns the next token if it is of kind want.
+// Otherwise the token is left in place and a *KindError is returned.
+func (d *Decoder) readToken(want Kind) ([]byte, error) {
+	if got := d.PeekKind(); got != want && got != KindInvalid {
+		return nil, &KindError{Want: want, Got: got, Offset: int64(d.peekOffset())}
+	}
+	// an invalid token, or the end of the input, is reported by NextToken.
+	return d.NextToken()
+}
+
+// peekOffset returns the offset of the first byte of the next token, looking
+// past whitespace and a comma or colon separator as peek does.
+func (d *Decoder) peekOffset() int {
+	i := d.scanner.skipSpace(d.scanner.offset)
+	if c := d.scanner.at(i); c == Comma || c == Colon {
+		i = d.scanner.skipSpace(i + 1)
+	}
+	return i
+}
+
+// A mark records the position of the Decoder before a scalar token is read,
+// so that the token can be unread.
+type mark struct {
+	offset int
+	state  func(*Decoder) ([]byte, error)
+	top    frame
+	values int
+}
+
+// mark returns the current position of the Decoder.
+func (d *Decoder) mark() mark {
+	m := mark{offset: d.scanner.offset, state: d.state, values: d.values}
+	if d.len() > 0 {
+		m.top = *d.top()
+	}
+	return m
+}
+
+// rewind returns the Decoder to m, which must have been taken at the same
+// depth. Whitespace before a top-level value may have been discarded since
+// m was taken, reading from an io.Reader, so it is not returned to.
+func (d *Decoder) rewind(m mark) {
+	d.scanner.offset, d.state, d.values = max(m.offset, d.scanner.keep), m.state, m.values
+	if d.len() > 0 {
+		*d.top() = m.top
+	}
+}
+
+// unreadToken returns the decoder to m, from before the token just read, and
+// returns err describing why it could not be converted.
+func (d *Decoder) unreadToken(m mark, err error) error {
+	start := d.scanner.start
+	d.rewind(m)
+	return fmt.Errorf("json: cannot convert number at offset %d: %w", start, err)
+}
+
+// unescapeToken appends the decoded contents of the string token tok, most
+// recently returned by the scanner, to dst.
+func (d *Decoder) unescapeToken(dst, tok []byte) ([]byte, error) {
+	dst, i := unescape(dst, tok, d.allowLoneSurrogates)
+	if i >= 0 {
+		return dst, d.scanner.syntaxError(d.scanner.start+i, escapeError(tok, i))
+	}
+	return dst, nil
+}
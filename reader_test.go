@@ -0,0 +1,247 @@
+package json
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestDecoderRead(t *testing.T) {
+	d := NewDecoder([]byte(`{"a": -12, "b": 18446744073709551615, "c": 1.5e3, "d": true, "e": false, "f\u00e9": "x\"\n\u263a\ud83d\ude00\/"}`))
+	mustToken := func(want string) {
+		t.Helper()
+		tok, err := d.NextToken()
+		check(t, err)
+		if string(tok) != want {
+			t.Fatalf("NextToken: got %q, want %q", tok, want)
+		}
+	}
+	mustKey := func(want string) {
+		t.Helper()
+		key, err := d.ReadString()
+		check(t, err)
+		if key != want {
+			t.Fatalf("ReadString: got %q, want %q", key, want)
+		}
+	}
+
+	mustToken("{")
+	mustKey("a")
+	i, err := d.ReadInt64()
+	check(t, err)
+	if i != -12 {
+		t.Errorf("ReadInt64: got %d, want -12", i)
+	}
+	mustKey("b")
+	u, err := d.ReadUint64()
+	check(t, err)
+	if u != math.MaxUint64 {
+		t.Errorf("ReadUint64: got %d, want %d", u, uint64(math.MaxUint64))
+	}
+	mustKey("c")
+	f, err := d.ReadFloat64()
+	check(t, err)
+	if f != 1500 {
+		t.Errorf("ReadFloat64: got %v, want 1500", f)
+	}
+	mustKey("d")
+	b, err := d.ReadBool()
+	check(t, err)
+	if !b {
+		t.Errorf("ReadBool: got false, want true")
+	}
+	mustKey("e")
+	b, err = d.ReadBool()
+	check(t, err)
+	if b {
+		t.Errorf("ReadBool: got true, want false")
+	}
+	mustKey("fé")
+	s, err := d.ReadString()
+	check(t, err)
+	if want := "x\"\n\u263a\U0001f600/"; s != want {
+		t.Errorf("ReadString: got %q, want %q", s, want)
+	}
+	mustToken("}")
+	if _, err := d.ReadString(); err != io.EOF {
+		t.Errorf("ReadString at end of input: got %v, want io.EOF", err)
+	}
+}
+
+func TestDecoderReadKindMismatch(t *testing.T) {
+	d := NewDecoder([]byte(`["one", 2, true]`))
+	if _, err := d.NextToken(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := d.ReadInt64()
+	var kerr *KindError
+	if !errors.As(err, &kerr) {
+		t.Fatalf("ReadInt64: got %v, want *KindError", err)
+	}
+	if kerr.Want != KindNumber || kerr.Got != KindString || kerr.Offset != 1 {
+		t.Errorf("ReadInt64: got %+v, want {Want:number Got:string Offset:1}", *kerr)
+	}
+	s, err := d.ReadString()
+	check(t, err)
+	if s != "one" {
+		t.Fatalf("ReadString: got %q, want %q", s, "one")
+	}
+
+	_, err = d.ReadBool()
+	if !errors.As(err, &kerr) || kerr.Got != KindNumber || kerr.Offset != 8 {
+		t.Fatalf("ReadBool: got %v, want *KindError for number at offset 8", err)
+	}
+	i, err := d.ReadInt64()
+	check(t, err)
+	if i != 2 {
+		t.Fatalf("ReadInt64: got %d, want 2", i)
+	}
+
+	if _, err := d.ReadString(); !errors.As(err, &kerr) || kerr.Got != KindBool {
+		t.Fatalf("ReadString: got %v, want *KindError for bool", err)
+	}
+	b, err := d.ReadBool()
+	check(t, err)
+	if !b {
+		t.Fatal("ReadBool: got false, want true")
+	}
+	if _, err := d.ReadBool(); !errors.As(err, &kerr) || kerr.Got != KindArrayEnd {
+		t.Fatalf("ReadBool: got %v, want *KindError for array end", err)
+	}
+	tok, err := d.NextToken()
+	check(t, err)
+	if string(tok) != "]" {
+		t.Fatalf("NextToken: got %q, want %q", tok, "]")
+	}
+}
+
+func TestDecoderReadConversionError(t *testing.T) {
+	tests := []struct {
+		input string
+		read  func(d *Decoder) error
+	}{
+		{`[1, 1.5]`, func(d *Decoder) error { _, err := d.ReadInt64(); return err }},
+		{`[1, 9223372036854775808]`, func(d *Decoder) error { _, err := d.ReadInt64(); return err }},
+		{`[1, -1]`, func(d *Decoder) error { _, err := d.ReadUint64(); return err }},
+		{`[1, 1e400]`, func(d *Decoder) error { _, err := d.ReadFloat64(); return err }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			d := NewDecoder([]byte(tc.input))
+			if _, err := d.NextToken(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := d.ReadInt64(); err != nil {
+				t.Fatal(err)
+			}
+			err := tc.read(d)
+			if !errors.Is(err, strconv.ErrSyntax) && !errors.Is(err, strconv.ErrRange) {
+				t.Fatalf("got %v, want a wrapped strconv error", err)
+			}
+			// the number was not consumed.
+			tok, err := d.NextToken()
+			check(t, err)
+			if want := tc.input[4 : len(tc.input)-1]; string(tok) != want {
+				t.Fatalf("NextToken: got %q, want %q", tok, want)
+			}
+			if tok, err = d.NextToken(); err != nil || string(tok) != "]" {
+				t.Fatalf("NextToken: got %q, %v, want %q", tok, err, "]")
+			}
+		})
+	}
+}
+
+func TestDecoderReadStringInvalidEscape(t *testing.T) {
+	d := NewDecoder([]byte(`"a\qb"`))
+	_, err := d.ReadString()
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Offset != 3 {
+		t.Fatalf("ReadString: got %v, want *SyntaxError at offset 3", err)
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		in, want string
+		lenient  bool
+	}{
+		{in: `""`, want: ""},
+		{in: `"plain"`, want: "plain"},
+		{in: `"\"\\\/\b\f\n\r\t"`, want: "\"\\/\b\f\n\r\t"},
+		{in: `"\u0041\u00e9\u263A"`, want: "A\u00e9\u263a"},
+		{in: `"\ud83d\ude00"`, want: "\U0001f600"},
+		{in: `"\ud83d"`, want: "\ufffd", lenient: true},
+		{in: `"\ud83dx"`, want: "\ufffdx", lenient: true},
+		{in: `"\ude00\ud83d\ude00"`, want: "\ufffd\U0001f600", lenient: true},
+		{in: `"\ud83d\u0041"`, want: "\ufffdA", lenient: true},
+	}
+	for _, tc := range tests {
+		got, i := unescape(nil, []byte(tc.in), tc.lenient)
+		if i >= 0 || string(got) != tc.want {
+			t.Errorf("unescape(%s): got %q, %d, want %q", tc.in, got, i, tc.want)
+		}
+		if !tc.lenient {
+			continue
+		}
+		if _, i := unescape(nil, []byte(tc.in), false); i < 0 {
+			t.Errorf("unescape(%s) not lenient: expected error", tc.in)
+		}
+	}
+	for _, in := range []string{`"\x"`, `"\u12"`, `"\u12g4"`, `"ok\"`} {
+		if _, i := unescape(nil, []byte(in), true); i < 0 {
+			t.Errorf("unescape(%s): expected error", in)
+		}
+	}
+}
+
+func TestDecoderReadStringBytes(t *testing.T) {
+	data := []byte(`{"plain": "value", "esc\u0061ped": "a\tb"}`)
+	d := NewDecoder(data)
+	if _, err := d.NextToken(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"plain", "value", "escaped", "a\tb"}
+	for i, w := range want {
+		b, err := d.ReadStringBytes()
+		check(t, err)
+		if string(b) != w {
+			t.Fatalf("ReadStringBytes: got %q, want %q", b, w)
+		}
+		// escape-free strings are returned in place.
+		if i < 2 && &b[0] != &data[d.scanner.start+1] {
+			t.Errorf("ReadStringBytes(%q): result does not refer to the input", b)
+		}
+	}
+}
+
+func TestDecoderReadStringBytesAllocs(t *testing.T) {
+	data := []byte(`{"id": "abc", "name": "x\ny", "tags": ["a", "b\u00e9"]}`)
+	d := NewDecoder(nil)
+	// the scratch buffer for the escaped strings is allocated by the
+	// warm-up run and reused after that.
+	allocs := testing.AllocsPerRun(100, func() {
+		d.Reset(data)
+		d.NextToken()
+		for d.More() {
+			if _, err := d.ReadStringBytes(); err != nil {
+				t.Fatal(err)
+			}
+			if d.PeekKind() == KindArrayStart {
+				d.NextToken()
+				for d.More() {
+					d.ReadStringBytes()
+				}
+				d.NextToken()
+				continue
+			}
+			if _, err := d.ReadStringBytes(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("ReadStringBytes: got %v allocs, want 0", allocs)
+	}
+}
@@ -1,5 +1,15 @@
 package json
 
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
 const (
 	ObjectStart = '{' // {
 	ObjectEnd   = '}' // }
@@ -13,45 +23,300 @@ const (
 	Null        = 'n' // n
 )
 
+// Kind describes the type of a JSON token.
+type Kind uint8
+
+const (
+	KindInvalid     Kind = iota // not a valid token, or no token at all
+	KindObjectStart             // {
+	KindObjectEnd               // }
+	KindArrayStart              // [
+	KindArrayEnd                // ]
+	KindString                  // "
+	KindNumber                  // -, 0-9
+	KindBool                    // true, false
+	KindNull                    // null
+	KindColon                   // :
+	KindComma                   // ,
+)
+
+var kindNames = [...]string{
+	KindInvalid:     "invalid",
+	KindObjectStart: "object start",
+	KindObjectEnd:   "object end",
+	KindArrayStart:  "array start",
+	KindArrayEnd:    "array end",
+	KindString:      "string",
+	KindNumber:      "number",
+	KindBool:        "bool",
+	KindNull:        "null",
+	KindColon:       "colon",
+	KindComma:       "comma",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// kinds maps the first byte of a token to its Kind.
+var kinds = [256]Kind{
+	ObjectStart: KindObjectStart,
+	ObjectEnd:   KindObjectEnd,
+	ArrayStart:  KindArrayStart,
+	ArrayEnd:    KindArrayEnd,
+	String:      KindString,
+	Colon:       KindColon,
+	Comma:       KindComma,
+	True:        KindBool,
+	False:       KindBool,
+	Null:        KindNull,
+	'-':         KindNumber,
+	'0':         KindNumber,
+	'1':         KindNumber,
+	'2':         KindNumber,
+	'3':         KindNumber,
+	'4':         KindNumber,
+	'5':         KindNumber,
+	'6':         KindNumber,
+	'7':         KindNumber,
+	'8':         KindNumber,
+	'9':         KindNumber,
+}
+
 // NewScanner returns a new Scanner for given []byte
-// A Scanner produces a stream of tokens
+// A Scanner produces a stream of tokens. A UTF-8 byte order mark at the start
+// of data is skipped; offsets remain relative to data.
 func NewScanner(data []byte) *Scanner {
+	n := bomLen(data)
 	return &Scanner{
-		data: data,
+		data:   data,
+		offset: n,
+		start:  n,
+	}
+}
+
+// reset makes the Scanner scan data, as a new one would, keeping its flags
+// and the buffers it reuses.
+func (s *Scanner) reset(data []byte) {
+	n := bomLen(data)
+	*s = Scanner{data: data, offset: n, start: n, flags: s.flags, quoted: s.quoted, brackets: s.brackets, maxString: s.maxString}
+}
+
+// bom is the UTF-8 encoding of U+FEFF, the byte order mark some editors write
+// at the start of a file.
+const bom = "\xef\xbb\xbf"
+
+// bomLen returns the length of the byte order mark data starts with, or 0.
+func bomLen(data []byte) int {
+	if len(data) >= len(bom) && string(data[:len(bom)]) == bom {
+		return len(bom)
 	}
+	return 0
 }
 
 // Scanner implements a JSON scanner as defined in RFC 7159.
 type Scanner struct {
 	data   []byte
 	offset int
+	start  int   // offset of the first byte of the last token
+	err    error // first error encountered, if any
+
+	flags  scanFlags // extensions to standard JSON which are accepted
+	quoted []byte    // relaxed string token rewritten in standard form
+
+	brackets []byte // bracket stack of skipContainer, retained across calls
+
+	// data is the first bytes of a longer input, cut at the Decoder's
+	// maximum input size, so reaching its end means the input is too large.
+	truncated bool
+
+	// While the input is read from an io.Reader, r is the rest of it, and
+	// data holds what has been read and not yet discarded. Offsets are
+	// relative to the whole input, data[0] being at offset base, and the
+	// bytes before keep may be discarded to make room for a read.
+	r         io.Reader
+	rerr      error // error reading r, other than io.EOF
+	base      int
+	keep      int
+	size      int // maximum size of the input, or 0 for no limit
+	lines     int // number of newlines before base
+	lineStart int // offset just past the last newline before base
+
+	// while reading, the longest string, and the largest value from
+	// valueStart on, read before a *LimitError stops the Scanner, or 0 for
+	// no limit. While discard is set, a value is skipped rather than
+	// scanned, and its input discarded as it is read.
+	maxString  int
+	maxValue   int
+	valueStart int
+	discard    bool
+}
+
+// minRead is the least room more makes at the end of the buffer for a read.
+const minRead = 512
+
+// more reads more of the input into data, and reports whether it did. It
+// returns false once the input is exhausted, or reading it has failed, and
+// always if the Scanner was not given an io.Reader. The bytes before keep
+// are discarded, when that makes enough room, rather than the buffer grown.
+func (s *Scanner) more() bool {
+	if s.r == nil {
+		return false
+	}
+	if s.maxValue > 0 && s.end()-s.valueStart > s.maxValue {
+		// the value is over the limit: stop, rather than read the rest.
+		s.rerr, s.r = &LimitError{What: "value", Len: s.end() - s.valueStart, Limit: s.maxValue, Offset: int64(s.valueStart)}, nil
+		return false
+	}
+	if cap(s.data)-len(s.data) < minRead {
+		s.compact()
+	}
+	for i := 0; i < 100; i++ {
+		p := s.data[len(s.data):cap(s.data)]
+		if s.size > 0 {
+			// read no more than needed to tell the input is too large.
+			p = p[:min(len(p), s.size+1-s.end())]
+		}
+		n, err := s.r.Read(p)
+		s.data = s.data[:len(s.data)+n]
+		if s.size > 0 && s.end() > s.size {
+			s.data = s.data[:s.size-s.base]
+			s.truncated, s.r = true, nil
+			return n > 1
+		}
+		if err != nil {
+			if err != io.EOF {
+				s.rerr = err
+			}
+			s.r = nil
+			return n > 0
+		}
+		if n > 0 {
+			if s.base == 0 && len(s.data) < len(bom) && string(s.data) == bom[:len(s.data)] {
+				// read on past the end of a byte order mark, for
+				// skipSpace to skip it whole.
+				continue
+			}
+			return true
+		}
+	}
+	s.rerr, s.r = io.ErrNoProgress, nil
+	return false
 }
 
-var whitespace = [256]bool{
-	' ':  true,
-	'\r': true,
-	'\n': true,
-	'\t': true,
+// compact makes room for a read at the end of the buffer, discarding the
+// bytes before keep, and moving the rest to a new buffer of twice the size
+// if they would fill more than half of it.
+func (s *Scanner) compact() {
+	n := s.keep - s.base
+	discarded := s.data[:n]
+	if i := bytes.LastIndexByte(discarded, '\n'); i >= 0 {
+		s.lines += bytes.Count(discarded, []byte{'\n'})
+		s.lineStart = s.base + i + 1
+	}
+	buf := s.data[:0]
+	if live := len(s.data) - n; live > cap(s.data)/2 || cap(s.data)-live < minRead {
+		buf = make([]byte, 0, 2*cap(s.data)+minRead)
+	}
+	s.data = append(buf, s.data[n:]...)
+	s.base = s.keep
+}
+
+// end returns the offset just past the input read so far.
+func (s *Scanner) end() int {
+	return s.base + len(s.data)
+}
+
+// window returns the data from offset i on, after reading more of the input
+// until it holds at least n bytes from i or the input is exhausted.
+func (s *Scanner) window(i, n int) []byte {
+	for s.end()-i < n && s.more() {
+	}
+	return s.data[i-s.base:]
 }
 
-var openArray = [256]bool{
-	'[': true,
+// span returns the bytes of the input from offset start to end, which must
+// not have been discarded.
+func (s *Scanner) span(start, end int) []byte {
+	return s.data[start-s.base : end-s.base]
 }
-var closeArray = [256]bool{
-	']': true,
+
+// at returns the byte of the input at offset i, or 0 at the end of the
+// input, reading more of it if needed.
+func (s *Scanner) at(i int) byte {
+	if w := s.window(i, 1); len(w) > 0 {
+		return w[0]
+	}
+	return 0
 }
 
-var openObject = [256]bool{
-	'{': true,
+// syntaxError returns a SyntaxError for the byte at offset, as
+// newSyntaxError does, counting the lines of the input discarded before
+// data.
+func (s *Scanner) syntaxError(offset int, msg string) *SyntaxError {
+	if s.base == 0 {
+		return newSyntaxError(s.data, offset, msg)
+	}
+	prefix := s.data[:offset-s.base]
+	e := &SyntaxError{
+		msg:    msg,
+		Offset: int64(offset),
+		Line:   1 + s.lines + bytes.Count(prefix, []byte{'\n'}),
+		Column: offset - s.lineStart + 1,
+	}
+	if i := bytes.LastIndexByte(prefix, '\n'); i >= 0 {
+		e.Column = offset - (s.base + i)
+	}
+	return e
 }
-var closeObject = [256]bool{
-	'}': true,
+
+// scanFlags are the extensions to standard JSON a Scanner accepts, enabled by
+// the Decoder's Allow options.
+type scanFlags uint8
+
+const (
+	scanComments    scanFlags = 1 << iota // comments are whitespace
+	scanRelaxed                           // single-quoted strings and bare object keys
+	scanNaNInf                            // NaN, Infinity and -Infinity are numbers
+	scanValidUTF8                         // invalid UTF-8 in strings is an error
+	scanReplaceUTF8                       // invalid UTF-8 in strings becomes U+FFFD
+)
+
+// scanUTF8 are the flags under which the contents of strings are checked.
+const scanUTF8 = scanValidUTF8 | scanReplaceUTF8
+
+// classes maps a byte to the bits of its character class, so that scanning
+// loops can test for several kinds of byte with a single load.
+var classes = [256]byte{
+	' ':         classSpace,
+	'\r':        classSpace,
+	'\n':        classSpace,
+	'\t':        classSpace,
+	ObjectStart: classOpen,
+	ArrayStart:  classOpen,
+	ObjectEnd:   classClose,
+	ArrayEnd:    classClose,
+	'"':         classQuote,
+	'\'':        classQuote,
+	'/':         classSlash,
 }
 
+const (
+	classSpace byte = 1 << iota // insignificant whitespace
+	classOpen                   // opens an array or object
+	classClose                  // closes an array or object
+	classQuote                  // opens a string, ' only if relaxed
+	classSlash                  // may open a comment
+)
+
 // Next returns a []byte referencing the next lexical token in the stream.
 // The []byte is valid until Next is called again.
 // If the stream is at its end, or an error has occurred, Next returns a zero
-// length []byte slice.
+// length []byte slice; Error then distinguishes the two. Once an error has
+// occurred Next keeps returning a zero length slice.
 //
 // A valid token begins with one of the following:
 //
@@ -67,168 +332,900 @@ var closeObject = [256]bool{
 //	" A string, possibly containing backslash escaped entites.
 //	-, 0-9 A number
 func (s *Scanner) Next() []byte {
-	if s.offset > len(s.data)-1 {
+	if s.err != nil {
 		return nil
 	}
-	w := s.data[s.offset:]
-	initialOffset := s.offset
-	for {
-		for pos, c := range w {
-			// strip any leading whitespace.
-			if whitespace[c] {
-				continue
-			}
+	if s.flags&scanComments != 0 {
+		s.offset = s.skipSpace(s.offset)
+	}
+	// the unsigned comparisons let the compiler drop the bounds checks on
+	// data[i] below.
+	data, i := s.data, s.offset-s.base
+	if uint(i) < uint(len(data)) && classes[data[i]]&classSpace != 0 {
+		// strip any leading whitespace.
+		i += skipWhitespace(data[i:])
+	}
+	if uint(i) >= uint(len(data)) {
+		// read on, past any more whitespace.
+		if s.offset = s.skipSpace(s.base + i); s.offset == s.end() {
+			// eof
+			s.err = s.endError(io.EOF)
+			return nil
+		}
+		data, i = s.data, s.offset-s.base
+	}
+	c := data[i]
+	s.start = s.base + i
 
-			// simple case
-			switch c {
-			case ObjectStart, ObjectEnd, Colon, Comma, ArrayStart, ArrayEnd:
-				s.offset += pos + 1
-				return w[pos : pos+1]
-			}
-			s.offset = initialOffset + pos
-
-			switch c {
-			case True:
-				s.offset += s.validateToken("true")
-			case False:
-				s.offset += s.validateToken("false")
-			case Null:
-				s.offset += s.validateToken("null")
-			case String:
-				length := s.parseString()
-				if length < 2 {
-					return nil
-				}
-				s.offset += length
+	// simple case
+	switch c {
+	case ObjectStart, ObjectEnd, Colon, Comma, ArrayStart, ArrayEnd:
+		s.offset = s.start + 1
+		return data[i : i+1]
+	}
+	s.offset = s.start
 
-			default:
-				// ensure the number is correct.
-				s.offset += s.parseNumber(c)
+	switch c {
+	case True:
+		if s.flags&scanRelaxed != 0 {
+			return s.extendedNext(c)
+		}
+		s.offset += s.validateToken("true")
+	case False:
+		if s.flags&scanRelaxed != 0 {
+			return s.extendedNext(c)
+		}
+		s.offset += s.validateToken("false")
+	case Null:
+		if s.flags&scanRelaxed != 0 {
+			return s.extendedNext(c)
+		}
+		s.offset += s.validateToken("null")
+	case String:
+		if s.flags&scanUTF8 != 0 || s.discard {
+			if s.discard {
+				return s.discardString(c)
 			}
-			return s.data[initialOffset+pos : s.offset]
+			return s.utf8String()
 		}
+		s.offset += s.parseString()
 
-		s.offset += len(w)
-		w = s.data[s.offset:]
-		if len(w) == 0 {
-			// eof
-			return nil
+	default:
+		if s.flags != 0 {
+			return s.extendedNext(c)
 		}
+		// ensure the number is correct.
+		s.offset += s.parseNumber(c)
+	}
+	if s.err != nil {
+		return nil
 	}
+	return s.span(s.start, s.offset)
 }
 
-func (s *Scanner) skipArray() {
-	w := s.data[s.offset:]
-	count := 1
-	inString := false
-	escaped := false
+// swarOnes and swarHigh have the low and the high bit set in every byte of a
+// word, for testing the eight bytes of a word at once.
+const (
+	swarOnes uint64 = 0x0101010101010101
+	swarHigh uint64 = 0x8080808080808080
+)
 
-	for i, c := range w {
-		if c == '"' && !inString {
-			inString = true
-			continue
+// skipWhitespace returns the index of the first byte of w which is not
+// whitespace, or len(w). Runs of whitespace, such as the indentation of
+// pretty-printed input, are skipped a word of eight bytes at a time.
+func skipWhitespace(w []byte) int {
+	// most runs, as after a colon or comma, are a single byte.
+	i := 0
+	for ; i < 2; i++ {
+		if i == len(w) || classes[w[i]]&classSpace == 0 {
+			return i
+		}
+	}
+	for ; len(w)-i >= 8; i += 8 {
+		x := binary.LittleEndian.Uint64(w[i:])
+		ws := swarEqual(x, ' ') | swarEqual(x, '\t') | swarEqual(x, '\n') | swarEqual(x, '\r')
+		if ws != swarHigh {
+			// the lowest clear high bit is the first other byte.
+			return i + bits.TrailingZeros64(^ws&swarHigh)/8
 		}
+	}
+	for i < len(w) && classes[w[i]]&classSpace != 0 {
+		i++
+	}
+	return i
+}
+
+// swarEqual returns a word with the high bit set in each byte of x which is
+// equal to c, and all other bits clear.
+func swarEqual(x uint64, c byte) uint64 {
+	t := x ^ swarOnes*uint64(c)
+	// the high bit of each byte of t is set if the byte is not zero.
+	return ^((t&^swarHigh + ^swarHigh) | t) & swarHigh
+}
+
+// extendedNext completes Next for the token starting with c at the offset
+// when any extension to standard JSON is accepted. It is kept out of Next so
+// that scanning standard JSON does not pay for them. Comments have already
+// been skipped, so one starting here is unterminated.
+func (s *Scanner) extendedNext(c byte) []byte {
+	switch {
+	case c == '/' && s.flags&scanComments != 0 && s.isComment(s.offset):
+		s.err = s.syntaxError(s.offset, "unterminated comment")
+		return nil
+	case c == '\'' && s.flags&scanRelaxed != 0 && s.discard:
+		return s.discardString(c)
+	case s.flags&scanRelaxed != 0 && (c == '\'' || isIdentStart(c)):
+		if tok, ok := s.relaxedToken(c); ok {
+			return tok
+		}
+	}
+	if s.flags&scanNaNInf != 0 {
+		if lit := nanInfLiteral(s.window(s.offset, 2)); lit != "" {
+			s.offset += s.validateToken(lit)
+			if s.err != nil {
+				return nil
+			}
+			return s.span(s.start, s.offset)
+		}
+	}
+	switch c {
+	case True:
+		s.offset += s.validateToken("true")
+	case False:
+		s.offset += s.validateToken("false")
+	case Null:
+		s.offset += s.validateToken("null")
+	default:
+		s.offset += s.parseNumber(c)
+	}
+	if s.err != nil {
+		return nil
+	}
+	return s.span(s.start, s.offset)
+}
+
+// Offset returns the byte offset of the Scanner's current position in the
+// input. Immediately after Next it is the offset just past the returned token.
+func (s *Scanner) Offset() int {
+	return s.offset
+}
+
+// TokenStart returns the byte offset of the first byte of the token most
+// recently returned by Next, so that the token spans [TokenStart, Offset).
+func (s *Scanner) TokenStart() int {
+	return s.start
+}
+
+// TokenSpan returns the byte offsets of the start and end of the token most
+// recently returned by Next, which spans [start, end) of the data.
+func (s *Scanner) TokenSpan() (start, end int) {
+	return s.start, s.offset
+}
+
+// NextKind is like Next but also reports the Kind of the returned token, so
+// callers need not inspect its first byte. If the stream is at its end, or an
+// error has occurred, NextKind returns KindInvalid and a zero length []byte.
+func (s *Scanner) NextKind() (Kind, []byte) {
+	tok := s.Next()
+	if len(tok) < 1 {
+		return KindInvalid, nil
+	}
+	return tokenKind(tok), tok
+}
+
+// skipContainer advances the scanner past the end of the array or object
+// whose opening bracket, open, has already been consumed. Nested brackets
+// must be properly matched; a crossed bracket is reported as an error. If the
+// container is unterminated the offset is left at the end of the data and
+// io.ErrUnexpectedEOF is returned. If maxDepth is positive, nesting more than
+// maxDepth levels deep, counting the container itself, is an error.
+func (s *Scanner) skipContainer(open byte, maxDepth int) error {
+	stack := append(s.brackets[:0], open)
+	s.brackets = stack[:0]
+	// w is the data from the offset on; the offset is moved past it, or to
+	// a string or comment which continues beyond it, before reading on.
+scan:
+	for {
+		w := s.data[s.offset-s.base:]
+		for i := 0; i < len(w); i++ {
+			c := w[i]
+			cl := classes[c]
+			if cl&^classSpace == 0 {
+				continue
+			}
 
-		if inString {
 			switch {
-			case escaped:
-				escaped = false
-			case c == '"':
-				inString = false
-			case c == '\\':
-				escaped = true
+			case cl&classQuote != 0:
+				if c == '\'' && s.flags&scanRelaxed == 0 {
+					continue
+				}
+				if end := stringEnd(w[i+1:], c); end >= 0 {
+					// continue after the closing quote.
+					i += end
+					continue
+				}
+				s.offset += i
+				end := s.skipString(s.offset, c)
+				if end < 0 {
+					s.offset = s.end()
+					return s.endError(io.ErrUnexpectedEOF)
+				}
+				s.offset = end
+				continue scan
+			case cl&classSlash != 0:
+				if s.flags&scanComments == 0 {
+					continue
+				}
+				// isComment may read on, moving the data.
+				s.offset += i
+				if !s.isComment(s.offset) {
+					s.offset++
+					continue scan
+				}
+				end := s.commentEnd(s.offset)
+				if end < 0 {
+					return s.syntaxError(s.offset, "unterminated comment")
+				}
+				s.offset = end
+				continue scan
+			case cl&classOpen != 0:
+				if len(stack) == maxDepth {
+					s.offset += i
+					return depthError(maxDepth, s.offset)
+				}
+				if len(stack) == cap(stack) {
+					// keep the grown stack for the next call.
+					stack = append(stack, c)
+					s.brackets = stack[:0]
+					continue
+				}
+				stack = append(stack, c)
+			default:
+				top := stack[len(stack)-1]
+				if (top == ArrayStart) != (c == ArrayEnd) {
+					s.offset += i
+					return s.syntaxError(s.offset, "invalid character "+quoteChar(c)+" in mismatched container")
+				}
+				stack = stack[:len(stack)-1]
+				if len(stack) == 0 {
+					s.offset += i + 1
+					return nil
+				}
 			}
-			continue
 		}
+		s.offset += len(w)
+		s.release(s.offset)
+		if !s.more() {
+			return s.endError(io.ErrUnexpectedEOF)
+		}
+	}
+}
 
-		if openArray[c] {
-			count++
+// skipString returns the offset just past the closing quote of the string
+// whose opening quote, quote, is at offset i, reading on until it is found,
+// or -1 if the input ends first. Escapes are stepped over as by stringEnd.
+func (s *Scanner) skipString(i int, quote byte) int {
+	j := i + 1 // where the search resumes, never in the middle of an escape
+	for {
+		w := s.data[j-s.base:]
+		if end := stringEnd(w, quote); end >= 0 {
+			return j + end
 		}
-		if closeArray[c] {
-			count--
-			if count == 0 {
-				s.offset += i + 1
-				return
-			}
+		// an odd run of backslashes at the end of w leaves the last one
+		// escaping a byte not read yet, so resume the search from it.
+		n := len(w)
+		for n > 0 && w[n-1] == '\\' {
+			n--
+		}
+		j += len(w) - (len(w)-n)%2
+		s.release(j)
+		if !s.more() {
+			return -1
 		}
 	}
+}
 
-	s.offset += len(w) + 1
+// discardString consumes the string token starting with quote at the
+// offset, without checking its contents, and returns an empty string token
+// in s.quoted in its place, as the input holding it is discarded as it is
+// read.
+func (s *Scanner) discardString(quote byte) []byte {
+	end := s.skipString(s.offset, quote)
+	if end < 0 {
+		s.offset = s.end()
+		s.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
+	s.offset = end
+	s.quoted = append(s.quoted[:0], '"', '"')
+	return s.quoted
 }
 
-func (s *Scanner) skipObject() {
-	w := s.data[s.offset:]
-	count := 1
-	inString := false
-	escaped := false
+// release lets the input before offset i be discarded, while discard is
+// set.
+func (s *Scanner) release(i int) {
+	if s.discard && i > s.keep {
+		s.keep = i
+	}
+}
 
-	for i, c := range w {
-		if c == '"' && !inString {
-			inString = true
-			continue
+// stringEnd returns the offset in w just past the closing quote of a string
+// whose opening quote precedes w, or -1 if the string is unterminated.
+// Escapes are stepped over but not validated.
+func stringEnd(w []byte, quote byte) int {
+	i := 0
+	for {
+		j := bytes.IndexByte(w[i:], quote)
+		if j < 0 {
+			return -1
 		}
+		j += i
+		// step over any escapes before the quote at j.
+		for {
+			k := bytes.IndexByte(w[i:j], '\\')
+			if k < 0 {
+				return j + 1
+			}
+			if i += k + 2; i > j {
+				// the quote was escaped; look for the next one.
+				break
+			}
+		}
+	}
+}
 
-		if inString {
-			switch {
-			case escaped:
-				escaped = false
-			case c == '"':
-				inString = false
-			case c == '\\':
-				escaped = true
+// peek advances past any whitespace, and comments if they are allowed, and
+// returns the first byte of the next token without consuming it. At the end
+// of the data peek returns 0.
+func (s *Scanner) peek() byte {
+	s.offset = s.skipSpace(s.offset)
+	return s.at(s.offset)
+}
+
+// skipSpace returns the offset of the first byte at or after i which is not
+// whitespace or, if comments are allowed, part of a comment. An unterminated
+// comment is not skipped, so that Next reports it.
+func (s *Scanner) skipSpace(i int) int {
+	for {
+		w := s.data[i-s.base:]
+		n := skipWhitespace(w)
+		if i += n; n == len(w) {
+			if s.more() {
+				if i == 0 {
+					// the input read from an io.Reader may start with a
+					// byte order mark.
+					i = bomLen(s.data)
+				}
+				continue
 			}
-			continue
+			return i
+		}
+		if w[n] != '/' || s.flags&scanComments == 0 {
+			return i
+		}
+		end := s.commentEnd(i)
+		if end < 0 {
+			return i
 		}
+		i = end
+	}
+}
+
+// trailingError returns the error for the byte at the offset, which follows
+// a complete top-level value where only whitespace may follow.
+func (s *Scanner) trailingError() error {
+	if s.flags&scanComments != 0 && s.isComment(s.offset) {
+		return s.syntaxError(s.offset, "unterminated comment")
+	}
+	return s.syntaxError(s.offset, "invalid character "+quoteChar(s.at(s.offset))+" after top-level value")
+}
+
+// isComment reports whether a comment, terminated or not, starts at i.
+func (s *Scanner) isComment(i int) bool {
+	w := s.window(i, 2)
+	return len(w) >= 2 && w[0] == '/' && (w[1] == '/' || w[1] == '*')
+}
 
-		if openObject[c] {
-			count++
+// commentEnd returns the offset just past the comment starting at i, or -1
+// if no comment starts at i or it is an unterminated block comment. A line
+// comment runs to the end of the line, including the newline, or of the data.
+func (s *Scanner) commentEnd(i int) int {
+	if !s.isComment(i) {
+		return -1
+	}
+	line := s.data[i+1-s.base] == '/'
+	for j := i + 2; ; {
+		w := s.data[j-s.base:]
+		if line {
+			if k := bytes.IndexByte(w, '\n'); k >= 0 {
+				return j + k + 1
+			}
+		} else if k := bytes.Index(w, []byte("*/")); k >= 0 {
+			return j + k + 2
+		}
+		// a last '*' may begin the end of a block comment read on.
+		if j += len(w); !line && len(w) > 0 {
+			j--
 		}
-		if closeObject[c] {
-			count--
-			if count == 0 {
-				s.offset += i + 1
-				return
+		s.release(j)
+		if !s.more() {
+			if line {
+				return s.end()
 			}
+			return -1
 		}
 	}
-	s.offset += len(w) + 1
 }
 
+// validateToken returns the length of the literal expected located at the
+// start of the window, or 0 if the window does not hold that literal.
 func (s *Scanner) validateToken(expected string) int {
-	w := s.data[s.offset:]
 	n := len(expected)
-	if len(w) >= n {
-		if string(w[:n]) != expected {
-			// doesn't match
-			return 0
-		}
+	w := s.window(s.offset, n)
+	if len(w) >= n && string(w[:n]) == expected {
 		return n
 	}
+	for i := 0; i < n; i++ {
+		if i >= len(w) {
+			s.setError(io.ErrUnexpectedEOF)
+			break
+		}
+		if w[i] != expected[i] {
+			s.setError(s.syntaxError(s.offset+i, "invalid character "+quoteChar(w[i])+" in literal "+expected+" (expecting "+quoteChar(expected[i])+")"))
+			break
+		}
+	}
 	return 0
 }
 
-// parseString returns the length of the string token
-// located at the start of the window or 0 if there is no closing " before the end of the data
+// setError records err as the Scanner's error unless one is already recorded.
+func (s *Scanner) setError(err error) {
+	if s.err == nil {
+		s.err = s.endError(err)
+	}
+}
+
+// endError returns err, which reports reaching the end of the data, unless
+// the data is truncated, when it returns an error wrapping
+// ErrInputTooLarge instead, or reading the input failed, when it returns
+// the error of the io.Reader. Other errors are returned as they are.
+func (s *Scanner) endError(err error) error {
+	if err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if s.rerr != nil {
+		return s.rerr
+	}
+	if s.truncated {
+		return inputTooLargeError(s.end())
+	}
+	return err
+}
+
+// Error returns the first error encountered by the Scanner. If Next stopped
+// returning tokens because the input was cleanly exhausted, Error returns
+// io.EOF. While tokens remain, Error returns nil.
+func (s *Scanner) Error() error {
+	return s.err
+}
+
+// tokenError returns the error explaining why the last call to Next returned
+// no token: the recorded syntax error if any, otherwise io.ErrUnexpectedEOF.
+func (s *Scanner) tokenError() error {
+	if s.err != nil && s.err != io.EOF {
+		return s.err
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// parseString returns the length of the string token located at the start
+// of the window, or 0 if there is no closing " before the end of the data or
+// the string is invalid. Escape sequences must be valid and control
+// characters must be escaped.
 func (s *Scanner) parseString() int {
-	escaped := false
-	w := s.data[s.offset+1:]
-	offset := 0
-	for _, c := range w {
-		offset++
-		switch {
-		case escaped:
-			escaped = false
+	w := s.data[s.offset+1-s.base:]
+	i := 0
+	// most strings hold no escapes, so find the closing quote directly and
+	// scan byte by byte only from the first backslash.
+	if j := bytes.IndexByte(w, '"'); j >= 0 {
+		k := bytes.IndexByte(w[:j], '\\')
+		if k < 0 {
+			k = j
+		}
+		if !hasControl(w[:k]) {
+			if k == j {
+				return j + 2
+			}
+			i = k
+		}
+	}
+	for ; ; i++ {
+		if len(w)-i < 6 && s.r != nil {
+			if s.maxString > 0 && i > s.maxString {
+				// stop, rather than read the rest of the string.
+				s.setError(&LimitError{What: "string", Len: i, Limit: s.maxString, Offset: int64(s.offset)})
+				return 0
+			}
+			// read on, so that no escape is cut short by the end of w.
+			s.window(s.offset+1+i, 6)
+			w = s.data[s.offset+1-s.base:]
+		}
+		if i >= len(w) {
+			break
+		}
+		switch c := w[i]; {
 		case c == '"':
 			// finished
-			return offset + 1
+			return i + 2
 		case c == '\\':
-			escaped = true
+			i++
+			if i == len(w) {
+				break
+			}
+			switch w[i] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			case 'u':
+				for n := 0; n < 4; n++ {
+					i++
+					if i == len(w) {
+						break
+					}
+					if c := w[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+						s.setError(s.syntaxError(s.offset+1+i, "invalid character "+quoteChar(c)+" in \\u hexadecimal character escape"))
+						return 0
+					}
+				}
+			default:
+				s.setError(s.syntaxError(s.offset+1+i, "invalid character "+quoteChar(w[i])+" in string escape code"))
+				return 0
+			}
+		case c < ' ':
+			s.setError(s.syntaxError(s.offset+1+i, "invalid character "+quoteChar(c)+" in string literal"))
+			return 0
 		}
 	}
 	// no closing "
+	s.setError(io.ErrUnexpectedEOF)
+	return 0
+}
+
+// hasControl reports whether b holds a control character, which must be
+// escaped in a string. Like skipWhitespace it tests a word at a time.
+func hasControl(b []byte) bool {
+	i := 0
+	for ; len(b)-i >= 8; i += 8 {
+		x := binary.LittleEndian.Uint64(b[i:])
+		// non-zero exactly when some byte of x is less than a space.
+		if (x-swarOnes*' ')&^x&swarHigh != 0 {
+			return true
+		}
+	}
+	for ; i < len(b); i++ {
+		if b[i] < ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// relaxedToken scans the single-quoted string or bare identifier starting
+// with c at the offset, as accepted by Decoder.AllowRelaxedStrings. A string,
+// or an identifier followed by a colon, which makes it an object key, is
+// returned rewritten as a standard string token, valid until the next call
+// to Next. Otherwise relaxedToken returns false and consumes nothing, so
+// that the identifier is scanned as a literal, or rejected.
+func (s *Scanner) relaxedToken(c byte) ([]byte, bool) {
+	if c == '\'' {
+		n := s.parseQuoted()
+		if s.err != nil {
+			return nil, true
+		}
+		s.offset += n
+		return s.quoted, true
+	}
+	end := s.identEnd(s.offset)
+	if s.at(s.skipSpace(end)) != Colon {
+		return nil, false
+	}
+	s.quoted = append(append(append(s.quoted[:0], '"'), s.span(s.offset, end)...), '"')
+	s.offset = end
+	return s.quoted, true
+}
+
+// extendedKind returns the Kind of the token starting at offset i, taking
+// into account the extensions to standard JSON which are accepted.
+func (s *Scanner) extendedKind(i int) Kind {
+	w := s.window(i, 2)
+	if len(w) == 0 {
+		return KindInvalid
+	}
+	c := w[0]
+	if s.flags&scanRelaxed != 0 {
+		switch {
+		case c == '\'':
+			return KindString
+		case isIdentStart(c):
+			if s.at(s.skipSpace(s.identEnd(i))) == Colon {
+				return KindString
+			}
+		}
+	}
+	if s.flags&scanNaNInf != 0 && nanInfLiteral(s.window(i, 2)) != "" {
+		return KindNumber
+	}
+	return kinds[c]
+}
+
+// nanInfLiteral returns the literal NaN, Infinity or -Infinity which the
+// token starting at the beginning of w must be, judging by its first bytes,
+// or "" if it is none of them.
+func nanInfLiteral(w []byte) string {
+	switch {
+	case w[0] == 'N':
+		return "NaN"
+	case w[0] == 'I':
+		return "Infinity"
+	case w[0] == '-' && len(w) > 1 && w[1] == 'I':
+		return "-Infinity"
+	}
+	return ""
+}
+
+// tokenKind returns the Kind of the token tok, as returned by Next, which
+// unlike kinds[tok[0]] counts NaN and Infinity as numbers.
+func tokenKind(tok []byte) Kind {
+	if k := kinds[tok[0]]; k != KindInvalid {
+		return k
+	}
+	if tok[0] == 'N' || tok[0] == 'I' {
+		return KindNumber
+	}
+	return KindInvalid
+}
+
+// isIdentStart reports whether c may begin a bare object key.
+func isIdentStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c == '$'
+}
+
+// identEnd returns the offset just past the bare identifier starting at i.
+func (s *Scanner) identEnd(i int) int {
+	for i++; i < s.end() || s.more(); i++ {
+		if c := s.data[i-s.base]; !isIdentStart(c) && !('0' <= c && c <= '9') {
+			break
+		}
+	}
+	return i
+}
+
+// utf8String scans the string token at the offset, as Next does, and checks
+// its contents are valid UTF-8. An invalid byte is a syntax error under
+// scanValidUTF8; otherwise the token is rewritten in s.quoted with each
+// invalid byte replaced by U+FFFD, as encoding/json does when unquoting.
+func (s *Scanner) utf8String() []byte {
+	s.offset += s.parseString()
+	if s.err != nil {
+		return nil
+	}
+	tok := s.span(s.start, s.offset)
+	i := invalidUTF8(tok)
+	if i < 0 {
+		return tok
+	}
+	if s.flags&scanValidUTF8 != 0 {
+		s.setError(s.invalidUTF8Error(s.start + i))
+		return nil
+	}
+	b := append(s.quoted[:0], tok[:i]...)
+	for i < len(tok) {
+		r, n := utf8.DecodeRune(tok[i:])
+		if r == utf8.RuneError && n == 1 {
+			b = append(b, "\uFFFD"...)
+		} else {
+			b = append(b, tok[i:i+n]...)
+		}
+		i += n
+	}
+	s.quoted = b
+	return b
+}
+
+// invalidUTF8 returns the offset of the first byte in b which does not begin
+// a valid UTF-8 sequence, or -1 if b is valid UTF-8.
+func invalidUTF8(b []byte) int {
+	if utf8.Valid(b) {
+		return -1
+	}
+	for i := 0; i < len(b); {
+		if b[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, n := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && n == 1 {
+			return i
+		}
+		i += n
+	}
+	return -1
+}
+
+// invalidUTF8Error returns the error for the invalid UTF-8 byte at offset i
+// of the input, inside a string.
+func (s *Scanner) invalidUTF8Error(i int) error {
+	return s.syntaxError(i, "invalid UTF-8 byte 0x"+strconv.FormatUint(uint64(s.at(i)), 16)+" in string literal")
+}
+
+// parseQuoted is like parseString for a single-quoted string, in which \'
+// is also a valid escape. The string is rewritten as a standard string token
+// in s.quoted.
+func (s *Scanner) parseQuoted() int {
+	w := s.data[s.offset+1-s.base:]
+	b := append(s.quoted[:0], '"')
+	for i := 0; ; i++ {
+		if len(w)-i < 6 && s.r != nil {
+			if s.maxString > 0 && i > s.maxString {
+				// stop, rather than read the rest of the string.
+				s.setError(&LimitError{What: "string", Len: i, Limit: s.maxString, Offset: int64(s.offset)})
+				return 0
+			}
+			// read on, so that no escape is cut short by the end of w.
+			s.window(s.offset+1+i, 6)
+			w = s.data[s.offset+1-s.base:]
+		}
+		if i >= len(w) {
+			break
+		}
+		switch c := w[i]; {
+		case c == '\'':
+			s.quoted = append(b, '"')
+			return i + 2
+		case c == '"':
+			b = append(b, '\\', '"')
+		case c == '\\':
+			i++
+			if i == len(w) {
+				break
+			}
+			switch w[i] {
+			case '\'':
+				b = append(b, '\'')
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				b = append(b, c, w[i])
+			case 'u':
+				if i+5 > len(w) {
+					i = len(w) - 1
+					continue
+				}
+				for n := 1; n <= 4; n++ {
+					if c := w[i+n]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+						s.setError(s.syntaxError(s.offset+1+i+n, "invalid character "+quoteChar(c)+" in \\u hexadecimal character escape"))
+						return 0
+					}
+				}
+				b = append(b, w[i-1:i+5]...)
+				i += 4
+			default:
+				s.setError(s.syntaxError(s.offset+1+i, "invalid character "+quoteChar(w[i])+" in string escape code"))
+				return 0
+			}
+		case c < ' ':
+			s.setError(s.syntaxError(s.offset+1+i, "invalid character "+quoteChar(c)+" in string literal"))
+			return 0
+		case c >= utf8.RuneSelf && s.flags&scanUTF8 != 0:
+			r, n := utf8.DecodeRune(w[i:])
+			switch {
+			case r != utf8.RuneError || n > 1:
+				b = append(b, w[i:i+n]...)
+			case s.flags&scanValidUTF8 != 0:
+				s.setError(s.invalidUTF8Error(s.offset + 1 + i))
+				return 0
+			default:
+				b = append(b, "\uFFFD"...)
+			}
+			i += n - 1
+		default:
+			b = append(b, c)
+		}
+	}
+	// no closing '
+	s.quoted = b
+	s.setError(io.ErrUnexpectedEOF)
 	return 0
 }
 
+// unescape appends the contents of the string token tok to dst, with the
+// quotes removed and escape sequences decoded. If tok contains an invalid
+// escape sequence, unescape returns the offset of its backslash within tok.
+// A \u escape of a surrogate which is not part of a pair is invalid, unless
+// lenient is set, when it decodes to utf8.RuneError as in encoding/json.
+func unescape(dst, tok []byte, lenient bool) ([]byte, int) {
+	s := tok[1 : len(tok)-1]
+	for i := 0; i < len(s); {
+		j := bytes.IndexByte(s[i:], '\\')
+		if j < 0 {
+			return append(dst, s[i:]...), -1
+		}
+		dst = append(dst, s[i:i+j]...)
+		i += j
+		if i+1 == len(s) {
+			return dst, i + 1
+		}
+		switch c := s[i+1]; c {
+		case '"', '\\', '/':
+			dst = append(dst, c)
+		case 'b':
+			dst = append(dst, '\b')
+		case 'f':
+			dst = append(dst, '\f')
+		case 'n':
+			dst = append(dst, '\n')
+		case 'r':
+			dst = append(dst, '\r')
+		case 't':
+			dst = append(dst, '\t')
+		case 'u':
+			r := hexRune(s[i+2:])
+			if r < 0 {
+				return dst, i + 1
+			}
+			i += 6
+			if utf16.IsSurrogate(r) {
+				r2 := rune(-1)
+				if len(s)-i >= 6 && s[i] == '\\' && s[i+1] == 'u' {
+					r2 = hexRune(s[i+2:])
+				}
+				if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+					r = dec
+					i += 6
+				} else if lenient {
+					r = utf8.RuneError
+				} else {
+					return dst, i - 5
+				}
+			}
+			dst = utf8.AppendRune(dst, r)
+			continue
+		default:
+			return dst, i + 1
+		}
+		i += 2
+	}
+	return dst, -1
+}
+
+// escapeError returns the message for the invalid escape sequence at offset
+// i of the string token tok, as reported by unescape.
+func escapeError(tok []byte, i int) string {
+	if tok[i+1] == 'u' && utf16.IsSurrogate(hexRune(tok[i+2:])) {
+		return "unpaired surrogate " + string(tok[i:i+6]) + " in string"
+	}
+	return "invalid escape sequence in string"
+}
+
+// hexRune decodes the four hex digits at the start of b, returning -1 if b
+// does not start with four hex digits.
+func hexRune(b []byte) rune {
+	if len(b) < 4 {
+		return -1
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return -1
+		}
+		r = r<<4 | rune(c)
+	}
+	return r
+}
+
 func (s *Scanner) parseNumber(c byte) int {
 	const (
 		begin = iota
@@ -242,8 +1239,7 @@ func (s *Scanner) parseNumber(c byte) int {
 	)
 
 	offset := 0
-	w := s.data[s.offset:]
-	// w := s.data[s.offset:]
+	w := s.data[s.offset-s.base:]
 	// int vs uint8 costs 10% on canada.json
 	var state uint8 = begin
 
@@ -261,8 +1257,7 @@ func (s *Scanner) parseNumber(c byte) int {
 				} else if elem == '0' {
 					state = leadingzero
 				} else {
-					// error
-					return 0
+					return s.numberError(offset, elem)
 				}
 			case anydigit1:
 				if elem >= '0' && elem <= '9' {
@@ -284,8 +1279,7 @@ func (s *Scanner) parseNumber(c byte) int {
 				if elem >= '0' && elem <= '9' {
 					state = anydigit2
 				} else {
-					// error
-					return 0
+					return s.numberError(offset, elem)
 				}
 			case anydigit2:
 				if elem >= '0' && elem <= '9' {
@@ -307,8 +1301,7 @@ func (s *Scanner) parseNumber(c byte) int {
 					state = anydigit3
 					break
 				}
-				// error
-				return 0
+				return s.numberError(offset, elem)
 			case anydigit3:
 				if elem < '0' || elem > '9' {
 					return offset
@@ -316,19 +1309,36 @@ func (s *Scanner) parseNumber(c byte) int {
 			}
 			offset++
 		}
-
-		w = s.data[offset:]
-		if len(w) == 0 {
-			// end of the item. However, not necessarily an error. Make
-			// sure we are in a state that allows ending the number.
-			switch state {
-			case leadingzero, anydigit1, anydigit2, anydigit3:
-				return offset
-			default:
-				// error otherwise, the number isn't complete.
-				return 0
-			}
+		if !s.more() {
+			break
 		}
+		w = s.data[s.offset-s.base:]
+	}
+
+	// end of the data. However, not necessarily an error. Make
+	// sure we are in a state that allows ending the number, and that the
+	// data does not stop short of the rest of it.
+	if s.truncated {
+		s.setError(io.ErrUnexpectedEOF)
+		return 0
+	}
+	switch state {
+	case leadingzero, anydigit1, anydigit2, anydigit3:
+		return offset
+	default:
+		// error otherwise, the number isn't complete.
+		s.setError(io.ErrUnexpectedEOF)
+		return 0
 	}
-	return offset
+}
+
+// numberError records a syntax error for the invalid byte c found offset bytes
+// into the number at the start of the window, and returns 0.
+func (s *Scanner) numberError(offset int, c byte) int {
+	msg := "in numeric literal"
+	if offset == 0 {
+		msg = "looking for beginning of value"
+	}
+	s.setError(s.syntaxError(s.offset+offset, "invalid character "+quoteChar(c)+" "+msg))
+	return 0
 }
@@ -1,7 +1,11 @@
 package json
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"testing"
 )
 
@@ -53,9 +57,115 @@ func TestScannerNext(t *testing.T) {
 			if len(last) > 0 {
 				t.Fatalf("expected: %q, got: %q", "", string(last))
 			}
-			//if err := scanner.Error(); err != io.EOF {
-			//	t.Fatalf("expected: %v, got: %v", io.EOF, err)
-			//}
+			if err := scanner.Error(); err != io.EOF {
+				t.Fatalf("expected: %v, got: %v", io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestScannerNextKind(t *testing.T) {
+	input := `{"a": [1, -2, "three", true, false, null]} x`
+	want := []Kind{
+		KindObjectStart, KindString, KindColon, KindArrayStart,
+		KindNumber, KindComma, KindNumber, KindComma, KindString, KindComma,
+		KindBool, KindComma, KindBool, KindComma, KindNull,
+		KindArrayEnd, KindObjectEnd,
+		KindInvalid,
+	}
+	sc := NewScanner([]byte(input))
+	for n, kind := range want {
+		got, tok := sc.NextKind()
+		if got != kind {
+			t.Fatalf("%v: expected: %v, got: %v %q", n+1, kind, got, tok)
+		}
+	}
+}
+
+func TestScannerOffset(t *testing.T) {
+	input := `{  "a" :  [ 1 ,  2 ] }`
+	sc := NewScanner([]byte(input))
+	for {
+		tok := sc.Next()
+		if len(tok) < 1 {
+			break
+		}
+		if got := input[sc.TokenStart():sc.Offset()]; got != string(tok) {
+			t.Fatalf("expected span %q, got %q", tok, got)
+		}
+		if start, end := sc.TokenSpan(); start != sc.TokenStart() || end != sc.Offset() {
+			t.Fatalf("expected span [%v, %v), got [%v, %v)", sc.TokenStart(), sc.Offset(), start, end)
+		}
+	}
+	if sc.Offset() != len(input) {
+		t.Fatalf("expected offset %v, got %v", len(input), sc.Offset())
+	}
+}
+
+func TestScannerError(t *testing.T) {
+	tests := []struct {
+		in     string
+		tokens int
+		err    error
+	}{
+		{in: `[1, 2]`, tokens: 5, err: io.EOF},
+		{in: `[1, 2]   `, tokens: 5, err: io.EOF},
+		{in: `["abc`, tokens: 1, err: io.ErrUnexpectedEOF},
+		{in: `[1.`, tokens: 1, err: io.ErrUnexpectedEOF},
+		{in: `[tr`, tokens: 1, err: io.ErrUnexpectedEOF},
+		{in: `[1.x, 2]`, tokens: 1},
+		{in: `[nul, 2]`, tokens: 1},
+		{in: `[+1, 2]`, tokens: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			sc := NewScanner([]byte(tc.in))
+			n := 0
+			for len(sc.Next()) > 0 {
+				n++
+			}
+			if n != tc.tokens {
+				t.Fatalf("expected %v tokens, got %v", tc.tokens, n)
+			}
+			err := sc.Error()
+			if tc.err != nil {
+				if err != tc.err {
+					t.Fatalf("expected: %v, got: %v", tc.err, err)
+				}
+			} else {
+				var serr *SyntaxError
+				if !errors.As(err, &serr) {
+					t.Fatalf("expected *SyntaxError, got: %v", err)
+				}
+			}
+			// Next must not resume scanning after an error.
+			if tok := sc.Next(); tok != nil {
+				t.Fatalf("expected nil, got: %q", tok)
+			}
+		})
+	}
+}
+
+func TestScannerNextAllocs(t *testing.T) {
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(t, tc.path))
+		check(t, err)
+		t.Run(tc.path, func(t *testing.T) {
+			sc := NewScanner(data)
+			allocs := testing.AllocsPerRun(5, func() {
+				*sc = Scanner{data: data}
+				n := 0
+				for len(sc.Next()) > 0 {
+					n++
+				}
+				if n != tc.alltokens {
+					t.Fatalf("expected %v tokens, got %v", tc.alltokens, n)
+				}
+			})
+			if allocs != 0 {
+				t.Fatalf("expected no allocations, got %v", allocs)
+			}
 		})
 	}
 }
@@ -64,8 +174,72 @@ func TestParseString(t *testing.T) {
 	testParseString(t, `""`, `""`)
 	testParseString(t, `"" `, `""`)
 	testParseString(t, `"\""`, `"\""`)
-	testParseString(t, `"\\\\\\\\\6"`, `"\\\\\\\\\6"`)
-	testParseString(t, `"\6"`, `"\6"`)
+	testParseString(t, `"\\\\\\\\"`, `"\\\\\\\\"`)
+	testParseString(t, `"\/\b\f\n\r\t"`, `"\/\b\f\n\r\t"`)
+	testParseString(t, `"\u00e9\uD83D\ude00"`, `"\u00e9\uD83D\ude00"`)
+	testParseString(t, "\"\x7f\xff\"", "\"\x7f\xff\"")
+	testParseString(t, `"0123456789abcdef\"0123456789\\"`, `"0123456789abcdef\"0123456789\\"`)
+	testParseString(t, `"0123456789abcdef\\" "`, `"0123456789abcdef\\"`)
+}
+
+func TestParseStringInvalid(t *testing.T) {
+	tests := []struct {
+		json   string
+		offset int64
+		msg    string
+	}{
+		{`"\6"`, 2, `invalid character '6' in string escape code`},
+		{`"\\\\\\\\\6"`, 10, `invalid character '6' in string escape code`},
+		{`"\x41"`, 2, `invalid character 'x' in string escape code`},
+		{`"\u12g4"`, 5, `invalid character 'g' in \u hexadecimal character escape`},
+		{`"\u12"`, 5, `invalid character '"' in \u hexadecimal character escape`},
+		{"\"a\tb\"", 2, `invalid character '\t' in string literal`},
+		{"\"\x00\"", 1, `invalid character '\x00' in string literal`},
+		{"\"0123456789abcdef\x1f\"", 17, `invalid character '\x1f' in string literal`},
+		{"\"0123456789\\n\x1f\"", 13, `invalid character '\x1f' in string literal`},
+		{"\"0123456789\x1f\\n\"", 11, `invalid character '\x1f' in string literal`},
+	}
+	for _, tc := range tests {
+		sc := NewScanner([]byte(tc.json))
+		if tok := sc.Next(); len(tok) != 0 {
+			t.Errorf("%q: got token %q, want error", tc.json, tok)
+			continue
+		}
+		var serr *SyntaxError
+		if !errors.As(sc.Error(), &serr) || serr.Offset != tc.offset || serr.msg != tc.msg {
+			t.Errorf("%q: got %v, want %q at offset %d", tc.json, sc.Error(), tc.msg, tc.offset)
+		}
+	}
+	for _, json := range []string{`"\`, `"\u12`, `"abc`} {
+		sc := NewScanner([]byte(json))
+		if tok := sc.Next(); len(tok) != 0 || sc.Error() != io.ErrUnexpectedEOF {
+			t.Errorf("%q: got %q, %v, want io.ErrUnexpectedEOF", json, tok, sc.Error())
+		}
+	}
+}
+
+func TestStringEnd(t *testing.T) {
+	tests := []struct {
+		in    string
+		quote byte
+		want  int
+	}{
+		{`"`, '"', 1},
+		{`abc"]`, '"', 4},
+		{`a][\"b"`, '"', 7},
+		{`\\"`, '"', 3},
+		{`\\\""`, '"', 5},
+		{`\\\"\\"`, '"', 7},
+		{`a\'b'"`, '\'', 5},
+		{`abc`, '"', -1},
+		{`abc\"`, '"', -1},
+		{`abc\`, '"', -1},
+	}
+	for _, tc := range tests {
+		if got := stringEnd([]byte(tc.in), tc.quote); got != tc.want {
+			t.Errorf("%q: expected %v, got %v", tc.in, tc.want, got)
+		}
+	}
 }
 
 func testParseString(t *testing.T, json, want string) {
@@ -103,6 +277,36 @@ func testParseNumber(t *testing.T, tc string) {
 	}
 }
 
+func TestParseNumberDeepInDocument(t *testing.T) {
+	const number = `-1234567.891011121314e+10`
+	var buf bytes.Buffer
+	buf.WriteString(`[`)
+	for i := 0; i < 1000; i++ {
+		buf.WriteString(`{"a": [1, 2.5, "three"]}, `)
+	}
+	buf.WriteString(number + `] ` + number)
+
+	sc := NewScanner(buf.Bytes())
+	var numbers []string
+	for {
+		tok := sc.Next()
+		if len(tok) < 1 {
+			break
+		}
+		if tok[0] == '-' {
+			numbers = append(numbers, string(tok))
+		}
+	}
+	if len(numbers) != 2 {
+		t.Fatalf("expected 2 numbers, got %v: %q", len(numbers), numbers)
+	}
+	for _, got := range numbers {
+		if got != number {
+			t.Fatalf("expected: %q, got: %q", number, got)
+		}
+	}
+}
+
 func BenchmarkParseNumber(b *testing.B) {
 	tests := []string{
 		`1`,
@@ -169,7 +373,7 @@ func testScanner(t *testing.T, sz int) {
 	}
 }
 
-func BenchmarkScanner_skipArray(b *testing.B) {
+func BenchmarkScanner_skipContainer(b *testing.B) {
 	input := []byte(`[{"some": "value", "props": [1, 2, 3]}, {"some": "value2", "props": [1, 2, 3]}, {"some": "value3", "props": [1, 2, 3]}]
 		"c": [1, 2, true]
 	}`)
@@ -180,6 +384,92 @@ func BenchmarkScanner_skipArray(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		s.offset = 1
-		s.skipArray()
+		s.skipContainer(ArrayStart, 0)
+	}
+}
+
+func TestScannerNextWhitespace(t *testing.T) {
+	tokens := []string{`[`, `{`, `"a"`, `:`, `1`, `,`, `"b\""`, `:`, `[`, `true`, `,`, `-2.5e3`, `]`, `}`, `,`, `null`, `]`}
+	pads := []string{"", " ", "\t", "\r\n", " \t\r\n", "        ", "\r\n\t\t\t\t\t\t\t\t", "    \t \r\n  \t\t   \r\n \t  "}
+	for n, pad := range pads {
+		var buf bytes.Buffer
+		for i, tok := range tokens {
+			// vary the run length so that runs end at every position within a word.
+			buf.WriteString(pad)
+			buf.Write(bytes.Repeat([]byte(" \t\r\n"), 3)[:i%9])
+			buf.WriteString(tok)
+		}
+		buf.WriteString(pad)
+		input := buf.Bytes()
+		sc := NewScanner(input)
+		for i, want := range tokens {
+			got := sc.Next()
+			if string(got) != want {
+				t.Fatalf("%v: %v: expected: %q, got: %q", n, i+1, want, got)
+			}
+			if got := string(input[sc.TokenStart():sc.Offset()]); got != want {
+				t.Fatalf("%v: %v: expected span %q, got %q", n, i+1, want, got)
+			}
+		}
+		if tok := sc.Next(); tok != nil {
+			t.Fatalf("%v: expected nil, got: %q", n, tok)
+		}
+		if err := sc.Error(); err != io.EOF {
+			t.Fatalf("%v: expected: %v, got: %v", n, io.EOF, err)
+		}
+		if sc.Offset() != len(input) {
+			t.Fatalf("%v: expected offset %v, got %v", n, len(input), sc.Offset())
+		}
+	}
+}
+
+func TestSkipWhitespace(t *testing.T) {
+	for n := 0; n < 40; n++ {
+		for _, stop := range []byte{'x', '{', 0, 0x80, 0xff, ' ' + 0x80, '\v', '\f', '\r' + 1} {
+			w := make([]byte, n+1)
+			for i := range w[:n] {
+				w[i] = " \t\r\n"[i%4]
+			}
+			w[n] = stop
+			if got := skipWhitespace(w); got != n {
+				t.Fatalf("%q: expected %v, got %v", w, n, got)
+			}
+			if got := skipWhitespace(w[:n]); got != n {
+				t.Fatalf("%q: expected %v, got %v", w[:n], n, got)
+			}
+		}
+	}
+}
+
+func BenchmarkScanner_skipContainerString(b *testing.B) {
+	input := []byte(`["` + strings.Repeat("abcdefg ", 8<<10) + `"]`)
+	b.SetBytes(int64(len(input)))
+	s := Scanner{data: input}
+	for i := 0; i < b.N; i++ {
+		s.offset = 1
+		if err := s.skipContainer(ArrayStart, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanner_skipObject(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteString(`{`)
+	for i := 0; i < 100; i++ {
+		if i > 0 {
+			buf.WriteString(`, `)
+		}
+		fmt.Fprintf(&buf, `"member%d": {"id": %d, "tags": ["a", "b"], "ok": true}`, i, i)
+	}
+	buf.WriteString(`}`)
+	input := buf.Bytes()
+	b.SetBytes(int64(len(input)))
+	s := Scanner{data: input}
+	for i := 0; i < b.N; i++ {
+		s.offset = 1
+		if err := s.skipContainer(ObjectStart, 0); err != nil {
+			b.Fatal(err)
+		}
 	}
 }
@@ -0,0 +1,43 @@
+package json
+
+import "io"
+
+// TokenInfo describes a token of a JSON document, as returned by Tokenize.
+type TokenInfo struct {
+	Kind  Kind
+	Start int // offset of the first byte of the token
+	End   int // offset just past the last byte of the token
+	Depth int // number of arrays and objects enclosing the token
+}
+
+// Tokenize returns the tokens of data, in order, with their locations. As
+// with NextToken, the commas and colons separating values are checked but
+// not returned, and data may hold several top-level values. The brackets
+// of an array or object have the depth of the container, one less than its
+// elements. opts are applied as for NewDecoder; the spans of single-quoted
+// strings and bare keys accepted by AllowRelaxedStrings cover them as
+// written.
+//
+// If data is not valid, Tokenize returns the tokens before the error along
+// with the error.
+func Tokenize(data []byte, opts ...Option) ([]TokenInfo, error) {
+	d := GetDecoder(data, opts...)
+	defer PutDecoder(d)
+	var toks []TokenInfo
+	for {
+		tok, err := d.NextToken()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return toks, err
+		}
+		kind := tokenKind(tok)
+		depth := d.len()
+		if kind == KindObjectStart || kind == KindArrayStart {
+			depth--
+		}
+		start, end := d.scanner.TokenSpan()
+		toks = append(toks, TokenInfo{Kind: kind, Start: start, End: end, Depth: depth})
+	}
+}
@@ -0,0 +1,67 @@
+package json
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	input := " \r\n{\t\"a\"  :\n\n[ 1 ,\t\t-2.5e3,\r\n  \"x\\\"y\" ] ,   \"b\":{ }\r\n,\"c\" :null   }\n\t true  "
+	want := []struct {
+		kind  Kind
+		text  string
+		depth int
+	}{
+		{KindObjectStart, `{`, 0},
+		{KindString, `"a"`, 1},
+		{KindArrayStart, `[`, 1},
+		{KindNumber, `1`, 2},
+		{KindNumber, `-2.5e3`, 2},
+		{KindString, `"x\"y"`, 2},
+		{KindArrayEnd, `]`, 1},
+		{KindString, `"b"`, 1},
+		{KindObjectStart, `{`, 1},
+		{KindObjectEnd, `}`, 1},
+		{KindString, `"c"`, 1},
+		{KindNull, `null`, 1},
+		{KindObjectEnd, `}`, 0},
+		{KindBool, `true`, 0},
+	}
+	toks, err := Tokenize([]byte(input))
+	check(t, err)
+	if len(toks) != len(want) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(want), len(toks), toks)
+	}
+	for i, w := range want {
+		tok := toks[i]
+		if tok.Kind != w.kind || input[tok.Start:tok.End] != w.text || tok.Depth != w.depth {
+			t.Errorf("%d: got %v %q depth %d, want %v %q depth %d", i, tok.Kind, input[tok.Start:tok.End], tok.Depth, w.kind, w.text, w.depth)
+		}
+	}
+
+	// relaxed strings keep their spans in the input.
+	input = `{key: 'v'}`
+	toks, err = Tokenize([]byte(input), AllowRelaxedStrings())
+	check(t, err)
+	if len(toks) != 4 || input[toks[1].Start:toks[1].End] != `key` || input[toks[2].Start:toks[2].End] != `'v'` || toks[2].Kind != KindString {
+		t.Errorf("Tokenize(%s): got %v", input, toks)
+	}
+
+	// the tokens before an error are returned.
+	toks, err = Tokenize([]byte(`[1, 2 3]`))
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || len(toks) != 3 {
+		t.Errorf("Tokenize: got %v, %v, want 3 tokens and a *SyntaxError", toks, err)
+	}
+	if toks, err = Tokenize([]byte(`[1,`)); err != io.ErrUnexpectedEOF || len(toks) != 2 {
+		t.Errorf("Tokenize: got %v, %v, want 2 tokens and io.ErrUnexpectedEOF", toks, err)
+	}
+	if toks, err = Tokenize(nil); toks != nil || err != nil {
+		t.Errorf("Tokenize(nil): got %v, %v", toks, err)
+	}
+	if toks, _ := Tokenize([]byte(`1 [2]`)); !reflect.DeepEqual(toks, []TokenInfo{{KindNumber, 0, 1, 0}, {KindArrayStart, 2, 3, 0}, {KindNumber, 3, 4, 1}, {KindArrayEnd, 4, 5, 0}}) {
+		t.Errorf("Tokenize: got %v", toks)
+	}
+}
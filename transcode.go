@@ -0,0 +1,63 @@
+package json
+
+import (
+	"fmt"
+	"io"
+)
+
+// Transcode reads the values from src to the end of its input and writes
+// them to dst with the streaming writer API, token by token, so each is
+// re-serialized as dst is configured: compact, or indented after SetIndent,
+// with strings escaped as SetEscapeHTML specifies and each top-level value
+// followed by a newline. Numbers are copied verbatim rather than converted
+// to floating point and back.
+//
+// Input accepted by src's relaxed options, such as comments, trailing commas
+// or single-quoted strings, comes out as strict JSON. NaN and infinities,
+// which strict JSON cannot represent, are an error.
+//
+// If src returns an error, Transcode returns it, and dst is left in the
+// middle of the value being written.
+func Transcode(dst *Encoder, src *Decoder) error {
+	for {
+		tok, err := src.NextToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tok[0] {
+		case ObjectStart:
+			err = dst.WriteObjectStart()
+		case ObjectEnd:
+			err = dst.WriteObjectEnd()
+		case ArrayStart:
+			err = dst.WriteArrayStart()
+		case ArrayEnd:
+			err = dst.WriteArrayEnd()
+		case String:
+			var s []byte
+			if s, err = src.unquoteBytes(tok); err != nil {
+				return err
+			}
+			if dst.expectingKey() {
+				err = dst.WriteKey(bytesToString(s))
+			} else {
+				err = dst.WriteString(bytesToString(s))
+			}
+		case True, False:
+			err = dst.WriteBool(tok[0] == True)
+		case Null:
+			err = dst.WriteNull()
+		default:
+			if lit := nanInfLiteral(tok); lit != "" {
+				return fmt.Errorf("json: Transcode: %s at offset %d cannot be represented in JSON", lit, src.scanner.start)
+			}
+			err = dst.writeRaw("WriteRaw", tok)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestTranscodeFixtures(t *testing.T) {
+	for _, tc := range inputs {
+		t.Run(tc.path, func(t *testing.T) {
+			data, err := io.ReadAll(fixture(t, tc.path))
+			check(t, err)
+			var compact, indented bytes.Buffer
+			check(t, Transcode(NewEncoder(&compact), NewDecoder(data)))
+			if ok, err := Equal(compact.Bytes(), data); err != nil || !ok {
+				t.Fatalf("got a different document, %v", err)
+			}
+			enc := NewEncoder(&indented)
+			enc.SetIndent(">", "\t")
+			check(t, Transcode(enc, NewDecoder(data)))
+			var want bytes.Buffer
+			check(t, json.Indent(&want, compact.Bytes(), ">", "\t"))
+			if !bytes.Equal(indented.Bytes(), want.Bytes()) {
+				t.Fatalf("indented output differs from Indent")
+			}
+		})
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	tests := []struct {
+		in     string
+		opts   []Option
+		indent string
+		want   string
+	}{
+		{in: `{"a" : [1.50, -0, 1E+2], "b": {}, "c": []}`, want: `{"a":[1.50,-0,1E+2],"b":{},"c":[]}` + "\n"},
+		{in: `1 "two" [3]`, want: "1\n\"two\"\n[3]\n"},
+		{in: ``, want: ``},
+		{in: `"<A\/>"`, want: `"\u003cA/\u003e"` + "\n"},
+		{
+			in:     `{"a": [1, {"b": null}], "c": "d"}`,
+			indent: "  ",
+			want:   "{\n  \"a\": [\n    1,\n    {\n      \"b\": null\n    }\n  ],\n  \"c\": \"d\"\n}\n",
+		},
+		{
+			in:   "{\n  // settings\n  name: 'x\\'y', /* inline */ list: [1, 2,],\n}",
+			opts: []Option{AllowComments(), AllowRelaxedStrings(), AllowTrailingCommas()},
+			want: `{"name":"x'y","list":[1,2]}` + "\n",
+		},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetIndent("", tc.indent)
+		if err := Transcode(enc, NewDecoder([]byte(tc.in), tc.opts...)); err != nil || buf.String() != tc.want {
+			t.Errorf("Transcode(%s): got %q, %v, want %q", tc.in, buf.String(), err, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		in   string
+		opts []Option
+	}{
+		{in: `[1, 2`},
+		{in: `{"a" 1}`},
+		{in: `["\ud800"]`},
+		{in: `[NaN]`, opts: []Option{AllowNaNInf()}},
+		{in: `{"a": -Infinity}`, opts: []Option{AllowNaNInf()}},
+	} {
+		var buf bytes.Buffer
+		if err := Transcode(NewEncoder(&buf), NewDecoder([]byte(tc.in), tc.opts...)); err == nil {
+			t.Errorf("Transcode(%s): got %q, want error", tc.in, buf.String())
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecodeUnion consumes the next value, which must be an object, and decodes
+// it into a value whose type depends on the string member named field, the
+// discriminator, as in {"type": "click", "x": 1, "y": 2}. The object is
+// first read as NextAsBytes reads it, the discriminator is then found by a
+// second scan of its bytes, and factory is called with its value to return
+// a non-nil pointer to decode the object into, as Decode would; that
+// pointer is returned. The discriminator member is decoded along with the
+// others, so the type may have a field for it or ignore it.
+//
+// A null is consumed and returns nil without calling factory. If the next
+// token is neither an object start nor null, DecodeUnion returns a
+// *KindError and does not consume it. Otherwise the object is consumed
+// whatever the outcome, and the three ways it can fail to be decoded are
+// told apart by the error: it wraps ErrNoDiscriminator if the object has no
+// string member named field, ErrUnknownKind and the error of factory if
+// factory returns one, or else the error of decoding the object, such as an
+// *UnmarshalTypeError, if it does not match the type chosen.
+func (d *Decoder) DecodeUnion(field string, factory func(kind string) (interface{}, error)) (interface{}, error) {
+	start := d.peekOffset()
+	switch kind := d.PeekKind(); kind {
+	case KindNull:
+		_, err := d.NextToken()
+		return nil, err
+	case KindObjectStart, KindInvalid:
+		// an invalid token, or the end of the input, is reported by
+		// NextAsBytes.
+	default:
+		return nil, &KindError{Want: KindObjectStart, Got: kind, Offset: int64(start)}
+	}
+	depth, m := d.len(), d.mark()
+	obj, err := d.NextAsBytes()
+	if err != nil {
+		return nil, err
+	}
+	kind, err := d.discriminator(obj, field)
+	if err != nil {
+		return nil, fmt.Errorf("%w: object at offset %d: %w", ErrNoDiscriminator, start, err)
+	}
+	v, err := factory(kind)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q of object at offset %d: %w", ErrUnknownKind, kind, start, err)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, fmt.Errorf("json: DecodeUnion: factory returned %T for kind %q, not a non-nil pointer", v, kind)
+	}
+	// decode the object in place, rather than from a copy of its bytes, so
+	// that the offsets and paths of errors are those of the input.
+	end := d.mark()
+	d.rewind(m)
+	if err := d.decodeValue(rv.Elem()); err != nil {
+		// leave the Decoder after the object, however much of it was read.
+		if d.len() == depth || d.skipOut(depth+1) == nil {
+			d.rewind(end)
+		}
+		return nil, fmt.Errorf("json: decoding %q object at offset %d: %w", kind, start, err)
+	}
+	return v, nil
+}
+
+// discriminator returns the value of the string member named field of obj,
+// an object read by d, which is scanned with the same options as d.
+func (d *Decoder) discriminator(obj []byte, field string) (string, error) {
+	s := GetDecoder(obj)
+	defer PutDecoder(s)
+	s.scanner.flags = d.scanner.flags
+	s.allowLoneSurrogates = d.allowLoneSurrogates
+	if err := s.Seek(field); err != nil {
+		return "", err
+	}
+	kind, err := s.ReadString()
+	if err != nil {
+		return "", fmt.Errorf("member %q: %w", field, err)
+	}
+	return kind, nil
+}
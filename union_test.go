@@ -0,0 +1,115 @@
+package json
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type clickEvent struct {
+	Type string `json:"type"`
+	X, Y int
+}
+
+type keyEvent struct {
+	Key  string
+	Meta map[string]string
+}
+
+var errUnknownEvent = errors.New("unknown event")
+
+func newEvent(kind string) (interface{}, error) {
+	switch kind {
+	case "click":
+		return &clickEvent{}, nil
+	case "key":
+		return &keyEvent{}, nil
+	}
+	return nil, errUnknownEvent
+}
+
+func TestDecodeUnion(t *testing.T) {
+	in := `[
+		{"type": "click", "X": 1, "Y": 2},
+		{"Key": "a", "Meta": {"type": "ignored"}, "type": "key"},
+		null,
+		{"X": 1},
+		{"type": 1},
+		{"type": "scroll", "dy": 3},
+		{"type": "click", "X": {"nested": [1]}, "Y": 4},
+		{"type": "key", "Key": 5},
+		{"type": "click"}
+	]`
+	var got []interface{}
+	var errs []error
+	d := NewDecoder([]byte(in))
+	check(t, d.Array(func(i int) error {
+		v, err := d.DecodeUnion("type", newEvent)
+		got, errs = append(got, v), append(errs, err)
+		return nil
+	}))
+	want := []interface{}{
+		&clickEvent{Type: "click", X: 1, Y: 2},
+		&keyEvent{Key: "a", Meta: map[string]string{"type": "ignored"}},
+		nil, nil, nil, nil, nil, nil,
+		&clickEvent{Type: "click"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for i, err := range errs {
+		switch i {
+		case 0, 1, 2, 8:
+			if err != nil {
+				t.Errorf("element %d: got error %v", i, err)
+			}
+		case 3, 4:
+			if !errors.Is(err, ErrNoDiscriminator) {
+				t.Errorf("element %d: got %v, want ErrNoDiscriminator", i, err)
+			}
+		case 5:
+			if !errors.Is(err, ErrUnknownKind) || !errors.Is(err, errUnknownEvent) || !strings.Contains(err.Error(), `"scroll"`) {
+				t.Errorf("element %d: got %v, want ErrUnknownKind wrapping the factory's error", i, err)
+			}
+		case 6, 7:
+			var typeErr *UnmarshalTypeError
+			if !errors.As(err, &typeErr) || errors.Is(err, ErrNoDiscriminator) || errors.Is(err, ErrUnknownKind) {
+				t.Errorf("element %d: got %v, want an *UnmarshalTypeError", i, err)
+			} else if want := strings.Index(in, `{"nested"`); i == 6 && (typeErr.Offset != int64(want) || typeErr.Path != "$[6].X") {
+				t.Errorf("element %d: got offset %d, path %s, want %d, $[6].X", i, typeErr.Offset, typeErr.Path, want)
+			}
+		}
+	}
+
+	// values other than objects are left in place.
+	d = NewDecoder([]byte(`[1]`))
+	_, err := d.DecodeUnion("type", newEvent)
+	if kindErr, ok := err.(*KindError); !ok || kindErr.Got != KindArrayStart {
+		t.Errorf("got %v, want a *KindError", err)
+	}
+	if tok, err := d.NextToken(); err != nil || string(tok) != "[" {
+		t.Errorf("got %q, %v, want the array start", tok, err)
+	}
+
+	for _, tc := range []struct {
+		in      string
+		factory func(string) (interface{}, error)
+		err     string
+	}{
+		{`{"type": "click"`, newEvent, "unexpected EOF"},
+		{`{"type": "click"}`, func(string) (interface{}, error) { return clickEvent{}, nil }, "not a non-nil pointer"},
+		{`{"type": "click"}`, func(string) (interface{}, error) { return nil, nil }, "not a non-nil pointer"},
+	} {
+		_, err := NewDecoder([]byte(tc.in)).DecodeUnion("type", tc.factory)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("DecodeUnion(%s): got %v, want %s", tc.in, err, tc.err)
+		}
+	}
+
+	// the object is scanned with the Decoder's options.
+	d = NewDecoder([]byte(`{/* kind */ "type": "key", "Key": "b",}`), AllowComments(), AllowTrailingCommas())
+	if v, err := d.DecodeUnion("type", newEvent); err != nil || !reflect.DeepEqual(v, &keyEvent{Key: "b"}) {
+		t.Errorf("got %v, %v, want &{b}", v, err)
+	}
+}
@@ -0,0 +1,37 @@
+package json
+
+import "io"
+
+// Valid reports whether data is a valid JSON encoding of exactly one value,
+// optionally surrounded by whitespace and preceded by a UTF-8 byte order mark.
+// opts are applied as for NewDecoder.
+func Valid(data []byte, opts ...Option) bool {
+	return Validate(data, opts...) == nil
+}
+
+// Validate checks that data is a valid JSON encoding of exactly one value,
+// optionally surrounded by whitespace and preceded by a UTF-8 byte order mark.
+// Unlike Skip, every token is checked, including those inside arrays and
+// objects. If data is not valid, Validate returns a *SyntaxError locating the
+// first problem, or an error wrapping ErrMaxDepthExceeded if it is nested too
+// deeply. opts are applied as for NewDecoder; AllowComments, for instance,
+// permits comments in data.
+func Validate(data []byte, opts ...Option) error {
+	d := GetDecoder(data, opts...)
+	defer PutDecoder(d)
+	for {
+		if _, err := d.state(d); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return newSyntaxError(data, len(data), "unexpected end of JSON input")
+			}
+			return err
+		}
+		if d.len() == 0 {
+			break
+		}
+	}
+	if d.scanner.peek(); d.scanner.offset < len(data) {
+		return d.scanner.trailingError()
+	}
+	return nil
+}
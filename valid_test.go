@@ -0,0 +1,108 @@
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for _, tc := range inputs {
+		data, err := io.ReadAll(fixture(t, tc.path))
+		check(t, err)
+		if err := Validate(data); err != nil {
+			t.Errorf("%s: %v", tc.path, err)
+		}
+	}
+	for _, in := range []string{
+		`0`, ` true `, "\n\tnull\r\n", `"\u00e9\ud83d\ude00\/"`, `-1.5e+10`,
+		`{}`, `[]`, `{"a": [1, {"b": null}], "c": "d"}`,
+		strings.Repeat("[", 100) + strings.Repeat("]", 100),
+	} {
+		if !Valid([]byte(in)) {
+			t.Errorf("Valid(%q): got false, want true", in)
+		}
+		if !json.Valid([]byte(in)) {
+			t.Errorf("encoding/json considers %q invalid", in)
+		}
+	}
+}
+
+func TestValidateError(t *testing.T) {
+	tests := []struct {
+		json   string
+		offset int64
+		msg    string
+	}{
+		{``, 0, `unexpected end of JSON input`},
+		{`[1, 2`, 5, `unexpected end of JSON input`},
+		{`{"a": "b`, 8, `unexpected end of JSON input`},
+		{`[1] x`, 4, `invalid character 'x' after top-level value`},
+		{`{"a": [1, {"b": "\q"}]}`, 18, `invalid character 'q' in string escape code`},
+		{`{"a": [1, {"b": tru}]}`, 19, `invalid character '}' in literal true (expecting 'e')`},
+		{`[1, 2.]`, 6, `invalid character ']' in numeric literal`},
+	}
+	for _, tc := range tests {
+		err := Validate([]byte(tc.json))
+		var serr *SyntaxError
+		if !errors.As(err, &serr) || serr.Offset != tc.offset || serr.msg != tc.msg {
+			t.Errorf("Validate(%q): got %v, want %q at offset %d", tc.json, err, tc.msg, tc.offset)
+		}
+	}
+
+	deep := strings.Repeat("[", DefaultMaxDepth+1) + strings.Repeat("]", DefaultMaxDepth+1)
+	if err := Validate([]byte(deep)); !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Errorf("Validate: got %v, want ErrMaxDepthExceeded", err)
+	}
+}
+
+func TestValidAllocs(t *testing.T) {
+	data, err := io.ReadAll(fixture(t, "citm_catalog"))
+	check(t, err)
+	Valid(data) // warm the pool
+	allocs := testing.AllocsPerRun(5, func() {
+		if !Valid(data) {
+			t.Fatal("expected valid")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Valid: got %v allocs, want 0", allocs)
+	}
+}
+
+func TestValidateComments(t *testing.T) {
+	for _, in := range []string{
+		`// config` + "\n" + `{"a": 1}`,
+		`{"a": /* one */ 1, /* two */ "b": [1, // three` + "\n" + `2]} // end`,
+		"/* multi\n line\n */ [/**/]/***/",
+		`{"url": "http://x/*y*/"} //`,
+	} {
+		if err := Validate([]byte(in), AllowComments()); err != nil {
+			t.Errorf("Validate(%q, AllowComments()): %v", in, err)
+		}
+		if Valid([]byte(in)) {
+			t.Errorf("Valid(%q): got true without AllowComments", in)
+		}
+	}
+	tests := []struct {
+		json   string
+		offset int64
+		msg    string
+	}{
+		{`[1, /* 2 ]`, 4, `unterminated comment`},
+		{`/* [1]`, 0, `unterminated comment`},
+		{`[1] /* x`, 4, `unterminated comment`},
+		{`[1, /* x */ ]`, 12, `invalid character ']' looking for beginning of value`},
+		{`[1 / 2]`, 3, `invalid character '/' looking for beginning of value`},
+		{`/ []`, 0, `invalid character '/' looking for beginning of value`},
+	}
+	for _, tc := range tests {
+		err := Validate([]byte(tc.json), AllowComments())
+		var serr *SyntaxError
+		if !errors.As(err, &serr) || serr.Offset != tc.offset || serr.msg != tc.msg {
+			t.Errorf("Validate(%q, AllowComments()): got %v, want %q at offset %d", tc.json, err, tc.msg, tc.offset)
+		}
+	}
+}
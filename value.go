@@ -0,0 +1,219 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// A Value is a JSON value held as its raw bytes, which are only decoded as
+// far as its accessor methods require.
+//
+// The accessors of a Value of the wrong kind, or one which does not exist,
+// return the zero value of their result and record an error. Values looked
+// up from one another share the record, which keeps the first error, so a
+// chain of lookups need only be checked once, with Err, at the end.
+type Value struct {
+	raw  []byte
+	kind Kind
+	errs *valueErr
+}
+
+// valueErr is the error record shared by the Values derived from one Parse.
+type valueErr struct {
+	err error
+}
+
+// Parse returns the first JSON value in data as a Value. The Value refers to
+// data. Only as much of data is validated as is needed to find the end of
+// the value; the rest is checked by the accessors that read it.
+func Parse(data []byte) Value {
+	d := GetDecoder(data)
+	defer PutDecoder(d)
+	return d.Value()
+}
+
+// Value consumes the next value and returns it as a Value, which refers to
+// the input.
+func (d *Decoder) Value() Value {
+	v := Value{errs: new(valueErr)}
+	b, err := d.skipValue()
+	if err != nil {
+		v.fail(err)
+		return v
+	}
+	v.raw, v.kind = b, tokenKind(b)
+	return v
+}
+
+// skipValue consumes the next value, like Skip, and returns its raw bytes.
+func (d *Decoder) skipValue() ([]byte, error) {
+	start := d.peekOffset()
+	if err := d.skip(); err != nil {
+		return nil, err
+	}
+	return d.scanner.span(start, d.scanner.offset), nil
+}
+
+// derive returns a Value for raw, sharing the error record of v.
+func (v Value) derive(raw []byte) Value {
+	return Value{raw: raw, kind: kinds[raw[0]], errs: v.errs}
+}
+
+// fail records err, unless an earlier error has been recorded.
+func (v Value) fail(err error) {
+	if v.errs != nil && v.errs.err == nil {
+		v.errs.err = err
+	}
+}
+
+// want reports whether v is of kind k, recording an error if it is not.
+func (v Value) want(k Kind) bool {
+	if v.kind != k {
+		v.fail(&KindError{Want: k, Got: v.kind})
+		return false
+	}
+	return true
+}
+
+// Err returns the first error recorded by v, or by any Value it was looked
+// up from or which was looked up from it.
+func (v Value) Err() error {
+	if v.errs == nil {
+		return nil
+	}
+	return v.errs.err
+}
+
+// Exists reports whether v holds a value. Get returns a Value which does not
+// exist when the path is not found.
+func (v Value) Exists() bool {
+	return v.raw != nil
+}
+
+// Kind returns the kind of v, or KindInvalid if v does not exist.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// Raw returns the JSON encoding of v, which refers to the parsed data.
+func (v Value) Raw() []byte {
+	return v.raw
+}
+
+// Int returns v, which must be a number, as an int64.
+func (v Value) Int() int64 {
+	if !v.want(KindNumber) {
+		return 0
+	}
+	i, err := strconv.ParseInt(bytesToString(v.raw), 10, 64)
+	if err != nil {
+		v.fail(fmt.Errorf("json: cannot convert number: %w", err))
+		return 0
+	}
+	return i
+}
+
+// Float returns v, which must be a number, as a float64.
+func (v Value) Float() float64 {
+	if !v.want(KindNumber) {
+		return 0
+	}
+	f, err := strconv.ParseFloat(bytesToString(v.raw), 64)
+	if err != nil {
+		v.fail(fmt.Errorf("json: cannot convert number: %w", err))
+		return 0
+	}
+	return f
+}
+
+// Bool returns v, which must be true or false.
+func (v Value) Bool() bool {
+	return v.want(KindBool) && v.raw[0] == True
+}
+
+// Str returns the contents of v, which must be a string, with escape
+// sequences decoded.
+func (v Value) Str() string {
+	if !v.want(KindString) {
+		return ""
+	}
+	if bytes.IndexByte(v.raw, '\\') < 0 {
+		return string(v.raw[1 : len(v.raw)-1])
+	}
+	b, i := unescape(nil, v.raw, false)
+	if i >= 0 {
+		v.fail(newSyntaxError(v.raw, i, escapeError(v.raw, i)))
+		return ""
+	}
+	return string(b)
+}
+
+// Array returns the elements of v, which must be an array.
+func (v Value) Array() []Value {
+	if !v.want(KindArrayStart) {
+		return nil
+	}
+	d := GetDecoder(v.raw)
+	defer PutDecoder(d)
+	vs := []Value{}
+	err := d.Array(func(i int) error {
+		b, err := d.skipValue()
+		if err == nil {
+			vs = append(vs, v.derive(b))
+		}
+		return err
+	})
+	if err != nil {
+		v.fail(err)
+		return nil
+	}
+	return vs
+}
+
+// Map returns the members of v, which must be an object. If a key appears
+// more than once, its last value is used.
+func (v Value) Map() map[string]Value {
+	if !v.want(KindObjectStart) {
+		return nil
+	}
+	d := GetDecoder(v.raw)
+	defer PutDecoder(d)
+	m := make(map[string]Value)
+	err := d.Object(func(key []byte) error {
+		b, err := d.skipValue()
+		if err == nil {
+			m[string(key)] = v.derive(b)
+		}
+		return err
+	})
+	if err != nil {
+		v.fail(err)
+		return nil
+	}
+	return m
+}
+
+// Get returns the value found by following path from v, as described for
+// Decoder.Seek. If the path is not found, the result does not exist; that is
+// not itself recorded as an error, but reading the result is.
+func (v Value) Get(path ...string) Value {
+	if !v.Exists() {
+		return Value{errs: v.errs}
+	}
+	d := GetDecoder(v.raw)
+	defer PutDecoder(d)
+	if err := d.Seek(path...); err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			v.fail(err)
+		}
+		return Value{errs: v.errs}
+	}
+	b, err := d.skipValue()
+	if err != nil {
+		v.fail(err)
+		return Value{errs: v.errs}
+	}
+	return v.derive(b)
+}
@@ -0,0 +1,138 @@
+package json
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	v := Parse([]byte(` {"user": {"name": "Gopher", "age": 13, "admin": false, "score": 9.5},
+		"tags": ["a", "b", "c"], "none": null} `))
+	if !v.Exists() || v.Kind() != KindObjectStart {
+		t.Fatalf("Parse: got kind %v, exists %v", v.Kind(), v.Exists())
+	}
+	user := v.Get("user")
+	if got := user.Get("name").Str(); got != "Gopher" {
+		t.Errorf("name: got %q", got)
+	}
+	if got := user.Get("age").Int(); got != 13 {
+		t.Errorf("age: got %d", got)
+	}
+	if got := v.Get("user", "admin").Bool(); got {
+		t.Errorf("admin: got %v", got)
+	}
+	if got := v.Get("user", "score").Float(); got != 9.5 {
+		t.Errorf("score: got %v", got)
+	}
+	if got := v.Get("tags", "2").Str(); got != "c" {
+		t.Errorf("tags.2: got %q", got)
+	}
+	if n := v.Get("none"); !n.Exists() || n.Kind() != KindNull {
+		t.Errorf("none: got kind %v, exists %v", n.Kind(), n.Exists())
+	}
+	if string(v.Get("user", "age").Raw()) != "13" {
+		t.Errorf("age: got raw %q", v.Get("user", "age").Raw())
+	}
+
+	tags := v.Get("tags").Array()
+	if len(tags) != 3 || tags[0].Str() != "a" || tags[2].Str() != "c" {
+		t.Errorf("tags: got %v", tags)
+	}
+	m := user.Map()
+	if len(m) != 4 || m["name"].Str() != "Gopher" || m["age"].Int() != 13 {
+		t.Errorf("user: got %v", m)
+	}
+	if a := Parse([]byte(`[]`)).Array(); a == nil || len(a) != 0 {
+		t.Errorf("empty array: got %#v", a)
+	}
+	if err := v.Err(); err != nil {
+		t.Errorf("Err: got %v", err)
+	}
+}
+
+func TestValueErr(t *testing.T) {
+	v := Parse([]byte(`{"a": {"b": "str", "n": 1.5}}`))
+
+	// a missing path is not an error until it is read.
+	missing := v.Get("a", "x", "y")
+	if missing.Exists() || missing.Kind() != KindInvalid {
+		t.Errorf("missing: got kind %v, exists %v", missing.Kind(), missing.Exists())
+	}
+	if err := v.Err(); err != nil {
+		t.Fatalf("Err after Get: got %v, want nil", err)
+	}
+	if got := missing.Int(); got != 0 {
+		t.Errorf("missing.Int: got %d", got)
+	}
+	var kerr *KindError
+	if err := v.Err(); !errors.As(err, &kerr) || kerr.Want != KindNumber || kerr.Got != KindInvalid {
+		t.Fatalf("Err: got %v, want *KindError", err)
+	}
+
+	// only the first error is kept.
+	if got := v.Get("a", "b").Int(); got != 0 {
+		t.Errorf("a.b.Int: got %d", got)
+	}
+	if err := v.Err(); !errors.As(err, &kerr) || kerr.Got != KindInvalid {
+		t.Errorf("Err: got %v, want the first error", err)
+	}
+
+	// conversion errors are recorded too.
+	w := Parse([]byte(`{"n": 1.5, "big": 1e999}`))
+	if got := w.Get("n").Int(); got != 0 {
+		t.Errorf("n.Int: got %d", got)
+	}
+	if err := w.Err(); !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("Err: got %v, want strconv.ErrSyntax", err)
+	}
+	w = Parse([]byte(`{"n": 1.5, "big": 1e999}`))
+	if got := w.Get("n").Float(); got != 1.5 {
+		t.Errorf("n.Float: got %v", got)
+	}
+	w.Get("big").Float()
+	if err := w.Err(); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("Err: got %v, want strconv.ErrRange", err)
+	}
+}
+
+func TestValueInvalid(t *testing.T) {
+	v := Parse(nil)
+	if v.Exists() || v.Err() != io.EOF {
+		t.Errorf("Parse(nil): got exists %v, err %v", v.Exists(), v.Err())
+	}
+
+	// the contents of a container are only validated when they are read.
+	v = Parse([]byte(`{"ok": 1, "bad": [1 2]}`))
+	if err := v.Err(); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := v.Get("ok").Int(); got != 1 {
+		t.Errorf("ok: got %d", got)
+	}
+	if err := v.Err(); err != nil {
+		t.Fatalf("Err: got %v", err)
+	}
+	if got := v.Get("bad").Array(); got != nil {
+		t.Errorf("bad: got %v, want nil", got)
+	}
+	var serr *SyntaxError
+	if err := v.Err(); !errors.As(err, &serr) {
+		t.Errorf("Err: got %v, want *SyntaxError", err)
+	}
+}
+
+func TestDecoderValue(t *testing.T) {
+	d := NewDecoder([]byte(`[{"id": 1}, {"id": 2}]`))
+	d.NextToken()
+	var ids []int64
+	for d.More() {
+		v := d.Value()
+		ids = append(ids, v.Get("id").Int())
+		check(t, v.Err())
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("got ids %v", ids)
+	}
+}
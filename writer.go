@@ -0,0 +1,328 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// The streaming writer API builds a document one token at a time:
+//
+//	enc.WriteObjectStart()
+//	enc.WriteKey("a")
+//	enc.WriteInt(1)
+//	enc.WriteObjectEnd()
+//
+// The Encoder inserts commas and colons itself and rejects calls that
+// would produce structurally invalid JSON. Output is buffered and written
+// to the underlying io.Writer once it grows past writerFlushSize, and when
+// the top-level value is complete, at which point a newline is written as
+// with Encode. After SetIndent, the output is indented as Encode's is.
+
+// writerFlushSize is the size at which buffered writer output is flushed.
+const writerFlushSize = 4 << 10
+
+var errIncompleteDocument = errors.New("json: Encode called while a document written with the streaming API is incomplete")
+
+// writeFrame records an array or object opened by the streaming writer API.
+type writeFrame struct {
+	obj bool // object rather than array
+	n   int  // number of keys and values written into it
+}
+
+// WriteObjectStart begins a new object.
+func (e *Encoder) WriteObjectStart() error {
+	if err := e.beginValue("WriteObjectStart"); err != nil {
+		return err
+	}
+	e.wbuf = append(e.wbuf, ObjectStart)
+	e.frames = append(e.frames, writeFrame{obj: true})
+	return nil
+}
+
+// WriteObjectEnd ends the innermost object.
+func (e *Encoder) WriteObjectEnd() error {
+	n := len(e.frames)
+	switch {
+	case n == 0 || !e.frames[n-1].obj:
+		return fmt.Errorf("json: WriteObjectEnd: not inside an object")
+	case e.frames[n-1].n%2 == 1:
+		return fmt.Errorf("json: WriteObjectEnd: missing value for object key")
+	}
+	e.endContainer()
+	e.wbuf = append(e.wbuf, ObjectEnd)
+	return e.endValue()
+}
+
+// WriteArrayStart begins a new array.
+func (e *Encoder) WriteArrayStart() error {
+	if err := e.beginValue("WriteArrayStart"); err != nil {
+		return err
+	}
+	e.wbuf = append(e.wbuf, ArrayStart)
+	e.frames = append(e.frames, writeFrame{obj: false})
+	return nil
+}
+
+// WriteArrayEnd ends the innermost array.
+func (e *Encoder) WriteArrayEnd() error {
+	n := len(e.frames)
+	if n == 0 || e.frames[n-1].obj {
+		return fmt.Errorf("json: WriteArrayEnd: not inside an array")
+	}
+	e.endContainer()
+	e.wbuf = append(e.wbuf, ArrayEnd)
+	return e.endValue()
+}
+
+// WriteKey writes the key of the next member of the innermost object.
+func (e *Encoder) WriteKey(key string) error {
+	n := len(e.frames)
+	if n == 0 || !e.frames[n-1].obj {
+		return fmt.Errorf("json: WriteKey: not inside an object")
+	}
+	f := &e.frames[n-1]
+	if f.n%2 == 1 {
+		return fmt.Errorf("json: WriteKey: expecting a value, not another key")
+	}
+	if f.n > 0 {
+		e.wbuf = append(e.wbuf, Comma)
+	}
+	f.n++
+	e.writeNewline(n)
+	e.wbuf = appendString(e.wbuf, key, e.escapeHTML)
+	return nil
+}
+
+// WriteString writes a string value.
+func (e *Encoder) WriteString(s string) error {
+	if err := e.beginValue("WriteString"); err != nil {
+		return err
+	}
+	e.wbuf = appendString(e.wbuf, s, e.escapeHTML)
+	return e.endValue()
+}
+
+// WriteInt writes an integer value.
+func (e *Encoder) WriteInt(i int64) error {
+	if err := e.beginValue("WriteInt"); err != nil {
+		return err
+	}
+	e.wbuf = AppendInt(e.wbuf, i)
+	return e.endValue()
+}
+
+// WriteFloat writes a floating-point value. NaN and infinities are
+// reported as an *UnsupportedValueError and nothing is written.
+func (e *Encoder) WriteFloat(f float64) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		_, err := AppendFloat(nil, f, 64)
+		return err
+	}
+	if err := e.beginValue("WriteFloat"); err != nil {
+		return err
+	}
+	e.wbuf, _ = AppendFloat(e.wbuf, f, 64)
+	return e.endValue()
+}
+
+// WriteBool writes true or false.
+func (e *Encoder) WriteBool(b bool) error {
+	if err := e.beginValue("WriteBool"); err != nil {
+		return err
+	}
+	e.wbuf = AppendBool(e.wbuf, b)
+	return e.endValue()
+}
+
+// WriteNull writes null.
+func (e *Encoder) WriteNull() error {
+	if err := e.beginValue("WriteNull"); err != nil {
+		return err
+	}
+	e.wbuf = append(e.wbuf, "null"...)
+	return e.endValue()
+}
+
+// WriteRaw writes raw, which must be a single valid JSON value, verbatim.
+// Anything else, such as several values or a byte order mark, is an error
+// and nothing is written.
+func (e *Encoder) WriteRaw(raw []byte) error {
+	if bomLen(raw) > 0 {
+		return fmt.Errorf("json: WriteRaw: byte order mark before value")
+	}
+	if err := Validate(raw); err != nil {
+		return fmt.Errorf("json: WriteRaw: %w", err)
+	}
+	return e.writeRaw("WriteRaw", raw)
+}
+
+// writeRaw writes raw, which is known to be a single valid JSON value,
+// verbatim, naming the method op in an error.
+func (e *Encoder) writeRaw(op string, raw []byte) error {
+	if err := e.beginValue(op); err != nil {
+		return err
+	}
+	e.wbuf = append(e.wbuf, raw...)
+	return e.endValue()
+}
+
+// WriteValue writes the JSON encoding of v, as Encode would, as the next
+// value. If v cannot be encoded nothing is written.
+func (e *Encoder) WriteValue(v interface{}) error {
+	if err := e.checkValue("WriteValue"); err != nil {
+		return err
+	}
+	b, err := e.appendValue(e.buf[:0], reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	e.buf = b
+	if e.indenting() {
+		prefix := e.indentPrefix + strings.Repeat(e.indentValue, len(e.frames))
+		if b, err = appendIndent(e.indentBuf[:0], b, prefix, e.indentValue); err != nil {
+			return err
+		}
+		e.indentBuf = b
+	}
+	return e.writeRaw("WriteValue", b)
+}
+
+// WriteToken writes t, one of the tokens returned by Decoder.Token or by
+// the Token method of encoding/json's Decoder: a Delim, bool, float64,
+// Number, string or nil. A string is written as an object key where one is
+// expected, and as a string value otherwise. Copying the tokens of one
+// decoder to WriteToken reproduces its input, compacted.
+func (e *Encoder) WriteToken(t Token) error {
+	switch t := t.(type) {
+	case Delim:
+		switch t {
+		case '{':
+			return e.WriteObjectStart()
+		case '}':
+			return e.WriteObjectEnd()
+		case '[':
+			return e.WriteArrayStart()
+		case ']':
+			return e.WriteArrayEnd()
+		}
+		return fmt.Errorf("json: WriteToken: invalid delimiter %q", rune(t))
+	case string:
+		if e.expectingKey() {
+			return e.WriteKey(t)
+		}
+		return e.WriteString(t)
+	case float64:
+		return e.WriteFloat(t)
+	case Number:
+		if !isNumber(string(t)) {
+			return fmt.Errorf("json: WriteToken: invalid number literal %q", string(t))
+		}
+		return e.writeRaw("WriteToken", []byte(t))
+	case bool:
+		return e.WriteBool(t)
+	case nil:
+		return e.WriteNull()
+	default:
+		return fmt.Errorf("json: WriteToken: unsupported token type %T", t)
+	}
+}
+
+// isNumber reports whether s is a JSON number and nothing else.
+func isNumber(s string) bool {
+	if s == "" || s[0] != '-' && (s[0] < '0' || s[0] > '9') || s[len(s)-1] < '0' || s[len(s)-1] > '9' {
+		return false
+	}
+	return Valid(stringToBytes(s))
+}
+
+// checkValue returns an error if a value cannot be written at this point,
+// which is only the case directly after another value inside an object, or
+// after the value an EncodeFunc writes.
+func (e *Encoder) checkValue(op string) error {
+	if e.expectingKey() {
+		return fmt.Errorf("json: %s: expecting an object key", op)
+	}
+	if e.collected && len(e.frames) == 0 {
+		return fmt.Errorf("json: %s: an encode func may only write one value", op)
+	}
+	return nil
+}
+
+// expectingKey reports whether the next token written must be an object
+// key.
+func (e *Encoder) expectingKey() bool {
+	n := len(e.frames)
+	return n > 0 && e.frames[n-1].obj && e.frames[n-1].n%2 == 0
+}
+
+// beginValue checks a value may be written and writes any separator needed
+// before it.
+func (e *Encoder) beginValue(op string) error {
+	if err := e.checkValue(op); err != nil {
+		return err
+	}
+	if n := len(e.frames); n > 0 {
+		f := &e.frames[n-1]
+		if f.obj {
+			e.wbuf = append(e.wbuf, Colon)
+			if e.indenting() {
+				e.wbuf = append(e.wbuf, ' ')
+			}
+		} else {
+			if f.n > 0 {
+				e.wbuf = append(e.wbuf, Comma)
+			}
+			e.writeNewline(n)
+		}
+		f.n++
+	}
+	return nil
+}
+
+// endContainer pops the innermost array or object, starting a new line for
+// its closing bracket if it is not empty.
+func (e *Encoder) endContainer() {
+	n := len(e.frames)
+	if e.frames[n-1].n > 0 {
+		e.writeNewline(n - 1)
+	}
+	e.frames = e.frames[:n-1]
+}
+
+// indenting reports whether SetIndent has enabled indentation.
+func (e *Encoder) indenting() bool {
+	return e.indentPrefix != "" || e.indentValue != ""
+}
+
+// writeNewline starts a new line indented depth levels, if indenting.
+func (e *Encoder) writeNewline(depth int) {
+	if !e.indenting() {
+		return
+	}
+	e.wbuf = append(e.wbuf, '\n')
+	e.wbuf = append(e.wbuf, e.indentPrefix...)
+	for ; depth > 0; depth-- {
+		e.wbuf = append(e.wbuf, e.indentValue...)
+	}
+}
+
+// endValue is called after a complete value has been written. It flushes
+// the buffered output if the top-level value is complete or the buffer has
+// grown large.
+func (e *Encoder) endValue() error {
+	if e.collect {
+		e.collected = len(e.frames) == 0
+		return nil
+	}
+	if len(e.frames) == 0 {
+		e.wbuf = append(e.wbuf, '\n')
+	} else if len(e.wbuf) < writerFlushSize {
+		return nil
+	}
+	_, err := e.w.Write(e.wbuf)
+	e.wbuf = e.wbuf[:0]
+	return err
+}
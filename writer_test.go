@@ -0,0 +1,281 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncoderWriter(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	check(t, enc.WriteObjectStart())
+	check(t, enc.WriteKey("a"))
+	check(t, enc.WriteInt(1))
+	check(t, enc.WriteKey("b"))
+	check(t, enc.WriteFloat(123.456))
+	check(t, enc.WriteKey("c"))
+	check(t, enc.WriteArrayStart())
+	check(t, enc.WriteNull())
+	check(t, enc.WriteArrayEnd())
+	if buf.Len() > 0 {
+		t.Fatalf("expected no output before the document is complete, got: %q", buf.String())
+	}
+	check(t, enc.WriteObjectEnd())
+
+	// the input of ExampleDecoder_NextToken, compacted.
+	want := `{"a":1,"b":123.456,"c":[null]}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("expected: %q, got: %q", want, buf.String())
+	}
+
+	buf.Reset()
+	check(t, enc.WriteArrayStart())
+	check(t, enc.WriteString("x<y"))
+	check(t, enc.WriteBool(true))
+	check(t, enc.WriteRaw([]byte(`{"raw":[]}`)))
+	check(t, enc.WriteValue(map[string]int{"v": 2}))
+	check(t, enc.WriteObjectStart())
+	check(t, enc.WriteObjectEnd())
+	check(t, enc.WriteArrayEnd())
+	check(t, enc.WriteInt(7))
+	check(t, enc.Encode("encoded"))
+	want = `["x\u003cy",true,{"raw":[]},{"v":2},{}]` + "\n7\n\"encoded\"\n"
+	if buf.String() != want {
+		t.Fatalf("expected: %q, got: %q", want, buf.String())
+	}
+}
+
+func TestEncoderWriterInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls func(enc *Encoder) error
+	}{
+		{"two keys", func(enc *Encoder) error {
+			enc.WriteObjectStart()
+			enc.WriteKey("a")
+			return enc.WriteKey("b")
+		}},
+		{"value without key", func(enc *Encoder) error {
+			enc.WriteObjectStart()
+			return enc.WriteInt(1)
+		}},
+		{"key in array", func(enc *Encoder) error {
+			enc.WriteArrayStart()
+			return enc.WriteKey("a")
+		}},
+		{"key at top level", func(enc *Encoder) error {
+			return enc.WriteKey("a")
+		}},
+		{"object end for array", func(enc *Encoder) error {
+			enc.WriteArrayStart()
+			return enc.WriteObjectEnd()
+		}},
+		{"array end for object", func(enc *Encoder) error {
+			enc.WriteObjectStart()
+			return enc.WriteArrayEnd()
+		}},
+		{"object end after key", func(enc *Encoder) error {
+			enc.WriteObjectStart()
+			enc.WriteKey("a")
+			return enc.WriteObjectEnd()
+		}},
+		{"unbalanced end", func(enc *Encoder) error {
+			return enc.WriteArrayEnd()
+		}},
+		{"NaN", func(enc *Encoder) error {
+			return enc.WriteFloat(math.NaN())
+		}},
+		{"raw garbage", func(enc *Encoder) error {
+			return enc.WriteRaw([]byte(`{"a":`))
+		}},
+		{"raw values", func(enc *Encoder) error {
+			return enc.WriteRaw([]byte(`1 2`))
+		}},
+		{"raw empty", func(enc *Encoder) error {
+			return enc.WriteRaw(nil)
+		}},
+		{"raw byte order mark", func(enc *Encoder) error {
+			return enc.WriteRaw([]byte("\xef\xbb\xbf1"))
+		}},
+		{"Encode inside document", func(enc *Encoder) error {
+			enc.WriteArrayStart()
+			return enc.Encode(1)
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.calls(NewEncoder(new(bytes.Buffer))); err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+		})
+	}
+
+	// an invalid raw value leaves the document as it was.
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	check(t, enc.WriteArrayStart())
+	var serr *SyntaxError
+	if err := enc.WriteRaw([]byte(`[1] x`)); !errors.As(err, &serr) {
+		t.Fatalf("WriteRaw: got %v, want a *SyntaxError", err)
+	}
+	check(t, enc.WriteRaw([]byte(` {"a": [1, 2]} `)))
+	check(t, enc.WriteArrayEnd())
+	if got, want := buf.String(), "[ {\"a\": [1, 2]} ]\n"; got != want {
+		t.Fatalf("expected: %q, got: %q", want, got)
+	}
+}
+
+func TestEncoderWriterFlush(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	check(t, enc.WriteArrayStart())
+	s := strings.Repeat("x", 100)
+	for i := 0; i < 1000; i++ {
+		check(t, enc.WriteString(s))
+	}
+	if buf.Len() == 0 {
+		t.Fatalf("expected large documents to be flushed incrementally")
+	}
+	check(t, enc.WriteArrayEnd())
+
+	var v []string
+	if err := Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(v) != 1000 {
+		t.Fatalf("expected 1000 elements, got %v", len(v))
+	}
+}
+
+// copyTokens writes every token of src to enc.
+func copyTokens(enc *Encoder, src TokenDecoder) error {
+	for {
+		t, err := src.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := enc.WriteToken(t); err != nil {
+			return err
+		}
+	}
+}
+
+// allTokens returns the tokens of src.
+func allTokens(src TokenDecoder) ([]Token, error) {
+	var toks []Token
+	for {
+		t, err := src.Token()
+		if err == io.EOF {
+			return toks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, t)
+	}
+}
+
+func TestEncoderWriteTokenRoundTrip(t *testing.T) {
+	for _, tc := range inputs {
+		t.Run(tc.path, func(t *testing.T) {
+			data, err := io.ReadAll(fixture(t, tc.path))
+			check(t, err)
+
+			// encoding/json tokens, written by this package and read back
+			// by both decoders.
+			old := json.NewDecoder(bytes.NewReader(data))
+			old.UseNumber()
+			var buf bytes.Buffer
+			check(t, copyTokens(NewEncoder(&buf), old))
+
+			oldDec := json.NewDecoder(bytes.NewReader(data))
+			oldDec.UseNumber()
+			want, err := allTokens(oldDec)
+			check(t, err)
+			d := NewDecoder(buf.Bytes())
+			d.UseNumber()
+			got, err := allTokens(d)
+			check(t, err)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("tokens differ after a round trip through WriteToken")
+			}
+
+			// and this package's tokens, written back and decoded by
+			// encoding/json.
+			var buf2 bytes.Buffer
+			check(t, copyTokens(NewEncoder(&buf2), NewDecoder(data)))
+			var v1, v2 interface{}
+			check(t, json.Unmarshal(data, &v1))
+			check(t, json.Unmarshal(buf2.Bytes(), &v2))
+			if !reflect.DeepEqual(v1, v2) {
+				t.Fatalf("values differ after a round trip through WriteToken")
+			}
+
+			// Both decoders are at the same offset after each token.
+			var a, b TokenDecoder = json.NewDecoder(bytes.NewReader(data)), NewDecoder(data)
+			for n := 0; ; n++ {
+				_, erra := a.Token()
+				_, errb := b.Token()
+				if erra == io.EOF && errb == io.EOF {
+					break
+				}
+				if erra != nil || errb != nil {
+					t.Fatalf("Token %d: %v, %v", n, erra, errb)
+				}
+				if a.InputOffset() != b.InputOffset() || a.More() != b.More() {
+					t.Fatalf("InputOffset: got %d, want %d", b.InputOffset(), a.InputOffset())
+				}
+			}
+		})
+	}
+}
+
+func TestEncoderWriteToken(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, tok := range []Token{Delim('{'), "a", Delim('['), "b", 1.5, Number("-2e3"), true, nil, Delim(']'), "c", Delim('{'), Delim('}'), Delim('}')} {
+		check(t, enc.WriteToken(tok))
+	}
+	want := `{"a":["b",1.5,-2e3,true,null],"c":{}}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	for _, tok := range []Token{Delim('x'), Number(""), Number("1 2"), Number(" 1"), Number("01"), Number("1."), int(1), Delim('}')} {
+		if err := NewEncoder(new(bytes.Buffer)).WriteToken(tok); err == nil {
+			t.Errorf("WriteToken(%#v): got nil error", tok)
+		}
+	}
+}
+
+func TestEncoderWriterIndent(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetIndent("#", "  ")
+	check(t, enc.WriteObjectStart())
+	check(t, enc.WriteKey("a"))
+	check(t, enc.WriteArrayStart())
+	check(t, enc.WriteInt(1))
+	check(t, enc.WriteValue(map[string][]int{"b": {2}}))
+	check(t, enc.WriteArrayStart())
+	check(t, enc.WriteArrayEnd())
+	check(t, enc.WriteArrayEnd())
+	check(t, enc.WriteKey("c"))
+	check(t, enc.WriteObjectStart())
+	check(t, enc.WriteObjectEnd())
+	check(t, enc.WriteObjectEnd())
+	check(t, enc.WriteInt(3))
+	want := "{\n#  \"a\": [\n#    1,\n#    {\n#      \"b\": [\n#        2\n#      ]\n#    },\n#    []\n#  ],\n#  \"c\": {}\n#}\n3\n"
+	if buf.String() != want {
+		t.Fatalf("expected: %q, got: %q", want, buf.String())
+	}
+}